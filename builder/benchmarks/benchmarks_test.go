@@ -23,7 +23,7 @@ func BenchmarkSearch(b *testing.B) {
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				_ = search.PerformSearch(index, "test query", "")
+				_ = search.PerformSearch(index, "test query", "", "")
 			}
 		})
 	}
@@ -35,7 +35,7 @@ func BenchmarkSearchWithTagFilter(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_ = search.PerformSearch(index, "tag:go test query", "")
+		_ = search.PerformSearch(index, "tag:go test query", "", "")
 	}
 }
 
@@ -0,0 +1,84 @@
+// Package bibliography loads BibTeX and CSL-JSON bibliographies and formats
+// citations and reference lists in APA or IEEE style
+package bibliography
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Entry types every format is normalized to
+const (
+	TypeArticle       = "article"
+	TypeBook          = "book"
+	TypeInProceedings = "inproceedings" // Conference papers and book chapters
+	TypeMisc          = "misc"
+)
+
+// Name is an author. Literal holds names that are not split, like organisations.
+type Name struct {
+	Family  string
+	Given   string
+	Literal string
+}
+
+// Entry is one bibliography record
+type Entry struct {
+	Key       string
+	Type      string
+	Title     string
+	Authors   []Name
+	Year      string
+	Container string // Journal, proceedings or book title
+	Volume    string
+	Issue     string
+	Pages     string
+	Publisher string
+	DOI       string
+	URL       string
+}
+
+// Library holds the entries of a bibliography file by key
+type Library struct {
+	entries map[string]*Entry
+}
+
+// Get returns the entry with key
+func (l *Library) Get(key string) (*Entry, bool) {
+	if l == nil {
+		return nil, false
+	}
+	e, ok := l.entries[key]
+	return e, ok
+}
+
+// Len returns the number of entries
+func (l *Library) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.entries)
+}
+
+func (l *Library) add(e *Entry) {
+	if l.entries == nil {
+		l.entries = make(map[string]*Entry)
+	}
+	if _, ok := l.entries[e.Key]; !ok && e.Key != "" { // The first definition wins, as in BibTeX
+		l.entries[e.Key] = e
+	}
+}
+
+// Parse reads a bibliography file, picking the format from its extension:
+// .bib for BibTeX, .json for CSL-JSON
+func Parse(name string, data []byte) (*Library, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".bib", ".bibtex":
+		return ParseBibTeX(data)
+	case ".json":
+		return ParseCSLJSON(data)
+	default:
+		return nil, fmt.Errorf("unsupported bibliography format %q (use .bib or .json)", filepath.Ext(name))
+	}
+}
@@ -0,0 +1,194 @@
+package bibliography
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBibTeX(t *testing.T) {
+	src := `% A comment with a stray @ sign
+@string{nips = "Advances in Neural " # "Information Processing Systems"}
+@comment{ignored, entirely}
+@inproceedings{vaswani2017,
+  title     = {Attention Is {All} You Need},
+  author    = {Vaswani, Ashish and Shazeer, Noam and
+               Jean-Paul van der Berg and {World Health Organization}},
+  booktitle = nips,
+  pages     = {5998--6008},
+  year      = 2017,
+  doi       = {10.5555/3295222.3295349},
+}
+@article(erdos1950,
+  title = "On a problem of Gr{\"o}tzsch --- and \'{E}mile \c{c}a",
+  author = "Erd\H{o}s, P{\'a}l",
+  journal = {J. Math},
+  year = {1950}
+)
+@book{vaswani2017, title = {Duplicate}}`
+
+	lib, err := ParseBibTeX([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseBibTeX() error = %v", err)
+	}
+	if lib.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", lib.Len())
+	}
+
+	e, ok := lib.Get("vaswani2017")
+	if !ok {
+		t.Fatal("vaswani2017 not found")
+	}
+	want := &Entry{
+		Key:   "vaswani2017",
+		Type:  TypeInProceedings,
+		Title: "Attention Is All You Need",
+		Authors: []Name{
+			{Family: "Vaswani", Given: "Ashish"},
+			{Family: "Shazeer", Given: "Noam"},
+			{Family: "van der Berg", Given: "Jean-Paul"},
+			{Literal: "World Health Organization"},
+		},
+		Year:      "2017",
+		Container: "Advances in Neural Information Processing Systems",
+		Pages:     "5998–6008",
+		DOI:       "10.5555/3295222.3295349",
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("vaswani2017 = %+v\nwant %+v", e, want)
+	}
+
+	e, _ = lib.Get("erdos1950")
+	if e.Title != "On a problem of Grötzsch — and Émile ça" {
+		t.Errorf("Title = %q", e.Title)
+	}
+	if want := []Name{{Family: "Erdős", Given: "Pál"}}; !reflect.DeepEqual(e.Authors, want) {
+		t.Errorf("Authors = %+v, want %+v", e.Authors, want)
+	}
+	if e.Type != TypeArticle || e.Container != "J. Math" {
+		t.Errorf("Type, Container = %q, %q", e.Type, e.Container)
+	}
+}
+
+func TestParseBibTeXErrors(t *testing.T) {
+	for _, src := range []string{
+		"@article{key, title = {Unclosed}",
+		"@article{key, title {No equals}}",
+		"@article{key, title = {Brace}",
+	} {
+		if _, err := ParseBibTeX([]byte(src)); err == nil {
+			t.Errorf("ParseBibTeX(%q) should fail", src)
+		}
+	}
+}
+
+func TestLatexText(t *testing.T) {
+	tests := map[string]string{
+		`Schr{\"o}dinger`:         "Schrödinger",
+		`\'{\i}ndice`:             "índice",
+		`Stra\ss e`:               "Straße",
+		`\emph{Deep} Learning`:    "Deep Learning",
+		`The \TeX book`:           "The TeXbook",
+		`10\% of R\&D`:            "10% of R&D",
+		"pages 1--2, em---dash":   "pages 1–2, em—dash",
+		"  spread \n  over~lines": "spread over lines",
+	}
+	for in, want := range tests {
+		if got := latexText(in); got != want {
+			t.Errorf("latexText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseCSLJSON(t *testing.T) {
+	src := `[
+	  {"id": "he2016", "type": "paper-conference", "title": "Deep Residual Learning",
+	   "author": [{"family": "He", "given": "Kaiming"}, {"literal": "OpenAI"}],
+	   "issued": {"date-parts": [[2016, 6]]}, "container-title": "CVPR", "page": "770-778"},
+	  {"id": 42, "type": "webpage", "title": "A Page", "issued": {"raw": "2020-01-02"}, "volume": 3}
+	]`
+	lib, err := ParseCSLJSON([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseCSLJSON() error = %v", err)
+	}
+	e, _ := lib.Get("he2016")
+	want := &Entry{
+		Key: "he2016", Type: TypeInProceedings, Title: "Deep Residual Learning",
+		Authors: []Name{{Family: "He", Given: "Kaiming"}, {Literal: "OpenAI"}},
+		Year:    "2016", Container: "CVPR", Pages: "770–778",
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("he2016 = %+v\nwant %+v", e, want)
+	}
+	e, ok := lib.Get("42")
+	if !ok || e.Type != TypeMisc || e.Year != "2020" || e.Volume != "3" {
+		t.Errorf("42 = %+v", e)
+	}
+
+	if _, err := ParseCSLJSON([]byte(`{"id": "x"}`)); err == nil {
+		t.Error("ParseCSLJSON() should reject a non-array")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if lib, err := Parse("refs.BIB", []byte("@misc{a, title={A}}")); err != nil || lib.Len() != 1 {
+		t.Errorf("Parse(.BIB) = %v, %v", lib, err)
+	}
+	if lib, err := Parse("refs.json", []byte(`[{"id": "a"}]`)); err != nil || lib.Len() != 1 {
+		t.Errorf("Parse(.json) = %v, %v", lib, err)
+	}
+	if _, err := Parse("refs.ris", nil); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Parse(.ris) error = %v", err)
+	}
+}
+
+func TestStyles(t *testing.T) {
+	article := &Entry{
+		Key: "a", Type: TypeArticle, Title: "Learning things",
+		Authors: []Name{{Family: "Smith", Given: "Jane Ann"}, {Family: "Doe", Given: "John"}},
+		Year:    "2020", Container: "Journal of Things", Volume: "12", Issue: "3", Pages: "1–10",
+		DOI: "10.1/x",
+	}
+	book := &Entry{Key: "b", Type: TypeBook, Title: "A Book?", Authors: []Name{{Family: "Lee", Given: "Ann"}}, Publisher: "Press"}
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"apa label", APA.Label(article, 1, "", false), "Smith & Doe, 2020"},
+		{"apa label locator", APA.Label(book, 1, "ch. 2", false), "Lee, n.d., ch. 2"},
+		{"apa label suppress author", APA.Label(article, 1, "", true), "2020"},
+		{"ieee label", IEEE.Label(article, 3, "p. 4", true), "3, p. 4"},
+		{
+			"apa article", APA.Reference(article),
+			`Smith, J. A., &amp; Doe, J. (2020). Learning things. <em>Journal of Things</em>, <em>12</em>(3), 1–10. <a href="https://doi.org/10.1/x">https://doi.org/10.1/x</a>`,
+		},
+		{"apa book", APA.Reference(book), "Lee, A. (n.d.). <em>A Book?</em> Press."},
+		{
+			"ieee article", IEEE.Reference(article),
+			`J. A. Smith and J. Doe, “Learning things,” <em>Journal of Things</em>, vol. 12, no. 3, pp. 1–10, 2020, doi: <a href="https://doi.org/10.1/x">10.1/x</a>.`,
+		},
+		{"ieee book", IEEE.Reference(book), "A. Lee, <em>A Book?</em> Press."},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q\nwant %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	entries := []*Entry{article, book}
+	APA.Sort(entries)
+	if entries[0] != book {
+		t.Error("APA.Sort() should order by author")
+	}
+	IEEE.Sort(entries)
+	if entries[0] != book {
+		t.Error("IEEE.Sort() should keep citation order")
+	}
+
+	if s, ok := ParseStyle(" IEEE "); !ok || s != IEEE {
+		t.Errorf("ParseStyle(IEEE) = %q, %v", s, ok)
+	}
+	if _, ok := ParseStyle("chicago"); ok {
+		t.Error("ParseStyle(chicago) should fail")
+	}
+}
@@ -0,0 +1,374 @@
+package bibliography
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ParseBibTeX parses a BibTeX file. @string macros and # concatenation are
+// supported; @comment and @preamble are skipped.
+func ParseBibTeX(data []byte) (*Library, error) {
+	p := &bibParser{src: string(data), macros: map[string]string{}}
+	lib := &Library{}
+	for {
+		i := strings.IndexByte(p.src[p.pos:], '@')
+		if i < 0 {
+			return lib, nil
+		}
+		p.pos += i + 1
+		typ := strings.ToLower(p.ident())
+		p.skipSpace()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '{' && p.src[p.pos] != '(') {
+			continue // A stray @, e.g. in a comment
+		}
+		closer := byte('}')
+		if p.src[p.pos] == '(' {
+			closer = ')'
+		}
+		p.pos++
+
+		switch typ {
+		case "comment", "preamble":
+			if err := p.skipGroup(closer); err != nil {
+				return nil, err
+			}
+		case "string":
+			fields, err := p.fields(closer)
+			if err != nil {
+				return nil, err
+			}
+			for name, v := range fields {
+				p.macros[name] = v
+			}
+		default:
+			p.skipSpace()
+			key := strings.TrimSpace(p.until("," + string(closer)))
+			if p.pos < len(p.src) && p.src[p.pos] == ',' {
+				p.pos++
+			}
+			fields, err := p.fields(closer)
+			if err != nil {
+				return nil, fmt.Errorf("bibtex entry %q: %w", key, err)
+			}
+			lib.add(bibEntry(key, typ, fields))
+		}
+	}
+}
+
+// bibEntry maps BibTeX fields to an Entry
+func bibEntry(key, typ string, f map[string]string) *Entry {
+	e := &Entry{
+		Key:       key,
+		Title:     latexText(f["title"]),
+		Authors:   bibNames(f["author"]),
+		Year:      latexText(f["year"]),
+		Volume:    latexText(f["volume"]),
+		Issue:     latexText(f["number"]),
+		Pages:     latexText(f["pages"]),
+		Publisher: latexText(firstOf(f, "publisher", "organization", "institution", "school", "howpublished")),
+		DOI:       strings.TrimSpace(f["doi"]),
+		URL:       strings.TrimSpace(f["url"]),
+	}
+	if e.Year == "" && len(f["date"]) >= 4 {
+		e.Year = f["date"][:4]
+	}
+	switch typ {
+	case "article":
+		e.Type = TypeArticle
+		e.Container = latexText(firstOf(f, "journal", "journaltitle"))
+	case "book":
+		e.Type = TypeBook
+	case "inproceedings", "conference", "incollection", "inbook":
+		e.Type = TypeInProceedings
+		e.Container = latexText(f["booktitle"])
+	default:
+		e.Type = TypeMisc
+	}
+	return e
+}
+
+func firstOf(f map[string]string, names ...string) string {
+	for _, name := range names {
+		if v := f[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// bibParser is a cursor over BibTeX source
+type bibParser struct {
+	src    string
+	pos    int
+	macros map[string]string
+}
+
+func (p *bibParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *bibParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n{}(),=#\"", p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// until reads up to the first of the stop characters
+func (p *bibParser) until(stops string) string {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(stops, p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// skipGroup skips to the closer of the current entry
+func (p *bibParser) skipGroup(closer byte) error {
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == closer && depth == 0:
+			p.pos++
+			return nil
+		}
+	}
+	return fmt.Errorf("unterminated entry")
+}
+
+// fields reads "name = value" pairs up to the closer of the entry
+func (p *bibParser) fields(closer byte) (map[string]string, error) {
+	fields := map[string]string{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("unterminated entry")
+		}
+		if p.src[p.pos] == closer {
+			p.pos++
+			return fields, nil
+		}
+		if p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		name := strings.ToLower(p.ident())
+		p.skipSpace()
+		if name == "" || p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			return nil, fmt.Errorf("expected field at offset %d", p.pos)
+		}
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		fields[name] = value
+	}
+}
+
+// value reads a field value: {braced}, "quoted", a number or a macro, joined by #
+func (p *bibParser) value() (string, error) {
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("missing value")
+		}
+		switch p.src[p.pos] {
+		case '{':
+			s, err := p.delimited('{', '}')
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case '"':
+			s, err := p.delimited('"', '"')
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		default:
+			word := p.ident()
+			if word == "" {
+				return "", fmt.Errorf("missing value")
+			}
+			if v, ok := p.macros[strings.ToLower(word)]; ok {
+				word = v
+			}
+			b.WriteString(word)
+		}
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '#' {
+			p.pos++
+			continue
+		}
+		return b.String(), nil
+	}
+}
+
+// delimited reads a {…} or "…" value, keeping nested braces
+func (p *bibParser) delimited(open, close byte) (string, error) {
+	p.pos++ // open
+	start := p.pos
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == '\\':
+			p.pos++
+		case c == '{' && open == '"':
+			depth++
+		case c == '{' && open == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == close && depth == 0:
+			s := p.src[start:p.pos]
+			p.pos++
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unterminated value")
+}
+
+// bibNames splits an author field on "and" outside braces
+func bibNames(field string) []Name {
+	var names []Name
+	field = strings.Join(strings.Fields(field), " ")
+	for _, part := range splitTopLevel(field, " and ") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		names = append(names, bibName(part))
+	}
+	return names
+}
+
+// bibName parses "Family, Given", "Family, Jr, Given" or "Given von Family".
+// A name wrapped in braces, like {World Health Organization}, is kept whole.
+func bibName(s string) Name {
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") && len(splitTopLevel(s, " ")) == 1 {
+		return Name{Literal: latexText(s)}
+	}
+	if parts := splitTopLevel(s, ","); len(parts) > 1 {
+		return Name{Family: latexText(parts[0]), Given: latexText(parts[len(parts)-1])}
+	}
+	words := strings.Fields(s)
+	if len(words) == 1 {
+		return Name{Family: latexText(words[0])}
+	}
+	// The family name starts at the first lowercase word ("van der Berg"), or is the last word
+	family := len(words) - 1
+	for i := 1; i < len(words)-1; i++ {
+		if r := []rune(strings.TrimLeft(words[i], "{")); len(r) > 0 && unicode.IsLower(r[0]) {
+			family = i
+			break
+		}
+	}
+	return Name{
+		Family: latexText(strings.Join(words[family:], " ")),
+		Given:  latexText(strings.Join(words[:family], " ")),
+	}
+}
+
+// splitTopLevel splits s on sep outside braces
+func splitTopLevel(s, sep string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 && strings.HasPrefix(s[i:], sep) {
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// latexAccents maps LaTeX accent commands to combining characters
+var latexAccents = map[byte]rune{
+	'"': '̈', '\'': '́', '`': '̀', '^': '̂', '~': '̃',
+	'=': '̄', '.': '̇', 'c': '̧', 'v': '̌', 'u': '̆', 'H': '̋',
+}
+
+// latexSymbols maps LaTeX commands without arguments to text
+var latexSymbols = map[string]string{
+	"&": "&", "%": "%", "$": "$", "#": "#", "_": "_", "{": "{", "}": "}",
+	"ss": "ß", "o": "ø", "O": "Ø", "ae": "æ", "AE": "Æ", "aa": "å", "AA": "Å", "l": "ł", "L": "Ł", "i": "ı",
+}
+
+// latexText turns a BibTeX value into plain text: accents become Unicode,
+// braces and formatting commands are dropped and dashes are typeset
+func latexText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '{' || c == '}':
+		case c == '~':
+			b.WriteRune(' ')
+		case c == '\\' && i+1 < len(s):
+			i++
+			if accent, ok := latexAccents[s[i]]; ok && (!isLetter(s[i]) || i+1 < len(s) && !isLetter(s[i+1])) {
+				// \"o, \"{o}, \c{c}, \c c
+				j := i + 1
+				for j < len(s) && (s[j] == '{' || s[j] == ' ') {
+					j++
+				}
+				if j < len(s) {
+					letter := s[j]
+					if letter == '\\' && j+1 < len(s) { // \'{\i}: accents go on the dotted letter
+						j++
+						letter = s[j]
+					}
+					b.WriteByte(letter)
+					b.WriteRune(accent)
+					i = j
+				}
+				continue
+			}
+			// \command or \symbol
+			j := i
+			for j < len(s) && isLetter(s[j]) {
+				j++
+			}
+			if j == i {
+				j = i + 1
+			}
+			if sym, ok := latexSymbols[s[i:j]]; ok {
+				b.WriteString(sym)
+			} else if j < len(s) && s[j] != '{' {
+				b.WriteString(s[i:j]) // Logos like \TeX keep their name
+			}
+			// Formatting commands like \emph{…} keep their argument
+			i = j - 1
+			if j < len(s) && s[j] == ' ' && isLetter(s[i]) {
+				i = j
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	text := strings.NewReplacer("---", "—", "--", "–").Replace(b.String())
+	return strings.Join(strings.Fields(norm.NFC.String(text)), " ")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,97 @@
+package bibliography
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cslItem is the subset of CSL-JSON the styles use
+type cslItem struct {
+	ID        interface{} `json:"id"`
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Author    []cslName   `json:"author"`
+	Issued    cslDate     `json:"issued"`
+	Container string      `json:"container-title"`
+	Volume    interface{} `json:"volume"`
+	Issue     interface{} `json:"issue"`
+	Page      interface{} `json:"page"`
+	Publisher string      `json:"publisher"`
+	DOI       string      `json:"DOI"`
+	URL       string      `json:"URL"`
+}
+
+type cslName struct {
+	Family  string `json:"family"`
+	Given   string `json:"given"`
+	Literal string `json:"literal"`
+}
+
+type cslDate struct {
+	DateParts [][]interface{} `json:"date-parts"`
+	Literal   string          `json:"literal"`
+	Raw       string          `json:"raw"`
+}
+
+// ParseCSLJSON parses a CSL-JSON array of items, as exported by Zotero
+func ParseCSLJSON(data []byte) (*Library, error) {
+	var items []cslItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("csl-json: %w", err)
+	}
+	lib := &Library{}
+	for _, item := range items {
+		e := &Entry{
+			Key:       cslString(item.ID),
+			Title:     item.Title,
+			Year:      item.Issued.year(),
+			Container: item.Container,
+			Volume:    cslString(item.Volume),
+			Issue:     cslString(item.Issue),
+			Pages:     strings.ReplaceAll(cslString(item.Page), "-", "–"),
+			Publisher: item.Publisher,
+			DOI:       item.DOI,
+			URL:       item.URL,
+		}
+		for _, n := range item.Author {
+			e.Authors = append(e.Authors, Name(n))
+		}
+		switch item.Type {
+		case "article-journal", "article-magazine", "article-newspaper", "article":
+			e.Type = TypeArticle
+		case "book":
+			e.Type = TypeBook
+		case "paper-conference", "chapter":
+			e.Type = TypeInProceedings
+		default:
+			e.Type = TypeMisc
+		}
+		lib.add(e)
+	}
+	return lib, nil
+}
+
+func (d cslDate) year() string {
+	if len(d.DateParts) > 0 && len(d.DateParts[0]) > 0 {
+		return cslString(d.DateParts[0][0])
+	}
+	for _, s := range []string{d.Literal, d.Raw} {
+		if len(s) >= 4 {
+			return s[:4]
+		}
+	}
+	return ""
+}
+
+// cslString formats the string or number values CSL-JSON allows
+func cslString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
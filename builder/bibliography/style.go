@@ -0,0 +1,312 @@
+package bibliography
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Style is a citation style
+type Style string
+
+const (
+	APA  Style = "apa"  // (Vaswani et al., 2017); references sorted by author
+	IEEE Style = "ieee" // [1]; references numbered in order of first citation
+)
+
+// DefaultStyle is used when no style is configured
+const DefaultStyle = APA
+
+// ParseStyle returns the style named s, or false for unknown names
+func ParseStyle(s string) (Style, bool) {
+	switch Style(strings.ToLower(strings.TrimSpace(s))) {
+	case APA:
+		return APA, true
+	case IEEE:
+		return IEEE, true
+	}
+	return "", false
+}
+
+// Numbered reports whether the style cites by reference number
+func (s Style) Numbered() bool { return s == IEEE }
+
+// Delimiters returns the brackets around a citation and the separator between its items
+func (s Style) Delimiters() (open, sep, close string) {
+	if s.Numbered() {
+		return "[", ", ", "]"
+	}
+	return "(", "; ", ")"
+}
+
+// Label returns the text of one cited item: "Vaswani et al., 2017" or "1".
+// locator, e.g. "p. 5", is appended. suppressAuthor leaves only the year in
+// author-date styles, for citations whose sentence already names the author.
+func (s Style) Label(e *Entry, number int, locator string, suppressAuthor bool) string {
+	var label string
+	switch {
+	case s.Numbered():
+		label = fmt.Sprint(number)
+	case suppressAuthor:
+		label = yearOrND(e.Year)
+	default:
+		label = citeAuthors(e.Authors, e.Title) + ", " + yearOrND(e.Year)
+	}
+	if locator != "" {
+		label += ", " + locator
+	}
+	return label
+}
+
+// Sort orders cited entries for the reference list: by citation order for
+// numbered styles, by author and year otherwise
+func (s Style) Sort(entries []*Entry) {
+	if s.Numbered() {
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := sortKey(entries[i]), sortKey(entries[j])
+		return a < b
+	})
+}
+
+// Reference formats an entry of the reference list as HTML
+func (s Style) Reference(e *Entry) string {
+	if s.Numbered() {
+		return ieeeReference(e)
+	}
+	return apaReference(e)
+}
+
+func sortKey(e *Entry) string {
+	author := e.Title
+	if len(e.Authors) > 0 {
+		author = nameFamily(e.Authors[0])
+	}
+	return strings.ToLower(author) + "\x00" + e.Year + "\x00" + strings.ToLower(e.Title)
+}
+
+// citeAuthors is the author part of an author-date citation
+func citeAuthors(authors []Name, title string) string {
+	switch len(authors) {
+	case 0:
+		return title
+	case 1:
+		return nameFamily(authors[0])
+	case 2:
+		return nameFamily(authors[0]) + " & " + nameFamily(authors[1])
+	}
+	return nameFamily(authors[0]) + " et al."
+}
+
+func nameFamily(n Name) string {
+	if n.Literal != "" {
+		return n.Literal
+	}
+	return n.Family
+}
+
+func yearOrND(year string) string {
+	if year == "" {
+		return "n.d."
+	}
+	return year
+}
+
+// initials abbreviates given names: "Ashish" → "A.", "Jean-Paul" → "J.-P."
+func initials(given string) string {
+	var parts []string
+	for _, word := range strings.Fields(given) {
+		var hyphenated []string
+		for _, part := range strings.Split(word, "-") {
+			if r, _ := utf8.DecodeRuneInString(part); r != utf8.RuneError && unicode.IsLetter(r) {
+				hyphenated = append(hyphenated, string(r)+".")
+			}
+		}
+		if len(hyphenated) > 0 {
+			parts = append(parts, strings.Join(hyphenated, "-"))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// joinNames joins names as "A, B, and C" (IEEE) or "A, B, & C" (APA)
+func joinNames(names []string, and string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		if and == "&" { // APA keeps the comma before & for two authors too
+			return names[0] + ", & " + names[1]
+		}
+		return names[0] + " " + and + " " + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", " + and + " " + names[len(names)-1]
+}
+
+// sentence ends s with a period unless it already ends with punctuation
+func sentence(s string) string {
+	if s == "" || strings.ContainsAny(s[len(s)-1:], ".?!") {
+		return s
+	}
+	return s + "."
+}
+
+func esc(s string) string { return html.EscapeString(s) }
+
+func link(url, text string) string {
+	return `<a href="` + esc(url) + `">` + esc(text) + `</a>`
+}
+
+// apaReference formats an entry in APA 7 style
+func apaReference(e *Entry) string {
+	var names []string
+	for _, n := range e.Authors {
+		if n.Literal != "" || n.Given == "" {
+			names = append(names, nameFamily(n))
+		} else {
+			names = append(names, n.Family+", "+initials(n.Given))
+		}
+	}
+	if len(names) > 20 { // APA lists the first 19 and the last author
+		names = append(names[:19], "… "+names[len(names)-1])
+	}
+
+	var b strings.Builder
+	if len(names) > 0 {
+		b.WriteString(esc(sentence(joinNames(names, "&"))) + " ")
+	}
+	b.WriteString("(" + esc(yearOrND(e.Year)) + "). ")
+
+	switch e.Type {
+	case TypeArticle:
+		b.WriteString(esc(sentence(e.Title)))
+		if e.Container != "" {
+			b.WriteString(" <em>" + esc(e.Container) + "</em>")
+			if e.Volume != "" {
+				b.WriteString(", <em>" + esc(e.Volume) + "</em>")
+			}
+			if e.Issue != "" {
+				b.WriteString("(" + esc(e.Issue) + ")")
+			}
+			if e.Pages != "" {
+				b.WriteString(", " + esc(e.Pages))
+			}
+			b.WriteString(".")
+		}
+	case TypeInProceedings:
+		b.WriteString(esc(sentence(e.Title)))
+		if e.Container != "" {
+			b.WriteString(" In <em>" + esc(e.Container) + "</em>")
+			if e.Pages != "" {
+				b.WriteString(" (pp. " + esc(e.Pages) + ")")
+			}
+			b.WriteString(".")
+		}
+		if e.Publisher != "" {
+			b.WriteString(" " + esc(sentence(e.Publisher)))
+		}
+	default:
+		b.WriteString("<em>" + esc(e.Title) + "</em>")
+		if sentence(e.Title) != e.Title {
+			b.WriteString(".")
+		}
+		if e.Publisher != "" {
+			b.WriteString(" " + esc(sentence(e.Publisher)))
+		}
+	}
+
+	switch {
+	case e.DOI != "":
+		b.WriteString(" " + link("https://doi.org/"+e.DOI, "https://doi.org/"+e.DOI))
+	case e.URL != "":
+		b.WriteString(" " + link(e.URL, e.URL))
+	}
+	return b.String()
+}
+
+// ieeeReference formats an entry in IEEE style
+func ieeeReference(e *Entry) string {
+	var names []string
+	for _, n := range e.Authors {
+		if n.Literal != "" || n.Given == "" {
+			names = append(names, nameFamily(n))
+		} else {
+			names = append(names, initials(n.Given)+" "+n.Family)
+		}
+	}
+	authors := joinNames(names, "and")
+	if len(names) > 6 {
+		authors = names[0] + " et al."
+	}
+
+	var parts []string // Comma-separated parts after the title
+	var b strings.Builder
+	if authors != "" {
+		b.WriteString(esc(authors) + ", ")
+	}
+
+	switch e.Type {
+	case TypeBook, TypeMisc:
+		b.WriteString("<em>" + esc(e.Title) + "</em>")
+		if e.Publisher != "" {
+			sep := ". "
+			if sentence(e.Title) == e.Title { // The title ends with its own punctuation
+				sep = " "
+			}
+			b.WriteString(sep + esc(e.Publisher))
+		}
+	default:
+		b.WriteString("“" + esc(e.Title) + ",”")
+		if e.Container != "" {
+			in := ""
+			if e.Type == TypeInProceedings {
+				in = "in "
+			}
+			parts = append(parts, in+"<em>"+esc(e.Container)+"</em>")
+		}
+		if e.Volume != "" {
+			parts = append(parts, "vol. "+esc(e.Volume))
+		}
+		if e.Issue != "" {
+			parts = append(parts, "no. "+esc(e.Issue))
+		}
+	}
+	if e.Type == TypeArticle && e.Pages != "" {
+		parts = append(parts, pagesLabel(e.Pages))
+	}
+	if e.Year != "" {
+		parts = append(parts, esc(e.Year))
+	}
+	if e.Type == TypeInProceedings && e.Pages != "" {
+		parts = append(parts, pagesLabel(e.Pages))
+	}
+	if e.DOI != "" {
+		parts = append(parts, "doi: "+link("https://doi.org/"+e.DOI, e.DOI))
+	}
+
+	if len(parts) > 0 {
+		sep := ", "
+		if strings.HasSuffix(b.String(), ",”") {
+			sep = " "
+		}
+		b.WriteString(sep + strings.Join(parts, ", "))
+	}
+	b.WriteString(".")
+	if e.DOI == "" && e.URL != "" {
+		b.WriteString(" [Online]. Available: " + link(e.URL, e.URL))
+	}
+	return b.String()
+}
+
+func pagesLabel(pages string) string {
+	if strings.ContainsAny(pages, "–-,") {
+		return "pp. " + esc(pages)
+	}
+	return "p. " + esc(pages)
+}
@@ -127,6 +127,16 @@ func (m *Manager) initSchema() error {
 			}
 		}
 
+		// Backfill the inverted and trigram indexes for caches written before they were maintained
+		terms, grams := tx.Bucket([]byte(BucketSearchTerms)), tx.Bucket([]byte(BucketSearchGrams))
+		if terms.Stats().KeyN == 0 || grams.Stats().KeyN == 0 {
+			if k, _ := tx.Bucket([]byte(BucketSearch)).Cursor().First(); k != nil {
+				if err := rebuildSearchTerms(tx); err != nil {
+					return fmt.Errorf("failed to rebuild search index: %w", err)
+				}
+			}
+		}
+
 		meta := tx.Bucket([]byte(BucketMeta))
 		if meta.Get([]byte(KeySchemaVersion)) == nil {
 			v := make([]byte, 4)
@@ -213,6 +223,7 @@ type EncodedPost struct {
 	Data       []byte
 	Path       []byte
 	SearchData []byte
+	SearchFreq map[string]int // BM25Data of the new search record, for index deltas
 	DepsData   []byte
 	Tags       []string
 	Templates  []string
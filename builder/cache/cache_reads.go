@@ -162,11 +162,21 @@ func (m *Manager) GetPostsByIDs(postIDs []string) (map[string]*PostMeta, error)
 
 // GetPostsByTemplate retrieves all PostIDs associated with a template
 func (m *Manager) GetPostsByTemplate(templatePath string) ([]string, error) {
+	return m.postsByDependency(BucketDepsTemplates, templatePath)
+}
+
+// GetPostsByInclude retrieves all PostIDs that embed a file
+func (m *Manager) GetPostsByInclude(includePath string) ([]string, error) {
+	return m.postsByDependency(BucketDepsIncludes, includePath)
+}
+
+// postsByDependency scans a {dependency}/{PostID} bucket for a dependency
+func (m *Manager) postsByDependency(bucketName, dependency string) ([]string, error) {
 	var ids []string
-	key := []byte(templatePath)
+	key := []byte(dependency)
 
 	err := m.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(BucketDepsTemplates))
+		bucket := tx.Bucket([]byte(bucketName))
 		c := bucket.Cursor()
 		prefix := append(key, '/')
 		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
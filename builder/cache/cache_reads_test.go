@@ -384,6 +384,34 @@ func TestGetPostsByTemplate(t *testing.T) {
 	}
 }
 
+func TestGetPostsByInclude(t *testing.T) {
+	m, cleanup := createTestCache(t)
+	defer cleanup()
+
+	post1 := createSamplePostMeta()
+	post1.PostID = "post-1"
+
+	post2 := createSamplePostMeta()
+	post2.PostID = "post-2"
+
+	depsMap := map[string]*Dependencies{
+		"post-1": {Includes: []string{"snippets/train.go"}},
+		"post-2": {Includes: []string{"snippets/train.go.bak", "snippets/eval.go"}},
+	}
+
+	if err := m.BatchCommit([]*PostMeta{post1, post2}, nil, depsMap); err != nil {
+		t.Fatalf("BatchCommit failed: %v", err)
+	}
+
+	posts, err := m.GetPostsByInclude("snippets/train.go")
+	if err != nil {
+		t.Fatalf("GetPostsByInclude failed: %v", err)
+	}
+	if len(posts) != 1 || posts[0] != "post-1" {
+		t.Errorf("Expected [post-1], got %v", posts)
+	}
+}
+
 func TestGetCachedItem_Generic(t *testing.T) {
 	m, cleanup := createTestCache(t)
 	defer cleanup()
@@ -13,6 +13,10 @@ import (
 
 // BatchCommit commits all pending changes in a single transaction
 func (m *Manager) BatchCommit(posts []*PostMeta, searchRecords map[string]*SearchRecord, deps map[string]*Dependencies) error {
+	// Deltas are computed from the stored records before any write, so each
+	// post must apply its delta once
+	posts = uniquePosts(posts)
+
 	// Pre-allocate slice for parallel encoding results
 	encoded := make([]EncodedPost, len(posts))
 
@@ -53,6 +57,7 @@ func (m *Manager) BatchCommit(posts []*PostMeta, searchRecords map[string]*Searc
 					return
 				}
 				ep.SearchData = srData
+				ep.SearchFreq = sr.BM25Data
 			}
 
 			if d, ok := deps[p.PostID]; ok {
@@ -127,6 +132,16 @@ func (m *Manager) BatchCommit(posts []*PostMeta, searchRecords map[string]*Searc
 	}
 
 	err := m.db.Update(func(tx *bolt.Tx) error {
+		// Apply inverted index deltas before the old search records are overwritten
+		for _, ep := range encoded {
+			if ep.SearchData == nil {
+				continue
+			}
+			if err := applySearchDelta(tx, string(ep.PostID), storedSearchFreqs(tx, ep.PostID), ep.SearchFreq); err != nil {
+				return err
+			}
+		}
+
 		if err := writeOps(tx.Bucket([]byte(BucketPosts)), ops.posts); err != nil {
 			return err
 		}
@@ -149,6 +164,12 @@ func (m *Manager) BatchCommit(posts []*PostMeta, searchRecords map[string]*Searc
 			return err
 		}
 
+		if len(encoded) > 0 {
+			if err := bumpSearchGeneration(tx); err != nil {
+				return err
+			}
+		}
+
 		stats := tx.Bucket([]byte(BucketStats))
 		buildCount := uint32(1)
 		if data := stats.Get([]byte(KeyBuildCount)); data != nil {
@@ -174,6 +195,24 @@ func (m *Manager) BatchCommit(posts []*PostMeta, searchRecords map[string]*Searc
 	return err
 }
 
+// uniquePosts keeps the last entry of each PostID, in order
+func uniquePosts(posts []*PostMeta) []*PostMeta {
+	last := make(map[string]int, len(posts))
+	for i, p := range posts {
+		last[p.PostID] = i
+	}
+	if len(last) == len(posts) {
+		return posts
+	}
+	unique := make([]*PostMeta, 0, len(last))
+	for i, p := range posts {
+		if last[p.PostID] == i {
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
 // StoreHTML stores HTML content and returns its hash
 func (m *Manager) StoreHTML(content []byte) (string, error) {
 	hash, _, err := m.store.Put("html", content)
@@ -254,11 +293,15 @@ func (m *Manager) DeletePost(postID string) error {
 			}
 		}
 
+		if err := applySearchDelta(tx, postID, storedSearchFreqs(tx, postIDBytes), nil); err != nil {
+			return err
+		}
+
 		_ = postsBucket.Delete(postIDBytes)
 		_ = searchBucket.Delete(postIDBytes)
 		_ = depsBucket.Delete(postIDBytes)
 
-		return nil
+		return bumpSearchGeneration(tx)
 	})
 
 	// Invalidate memory cache
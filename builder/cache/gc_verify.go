@@ -100,6 +100,15 @@ func (m *Manager) Verify() ([]string, error) {
 			return nil
 		})
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.db.View(func(tx *bolt.Tx) error {
+		issues, err := verifySearchTerms(tx)
+		errors = append(errors, issues...)
+		return err
+	})
 
 	return errors, err
 }
@@ -3,12 +3,14 @@ package cache
 // BoltDB bucket names
 const (
 	// Core buckets
-	BucketPosts      = "posts"       // {PostID} -> PostMeta
-	BucketPaths      = "paths"       // {filepath} -> PostID
-	BucketSearch     = "search"      // {PostID} -> SearchRecord
-	BucketPostDeps   = "post_deps"   // {PostID} -> Dependencies
-	BucketSSR        = "ssr"         // {type}:{inputHash} -> SSRArtifact
-	BucketSocialCard = "social_card" // {path} -> hash
+	BucketPosts       = "posts"        // {PostID} -> PostMeta
+	BucketPaths       = "paths"        // {filepath} -> PostID
+	BucketSearch      = "search"       // {PostID} -> SearchRecord
+	BucketSearchTerms = "search_terms" // {term} -> map[PostID]frequency (maintained inverted index)
+	BucketSearchGrams = "search_grams" // {trigram} -> sorted terms (maintained with search_terms)
+	BucketPostDeps    = "post_deps"    // {PostID} -> Dependencies
+	BucketSSR         = "ssr"          // {type}:{inputHash} -> SSRArtifact
+	BucketSocialCard  = "social_card"  // {path} -> hash
 
 	// Index buckets (set-based, value is empty)
 	BucketTags          = "tags"           // {tag}/{PostID} -> empty
@@ -26,6 +28,8 @@ const (
 	KeyBuildCount    = "build_count"
 	KeyGraphHash     = "graph_hash"
 	KeyWasmHash      = "wasm_hash"
+	KeySearchGen     = "search_gen"
+	KeySearchHash    = "search_hash"
 )
 
 // AllBuckets returns all bucket names for initialization
@@ -34,6 +38,8 @@ func AllBuckets() []string {
 		BucketPosts,
 		BucketPaths,
 		BucketSearch,
+		BucketSearchTerms,
+		BucketSearchGrams,
 		BucketPostDeps,
 		BucketSSR,
 		BucketSocialCard,
@@ -0,0 +1,362 @@
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Kush-Singh-26/kosh/builder/search"
+)
+
+// SearchPostings is the maintained inverted index: term -> PostID -> frequency
+type SearchPostings map[string]map[string]int
+
+// maxSearchVerifyIssues caps the mismatches reported by verifySearchTerms
+const maxSearchVerifyIssues = 10
+
+// GetSearchPostings returns the inverted index maintained alongside search records
+func (m *Manager) GetSearchPostings() (SearchPostings, error) {
+	postings := make(SearchPostings)
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BucketSearchTerms)).ForEach(func(k, v []byte) error {
+			var posts map[string]int
+			if err := Decode(v, &posts); err != nil {
+				return fmt.Errorf("corrupt search term %q: %w", string(k), err)
+			}
+			postings[string(k)] = posts
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return postings, nil
+}
+
+// GetSearchGrams returns the trigram index maintained alongside the postings
+func (m *Manager) GetSearchGrams() (map[string][]string, error) {
+	grams := make(map[string][]string)
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BucketSearchGrams)).ForEach(func(k, v []byte) error {
+			var terms []string
+			if err := Decode(v, &terms); err != nil {
+				return fmt.Errorf("corrupt search trigram %q: %w", string(k), err)
+			}
+			grams[string(k)] = terms
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grams, nil
+}
+
+// SearchGeneration returns a counter that moves whenever posts or their search
+// records are written or deleted, so an unchanged index can be detected cheaply
+func (m *Manager) SearchGeneration() (uint64, error) {
+	var gen uint64
+	err := m.db.View(func(tx *bolt.Tx) error {
+		if data := tx.Bucket([]byte(BucketStats)).Get([]byte(KeySearchGen)); data != nil {
+			gen = binary.BigEndian.Uint64(data)
+		}
+		return nil
+	})
+	return gen, err
+}
+
+// GetSearchHash retrieves the hash of the last written search index
+func (m *Manager) GetSearchHash() (string, error) {
+	var hash string
+	err := m.db.View(func(tx *bolt.Tx) error {
+		if data := tx.Bucket([]byte(BucketMeta)).Get([]byte(KeySearchHash)); data != nil {
+			hash = string(data)
+		}
+		return nil
+	})
+	return hash, err
+}
+
+// SetSearchHash stores the hash of the last written search index
+func (m *Manager) SetSearchHash(hash string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BucketMeta)).Put([]byte(KeySearchHash), []byte(hash))
+	})
+}
+
+// bumpSearchGeneration moves the counter returned by SearchGeneration
+func bumpSearchGeneration(tx *bolt.Tx) error {
+	stats := tx.Bucket([]byte(BucketStats))
+	gen := uint64(1)
+	if data := stats.Get([]byte(KeySearchGen)); data != nil {
+		gen = binary.BigEndian.Uint64(data) + 1
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, gen)
+	return stats.Put([]byte(KeySearchGen), data)
+}
+
+// applySearchDelta moves a post's postings from its old term frequencies to the new ones.
+// Either side may be nil for a newly added or deleted post.
+func applySearchDelta(tx *bolt.Tx, postID string, oldFreqs, newFreqs map[string]int) error {
+	bucket := tx.Bucket([]byte(BucketSearchTerms))
+
+	update := func(term string, freq int) error {
+		key := []byte(term)
+		posts := make(map[string]int)
+		data := bucket.Get(key)
+		if data != nil {
+			if err := Decode(data, &posts); err != nil {
+				return err
+			}
+		}
+		if freq > 0 {
+			posts[postID] = freq
+		} else {
+			delete(posts, postID)
+		}
+		if len(posts) == 0 {
+			if data == nil {
+				return nil
+			}
+			// The last post left the term, so fuzzy lookups must not offer it
+			if err := updateSearchGrams(tx, term, false); err != nil {
+				return err
+			}
+			return bucket.Delete(key)
+		}
+		if data == nil {
+			if err := updateSearchGrams(tx, term, true); err != nil {
+				return err
+			}
+		}
+		encoded, err := Encode(posts)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, encoded)
+	}
+
+	for term := range oldFreqs {
+		if _, ok := newFreqs[term]; !ok {
+			if err := update(term, 0); err != nil {
+				return err
+			}
+		}
+	}
+	for term, freq := range newFreqs {
+		if old, ok := oldFreqs[term]; ok && old == freq {
+			continue
+		}
+		if err := update(term, freq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateSearchGrams adds a new term to the lists of its trigrams, or removes a dropped one
+func updateSearchGrams(tx *bolt.Tx, term string, add bool) error {
+	bucket := tx.Bucket([]byte(BucketSearchGrams))
+	for _, tg := range search.Trigrams(term) {
+		key := []byte(tg)
+		var terms []string
+		if data := bucket.Get(key); data != nil {
+			if err := Decode(data, &terms); err != nil {
+				return err
+			}
+		}
+		i := sort.SearchStrings(terms, term)
+		found := i < len(terms) && terms[i] == term
+		switch {
+		case add && !found:
+			terms = append(terms, "")
+			copy(terms[i+1:], terms[i:])
+			terms[i] = term
+		case !add && found:
+			terms = append(terms[:i], terms[i+1:]...)
+		default:
+			continue
+		}
+		if len(terms) == 0 {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := Encode(terms)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scratchSearchGrams builds the trigram index of the given postings
+func scratchSearchGrams(postings SearchPostings) map[string][]string {
+	grams := make(map[string][]string)
+	for term := range postings {
+		for _, tg := range search.Trigrams(term) {
+			grams[tg] = append(grams[tg], term)
+		}
+	}
+	for _, terms := range grams {
+		sort.Strings(terms)
+	}
+	return grams
+}
+
+// storedSearchFreqs returns the term frequencies of the search record currently stored for postID
+func storedSearchFreqs(tx *bolt.Tx, postID []byte) map[string]int {
+	data := tx.Bucket([]byte(BucketSearch)).Get(postID)
+	if data == nil {
+		return nil
+	}
+	var record SearchRecord
+	if err := Decode(data, &record); err != nil {
+		return nil
+	}
+	return record.BM25Data
+}
+
+// scratchSearchPostings builds the inverted index from the stored search records
+func scratchSearchPostings(tx *bolt.Tx) (SearchPostings, error) {
+	postings := make(SearchPostings)
+	err := tx.Bucket([]byte(BucketSearch)).ForEach(func(k, v []byte) error {
+		var record SearchRecord
+		if err := Decode(v, &record); err != nil {
+			return nil // Reported by Verify as a corrupt record
+		}
+		for term, freq := range record.BM25Data {
+			posts, ok := postings[term]
+			if !ok {
+				posts = make(map[string]int)
+				postings[term] = posts
+			}
+			posts[string(k)] = freq
+		}
+		return nil
+	})
+	return postings, err
+}
+
+// rebuildSearchTerms replaces the maintained inverted and trigram indexes with
+// a from-scratch rebuild. Used to backfill caches written before they were maintained.
+func rebuildSearchTerms(tx *bolt.Tx) error {
+	postings, err := scratchSearchPostings(tx)
+	if err != nil {
+		return err
+	}
+	if err := refillBucket(tx, BucketSearchTerms, postings); err != nil {
+		return err
+	}
+	return refillBucket(tx, BucketSearchGrams, scratchSearchGrams(postings))
+}
+
+// refillBucket replaces the contents of a bucket with the encoded entries
+func refillBucket[V any](tx *bolt.Tx, name string, entries map[string]V) error {
+	if err := tx.DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+	bucket, err := tx.CreateBucket([]byte(name))
+	if err != nil {
+		return err
+	}
+	for key, value := range entries {
+		data, err := Encode(value)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifySearchTerms compares the maintained inverted index against a from-scratch rebuild
+func verifySearchTerms(tx *bolt.Tx) ([]string, error) {
+	want, err := scratchSearchPostings(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []string
+	seen := make(map[string]bool, len(want))
+	err = tx.Bucket([]byte(BucketSearchTerms)).ForEach(func(k, v []byte) error {
+		term := string(k)
+		seen[term] = true
+		var got map[string]int
+		if err := Decode(v, &got); err != nil {
+			issues = append(issues, fmt.Sprintf("corrupt search term: %s", term))
+			return nil
+		}
+		if !samePostings(got, want[term]) {
+			issues = append(issues, fmt.Sprintf("search index mismatch for term %q: %d posts maintained, %d expected", term, len(got), len(want[term])))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0)
+	for term := range want {
+		if !seen[term] {
+			missing = append(missing, term)
+		}
+	}
+	sort.Strings(missing)
+	for _, term := range missing {
+		issues = append(issues, fmt.Sprintf("search index missing term %q", term))
+	}
+
+	wantGrams := scratchSearchGrams(want)
+	seenGrams := make(map[string]bool, len(wantGrams))
+	err = tx.Bucket([]byte(BucketSearchGrams)).ForEach(func(k, v []byte) error {
+		tg := string(k)
+		seenGrams[tg] = true
+		var got []string
+		if err := Decode(v, &got); err != nil || !reflect.DeepEqual(got, wantGrams[tg]) {
+			issues = append(issues, fmt.Sprintf("search trigram mismatch for %q", tg))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	missing = missing[:0]
+	for tg := range wantGrams {
+		if !seenGrams[tg] {
+			missing = append(missing, tg)
+		}
+	}
+	sort.Strings(missing)
+	for _, tg := range missing {
+		issues = append(issues, fmt.Sprintf("search trigram missing %q", tg))
+	}
+
+	if len(issues) > maxSearchVerifyIssues {
+		more := len(issues) - maxSearchVerifyIssues
+		issues = append(issues[:maxSearchVerifyIssues], fmt.Sprintf("... and %d more search index issues (run 'kosh cache rebuild')", more))
+	}
+	return issues, nil
+}
+
+func samePostings(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, freq := range a {
+		if b[id] != freq {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func commitSearch(t *testing.T, m *Manager, postID string, freqs map[string]int) {
+	t.Helper()
+	post := createSamplePostMeta()
+	post.PostID = postID
+	post.Path = "content/" + postID + ".md"
+	records := map[string]*SearchRecord{postID: {Title: postID, BM25Data: freqs}}
+	if err := m.BatchCommit([]*PostMeta{post}, records, nil); err != nil {
+		t.Fatalf("BatchCommit failed: %v", err)
+	}
+}
+
+func TestSearchPostingsDeltas(t *testing.T) {
+	m, cleanup := createTestCache(t)
+	defer cleanup()
+
+	commitSearch(t, m, "a", map[string]int{"attent": 2, "layer": 1})
+	commitSearch(t, m, "b", map[string]int{"layer": 3})
+	// Rewriting a post drops terms it no longer contains
+	commitSearch(t, m, "a", map[string]int{"attent": 1, "pool": 1})
+
+	got, err := m.GetSearchPostings()
+	if err != nil {
+		t.Fatalf("GetSearchPostings failed: %v", err)
+	}
+	want := SearchPostings{
+		"attent": {"a": 1},
+		"layer":  {"b": 3},
+		"pool":   {"a": 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("postings = %v, want %v", got, want)
+	}
+
+	grams, err := m.GetSearchGrams()
+	if err != nil {
+		t.Fatalf("GetSearchGrams failed: %v", err)
+	}
+	if !reflect.DeepEqual(grams, scratchSearchGrams(want)) {
+		t.Errorf("trigrams = %v, want %v", grams, scratchSearchGrams(want))
+	}
+
+	gen, _ := m.SearchGeneration()
+	if err := m.DeletePost("b"); err != nil {
+		t.Fatalf("DeletePost failed: %v", err)
+	}
+	got, _ = m.GetSearchPostings()
+	if _, ok := got["layer"]; ok {
+		t.Errorf("term of deleted post still indexed: %v", got)
+	}
+	if grams, _ := m.GetSearchGrams(); grams["lay"] != nil {
+		t.Errorf("trigram of a dropped term still indexed: %v", grams["lay"])
+	}
+	if after, _ := m.SearchGeneration(); after <= gen {
+		t.Errorf("SearchGeneration() = %d after a delete, want more than %d", after, gen)
+	}
+
+	issues, err := m.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("Verify() = %v, want no issues", issues)
+	}
+}
+
+func TestBatchCommitDuplicatePost(t *testing.T) {
+	m, cleanup := createTestCache(t)
+	defer cleanup()
+
+	commitSearch(t, m, "a", map[string]int{"attent": 2})
+
+	stale := createSamplePostMeta()
+	stale.PostID, stale.Path, stale.Title = "a", "content/a.md", "stale"
+	fresh := createSamplePostMeta()
+	fresh.PostID, fresh.Path, fresh.Title = "a", "content/a.md", "fresh"
+	records := map[string]*SearchRecord{"a": {Title: "fresh", BM25Data: map[string]int{"pool": 1}}}
+	if err := m.BatchCommit([]*PostMeta{stale, fresh}, records, nil); err != nil {
+		t.Fatalf("BatchCommit failed: %v", err)
+	}
+
+	post, err := m.GetPostByID("a")
+	if err != nil || post == nil || post.Title != "fresh" {
+		t.Fatalf("GetPostByID() = %v, %v, want the last entry", post, err)
+	}
+	got, _ := m.GetSearchPostings()
+	if !reflect.DeepEqual(got, SearchPostings{"pool": {"a": 1}}) {
+		t.Errorf("postings = %v", got)
+	}
+	if issues, err := m.Verify(); err != nil || len(issues) != 0 {
+		t.Errorf("Verify() = %v, %v, want no issues", issues, err)
+	}
+}
+
+func TestVerifyDetectsSearchDrift(t *testing.T) {
+	m, cleanup := createTestCache(t)
+	defer cleanup()
+
+	commitSearch(t, m, "a", map[string]int{"attent": 2})
+
+	// Corrupt the maintained index behind the manager's back
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BucketSearchTerms)).Delete([]byte("attent"))
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	issues, err := m.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(issues) != 1 || issues[0] != `search index missing term "attent"` {
+		t.Errorf("Verify() = %v", issues)
+	}
+}
+
+func TestOpenBackfillsSearchPostings(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Open(dir, false)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	commitSearch(t, m, "a", map[string]int{"attent": 2})
+
+	// Simulate a cache written before postings were maintained
+	if err := m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BucketSearchTerms)).Delete([]byte("attent"))
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	_ = m.Close()
+
+	m, err = Open(dir, false)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer func() { _ = m.Close() }()
+
+	got, _ := m.GetSearchPostings()
+	if !reflect.DeepEqual(got, SearchPostings{"attent": {"a": 2}}) {
+		t.Errorf("postings after reopen = %v", got)
+	}
+	grams, _ := m.GetSearchGrams()
+	if !reflect.DeepEqual(grams, scratchSearchGrams(got)) {
+		t.Errorf("trigrams after reopen = %v", grams)
+	}
+}
@@ -25,6 +25,9 @@ type PostMeta struct {
 	SSRInputHashes []string               `msgpack:"ssr_input_hashes"`
 	Title          string                 `msgpack:"title"`
 	Date           time.Time              `msgpack:"date"`
+	LastMod        time.Time              `msgpack:"lastmod"`
+	Summary        string                 `msgpack:"summary"`                // Rendered HTML of the summary
+	FeedSummary    string                 `msgpack:"feed_summary,omitempty"` // Summary with math.feedOutput math
 	Tags           []string               `msgpack:"tags"`
 	WordCount      int                    `msgpack:"word_count"`
 	ReadingTime    int                    `msgpack:"reading_time"`
@@ -36,6 +39,7 @@ type PostMeta struct {
 	Meta           map[string]interface{} `msgpack:"meta"`
 	TOC            []models.TOCEntry      `msgpack:"toc"`
 	Version        string                 `msgpack:"version"`
+	ImageProfiles  map[string]string      `msgpack:"image_profiles,omitempty"` // Static image -> imageProfile the post gives it
 }
 
 // Constants for inline HTML threshold
@@ -60,9 +64,14 @@ type SearchRecord struct {
 	BM25Data        map[string]int `msgpack:"bm25_data"` // word -> frequency
 	DocLen          int            `msgpack:"doc_len"`
 	Content         string         `msgpack:"content"`
-	NormalizedTags  []string       `msgpack:"norm_tags"` // Lowercase tags
+	NormalizedTags  []string       `msgpack:"norm_tags"`          // Lowercase tags
+	Analyzer        string         `msgpack:"analyzer,omitempty"` // Search analyzer name (empty = English)
 	// Cached tokenization to avoid re-tokenizing unchanged content
 	Words []string `msgpack:"words,omitempty"` // Cached tokenized words
+	// Derived once per post so index builds do not re-analyze unchanged posts
+	Stems       map[string][]string `msgpack:"stems,omitempty"`        // stem -> original words in Content
+	Synonyms    []string            `msgpack:"synonyms,omitempty"`     // Synonym terms the post gains, not in BM25Data
+	SynonymsKey string              `msgpack:"synonyms_key,omitempty"` // search.SynonymsKey of the table Synonyms came from
 }
 
 // Dependencies tracks what a post depends on
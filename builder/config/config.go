@@ -10,9 +10,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Kush-Singh-26/kosh/builder/frontmatter"
 	"github.com/Kush-Singh-26/kosh/builder/models"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 
+	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
 
@@ -65,11 +67,125 @@ type SocialCardsConfig struct {
 	TextColor  string   `yaml:"textColor"`
 }
 
+// SearchConfig tunes the generated search index
+type SearchConfig struct {
+	// Synonyms maps a term or acronym to equivalent phrases, e.g. mlp: ["multilayer perceptron"].
+	// Entries from synonyms.yaml in dataDir are merged in.
+	Synonyms map[string][]string `yaml:"synonyms"`
+	// IndexSynonyms also adds synonym terms to posts at build time
+	IndexSynonyms bool `yaml:"indexSynonyms"`
+}
+
+// ImageProfile is a named set of image encoding settings. Unset fields inherit
+// from the top-level images settings.
+type ImageProfile struct {
+	MaxWidth   int      `yaml:"maxWidth"`   // Width cap of the default rendition (default: 1200)
+	Widths     []int    `yaml:"widths"`     // Variant widths for srcset (default: 480, 800, 1200, 2000)
+	Formats    []string `yaml:"formats"`    // Variant formats in preference order (default: avif, webp)
+	Quality    int      `yaml:"quality"`    // Lossy encoder quality (default: 80)
+	Lossless   *bool    `yaml:"lossless"`   // Lossless WebP/AVIF, e.g. for PNG screenshots
+	Sharpen    float64  `yaml:"sharpen"`    // Sharpening sigma after resizing (default: 0, off)
+	AutoOrient *bool    `yaml:"autoOrient"` // Apply EXIF orientation (default: true); EXIF is always stripped
+}
+
+// ImageRule assigns a profile to static images whose path matches a glob
+type ImageRule struct {
+	Match   string `yaml:"match"` // e.g. "images/screenshots/**"
+	Profile string `yaml:"profile"`
+}
+
+// ImagesConfig controls the responsive renditions generated for local images
+type ImagesConfig struct {
+	ImageProfile `yaml:",inline"` // The default profile
+
+	Sizes       string                  `yaml:"sizes"`       // Default sizes attribute for <picture> sources
+	Placeholder string                  `yaml:"placeholder"` // Blur-up placeholder: none (default), color or lqip
+	Profiles    map[string]ImageProfile `yaml:"profiles"`
+	Rules       []ImageRule             `yaml:"rules"`
+}
+
+// MathConfig controls display math rendering
+type MathConfig struct {
+	// Numbering selects the numbered display equations: labelled (default, those
+	// with a \label), all, or none (only explicit \tag{}s)
+	Numbering string `yaml:"numbering"`
+	// Macros are KaTeX macros shared by every post, e.g. R: "\\mathbb{R}".
+	// A post's mathMacros front matter adds to or overrides them.
+	Macros map[string]string `yaml:"macros"`
+	// Extensions are KaTeX extension scripts loaded into the renderer, e.g.
+	// KaTeX's contrib/mhchem.min.js for \ce{} and \pu{}
+	Extensions []string `yaml:"extensions"`
+	// Output is the KaTeX output of pages: html (default), mathml or htmlAndMathml
+	Output string `yaml:"output"`
+	// FeedOutput is the KaTeX output of math in feeds, where KaTeX's CSS is
+	// unavailable (default: mathml)
+	FeedOutput string `yaml:"feedOutput"`
+}
+
+// DiagramsConfig sets the rendering defaults of D2, Graphviz and Mermaid
+// diagrams. Fence attributes override them per diagram.
+type DiagramsConfig struct {
+	Layout    string  `yaml:"layout"`    // dagre (default) or elk
+	Theme     int64   `yaml:"theme"`     // D2 theme ID of the light variant (default: 0)
+	DarkTheme int64   `yaml:"darkTheme"` // D2 theme ID of the dark variant (default: 200)
+	Sketch    bool    `yaml:"sketch"`    // Hand-drawn look
+	Pad       int64   `yaml:"pad"`       // Padding in pixels (default: 0)
+	Scale     float64 `yaml:"scale"`     // SVG scale factor; 0 (default) fits the container
+}
+
+// HighlightConfig picks the chroma styles of code blocks. `kosh highlight-css`
+// exports them to <staticDir>/css/chroma.css.
+type HighlightConfig struct {
+	Style      string `yaml:"style"`      // Default (dark) style (default: nord)
+	LightStyle string `yaml:"lightStyle"` // Style under [data-theme="light"] (default: github)
+}
+
+// CitationsConfig formats [@key] citations of posts with a bibliography
+type CitationsConfig struct {
+	Style string `yaml:"style"` // apa (default) or ieee; a post's citationStyle overrides it
+	Title string `yaml:"title"` // Heading of the references list (default: References)
+}
+
+// FootnotesConfig controls how footnotes are shown
+type FootnotesConfig struct {
+	// Sidenotes shows footnotes in the margin next to their reference instead
+	// of at the end of the post. A post's sidenotes front matter overrides it.
+	Sidenotes bool `yaml:"sidenotes"`
+}
+
+// NotebooksConfig controls Jupyter notebook (.ipynb) posts
+type NotebooksConfig struct {
+	// Language highlights code cells of notebooks whose metadata names no
+	// kernel language (default: python)
+	Language string `yaml:"language"`
+}
+
+// FrontMatterConfig checks post front matter, which may be YAML (---),
+// TOML (+++) or JSON ({...})
+type FrontMatterConfig struct {
+	Schema frontmatter.Schema `yaml:"schema"`
+}
+
+// SummaryConfig controls the summaries of posts shown on index and tag pages and in feeds
+type SummaryConfig struct {
+	// Words makes the summary of a post without a <!--more--> divider its
+	// first blocks that reach this many words; 0 turns this off (default: 70)
+	Words int `yaml:"words"`
+}
+
+// DatesConfig controls the dates of posts
+type DatesConfig struct {
+	// Git fills in the date and lastmod of posts that do not set them with
+	// the first and last commits that changed the file
+	Git bool `yaml:"git"`
+}
+
 type Config struct {
 	Title          string            `yaml:"title"`
 	Description    string            `yaml:"description"`
 	BaseURL        string            `yaml:"baseURL"`
 	Language       string            `yaml:"language"`
+	Timezone       string            `yaml:"timezone"` // IANA zone of dates without one, e.g. Asia/Kolkata (default: UTC)
 	Author         AuthorConfig      `yaml:"author"`
 	Menu           []MenuEntry       `yaml:"menu"`
 	PostsPerPage   int               `yaml:"postsPerPage"`
@@ -84,17 +200,31 @@ type Config struct {
 	Features       FeaturesConfig    `yaml:"features"` // Enable/Disable features
 	ThemeMetadata  ThemeConfig       `yaml:"-"`        // Loaded from theme.yaml
 	SocialCards    SocialCardsConfig `yaml:"socialCards"`
+	Search         SearchConfig      `yaml:"search"`
+	Images         ImagesConfig      `yaml:"images"`
+	Math           MathConfig        `yaml:"math"`
+	Diagrams       DiagramsConfig    `yaml:"diagrams"`
+	Highlight      HighlightConfig   `yaml:"highlight"`
+	Citations      CitationsConfig   `yaml:"citations"`
+	Footnotes      FootnotesConfig   `yaml:"footnotes"`
+	Notebooks      NotebooksConfig   `yaml:"notebooks"`
+	FrontMatter    FrontMatterConfig `yaml:"frontmatter"`
+	Dates          DatesConfig       `yaml:"dates"`
+	Summary        SummaryConfig     `yaml:"summary"`
 
 	// Configurable directory paths
-	ContentDir string `yaml:"contentDir"` // Content source directory (default: "content")
-	OutputDir  string `yaml:"outputDir"`  // Build output directory (default: "public")
-	CacheDir   string `yaml:"cacheDir"`   // Cache directory (default: ".kosh-cache")
+	ContentDir  string `yaml:"contentDir"`  // Content source directory (default: "content")
+	SnippetsDir string `yaml:"snippetsDir"` // Fallback directory of embedded code files (default: none)
+	DataDir     string `yaml:"dataDir"`     // Data files templates read as .Data (default: "data")
+	OutputDir   string `yaml:"outputDir"`   // Build output directory (default: "public")
+	CacheDir    string `yaml:"cacheDir"`    // Cache directory (default: ".kosh-cache")
 
 	// Internal / Runtime fields
 	ForceRebuild  bool  `yaml:"-"`
 	IncludeDrafts bool  `yaml:"-"`
 	BuildVersion  int64 `yaml:"-"`
 	IsDev         bool  `yaml:"-"`
+	location      *time.Location
 
 	// Build configuration (loaded from kosh.build.yaml)
 	Build *BuildConfig `yaml:"-"`
@@ -112,6 +242,7 @@ func Load(args []string) *Config {
 		Theme:          "blog",
 		ThemeDir:       "themes",
 		ContentDir:     "content",
+		DataDir:        "data",
 		OutputDir:      "public",
 		CacheDir:       ".kosh-cache",
 		Features: FeaturesConfig{
@@ -130,6 +261,32 @@ func Load(args []string) *Config {
 			Angle:      135,
 			TextColor:  "#1a1a1a",
 		},
+		Images: ImagesConfig{
+			Sizes: DefaultImageSizes,
+		},
+		Math: MathConfig{
+			Numbering:  "labelled",
+			Output:     "html",
+			FeedOutput: "mathml",
+		},
+		Diagrams: DiagramsConfig{
+			Layout:    "dagre",
+			DarkTheme: 200,
+		},
+		Highlight: HighlightConfig{
+			Style:      "nord",
+			LightStyle: "github",
+		},
+		Citations: CitationsConfig{
+			Style: "apa",
+			Title: "References",
+		},
+		Notebooks: NotebooksConfig{
+			Language: "python",
+		},
+		Summary: SummaryConfig{
+			Words: 70,
+		},
 	}
 
 	// 2. Load from YAML file if exists
@@ -210,6 +367,14 @@ func Load(args []string) *Config {
 		cfg.CacheDir = utils.NormalizePath(abs)
 	}
 
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			fmt.Printf("⚠️ Unknown timezone %q, using UTC: %v\n", cfg.Timezone, err)
+		}
+		cfg.location = loc
+	}
+
 	// 3. Override with CLI Flags
 	fs := flag.NewFlagSet("config", flag.ContinueOnError)
 	baseUrlFlag := fs.String("baseurl", "", "Base URL (overrides config file)")
@@ -234,6 +399,90 @@ func Load(args []string) *Config {
 	return cfg
 }
 
+// SearchSynonyms returns the configured synonyms merged with synonyms.yaml in
+// DataDir. The file is a data file, so editing it re-renders from the cache
+// and the search index is rewritten with the new table.
+func (cfg *Config) SearchSynonyms(fs afero.Fs) (map[string][]string, error) {
+	path := filepath.Join(cfg.DataDir, "synonyms.yaml")
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return cfg.Search.Synonyms, nil // The file is optional
+	}
+	var synonyms map[string][]string
+	if err := yaml.Unmarshal(data, &synonyms); err != nil {
+		return cfg.Search.Synonyms, fmt.Errorf("%s: %w", path, err)
+	}
+	return MergeSynonyms(cfg.Search.Synonyms, synonyms), nil
+}
+
+// MergeSynonyms combines two synonym tables, concatenating the values of shared keys
+func MergeSynonyms(base, extra map[string][]string) map[string][]string {
+	if len(extra) == 0 {
+		return base
+	}
+	merged := make(map[string][]string, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = append([]string(nil), v...)
+	}
+	for k, v := range extra {
+		merged[k] = append(merged[k], v...)
+	}
+	return merged
+}
+
+// DefaultImageSizes fits the default single-column content width
+const DefaultImageSizes = "(max-width: 800px) 100vw, 800px"
+
+// Location returns the time zone of Timezone; UTC when it is unset or unknown
+func (cfg *Config) Location() *time.Location {
+	if cfg.location == nil {
+		return time.UTC
+	}
+	return cfg.location
+}
+
+// ImageProfiles converts the images section into the profiles used by the asset pipeline
+func (cfg *Config) ImageProfiles() *utils.ImageProfiles {
+	def := cfg.Images.ImageProfile.apply("default", utils.DefaultImageOptions())
+	profiles := utils.NewImageProfiles(def)
+	profiles.Profiles = make(map[string]utils.ImageOptions, len(cfg.Images.Profiles))
+	for name, p := range cfg.Images.Profiles {
+		profiles.Profiles[name] = p.apply(name, def)
+	}
+	for _, r := range cfg.Images.Rules {
+		profiles.Rules = append(profiles.Rules, utils.ImageRule{Match: r.Match, Profile: r.Profile})
+	}
+	return profiles
+}
+
+// apply overlays the set fields of p onto base
+func (p ImageProfile) apply(name string, base utils.ImageOptions) utils.ImageOptions {
+	opts := base
+	opts.Profile = name
+	if p.MaxWidth > 0 {
+		opts.MaxWidth = p.MaxWidth
+	}
+	if len(p.Widths) > 0 {
+		opts.Widths = p.Widths
+	}
+	if len(p.Formats) > 0 {
+		opts.Formats = p.Formats
+	}
+	if p.Quality > 0 && p.Quality <= 100 {
+		opts.Quality = p.Quality
+	}
+	if p.Lossless != nil {
+		opts.Lossless = *p.Lossless
+	}
+	if p.Sharpen > 0 {
+		opts.Sharpen = p.Sharpen
+	}
+	if p.AutoOrient != nil {
+		opts.AutoOrient = *p.AutoOrient
+	}
+	return opts
+}
+
 // SetDevMode is a helper to set development mode on a config pointer
 func SetDevMode(cfg *Config, isDev bool) {
 	cfg.IsDev = isDev
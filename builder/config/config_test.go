@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/spf13/afero"
+
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 )
 
@@ -518,3 +521,247 @@ func TestConfig_FeaturesConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_ImageProfiles(t *testing.T) {
+	cleanup := changeToTempDir(t)
+	defer cleanup()
+
+	yamlContent := `
+images:
+  quality: 70
+  widths: [400, 800]
+  profiles:
+    screenshot:
+      lossless: true
+      sharpen: 0.5
+    hero:
+      maxWidth: 2400
+      quality: 90
+  rules:
+    - match: "images/screenshots/**"
+      profile: screenshot
+`
+	if err := os.WriteFile("kosh.yaml", []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test kosh.yaml: %v", err)
+	}
+
+	profiles := Load([]string{}).ImageProfiles()
+
+	if profiles.Default.Quality != 70 || profiles.Default.MaxWidth != 1200 || !profiles.Default.AutoOrient {
+		t.Errorf("Default = %+v, want quality 70 with default max width and auto-orient", profiles.Default)
+	}
+
+	shot := profiles.For("images/screenshots/ui/editor.png")
+	if shot.Profile != "screenshot" || !shot.Lossless || shot.Sharpen != 0.5 || shot.Quality != 70 {
+		t.Errorf("screenshot profile = %+v", shot)
+	}
+	if len(shot.Widths) != 2 {
+		t.Errorf("screenshot profile should inherit widths, got %v", shot.Widths)
+	}
+
+	if hero := profiles.Profiles["hero"]; hero.MaxWidth != 2400 || hero.Quality != 90 || hero.Lossless {
+		t.Errorf("hero profile = %+v", hero)
+	}
+
+	if got := profiles.For("images/photo.jpg").Profile; got != "default" {
+		t.Errorf("unmatched image profile = %q, want default", got)
+	}
+}
+
+func TestLoad_Math(t *testing.T) {
+	cleanup := changeToTempDir(t)
+	defer cleanup()
+
+	yamlContent := `
+math:
+  macros:
+    R: "\\mathbb{R}"
+  extensions: [static/js/mhchem.min.js]
+`
+	if err := os.WriteFile("kosh.yaml", []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test kosh.yaml: %v", err)
+	}
+
+	cfg := Load([]string{})
+	if cfg.Math.Numbering != "labelled" {
+		t.Errorf("Math.Numbering = %q, want default labelled", cfg.Math.Numbering)
+	}
+	if got := cfg.Math.Macros["R"]; got != `\mathbb{R}` {
+		t.Errorf("Math.Macros[R] = %q, want \\mathbb{R}", got)
+	}
+	if len(cfg.Math.Extensions) != 1 {
+		t.Errorf("Math.Extensions = %v", cfg.Math.Extensions)
+	}
+}
+
+func TestLoad_Diagrams(t *testing.T) {
+	cleanup := changeToTempDir(t)
+	defer cleanup()
+
+	yamlContent := `
+diagrams:
+  layout: elk
+  sketch: true
+  pad: 16
+`
+	if err := os.WriteFile("kosh.yaml", []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test kosh.yaml: %v", err)
+	}
+
+	cfg := Load([]string{})
+	want := DiagramsConfig{Layout: "elk", DarkTheme: 200, Sketch: true, Pad: 16}
+	if cfg.Diagrams != want {
+		t.Errorf("Diagrams = %+v, want %+v", cfg.Diagrams, want)
+	}
+}
+
+func TestLoad_Highlight(t *testing.T) {
+	cleanup := changeToTempDir(t)
+	defer cleanup()
+
+	if cfg := Load([]string{}); cfg.Highlight != (HighlightConfig{Style: "nord", LightStyle: "github"}) {
+		t.Errorf("default Highlight = %+v", cfg.Highlight)
+	}
+
+	yamlContent := `
+highlight:
+  style: dracula
+`
+	if err := os.WriteFile("kosh.yaml", []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test kosh.yaml: %v", err)
+	}
+
+	cfg := Load([]string{})
+	want := HighlightConfig{Style: "dracula", LightStyle: "github"}
+	if cfg.Highlight != want {
+		t.Errorf("Highlight = %+v, want %+v", cfg.Highlight, want)
+	}
+}
+
+func TestLoad_CitationsAndFootnotes(t *testing.T) {
+	cleanup := changeToTempDir(t)
+	defer cleanup()
+
+	cfg := Load([]string{})
+	if cfg.Citations != (CitationsConfig{Style: "apa", Title: "References"}) || cfg.Footnotes.Sidenotes {
+		t.Errorf("defaults = %+v, %+v", cfg.Citations, cfg.Footnotes)
+	}
+
+	yamlContent := `
+citations:
+  style: ieee
+footnotes:
+  sidenotes: true
+`
+	if err := os.WriteFile("kosh.yaml", []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test kosh.yaml: %v", err)
+	}
+
+	cfg = Load([]string{})
+	if want := (CitationsConfig{Style: "ieee", Title: "References"}); cfg.Citations != want {
+		t.Errorf("Citations = %+v, want %+v", cfg.Citations, want)
+	}
+	if !cfg.Footnotes.Sidenotes {
+		t.Error("Footnotes.Sidenotes = false, want true")
+	}
+}
+
+func TestLoad_FrontMatterSchema(t *testing.T) {
+	cleanup := changeToTempDir(t)
+	defer cleanup()
+
+	yamlContent := `
+frontmatter:
+  schema:
+    required: [title, date]
+    types:
+      series: string
+    tags: [go, web]
+    dateFormats: ["2006-01-02", "2006-01-02T15:04:05Z07:00"]
+    strict: true
+    level: error
+`
+	if err := os.WriteFile("kosh.yaml", []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test kosh.yaml: %v", err)
+	}
+
+	schema := Load([]string{}).FrontMatter.Schema
+	if !reflect.DeepEqual(schema.Required, []string{"title", "date"}) || schema.Types["series"] != "string" ||
+		len(schema.Tags) != 2 || len(schema.DateFormats) != 2 || !schema.Strict || !schema.IsError() {
+		t.Errorf("FrontMatter.Schema = %+v", schema)
+	}
+	if err := schema.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestLoad_Timezone(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		want     string
+	}{
+		{"unset", "", "UTC"},
+		{"iana", "Asia/Kolkata", "Asia/Kolkata"},
+		{"unknown", "Mars/Olympus", "UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanup := changeToTempDir(t)
+			defer cleanup()
+
+			yamlContent := "title: Site\ndates:\n  git: true\n"
+			if tt.timezone != "" {
+				yamlContent += "timezone: " + tt.timezone + "\n"
+			}
+			if err := os.WriteFile("kosh.yaml", []byte(yamlContent), 0644); err != nil {
+				t.Fatalf("Failed to create test kosh.yaml: %v", err)
+			}
+
+			cfg := Load([]string{})
+			if got := cfg.Location().String(); got != tt.want {
+				t.Errorf("Location() = %s, want %s", got, tt.want)
+			}
+			if !cfg.Dates.Git {
+				t.Error("Dates.Git = false, want true")
+			}
+		})
+	}
+}
+
+func TestSearchSynonyms(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "site-data/synonyms.yaml", []byte("mlp: [multilayer perceptron]\nllm: [large language model]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(fs, "broken/synonyms.yaml", []byte("mlp: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configured := map[string][]string{"mlp": {"feedforward network"}}
+
+	tests := []struct {
+		name    string
+		dataDir string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{"merged from the data dir", "site-data", map[string][]string{
+			"mlp": {"feedforward network", "multilayer perceptron"},
+			"llm": {"large language model"},
+		}, false},
+		{"no file", "data", configured, false},
+		{"broken file keeps the configured table", "broken", configured, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{DataDir: tt.dataDir, Search: SearchConfig{Synonyms: configured}}
+			got, err := cfg.SearchSynonyms(fs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SearchSynonyms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchSynonyms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package frontmatter reads post front matter in YAML ("---"), TOML ("+++")
+// or JSON ("{...}") and checks it against a schema
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	bom       = []byte("\xef\xbb\xbf")
+	yamlDelim = []byte("---")
+	tomlDelim = []byte("+++")
+)
+
+// DefaultDateFormats are the layouts dates are parsed with when the schema
+// lists none: a plain date, RFC 3339 and a date with a time but no zone
+var DefaultDateFormats = []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"}
+
+// Normalize rewrites TOML and JSON front matter as a YAML block, which is what
+// the Markdown parser reads. Other sources are returned unchanged.
+func Normalize(source []byte) ([]byte, error) {
+	trimmed := bytes.TrimPrefix(source, bom)
+	var fm map[string]interface{}
+	var body []byte
+	switch {
+	case bytes.HasPrefix(trimmed, tomlDelim):
+		block, rest, ok := split(trimmed, tomlDelim)
+		if !ok {
+			return nil, fmt.Errorf("TOML front matter: missing closing +++")
+		}
+		if err := toml.Unmarshal(block, &fm); err != nil {
+			return nil, fmt.Errorf("TOML front matter: %w", err)
+		}
+		body = rest
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		if err := dec.Decode(&fm); err != nil {
+			return nil, fmt.Errorf("JSON front matter: %w", err)
+		}
+		body = trimmed[dec.InputOffset():]
+	default:
+		return source, nil
+	}
+
+	out, err := yaml.Marshal(yamlValue(fm))
+	if err != nil {
+		return nil, fmt.Errorf("front matter: %w", err)
+	}
+	var b bytes.Buffer
+	b.Grow(len(out) + len(body) + 8)
+	b.Write(yamlDelim)
+	b.WriteByte('\n')
+	if len(fm) > 0 {
+		b.Write(out)
+	}
+	b.Write(yamlDelim)
+	b.WriteByte('\n')
+	b.Write(bytes.TrimLeft(body, "\r\n"))
+	return b.Bytes(), nil
+}
+
+// split cuts a block delimited by lines of delim off the start of source
+func split(source, delim []byte) (block, rest []byte, ok bool) {
+	after := source[len(delim):]
+	nl := bytes.IndexByte(after, '\n')
+	if nl < 0 || len(bytes.TrimSpace(after[:nl])) > 0 {
+		return nil, nil, false // The opening delimiter must be alone on its line
+	}
+	after = after[nl+1:]
+	for offset := 0; offset < len(after); {
+		end := bytes.IndexByte(after[offset:], '\n')
+		line := after[offset:]
+		if end >= 0 {
+			line = after[offset : offset+end]
+		}
+		if bytes.Equal(bytes.TrimRight(line, " \t\r"), delim) {
+			rest = after[offset+len(line):]
+			return after[:offset], rest, true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return nil, nil, false
+}
+
+// yamlValue prepares decoded TOML and JSON for YAML: TOML dates and times
+// become strings in the layout they were written in
+func yamlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = yamlValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = yamlValue(e)
+		}
+	case []map[string]interface{}:
+		for _, m := range v {
+			yamlValue(m)
+		}
+	case time.Time:
+		switch v.Location().String() { // TOML's local date and time zones
+		case "date-local":
+			return v.Format("2006-01-02")
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05")
+		case "time-local":
+			return v.Format("15:04:05")
+		}
+		return v.Format(time.RFC3339)
+	}
+	return v
+}
+
+// ParseDate parses a front matter date with the first matching layout.
+// Without layouts it uses DefaultDateFormats. Dates without a zone are in
+// loc, or UTC when loc is nil.
+func ParseDate(value string, layouts []string, loc *time.Location) (time.Time, error) {
+	if len(layouts) == 0 {
+		layouts = DefaultDateFormats
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date %q does not match %v", value, layouts)
+}
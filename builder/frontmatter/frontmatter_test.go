@@ -0,0 +1,130 @@
+package frontmatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "yaml is unchanged",
+			source: "---\ntitle: A\n---\nBody\n",
+			want:   "---\ntitle: A\n---\nBody\n",
+		},
+		{
+			name:   "no front matter",
+			source: "# Just Markdown\n",
+			want:   "# Just Markdown\n",
+		},
+		{
+			name:   "toml",
+			source: "+++\ntitle = \"A\"\ndate = 2024-01-05\ntags = [\"go\", \"web\"]\nweight = 3\n+++\n\nBody +++ text\n",
+			want:   "---\ndate: \"2024-01-05\"\ntags:\n    - go\n    - web\ntitle: A\nweight: 3\n---\nBody +++ text\n",
+		},
+		{
+			name:   "toml datetimes",
+			source: "+++\ndate = 2024-01-05T10:30:00+05:30\nlastmod = 2024-02-01T08:00:00\n+++\n",
+			want:   "---\ndate: \"2024-01-05T10:30:00+05:30\"\nlastmod: 2024-02-01T08:00:00\n---\n",
+		},
+		{
+			name:   "json",
+			source: "{\n  \"title\": \"A\",\n  \"draft\": true,\n  \"params\": {\"x\": 1.5}\n}\nBody\n",
+			want:   "---\ndraft: true\nparams:\n    x: 1.5\ntitle: A\n---\nBody\n",
+		},
+		{
+			name:   "empty toml",
+			source: "+++\n+++\nBody",
+			want:   "---\n---\nBody",
+		},
+		{
+			name:   "byte order mark",
+			source: "\xef\xbb\xbf+++\ntitle = \"A\"\n+++\n",
+			want:   "---\ntitle: A\n---\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize([]byte(tt.source))
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	tests := map[string]string{
+		"+++\ntitle = \"A\"\n":            "missing closing +++",
+		"+++\ntitle = A\n+++\n":           "TOML front matter",
+		"+++ title = 1\n+++\n":            "missing closing +++",
+		"{\"title\": \"A\",\n}\nBody":     "JSON front matter",
+		"{\"title\": \"A\"\n\nno brace\n": "JSON front matter",
+	}
+	for source, want := range tests {
+		if _, err := Normalize([]byte(source)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Normalize(%q) error = %v, want %q", source, err, want)
+		}
+	}
+}
+
+func TestNormalizedYAMLParses(t *testing.T) {
+	out, err := Normalize([]byte("+++\n[author]\nname = \"Ann\"\n[[links]]\nurl = \"/a\"\n+++\n"))
+	if err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+	parts := strings.SplitN(string(out), "---\n", 3)
+	var fm struct {
+		Author struct{ Name string }
+		Links  []struct{ URL string }
+	}
+	if err := yaml.Unmarshal([]byte(parts[1]), &fm); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	if fm.Author.Name != "Ann" || len(fm.Links) != 1 || fm.Links[0].URL != "/a" {
+		t.Errorf("front matter = %+v", fm)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+30*60)
+	tests := []struct {
+		value   string
+		layouts []string
+		loc     *time.Location
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-01-05", want: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-05T10:30:00Z", want: time.Date(2024, 1, 5, 10, 30, 0, 0, time.UTC)},
+		{value: "2024-01-05T10:30:00", want: time.Date(2024, 1, 5, 10, 30, 0, 0, time.UTC)},
+		{value: "2024-01-05", loc: ist, want: time.Date(2024, 1, 5, 0, 0, 0, 0, ist)},
+		{value: "2024-01-05T10:30:00", loc: ist, want: time.Date(2024, 1, 5, 10, 30, 0, 0, ist)},
+		{value: "2024-01-05T10:30:00Z", loc: ist, want: time.Date(2024, 1, 5, 10, 30, 0, 0, time.UTC)},
+		{value: "2024/01/05", wantErr: true},
+		{value: "05.01.2024", layouts: []string{"02.01.2006"}, want: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-05", layouts: []string{"02.01.2006"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.value, tt.layouts, tt.loc)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDate(%q) should fail", tt.value)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q, %v) = %v, %v, want %v", tt.value, tt.loc, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package frontmatter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Value types of front matter keys
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeNumber = "number"
+	TypeBool   = "bool"
+	TypeDate   = "date"
+	TypeList   = "list"
+	TypeMap    = "map"
+)
+
+// BuiltinKeys are the front matter keys kosh reads, with their types
+var BuiltinKeys = map[string]string{
+	"title":         TypeString,
+	"description":   TypeString,
+	"date":          TypeDate,
+	"lastmod":       TypeDate,
+	"tags":          TypeList,
+	"pinned":        TypeBool,
+	"weight":        TypeInt,
+	"draft":         TypeBool,
+	"image":         TypeString,
+	"lang":          TypeString,
+	"imageProfile":  TypeString,
+	"mathMacros":    TypeMap,
+	"bibliography":  TypeString,
+	"citationStyle": TypeString,
+	"sidenotes":     TypeBool,
+	"layout":        TypeString,
+}
+
+// Schema describes the front matter of posts. The zero Schema checks only
+// the types of the built-in keys and flags likely misspellings of them.
+type Schema struct {
+	Required    []string          `yaml:"required"`    // Keys every post must set
+	Types       map[string]string `yaml:"types"`       // Key → string, int, number, bool, date, list or map
+	Tags        []string          `yaml:"tags"`        // Allowed tags; empty allows any
+	DateFormats []string          `yaml:"dateFormats"` // Go layouts of dates (default: DefaultDateFormats)
+	Strict      bool              `yaml:"strict"`      // Report every key that is neither built in nor in Types
+	Level       string            `yaml:"level"`       // warn (default) or error, which skips the post
+}
+
+// Violation is a front matter key that does not fit the schema
+type Violation struct {
+	Key     string
+	Message string
+}
+
+func (v Violation) String() string { return v.Key + ": " + v.Message }
+
+// IsError reports whether violations are errors that keep a post from being built
+func (s Schema) IsError() bool { return strings.EqualFold(s.Level, "error") }
+
+// Check reports mistakes in the schema itself
+func (s Schema) Check() error {
+	switch strings.ToLower(s.Level) {
+	case "", "warn", "error":
+	default:
+		return fmt.Errorf("front matter schema: unknown level %q (use warn or error)", s.Level)
+	}
+	for key, typ := range s.Types {
+		if !validType(typ) {
+			return fmt.Errorf("front matter schema: unknown type %q of %q", typ, key)
+		}
+	}
+	return nil
+}
+
+func validType(typ string) bool {
+	switch typ {
+	case TypeString, TypeInt, TypeNumber, TypeBool, TypeDate, TypeList, TypeMap:
+		return true
+	}
+	return false
+}
+
+// Validate checks front matter against the schema. Violations are ordered by key.
+func (s Schema) Validate(fm map[string]interface{}) []Violation {
+	var violations []Violation
+	for _, key := range s.Required {
+		if v, ok := fm[key]; !ok || v == nil || v == "" {
+			violations = append(violations, Violation{key, "is required"})
+		}
+	}
+
+	keys := make([]string, 0, len(fm))
+	for key := range fm {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := fm[key]
+		typ, known := s.Types[key]
+		if !known {
+			typ, known = BuiltinKeys[key]
+		}
+		if !known {
+			if guess := s.closestKey(key); guess != "" {
+				violations = append(violations, Violation{key, fmt.Sprintf("unknown key, did you mean %q?", guess)})
+			} else if s.Strict && !s.required(key) {
+				violations = append(violations, Violation{key, "unknown key"})
+			}
+			continue
+		}
+		if value == nil {
+			continue // An empty value is left to Required
+		}
+		if msg := s.checkType(typ, value); msg != "" {
+			violations = append(violations, Violation{key, msg})
+		}
+	}
+
+	if len(s.Tags) > 0 {
+		allowed := make(map[string]bool, len(s.Tags))
+		for _, t := range s.Tags {
+			allowed[strings.ToLower(t)] = true
+		}
+		tags, _ := fm["tags"].([]interface{})
+		for _, t := range tags {
+			if tag := fmt.Sprint(t); !allowed[strings.ToLower(tag)] {
+				violations = append(violations, Violation{"tags", fmt.Sprintf("tag %q is not in the allowed tags", tag)})
+			}
+		}
+	}
+	return violations
+}
+
+// checkType describes how value fails to be of typ, or returns ""
+func (s Schema) checkType(typ string, value interface{}) string {
+	ok := false
+	switch typ {
+	case TypeString:
+		_, ok = value.(string)
+	case TypeBool:
+		_, ok = value.(bool)
+	case TypeInt:
+		switch n := value.(type) {
+		case int, int64, uint64:
+			ok = true
+		case float64:
+			ok = n == math.Trunc(n)
+		}
+	case TypeNumber:
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			ok = true
+		}
+	case TypeList:
+		_, ok = value.([]interface{})
+	case TypeMap:
+		switch value.(type) {
+		case map[interface{}]interface{}, map[string]interface{}:
+			ok = true
+		}
+	case TypeDate:
+		switch v := value.(type) {
+		case time.Time:
+			ok = true
+		case string:
+			if _, err := ParseDate(v, s.DateFormats, nil); err != nil {
+				layouts := s.DateFormats
+				if len(layouts) == 0 {
+					layouts = DefaultDateFormats
+				}
+				return fmt.Sprintf("%q is not a date in the format %s", v, strings.Join(layouts, " or "))
+			}
+			return ""
+		}
+	default:
+		return "" // Unknown types are reported by Check
+	}
+	if ok {
+		return ""
+	}
+	return fmt.Sprintf("%v is not of type %s", value, typ)
+}
+
+func (s Schema) required(key string) bool {
+	for _, r := range s.Required {
+		if r == key {
+			return true
+		}
+	}
+	return false
+}
+
+// closestKey returns the known key that key is likely a misspelling of
+func (s Schema) closestKey(key string) string {
+	maxDist := 1
+	if len(key) > 5 {
+		maxDist = 2
+	}
+	best, bestDist := "", maxDist+1
+	consider := func(k string) {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(k)); d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	for k := range BuiltinKeys {
+		consider(k)
+	}
+	for k := range s.Types {
+		consider(k)
+	}
+	for _, k := range s.Required {
+		consider(k)
+	}
+	if best == key {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and transpositions of adjacent letters
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,108 @@
+package frontmatter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema Schema
+		fm     map[string]interface{}
+		want   []string
+	}{
+		{
+			name: "valid built-in keys",
+			fm: map[string]interface{}{
+				"title": "A", "date": "2024-01-05T10:00:00Z", "tags": []interface{}{"go"}, "weight": 3,
+				"draft": false, "mathMacros": map[interface{}]interface{}{"R": `\mathbb{R}`}, "custom": 1,
+			},
+		},
+		{
+			name: "built-in types",
+			fm:   map[string]interface{}{"date": "2024/01/05", "tags": "go", "weight": 1.5, "draft": "no"},
+			want: []string{
+				`date: "2024/01/05" is not a date in the format 2006-01-02 or 2006-01-02T15:04:05Z07:00 or 2006-01-02T15:04:05`,
+				"draft: no is not of type bool",
+				"tags: go is not of type list",
+				"weight: 1.5 is not of type int",
+			},
+		},
+		{
+			name: "misspelled keys",
+			fm:   map[string]interface{}{"tage": []interface{}{"go"}, "Title": "A", "titel": "B", "author": "Ann"},
+			want: []string{
+				`Title: unknown key, did you mean "title"?`,
+				`tage: unknown key, did you mean "tags"?`,
+				`titel: unknown key, did you mean "title"?`,
+			},
+		},
+		{
+			name: "schema",
+			schema: Schema{
+				Required:    []string{"title", "summary"},
+				Types:       map[string]string{"summary": TypeString, "rating": TypeNumber, "weight": TypeString},
+				Tags:        []string{"Go", "Web"},
+				DateFormats: []string{"02.01.2006"},
+				Strict:      true,
+			},
+			fm: map[string]interface{}{
+				"title": "", "date": "05.01.2024", "rating": 4.5, "weight": "heavy",
+				"tags": []interface{}{"go", "rust"}, "author": "Ann", "sumary": "typo",
+			},
+			want: []string{
+				"title: is required",
+				"summary: is required",
+				"author: unknown key",
+				`sumary: unknown key, did you mean "summary"?`,
+				`tags: tag "rust" is not in the allowed tags`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range tt.schema.Validate(tt.fm) {
+				got = append(got, v.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	if err := (Schema{Types: map[string]string{"x": TypeDate}, Level: "Error"}).Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := (Schema{Types: map[string]string{"x": "datetime"}}).Check(); err == nil {
+		t.Error("Check() should reject unknown types")
+	}
+	if err := (Schema{Level: "fatal"}).Check(); err == nil {
+		t.Error("Check() should reject unknown levels")
+	}
+	if !(Schema{Level: "error"}).IsError() || (Schema{}).IsError() {
+		t.Error("IsError() should be true only for the error level")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"tags", "tags", 0},
+		{"tage", "tags", 1},
+		{"titel", "title", 1},
+		{"descripton", "description", 1},
+		{"lang", "date", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
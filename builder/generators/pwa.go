@@ -1,6 +1,7 @@
 package generators
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
 	"text/template"
@@ -9,17 +10,11 @@ import (
 	"github.com/spf13/afero"
 )
 
-// GenerateSW creates the service worker only if needed (smart build)
-func GenerateSW(destFs afero.Fs, destDir string, buildVersion int64, forceRebuild bool, baseURL string, assets map[string]string) error {
+// GenerateSW creates the service worker only if needed (smart build).
+// precache lists extra site-relative URLs (e.g. hashed search shards) to pre-cache.
+func GenerateSW(destFs afero.Fs, destDir string, buildVersion int64, forceRebuild bool, baseURL string, assets map[string]string, precache []string) error {
 	swPath := filepath.Join(destDir, "sw.js")
 
-	// 1. Smart Check: If not forcing rebuild and SW exists, skip
-	if !forceRebuild {
-		if exists, _ := afero.Exists(destFs, swPath); exists {
-			return nil
-		}
-	}
-
 	swTemplate := `
 const CACHE_NAME = 'kush-blog-cache-v{{ .Version }}';
 const STATIC_CACHE = 'kush-blog-static-v{{ .Version }}';
@@ -28,36 +23,34 @@ const STATIC_CACHE = 'kush-blog-static-v{{ .Version }}';
 const DEV_HOSTS = ['localhost', '127.0.0.1', '0.0.0.0'];
 
 // Core app shell assets
-const CORE_ASSETS = [
+{{ template "assets" . }}
+`
+	assetsTemplate := `const CORE_ASSETS = [
     '{{ .BaseURL }}/',
     '{{ .BaseURL }}/index.html',
     '{{ .BaseURL }}/404.html',
     '{{ .BaseURL }}/manifest.json'{{ range .CriticalAssets }},
+    '{{ $.BaseURL }}{{ . }}'{{ end }}{{ range .Precache }},
     '{{ $.BaseURL }}{{ . }}'{{ end }}
-];
-`
+];`
 
 	tmpl, err := template.New("sw").Parse(swTemplate)
 	if err != nil {
 		return err
 	}
-
-	if err := destFs.MkdirAll(filepath.Dir(swPath), 0755); err != nil {
+	if _, err := tmpl.New("assets").Parse(assetsTemplate); err != nil {
 		return err
 	}
-	f, err := destFs.Create(swPath)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = f.Close() }()
 
 	data := struct {
 		Version        int64
 		BaseURL        string
 		CriticalAssets []string
+		Precache       []string
 	}{
-		Version: buildVersion,
-		BaseURL: baseURL,
+		Version:  buildVersion,
+		BaseURL:  baseURL,
+		Precache: precache,
 	}
 
 	// Identify critical assets to pre-cache
@@ -78,6 +71,29 @@ const CORE_ASSETS = [
 		}
 	}
 
+	// 1. Smart Check: If not forcing rebuild and SW exists with the same asset list, skip
+	if !forceRebuild {
+		if existing, err := afero.ReadFile(destFs, swPath); err == nil {
+			var list bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&list, "assets", data); err != nil {
+				return err
+			}
+			// The list is delimited by its brackets, so this matches it entry for entry
+			if bytes.Contains(existing, list.Bytes()) {
+				return nil
+			}
+		}
+	}
+
+	if err := destFs.MkdirAll(filepath.Dir(swPath), 0755); err != nil {
+		return err
+	}
+	f, err := destFs.Create(swPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
 	return tmpl.Execute(f, data)
 }
 
@@ -0,0 +1,47 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestGenerateSWPrecache(t *testing.T) {
+	first := []string{"/search/manifest.bin?v=aaaa", "/search/v0-docs.bin?v=abcd"}
+	tests := []struct {
+		name      string
+		precache  []string
+		rewritten bool
+	}{
+		{"same list", first, false},
+		{"hash prefix", []string{"/search/manifest.bin?v=aaa", "/search/v0-docs.bin?v=abc"}, true},
+		{"entry removed", first[:1], true},
+		{"entry added", append(append([]string(nil), first...), "/search/trigrams-0.bin?v=ffff"), true},
+		{"manifest changed", []string{"/search/manifest.bin?v=bbbb", first[1]}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := GenerateSW(fs, "/public", 1, false, "", nil, first); err != nil {
+				t.Fatalf("GenerateSW: %v", err)
+			}
+			// A later build version only shows up if the file was rewritten
+			if err := GenerateSW(fs, "/public", 2, false, "", nil, tt.precache); err != nil {
+				t.Fatalf("GenerateSW: %v", err)
+			}
+			data, err := afero.ReadFile(fs, "/public/sw.js")
+			if err != nil {
+				t.Fatalf("read sw.js: %v", err)
+			}
+			if got := strings.Contains(string(data), "cache-v2"); got != tt.rewritten {
+				t.Errorf("rewritten = %v, want %v", got, tt.rewritten)
+			}
+			for _, url := range tt.precache {
+				if !strings.Contains(string(data), "'"+url+"'") {
+					t.Errorf("sw.js does not precache %q", url)
+				}
+			}
+		})
+	}
+}
@@ -11,26 +11,44 @@ import (
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 )
 
-func GenerateRSS(destFs afero.Fs, baseURL string, posts []models.PostMetadata, title, description string, outputPath string) {
+// GenerateRSS writes the RSS feed. formatDescription, if not nil, turns an item
+// description into the HTML placed in the feed, e.g. to render its math. Posts
+// without a description use their feed summary.
+func GenerateRSS(destFs afero.Fs, baseURL string, posts []models.PostMetadata, title, description string, outputPath string, formatDescription func(string) string) {
 	fmt.Println("📡 Generating RSS feed...")
 
 	var items []models.Item
+	var lastBuild time.Time
 	for _, p := range posts {
+		if p.LastMod.After(lastBuild) {
+			lastBuild = p.LastMod
+		}
+		desc := p.Description
+		if desc == "" {
+			desc = string(p.FeedSummary) // Already rendered, math included
+		} else if formatDescription != nil {
+			desc = formatDescription(desc)
+		}
 		items = append(items, models.Item{
 			Title:       p.Title,
 			Link:        p.Link,
-			Description: p.Description,
-			PubDate:     p.DateObj.Format(time.RFC1123),
+			Description: desc,
+			PubDate:     p.DateObj.Format(time.RFC1123Z),
 			Guid:        p.Link,
 		})
 	}
+	var lastBuildDate string
+	if !lastBuild.IsZero() {
+		lastBuildDate = lastBuild.Format(time.RFC1123Z)
+	}
 	rss := models.Rss{
 		Version: "2.0",
 		Channel: models.Channel{
-			Title:       title,
-			Link:        baseURL,
-			Description: description,
-			Items:       items,
+			Title:         title,
+			Link:          baseURL,
+			Description:   description,
+			LastBuildDate: lastBuildDate,
+			Items:         items,
 		},
 	}
 	output, _ := xml.MarshalIndent(rss, "", "  ")
@@ -0,0 +1,54 @@
+package generators
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+func TestGenerateRSS(t *testing.T) {
+	posts := []models.PostMetadata{
+		{
+			Title: "Described", Link: "https://example.com/a.html", Description: "Has $x$",
+			Summary: "<p>Ignored</p>", DateObj: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			LastMod: time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC),
+		},
+		{
+			Title: "Summarised", Link: "https://example.com/b.html", Summary: `<p><span class="katex">x</span></p>`,
+			FeedSummary: "<p>First <code>code</code></p>", DateObj: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	fs := afero.NewMemMapFs()
+	format := func(s string) string { return "[" + s + "]" }
+	GenerateRSS(fs, "https://example.com", posts, "Site", "About", "rss.xml", format)
+
+	data, err := afero.ReadFile(fs, "rss.xml")
+	if err != nil {
+		t.Fatalf("feed not written: %v", err)
+	}
+	var rss models.Rss
+	if err := xml.Unmarshal(data, &rss); err != nil {
+		t.Fatalf("invalid feed: %v", err)
+	}
+	items := rss.Channel.Items
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	tests := []struct {
+		name, got, want string
+	}{
+		{"description is formatted", items[0].Description, "[Has $x$]"},
+		{"feed summary replaces a missing description", items[1].Description, "<p>First <code>code</code></p>"},
+		{"pubDate", items[1].PubDate, "Thu, 01 Feb 2024 00:00:00 +0000"},
+		{"lastBuildDate is the latest lastmod", rss.Channel.LastBuildDate, "Mon, 04 Mar 2024 05:06:07 +0000"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -2,16 +2,63 @@ package generators
 
 import (
 	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"path/filepath"
+	"sort"
+	"unicode/utf8"
 
 	"github.com/spf13/afero"
 	"github.com/vmihailenco/msgpack/v5"
+	"github.com/zeebo/blake3"
 
 	"github.com/Kush-Singh-26/kosh/builder/models"
 	"github.com/Kush-Singh-26/kosh/builder/search"
 )
 
-func GenerateSearchIndex(destFs afero.Fs, outputDir string, indexedPosts []models.IndexedPost) error {
+const (
+	// SearchShardDir is the output subdirectory holding the sharded index
+	SearchShardDir = "search"
+	// SearchManifestFile is the manifest clients load before any shard
+	SearchManifestFile = "manifest.bin"
+)
+
+// searchShardMaxTerms is the soft limit of terms per term range shard.
+// Ranges only break between first runes, so a shard may exceed it. Tests
+// lower it to split small indexes.
+var searchShardMaxTerms = 2048
+
+// SearchIndexOptions controls optional search index features
+type SearchIndexOptions struct {
+	// Synonyms is shipped in the index and expanded at query time
+	Synonyms map[string][]string
+	// IndexSynonyms also adds synonym terms to the posts that mention a key
+	IndexSynonyms bool
+	// Postings is the inverted index maintained in the build cache (term -> PostID -> frequency).
+	// When it covers every post it is used instead of re-inverting each post's WordFreqs.
+	Postings map[string]map[string]int
+	// Grams is the trigram index maintained with Postings (trigram -> sorted terms)
+	Grams map[string][]string
+}
+
+// GenerateSearchIndex writes the monolithic search.bin used by existing themes
+// plus a sharded copy (manifest + per-version and per-term-range shards) under search/
+func GenerateSearchIndex(destFs afero.Fs, outputDir string, indexedPosts []models.IndexedPost, opts SearchIndexOptions) error {
+	index := buildSearchIndex(indexedPosts, opts)
+
+	if err := destFs.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	if err := writeSearchFile(destFs, filepath.Join(outputDir, "search.bin"), &index); err != nil {
+		return err
+	}
+
+	return writeSearchShards(destFs, filepath.Join(outputDir, SearchShardDir), &index)
+}
+
+func buildSearchIndex(indexedPosts []models.IndexedPost, opts SearchIndexOptions) models.SearchIndex {
 	totalDocs := len(indexedPosts)
 	estimatedUniqueWords := totalDocs * 100
 
@@ -20,68 +67,401 @@ func GenerateSearchIndex(destFs afero.Fs, outputDir string, indexedPosts []model
 		Inverted: make(map[string]map[int]int, estimatedUniqueWords),
 		DocLens:  make(map[int]int, totalDocs),
 		StemMap:  make(map[string][]string),
+		Synonyms: search.NormalizeSynonyms(opts.Synonyms),
 	}
 
-	analyzer := search.NewAnalyzer(true, true)
+	maintained := maintainedPostings(indexedPosts, opts.Postings)
+	for term, posts := range maintained {
+		index.Inverted[term] = posts
+	}
+	synonymsKey := search.SynonymsKey(index.Synonyms)
 
+	names := make([]string, 0, totalDocs)
 	totalLen := 0
 	for i, ip := range indexedPosts {
+		analyzer := search.AnalyzerByName(ip.Record.Analyzer)
+		names = append(names, analyzer.Name())
+
 		index.Posts[i] = ip.Record
 		index.DocLens[i] = ip.DocLen
 		totalLen += ip.DocLen
 
-		for word, freq := range ip.WordFreqs {
-			postMap, ok := index.Inverted[word]
-			if !ok {
-				postMap = make(map[int]int, 4)
-				index.Inverted[word] = postMap
+		if maintained == nil {
+			for word, freq := range ip.WordFreqs {
+				postMap, ok := index.Inverted[word]
+				if !ok {
+					postMap = make(map[int]int, 4)
+					index.Inverted[word] = postMap
+				}
+				postMap[i] = freq
 			}
-			postMap[i] = freq
 		}
 
-		// Build stem map for fuzzy matching
-		stemmed, originals := analyzer.AnalyzeWithOriginals(ip.Record.Content)
-		for j, stem := range stemmed {
-			if j < len(originals) {
-				orig := originals[j]
-				if stem != orig {
-					existing := index.StemMap[stem]
-					found := false
-					for _, e := range existing {
-						if e == orig {
-							found = true
-							break
-						}
-					}
-					if !found {
-						index.StemMap[stem] = append(index.StemMap[stem], orig)
+		if opts.IndexSynonyms {
+			terms := ip.Synonyms
+			if ip.SynonymsKey != synonymsKey { // Expanded with another table, or not at all
+				text := ip.Record.Title + " " + ip.Record.Content
+				terms = search.ExpandSynonyms(text, index.Synonyms, []search.Analyzer{analyzer}, nil)
+			}
+			for _, term := range terms {
+				if _, ok := ip.WordFreqs[term]; ok {
+					continue
+				}
+				postMap, ok := index.Inverted[term]
+				if !ok {
+					postMap = make(map[int]int, 4)
+					index.Inverted[term] = postMap
+				}
+				postMap[i] = 1
+			}
+		}
+
+		// Stem map for fuzzy matching, from the stems found when the post was analyzed
+		for stem, originals := range ip.Stems {
+			for _, orig := range originals {
+				found := false
+				for _, e := range index.StemMap[stem] {
+					if e == orig {
+						found = true
+						break
 					}
 				}
+				if !found {
+					index.StemMap[stem] = append(index.StemMap[stem], orig)
+				}
 			}
 		}
 	}
+	for _, originals := range index.StemMap {
+		sort.Strings(originals) // Posts merge in map order
+	}
 
+	index.Analyzers = search.AnalyzerNames(names)
 	index.TotalDocs = len(indexedPosts)
 	if index.TotalDocs > 0 {
 		index.AvgDocLen = float64(totalLen) / float64(index.TotalDocs)
 	}
 
 	// Build ngram index for fast fuzzy search
-	index.NgramIndex = search.BuildNgramIndex(index.Inverted)
+	if maintained != nil && opts.Grams != nil {
+		index.NgramIndex = maintainedGrams(opts.Grams, opts.Postings, index.Inverted)
+	} else {
+		index.NgramIndex = search.BuildNgramIndex(index.Inverted)
+	}
 
-	if err := destFs.MkdirAll(outputDir, 0755); err != nil {
+	return index
+}
+
+// maintainedGrams narrows the cached trigram index to the indexed terms and adds
+// the terms that only synonyms contributed, instead of rebuilding it per term
+func maintainedGrams(grams map[string][]string, postings map[string]map[string]int, inverted map[string]map[int]int) map[string][]string {
+	result := make(map[string][]string, len(grams))
+	for tg, terms := range grams {
+		kept := terms
+		for i, term := range terms {
+			if _, ok := inverted[term]; ok {
+				continue
+			}
+			// A term of unindexed posts only, such as drafts: copy the rest
+			kept = append([]string(nil), terms[:i]...)
+			for _, t := range terms[i+1:] {
+				if _, ok := inverted[t]; ok {
+					kept = append(kept, t)
+				}
+			}
+			break
+		}
+		if len(kept) > 0 {
+			result[tg] = kept
+		}
+	}
+	for term := range inverted {
+		if _, ok := postings[term]; ok {
+			continue
+		}
+		for _, tg := range search.Trigrams(term) {
+			terms := result[tg]
+			result[tg] = append(terms[:len(terms):len(terms)], term) // Never into the cached slice
+		}
+	}
+	return result
+}
+
+// maintainedPostings remaps cached postings from PostIDs to index positions.
+// Returns nil, so the caller re-inverts WordFreqs, when postings are missing or
+// disagree with any post's term count.
+func maintainedPostings(indexedPosts []models.IndexedPost, postings map[string]map[string]int) map[string]map[int]int {
+	if postings == nil {
+		return nil
+	}
+
+	positions := make(map[string]int, len(indexedPosts))
+	for i, ip := range indexedPosts {
+		if ip.PostID == "" {
+			return nil
+		}
+		positions[ip.PostID] = i
+	}
+
+	inverted := make(map[string]map[int]int, len(postings))
+	termCounts := make([]int, len(indexedPosts))
+	for term, posts := range postings {
+		for postID, freq := range posts {
+			i, ok := positions[postID]
+			if !ok {
+				continue
+			}
+			postMap, ok := inverted[term]
+			if !ok {
+				postMap = make(map[int]int, len(posts))
+				inverted[term] = postMap
+			}
+			postMap[i] = freq
+			termCounts[i]++
+		}
+	}
+
+	for i, ip := range indexedPosts {
+		if termCounts[i] != len(ip.WordFreqs) {
+			return nil
+		}
+	}
+	return inverted
+}
+
+// writeSearchShards splits the index per version, then splits each version's
+// terms into ranges of first runes. Every shard is named after its position
+// and carries a content hash in the manifest for cache busting.
+func writeSearchShards(destFs afero.Fs, shardDir string, index *models.SearchIndex) error {
+	if err := destFs.MkdirAll(shardDir, 0755); err != nil {
+		return err
+	}
+
+	manifest := models.SearchManifest{
+		Format:    models.SearchShardFormat,
+		TotalDocs: index.TotalDocs,
+		AvgDocLen: index.AvgDocLen,
+		Analyzers: index.Analyzers,
+		Synonyms:  index.Synonyms,
+	}
+
+	// Group global post IDs by version, in a stable order
+	docsByVersion := make(map[string][]int)
+	for id, post := range index.Posts {
+		docsByVersion[post.Version] = append(docsByVersion[post.Version], id)
+	}
+	versions := make([]string, 0, len(docsByVersion))
+	for v := range docsByVersion {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	for vi, version := range versions {
+		ids := docsByVersion[version]
+		localIDs := make(map[int]int, len(ids))
+
+		docs := models.SearchDocShard{
+			Posts:   make([]models.PostRecord, len(ids)),
+			DocLens: make([]int, len(ids)),
+		}
+		for local, id := range ids {
+			localIDs[id] = local
+			docs.Posts[local] = index.Posts[id]
+			docs.Posts[local].ID = local
+			docs.DocLens[local] = index.DocLens[id]
+		}
+
+		docsRef, err := writeSearchShard(destFs, shardDir, fmt.Sprintf("v%d-docs.bin", vi), &docs)
+		if err != nil {
+			return err
+		}
+		entry := models.SearchVersionShard{Version: version, Docs: docsRef}
+
+		// Collect this version's postings, remapped to local IDs
+		inverted := make(map[string]map[int]int)
+		for term, postings := range index.Inverted {
+			for id, freq := range postings {
+				local, ok := localIDs[id]
+				if !ok {
+					continue
+				}
+				postMap, ok := inverted[term]
+				if !ok {
+					postMap = make(map[int]int, 4)
+					inverted[term] = postMap
+				}
+				postMap[local] = freq
+			}
+		}
+
+		terms := make([]string, 0, len(inverted))
+		for term := range inverted {
+			terms = append(terms, term)
+		}
+		sort.Strings(terms)
+
+		for ti, group := range splitTermRanges(terms, searchShardMaxTerms) {
+			shard := models.SearchTermShard{
+				Inverted: make(map[string]map[int]int, len(group)),
+				DocFreqs: make(map[string]int, len(group)),
+			}
+			for _, term := range group {
+				shard.Inverted[term] = inverted[term]
+				shard.DocFreqs[term] = len(index.Inverted[term])
+			}
+
+			ref, err := writeSearchShard(destFs, shardDir, fmt.Sprintf("v%d-terms-%d.bin", vi, ti), &shard)
+			if err != nil {
+				return err
+			}
+			entry.Terms = append(entry.Terms, models.SearchTermRange{
+				Start: firstRune(group[0]),
+				End:   firstRune(group[len(group)-1]),
+				Shard: ref,
+			})
+		}
+
+		manifest.Versions = append(manifest.Versions, entry)
+	}
+
+	trigrams, err := writeTrigramShards(destFs, shardDir, index.NgramIndex)
+	if err != nil {
 		return err
 	}
+	manifest.Trigrams = trigrams
+
+	return writeSearchFile(destFs, filepath.Join(shardDir, SearchManifestFile), &manifest)
+}
 
-	file, err := destFs.Create(filepath.Join(outputDir, "search.bin"))
+// writeTrigramShards splits the trigram index of all versions into ranges of
+// first runes, so fuzzy candidates load on demand instead of with the manifest
+func writeTrigramShards(destFs afero.Fs, shardDir string, ngrams map[string][]string) ([]models.SearchTermRange, error) {
+	keys := make([]string, 0, len(ngrams))
+	for key := range ngrams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var ranges []models.SearchTermRange
+	for gi, group := range splitTermRanges(keys, searchShardMaxTerms) {
+		shard := models.SearchTrigramShard{Ngrams: make(map[string][]string, len(group))}
+		for _, key := range group {
+			terms := append([]string(nil), ngrams[key]...)
+			sort.Strings(terms) // Stable bytes, so the hash only changes with the terms
+			shard.Ngrams[key] = terms
+		}
+		ref, err := writeSearchShard(destFs, shardDir, fmt.Sprintf("trigrams-%d.bin", gi), &shard)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, models.SearchTermRange{
+			Start: firstRune(group[0]),
+			End:   firstRune(group[len(group)-1]),
+			Shard: ref,
+		})
+	}
+	return ranges, nil
+}
+
+// splitTermRanges groups sorted terms into chunks of roughly maxTerms,
+// only breaking where the first rune changes so ranges never overlap
+func splitTermRanges(terms []string, maxTerms int) [][]string {
+	var groups [][]string
+	start := 0
+	for i := 1; i <= len(terms); i++ {
+		if i == len(terms) {
+			groups = append(groups, terms[start:i])
+			break
+		}
+		if i-start >= maxTerms && firstRune(terms[i]) != firstRune(terms[i-1]) {
+			groups = append(groups, terms[start:i])
+			start = i
+		}
+	}
+	return groups
+}
+
+func firstRune(term string) string {
+	r, _ := utf8.DecodeRuneInString(term)
+	return string(r)
+}
+
+// writeSearchShard encodes a shard and returns its reference with content hash
+func writeSearchShard(destFs afero.Fs, shardDir, name string, v interface{}) (models.SearchShardRef, error) {
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		return models.SearchShardRef{}, err
+	}
+	ref := models.SearchShardRef{File: name, Hash: shardHash(data)}
+	return ref, writeGzip(destFs, filepath.Join(shardDir, name), data)
+}
+
+// shardHash versions a shard URL by its decompressed content
+func shardHash(data []byte) string {
+	sum := blake3.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func writeSearchFile(destFs afero.Fs, path string, v interface{}) error {
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeGzip(destFs, path, data)
+}
+
+func writeGzip(destFs afero.Fs, path string, data []byte) error {
+	file, err := destFs.Create(path)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = file.Close() }()
 
 	gw := gzip.NewWriter(file)
-	defer func() { _ = gw.Close() }()
+	if _, err := gw.Write(data); err != nil {
+		_ = gw.Close()
+		return err
+	}
+	return gw.Close()
+}
+
+// SearchShardURLs lists the site-relative URLs of the sharded index (manifest
+// first), each versioned by its content hash. Returns nil if no manifest exists.
+func SearchShardURLs(fs afero.Fs, outputDir string) []string {
+	file, err := fs.Open(filepath.Join(outputDir, SearchShardDir, SearchManifestFile))
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = gr.Close() }()
 
-	enc := msgpack.NewEncoder(gw)
-	return enc.Encode(&index)
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		return nil
+	}
+	var manifest models.SearchManifest
+	if err := msgpack.Unmarshal(data, &manifest); err != nil {
+		return nil
+	}
+
+	prefix := "/" + SearchShardDir + "/"
+	urls := []string{prefix + SearchManifestFile + "?v=" + shardHash(data)}
+	addRef := func(ref models.SearchShardRef) {
+		urls = append(urls, prefix+ref.File+"?v="+ref.Hash)
+	}
+	for _, v := range manifest.Versions {
+		addRef(v.Docs)
+		for _, t := range v.Terms {
+			addRef(t.Shard)
+		}
+	}
+	for _, t := range manifest.Trigrams {
+		addRef(t.Shard)
+	}
+	return urls
 }
@@ -0,0 +1,350 @@
+package generators
+
+import (
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/search"
+)
+
+func testIndexedPosts() []models.IndexedPost {
+	docs := []struct {
+		title, content, version string
+	}{
+		{"Transformers", "attention layers in transformer models", ""},
+		{"Convolutions", "convolution kernels and pooling layers", ""},
+		{"Old Transformers", "attention in the legacy transformer docs", "v1.0"},
+		{"Zebra Notes", "zebra crossing and yak shaving", "v1.0"},
+	}
+
+	posts := make([]models.IndexedPost, len(docs))
+	for i, d := range docs {
+		words := search.DefaultAnalyzer.Analyze(d.title + " " + d.content)
+		freqs := make(map[string]int)
+		for _, w := range words {
+			freqs[w]++
+		}
+		posts[i] = models.IndexedPost{
+			Record: models.PostRecord{
+				ID: i, Title: d.title, NormalizedTitle: strings.ToLower(d.title),
+				Link: strings.ToLower(d.title) + ".html", Content: d.content, Version: d.version,
+			},
+			WordFreqs: freqs,
+			DocLen:    len(words),
+		}
+	}
+	return posts
+}
+
+func readGzip(t *testing.T, fs afero.Fs, path string) []byte {
+	t.Helper()
+	f, err := fs.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip %s: %v", path, err)
+	}
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return data
+}
+
+func loadShardedIndex(t *testing.T, fs afero.Fs, outputDir string) *search.ShardedIndex {
+	t.Helper()
+	shardDir := filepath.Join(outputDir, SearchShardDir)
+
+	var manifest models.SearchManifest
+	if err := msgpack.Unmarshal(readGzip(t, fs, filepath.Join(shardDir, SearchManifestFile)), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if manifest.Format != models.SearchShardFormat {
+		t.Fatalf("manifest format = %d, want %d", manifest.Format, models.SearchShardFormat)
+	}
+
+	return search.NewShardedIndex(manifest, func(ref models.SearchShardRef) ([]byte, error) {
+		return readGzip(t, fs, filepath.Join(shardDir, ref.File)), nil
+	})
+}
+
+func scoresByLink(results []search.Result) map[string]float64 {
+	scores := make(map[string]float64, len(results))
+	for _, r := range results {
+		scores[r.Link] = r.Score
+	}
+	return scores
+}
+
+func TestGenerateSearchIndexShards(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	posts := testIndexedPosts()
+	if err := GenerateSearchIndex(fs, "/public", posts, SearchIndexOptions{}); err != nil {
+		t.Fatalf("GenerateSearchIndex: %v", err)
+	}
+
+	var full models.SearchIndex
+	if err := msgpack.Unmarshal(readGzip(t, fs, "/public/search.bin"), &full); err != nil {
+		t.Fatalf("decode search.bin: %v", err)
+	}
+
+	tests := []struct {
+		query   string
+		version string
+	}{
+		{"attention", ""},
+		{"attention", "v1.0"},
+		{"attention", "all"},
+		{"layers", "all"},
+		{"zebra", "v1.0"},
+		{"atention", "all"}, // fuzzy
+		{`"pooling layers"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+"@"+tt.version, func(t *testing.T) {
+			sharded := loadShardedIndex(t, fs, "/public")
+			got, err := sharded.Search(tt.query, tt.version, "")
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			want := search.PerformSearch(&full, tt.query, tt.version, "")
+			if len(want) == 0 {
+				t.Fatalf("monolithic index returned no results")
+			}
+			// Ties may come back in any order, so compare scores per link
+			if !reflect.DeepEqual(scoresByLink(got), scoresByLink(want)) {
+				t.Errorf("sharded scores = %v, want %v", scoresByLink(got), scoresByLink(want))
+			}
+		})
+	}
+}
+
+// TestShardedFuzzyColdAndWarm checks that a typo finds the same posts on a
+// fresh index as on one that earlier queries filled, even when the typo's
+// first rune points at another shard than the term it matches
+func TestShardedFuzzyColdAndWarm(t *testing.T) {
+	defer func(n int) { searchShardMaxTerms = n }(searchShardMaxTerms)
+	searchShardMaxTerms = 1 // One shard per first rune
+
+	fs := afero.NewMemMapFs()
+	if err := GenerateSearchIndex(fs, "/public", testIndexedPosts(), SearchIndexOptions{}); err != nil {
+		t.Fatalf("GenerateSearchIndex: %v", err)
+	}
+	var full models.SearchIndex
+	if err := msgpack.Unmarshal(readGzip(t, fs, "/public/search.bin"), &full); err != nil {
+		t.Fatalf("decode search.bin: %v", err)
+	}
+
+	for _, query := range []string{"xransformer", "ttention", "convolution", "zebra"} {
+		for _, version := range []string{"", "v1.0", "all"} {
+			want := search.PerformSearch(&full, query, version, "")
+
+			cold := loadShardedIndex(t, fs, "/public")
+			got, err := cold.Search(query, version, "")
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			warm := loadShardedIndex(t, fs, "/public")
+			for _, q := range []string{"transformer", "attention", "layers", "yak"} {
+				if _, err := warm.Search(q, "all", ""); err != nil {
+					t.Fatalf("Search: %v", err)
+				}
+			}
+			gotWarm, err := warm.Search(query, version, "")
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			if !reflect.DeepEqual(scoresByLink(got), scoresByLink(want)) {
+				t.Errorf("%s@%s cold scores = %v, want %v", query, version, scoresByLink(got), scoresByLink(want))
+			}
+			if !reflect.DeepEqual(scoresByLink(gotWarm), scoresByLink(got)) {
+				t.Errorf("%s@%s warm scores = %v, cold %v", query, version, scoresByLink(gotWarm), scoresByLink(got))
+			}
+		}
+	}
+	if want := search.PerformSearch(&full, "xransformer", "all", ""); len(want) != 2 {
+		t.Errorf("typo matched %d posts in the full index, want 2", len(want))
+	}
+}
+
+func TestShardedIndexLoadsLazily(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := GenerateSearchIndex(fs, "/public", testIndexedPosts(), SearchIndexOptions{}); err != nil {
+		t.Fatalf("GenerateSearchIndex: %v", err)
+	}
+
+	sharded := loadShardedIndex(t, fs, "/public")
+	if _, err := sharded.Search("zebra", "v1.0", ""); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	// Docs shard and the single term shard of v1.0, plus the trigram shard
+	if n := sharded.LoadedShards(); n != 3 {
+		t.Errorf("LoadedShards() = %d, want 3", n)
+	}
+
+	if _, err := sharded.Search("zebra", "v1.0", ""); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if n := sharded.LoadedShards(); n != 3 {
+		t.Errorf("LoadedShards() after repeat = %d, want 3", n)
+	}
+}
+
+func TestSearchShardURLs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if got := SearchShardURLs(fs, "/public"); got != nil {
+		t.Errorf("SearchShardURLs without manifest = %v, want nil", got)
+	}
+
+	if err := GenerateSearchIndex(fs, "/public", testIndexedPosts(), SearchIndexOptions{}); err != nil {
+		t.Fatalf("GenerateSearchIndex: %v", err)
+	}
+
+	urls := SearchShardURLs(fs, "/public")
+	want := []string{
+		"/search/manifest.bin?v=",
+		"/search/v0-docs.bin?v=",
+		"/search/v0-terms-0.bin?v=",
+		"/search/v1-docs.bin?v=",
+		"/search/v1-terms-0.bin?v=",
+		"/search/trigrams-0.bin?v=",
+	}
+	if len(urls) != len(want) {
+		t.Fatalf("SearchShardURLs() = %v", urls)
+	}
+	for i := range want {
+		if !strings.HasPrefix(urls[i], want[i]) {
+			t.Errorf("url %d = %q, want prefix %q", i, urls[i], want[i])
+		}
+	}
+}
+
+func TestSplitTermRanges(t *testing.T) {
+	terms := []string{"aa", "ab", "ac", "ba", "bb", "ca"}
+
+	got := splitTermRanges(terms, 2)
+	want := [][]string{{"aa", "ab", "ac"}, {"ba", "bb"}, {"ca"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitTermRanges() = %v, want %v", got, want)
+	}
+
+	if got := splitTermRanges(nil, 2); got != nil {
+		t.Errorf("splitTermRanges(nil) = %v, want nil", got)
+	}
+}
+
+func TestGenerateSearchIndexSynonyms(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	opts := SearchIndexOptions{
+		Synonyms:      map[string][]string{"cnn": {"convolution"}},
+		IndexSynonyms: true,
+	}
+	if err := GenerateSearchIndex(fs, "/public", testIndexedPosts(), opts); err != nil {
+		t.Fatalf("GenerateSearchIndex: %v", err)
+	}
+
+	var full models.SearchIndex
+	if err := msgpack.Unmarshal(readGzip(t, fs, "/public/search.bin"), &full); err != nil {
+		t.Fatalf("decode search.bin: %v", err)
+	}
+	if len(full.Synonyms["cnn"]) == 0 {
+		t.Fatalf("synonyms not shipped in index: %v", full.Synonyms)
+	}
+	// Index-time expansion adds "cnn" to the post mentioning convolutions
+	if _, ok := full.Inverted["cnn"][1]; !ok {
+		t.Errorf("Inverted[cnn] = %v, want post 1", full.Inverted["cnn"])
+	}
+
+	sharded := loadShardedIndex(t, fs, "/public")
+	got, err := sharded.Search("cnn", "", "")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	want := search.PerformSearch(&full, "cnn", "", "")
+	if len(want) == 0 || !reflect.DeepEqual(scoresByLink(got), scoresByLink(want)) {
+		t.Errorf("sharded scores = %v, want %v", scoresByLink(got), scoresByLink(want))
+	}
+}
+
+func TestBuildSearchIndexStoredSynonyms(t *testing.T) {
+	table := map[string][]string{"cnn": {"convolution"}}
+	key := search.SynonymsKey(search.NormalizeSynonyms(table))
+	tests := []struct {
+		name     string
+		key      string
+		wantTerm string
+	}{
+		{"same table", key, "stored"},
+		{"other table", "stale", "cnn"}, // Expanded from the text again
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts := testIndexedPosts()
+			posts[1].Synonyms, posts[1].SynonymsKey = []string{"stored"}, tt.key
+			index := buildSearchIndex(posts, SearchIndexOptions{Synonyms: table, IndexSynonyms: true})
+			if _, ok := index.Inverted[tt.wantTerm][1]; !ok {
+				t.Errorf("Inverted[%s] = %v, want post 1", tt.wantTerm, index.Inverted[tt.wantTerm])
+			}
+		})
+	}
+}
+
+func TestBuildSearchIndexFromMaintainedPostings(t *testing.T) {
+	posts := testIndexedPosts()
+	postings := make(map[string]map[string]int)
+	for i := range posts {
+		posts[i].PostID = "post-" + posts[i].Record.Link
+		for term, freq := range posts[i].WordFreqs {
+			if postings[term] == nil {
+				postings[term] = make(map[string]int)
+			}
+			postings[term][posts[i].PostID] = freq
+		}
+	}
+
+	// A term of a post that is not indexed, such as a draft
+	postings["draftonly"] = map[string]int{"post-draft": 1}
+	grams := make(map[string][]string)
+	for term := range postings {
+		for _, tg := range search.Trigrams(term) {
+			grams[tg] = append(grams[tg], term)
+		}
+	}
+	for _, terms := range grams {
+		sort.Strings(terms)
+	}
+
+	opts := SearchIndexOptions{Synonyms: map[string][]string{"cnn": {"convolution"}}, IndexSynonyms: true}
+	want := buildSearchIndex(posts, opts)
+	opts.Postings, opts.Grams = postings, grams
+	got := buildSearchIndex(posts, opts)
+	if !reflect.DeepEqual(got.Inverted, want.Inverted) {
+		t.Errorf("maintained Inverted = %v, want %v", got.Inverted, want.Inverted)
+	}
+	for _, terms := range got.NgramIndex {
+		sort.Strings(terms)
+	}
+	if !reflect.DeepEqual(got.NgramIndex, search.BuildNgramIndex(want.Inverted)) {
+		t.Errorf("maintained NgramIndex = %v, want %v", got.NgramIndex, search.BuildNgramIndex(want.Inverted))
+	}
+
+	// Stale postings are ignored in favour of the posts' own frequencies
+	delete(postings, "zebra")
+	if got := maintainedPostings(posts, postings); got != nil {
+		t.Errorf("maintainedPostings() with stale postings = %v, want nil", got)
+	}
+}
@@ -27,7 +27,7 @@ func GenerateSitemap(destFs afero.Fs, baseURL string, posts []models.PostMetadat
 	for _, p := range posts {
 		urls = append(urls, models.Url{
 			Loc:     p.Link,
-			LastMod: p.DateObj.Format("2006-01-02"),
+			LastMod: sitemapDate(p.LastMod),
 		})
 	}
 
@@ -36,14 +36,14 @@ func GenerateSitemap(destFs afero.Fs, baseURL string, posts []models.PostMetadat
 		// Find the latest date among posts with this tag
 		var latest time.Time
 		for _, p := range tagPosts {
-			if p.DateObj.After(latest) {
-				latest = p.DateObj
+			if p.LastMod.After(latest) {
+				latest = p.LastMod
 			}
 		}
 
 		urls = append(urls, models.Url{
 			Loc:     fmt.Sprintf("%s/tags/%s.html", baseURL, url.PathEscape(t)),
-			LastMod: latest.Format("2006-01-02"),
+			LastMod: sitemapDate(latest),
 		})
 	}
 
@@ -59,3 +59,15 @@ func GenerateSitemap(destFs afero.Fs, baseURL string, posts []models.PostMetadat
 		fmt.Printf("⚠️ Failed to write sitemap.xml: %v\n", err)
 	}
 }
+
+// sitemapDate formats t as a W3C date, with the time only when it has one.
+// An unknown date is left out.
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
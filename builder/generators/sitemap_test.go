@@ -0,0 +1,56 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+func TestSitemapDate(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+	}{
+		{"unknown", time.Time{}, ""},
+		{"date only", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "2024-03-01"},
+		{"with time", time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), "2024-03-01T09:30:00Z"},
+		{"with zone", time.Date(2024, 3, 1, 9, 30, 0, 0, ist), "2024-03-01T09:30:00+05:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sitemapDate(tt.in); got != tt.want {
+				t.Errorf("sitemapDate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSitemap_LastMod(t *testing.T) {
+	date := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	post := models.PostMetadata{
+		Link:    "https://example.com/post.html",
+		DateObj: date,
+		LastMod: time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC),
+	}
+	fs := afero.NewMemMapFs()
+	GenerateSitemap(fs, "https://example.com", []models.PostMetadata{post},
+		map[string][]models.PostMetadata{"go": {post}}, "sitemap.xml")
+
+	data, err := afero.ReadFile(fs, "sitemap.xml")
+	if err != nil {
+		t.Fatalf("sitemap not written: %v", err)
+	}
+	out := string(data)
+	if strings.Contains(out, "2024-01-10") {
+		t.Errorf("sitemap uses the post date instead of lastmod:\n%s", out)
+	}
+	if n := strings.Count(out, "<lastmod>2024-02-05</lastmod>"); n != 2 {
+		t.Errorf("want lastmod on the post and its tag page, found %d:\n%s", n, out)
+	}
+}
@@ -0,0 +1,212 @@
+package gitinfo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testRepo builds a repository with the git executable:
+//
+//	day 1  add site/content/a.md, README.md
+//	day 2  add site/content/posts/b.md on a branch
+//	day 3  change a.md on main
+//	day 4  merge the branch
+//	day 5  change README.md only
+func testRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(day int, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		date := time.Date(2024, 1, day, 10, 0, 0, 0, time.FixedZone("", 5*3600+30*60)).Format(time.RFC3339)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=A", "GIT_AUTHOR_EMAIL=a@example.com", "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_NAME=A", "GIT_COMMITTER_EMAIL=a@example.com", "GIT_COMMITTER_DATE="+date,
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_SYSTEM=/dev/null",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git(1, "init", "-q", "-b", "main")
+	write("site/content/a.md", "a")
+	write("README.md", "r")
+	git(1, "add", ".")
+	git(1, "commit", "-q", "-m", "one")
+	git(2, "checkout", "-q", "-b", "topic")
+	write("site/content/posts/b.md", "b")
+	git(2, "add", ".")
+	git(2, "commit", "-q", "-m", "two")
+	git(3, "checkout", "-q", "main")
+	write("site/content/a.md", "a2")
+	git(3, "commit", "-q", "-am", "three")
+	git(4, "merge", "-q", "--no-ff", "-m", "merge", "topic")
+	write("README.md", "r2")
+	git(5, "commit", "-q", "-am", "five")
+	return dir
+}
+
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 10, 0, 0, 0, time.FixedZone("", 5*3600+30*60))
+}
+
+func checkHistory(t *testing.T, dir string) {
+	t.Helper()
+	h, err := Load(filepath.Join(dir, "site", "content"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tests := []struct {
+		path              string
+		created, modified time.Time
+	}{
+		{"site/content/a.md", day(1), day(3)},
+		{"site/content/posts/b.md", day(2), day(2)}, // The merge itself changes nothing
+	}
+	for _, tt := range tests {
+		d, ok := h.Get(filepath.Join(dir, tt.path))
+		if !ok {
+			t.Errorf("Get(%s) found nothing", tt.path)
+			continue
+		}
+		if !d.Created.Equal(tt.created) || !d.Modified.Equal(tt.modified) {
+			t.Errorf("Get(%s) = %v, %v, want %v, %v", tt.path, d.Created, d.Modified, tt.created, tt.modified)
+		}
+	}
+	if _, ok := h.Get(filepath.Join(dir, "README.md")); ok {
+		t.Error("files outside the directory should not be tracked")
+	}
+	if h.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", h.Len())
+	}
+	if d, _ := h.Get(filepath.Join(dir, "site/content/a.md")); d.Created.Format("-0700") != "+0530" {
+		t.Errorf("Created zone = %s, want the author's +0530", d.Created.Format("-0700"))
+	}
+}
+
+func TestLoadLooseObjects(t *testing.T) {
+	checkHistory(t, testRepo(t))
+}
+
+func TestLoadPackedObjects(t *testing.T) {
+	dir := testRepo(t)
+	cmd := exec.Command("git", "gc", "-q", "--aggressive")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git gc: %v\n%s", err, out)
+	}
+	if loose, _ := filepath.Glob(filepath.Join(dir, ".git", "objects", "??")); len(loose) > 0 {
+		t.Fatalf("objects left loose after gc: %v", loose)
+	}
+	checkHistory(t, dir)
+}
+
+func TestLoadShallowClone(t *testing.T) {
+	clone := filepath.Join(t.TempDir(), "clone")
+	git := func(dir string, d int, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		date := day(d).Format(time.RFC3339)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=A", "GIT_AUTHOR_EMAIL=a@example.com", "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_NAME=A", "GIT_COMMITTER_EMAIL=a@example.com", "GIT_COMMITTER_DATE="+date,
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_SYSTEM=/dev/null",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git(testRepo(t), 5, "clone", "-q", "--depth", "1", "--no-local", ".", clone)
+	if err := os.WriteFile(filepath.Join(clone, "site", "content", "a.md"), []byte("a3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(clone, 6, "commit", "-q", "-am", "six")
+
+	h, err := Load(filepath.Join(clone, "site", "content"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tests := []struct {
+		path              string
+		created, modified time.Time
+	}{
+		{"site/content/a.md", time.Time{}, day(6)},            // Created before the clone's boundary
+		{"site/content/posts/b.md", time.Time{}, time.Time{}}, // Unchanged since the boundary
+	}
+	for _, tt := range tests {
+		d, ok := h.Get(filepath.Join(clone, tt.path))
+		if !ok {
+			t.Errorf("Get(%s) found nothing", tt.path)
+			continue
+		}
+		if !d.Created.Equal(tt.created) || !d.Modified.Equal(tt.modified) {
+			t.Errorf("Get(%s) = %v, %v, want %v, %v", tt.path, d.Created, d.Modified, tt.created, tt.modified)
+		}
+	}
+}
+
+func TestHead(t *testing.T) {
+	dir := testRepo(t)
+	content := filepath.Join(dir, "site", "content")
+	h, err := Load(content)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	head, err := Head(content)
+	if err != nil || len(head) != 40 || head != h.Head() {
+		t.Fatalf("Head() = %q, %v, want the history's %q", head, err, h.Head())
+	}
+
+	cmd := exec.Command("git", "commit", "-q", "--allow-empty", "-m", "six")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=A", "GIT_AUTHOR_EMAIL=a@example.com",
+		"GIT_COMMITTER_NAME=A", "GIT_COMMITTER_EMAIL=a@example.com", "GIT_CONFIG_GLOBAL=/dev/null")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, out)
+	}
+	if moved, _ := Head(content); moved == head || moved == "" {
+		t.Errorf("Head() after a commit = %q, want a new commit", moved)
+	}
+}
+
+func TestLoadNotRepository(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Skip("the temporary directory is inside a repository")
+	}
+	var h *History
+	if _, ok := h.Get("x"); ok {
+		t.Error("a nil History should find nothing")
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello world")
+	// Source size 11, target size 9, copy 5 bytes at 6 ("world"), insert "!!!!"
+	delta := []byte{11, 9, 0x80 | 0x01 | 0x10, 6, 5, 4, '!', '!', '!', '!'}
+	got, err := applyDelta(base, delta)
+	if err != nil || string(got) != "world!!!!" {
+		t.Errorf("applyDelta() = %q, %v", got, err)
+	}
+	if _, err := applyDelta(base, []byte{12, 1, 1, 'x'}); err == nil {
+		t.Error("applyDelta() should reject a wrong source size")
+	}
+}
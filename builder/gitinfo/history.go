@@ -0,0 +1,373 @@
+package gitinfo
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dates are when a file was first and last committed. In a shallow clone, a
+// file that already exists in the oldest fetched commits has no Created date,
+// and no Modified date unless a later commit changed it.
+type Dates struct {
+	Created  time.Time // Author date of the first commit that added the file
+	Modified time.Time // Author date of the last commit that changed it
+}
+
+// History holds the dates of the files under a directory of a repository
+type History struct {
+	root  string
+	head  string           // Commit the history was read from; "" before the first commit
+	files map[string]Dates // Slash-separated paths relative to root
+}
+
+// Load reads the dates of the files under dir from the history of HEAD. Only
+// the commits that change dir are inspected. Renames are not followed, and a
+// shallow clone leaves the dates it cannot know zero rather than using the
+// date of the commit the clone stops at.
+func Load(dir string) (*History, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = repo.Close() }()
+
+	prefix, err := filepath.Rel(repo.Root, abs)
+	if err != nil {
+		return nil, err
+	}
+	prefix = filepath.ToSlash(prefix)
+	if prefix == "." {
+		prefix = ""
+	}
+
+	shallow, err := repo.shallowCommits()
+	if err != nil {
+		return nil, err
+	}
+	w := &walker{repo: repo, prefix: prefix, shallow: shallow, commits: map[hash]*commit{}, trees: map[hash][]treeEntry{}}
+	h := &History{root: repo.Root, files: map[string]Dates{}}
+	head, ok, err := repo.head()
+	if err != nil || !ok {
+		return h, err
+	}
+	h.head = head.String()
+	if err := w.walk(head, h.record); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Head returns the commit HEAD of the repository holding dir points at, or ""
+// before the first commit. It is cheap next to Load, so callers can keep a
+// History until HEAD moves.
+func Head(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	repo, err := Open(abs)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = repo.Close() }()
+	head, ok, err := repo.head()
+	if err != nil || !ok {
+		return "", err
+	}
+	return head.String(), nil
+}
+
+// Head returns the commit the history was read from
+func (h *History) Head() string {
+	if h == nil {
+		return ""
+	}
+	return h.head
+}
+
+// Get returns the dates of a file, by absolute path or relative to the working directory
+func (h *History) Get(path string) (Dates, bool) {
+	if h == nil {
+		return Dates{}, false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Dates{}, false
+	}
+	rel, err := filepath.Rel(h.root, abs)
+	if err != nil {
+		return Dates{}, false
+	}
+	d, ok := h.files[filepath.ToSlash(rel)]
+	return d, ok
+}
+
+// Len returns the number of files with dates
+func (h *History) Len() int { return len(h.files) }
+
+// record adds a commit of path at t; a zero t marks a file whose earlier
+// history is unknown
+func (h *History) record(path string, t time.Time) {
+	d, ok := h.files[path]
+	if !ok {
+		h.files[path] = Dates{Created: t, Modified: t}
+		return
+	}
+	if t.Before(d.Created) {
+		d.Created = t
+	}
+	if t.After(d.Modified) {
+		d.Modified = t
+	}
+	h.files[path] = d
+}
+
+type commit struct {
+	tree    hash
+	parents []hash
+	author  time.Time
+}
+
+type treeEntry struct {
+	name string
+	hash hash
+	dir  bool
+	file bool // A blob; symlinks count, submodules do not
+}
+
+// walker visits every commit reachable from HEAD
+type walker struct {
+	repo    *Repo
+	prefix  string        // Directory whose files are tracked
+	shallow map[hash]bool // Commits a shallow clone stops at
+	commits map[hash]*commit
+	trees   map[hash][]treeEntry
+}
+
+// walk reports each file a commit changed: files that differ from every
+// parent, so merges only count their own changes
+func (w *walker) walk(head hash, record func(string, time.Time)) error {
+	seen := map[hash]bool{head: true}
+	stack := []hash{head}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		c, err := w.commit(h)
+		if err != nil {
+			return err
+		}
+		tree, err := w.subtree(c.tree)
+		if err != nil {
+			return err
+		}
+
+		if w.shallow[h] {
+			// What this commit changed is unknown, so its files only get zero dates
+			files := map[string]bool{}
+			if err := w.diff(hash{}, tree, w.prefix, files); err != nil {
+				return err
+			}
+			for path := range files {
+				record(path, time.Time{})
+			}
+			continue
+		}
+
+		var changed map[string]bool
+		parents := 0
+		for _, ph := range c.parents {
+			p, err := w.commit(ph)
+			if err != nil {
+				continue // Missing from a shallow clone
+			}
+			parents++
+			if !seen[ph] {
+				seen[ph] = true
+				stack = append(stack, ph)
+			}
+			ptree, err := w.subtree(p.tree)
+			if err != nil {
+				return err
+			}
+			diff := map[string]bool{}
+			if err := w.diff(ptree, tree, w.prefix, diff); err != nil {
+				return err
+			}
+			if changed == nil {
+				changed = diff
+				continue
+			}
+			for path := range changed {
+				if !diff[path] {
+					delete(changed, path)
+				}
+			}
+		}
+		if parents == 0 { // A root commit adds everything
+			changed = map[string]bool{}
+			if err := w.diff(hash{}, tree, w.prefix, changed); err != nil {
+				return err
+			}
+		}
+		for path := range changed {
+			record(path, c.author)
+		}
+	}
+	return nil
+}
+
+func (w *walker) commit(h hash) (*commit, error) {
+	if c, ok := w.commits[h]; ok {
+		return c, nil
+	}
+	typ, data, err := w.repo.readObject(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != objCommit {
+		return nil, fmt.Errorf("object %s is not a commit", h)
+	}
+	c := &commit{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break // The message follows the headers
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			c.tree, _ = parseHash(value)
+		case "parent":
+			if p, ok := parseHash(value); ok {
+				c.parents = append(c.parents, p)
+			}
+		case "author":
+			c.author = signatureTime(value)
+		}
+	}
+	w.commits[h] = c
+	return c, nil
+}
+
+// signatureTime parses the time of "Name <email> 1700000000 +0530"
+func signatureTime(sig string) time.Time {
+	fields := strings.Fields(sig[strings.LastIndexByte(sig, '>')+1:])
+	if len(fields) != 2 {
+		return time.Time{}
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	t := time.Unix(sec, 0)
+	if tz, err := strconv.Atoi(fields[1]); err == nil {
+		offset := (tz/100*60 + tz%100) * 60
+		t = t.In(time.FixedZone("", offset))
+	}
+	return t
+}
+
+// subtree returns the tree of the tracked directory, or the zero hash
+func (w *walker) subtree(root hash) (hash, error) {
+	h := root
+	if w.prefix == "" {
+		return h, nil
+	}
+	for _, name := range strings.Split(w.prefix, "/") {
+		entries, err := w.tree(h)
+		if err != nil {
+			return hash{}, err
+		}
+		found := false
+		for _, e := range entries {
+			if e.name == name && e.dir {
+				h, found = e.hash, true
+				break
+			}
+		}
+		if !found {
+			return hash{}, nil
+		}
+	}
+	return h, nil
+}
+
+func (w *walker) tree(h hash) ([]treeEntry, error) {
+	if h == (hash{}) {
+		return nil, nil
+	}
+	if entries, ok := w.trees[h]; ok {
+		return entries, nil
+	}
+	typ, data, err := w.repo.readObject(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != objTree {
+		return nil, fmt.Errorf("object %s is not a tree", h)
+	}
+	var entries []treeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			return nil, fmt.Errorf("tree %s is corrupt", h)
+		}
+		mode := string(data[:sp])
+		e := treeEntry{name: string(data[sp+1 : nul]), dir: mode == "40000"}
+		e.file = strings.HasPrefix(mode, "100") || mode == "120000"
+		copy(e.hash[:], data[nul+1:nul+21])
+		entries = append(entries, e)
+		data = data[nul+21:]
+	}
+	w.trees[h] = entries
+	return entries, nil
+}
+
+// diff adds the files of tree b that are new or different in tree a
+func (w *walker) diff(a, b hash, prefix string, out map[string]bool) error {
+	if a == b {
+		return nil
+	}
+	before, err := w.tree(a)
+	if err != nil {
+		return err
+	}
+	after, err := w.tree(b)
+	if err != nil {
+		return err
+	}
+	old := make(map[string]treeEntry, len(before))
+	for _, e := range before {
+		old[e.name] = e
+	}
+	for _, e := range after {
+		path := e.name
+		if prefix != "" {
+			path = prefix + "/" + e.name
+		}
+		prev, existed := old[e.name]
+		switch {
+		case e.dir:
+			var base hash
+			if existed && prev.dir {
+				base = prev.hash
+			}
+			if err := w.diff(base, e.hash, path, out); err != nil {
+				return err
+			}
+		case e.file:
+			if !existed || !prev.file || prev.hash != e.hash {
+				out[path] = true
+			}
+		}
+	}
+	return nil
+}
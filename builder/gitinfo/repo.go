@@ -0,0 +1,470 @@
+// Package gitinfo reads when files were first and last committed from the
+// .git directory of a repository, without running git
+package gitinfo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotRepository is returned for paths outside any git worktree
+var ErrNotRepository = errors.New("not a git repository")
+
+// Object types, as numbered in packfiles
+const (
+	objCommit   = 1
+	objTree     = 2
+	objBlob     = 3
+	objTag      = 4
+	objOfsDelta = 6
+	objRefDelta = 7
+)
+
+var objTypes = map[string]int{"commit": objCommit, "tree": objTree, "blob": objBlob, "tag": objTag}
+
+type hash [20]byte
+
+func (h hash) String() string { return hex.EncodeToString(h[:]) }
+
+func parseHash(s string) (hash, bool) {
+	var h hash
+	if len(s) != 40 {
+		return h, false
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, false
+	}
+	return h, true
+}
+
+// Repo reads the objects and refs of a repository
+type Repo struct {
+	Root   string // Worktree root
+	gitDir string // .git, or the worktree's directory under .git/worktrees
+	common string // Directory of the objects and shared refs
+
+	packs     []*pack
+	packsRead bool
+}
+
+// Open finds the repository whose worktree contains path
+func Open(path string) (*Repo, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			gitDir := dotGit
+			if !info.IsDir() { // A linked worktree or submodule: "gitdir: <path>"
+				if gitDir, err = readGitFile(dotGit); err != nil {
+					return nil, err
+				}
+			}
+			return newRepo(dir, gitDir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("%s: %w", path, ErrNotRepository)
+		}
+		dir = parent
+	}
+}
+
+func readGitFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("%s: invalid gitdir file", path)
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	return gitDir, nil
+}
+
+func newRepo(root, gitDir string) (*Repo, error) {
+	r := &Repo{Root: root, gitDir: gitDir, common: gitDir}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		r.common = common
+	}
+	if cfg, err := os.ReadFile(filepath.Join(r.common, "config")); err == nil &&
+		bytes.Contains(bytes.ReplaceAll(cfg, []byte(" "), nil), []byte("objectformat=sha256")) {
+		return nil, errors.New("SHA-256 repositories are not supported")
+	}
+	return r, nil
+}
+
+// Close releases the open packfiles
+func (r *Repo) Close() error {
+	var firstErr error
+	for _, p := range r.packs {
+		if err := p.file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	r.packs = nil
+	return firstErr
+}
+
+// head resolves HEAD to a commit. ok is false on a branch without commits.
+func (r *Repo) head() (h hash, ok bool, err error) {
+	ref := "HEAD"
+	for i := 0; i < 10; i++ { // Symbolic refs nest at most a few levels
+		value, found, err := r.readRef(ref)
+		if err != nil || !found {
+			return h, false, err
+		}
+		if target, symbolic := strings.CutPrefix(value, "ref: "); symbolic {
+			ref = strings.TrimSpace(target)
+			continue
+		}
+		h, ok = parseHash(value)
+		if !ok {
+			return h, false, fmt.Errorf("ref %s: invalid value %q", ref, value)
+		}
+		return h, true, nil
+	}
+	return h, false, errors.New("HEAD: too many levels of symbolic refs")
+}
+
+// shallowCommits returns the commits a shallow clone stops at, listed in
+// .git/shallow, or nil for a full clone
+func (r *Repo) shallowCommits() (map[hash]bool, error) {
+	data, err := os.ReadFile(filepath.Join(r.common, "shallow"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	commits := map[hash]bool{}
+	for _, line := range strings.Fields(string(data)) {
+		if h, ok := parseHash(line); ok {
+			commits[h] = true
+		}
+	}
+	return commits, nil
+}
+
+// readRef reads a loose ref, then the packed refs
+func (r *Repo) readRef(name string) (string, bool, error) {
+	for _, dir := range []string{r.gitDir, r.common} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), true, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", false, err
+		}
+	}
+	f, err := os.Open(filepath.Join(r.common, "packed-refs"))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	defer func() { _ = f.Close() }()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if len(line) > 41 && line[40] == ' ' && line[41:] == name {
+			return line[:40], true, nil
+		}
+	}
+	return "", false, sc.Err()
+}
+
+// readObject returns the type and content of an object
+func (r *Repo) readObject(h hash) (int, []byte, error) {
+	s := h.String()
+	data, err := os.ReadFile(filepath.Join(r.common, "objects", s[:2], s[2:]))
+	if err == nil {
+		return parseLoose(data, s)
+	}
+	if !os.IsNotExist(err) {
+		return 0, nil, err
+	}
+
+	if !r.packsRead {
+		r.packsRead = true
+		if err := r.openPacks(); err != nil {
+			return 0, nil, err
+		}
+	}
+	for _, p := range r.packs {
+		if offset, ok := p.find(h); ok {
+			return p.readAt(r, offset)
+		}
+	}
+	return 0, nil, fmt.Errorf("object %s not found", s)
+}
+
+func parseLoose(data []byte, name string) (int, []byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, fmt.Errorf("object %s: %w", name, err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("object %s: %w", name, err)
+	}
+	header, body, found := bytes.Cut(raw, []byte{0})
+	typeName, _, _ := strings.Cut(string(header), " ")
+	typ, known := objTypes[typeName]
+	if !found || !known {
+		return 0, nil, fmt.Errorf("object %s: invalid header", name)
+	}
+	return typ, body, nil
+}
+
+// pack is a packfile with its version 2 index
+type pack struct {
+	file    *os.File
+	names   []byte // Sorted object names, 20 bytes each
+	offsets []byte // 4-byte offsets; those with the high bit set index large
+	large   []byte // 8-byte offsets
+	fanout  [256]uint32
+
+	bases map[int64]packObject // Decoded delta bases by offset
+}
+
+type packObject struct {
+	typ  int
+	data []byte
+}
+
+// maxCachedBases bounds the delta base cache of a pack
+const maxCachedBases = 512
+
+func (r *Repo) openPacks() error {
+	idxFiles, err := filepath.Glob(filepath.Join(r.common, "objects", "pack", "*.idx"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(idxFiles)
+	for _, idxPath := range idxFiles {
+		p, err := openPack(idxPath)
+		if err != nil {
+			return err
+		}
+		r.packs = append(r.packs, p)
+	}
+	return nil
+}
+
+func openPack(idxPath string) (*pack, error) {
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte("\xfftOc")) || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("%s: unsupported pack index", idxPath)
+	}
+	p := &pack{bases: map[int64]packObject{}}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[8+i*4:])
+	}
+	n := int(p.fanout[255])
+	pos := 8 + 256*4
+	if len(idx) < pos+n*(20+4+4) {
+		return nil, fmt.Errorf("%s: truncated pack index", idxPath)
+	}
+	p.names = idx[pos : pos+n*20]
+	pos += n * 20
+	pos += n * 4 // CRC32s
+	p.offsets = idx[pos : pos+n*4]
+	pos += n * 4
+	p.large = idx[pos:]
+
+	if p.file, err = os.Open(strings.TrimSuffix(idxPath, ".idx") + ".pack"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// find returns the offset of an object in the pack
+func (p *pack) find(h hash) (int64, bool) {
+	lo := 0
+	if h[0] > 0 {
+		lo = int(p.fanout[h[0]-1])
+	}
+	hi := int(p.fanout[h[0]])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.names[(lo+i)*20:(lo+i+1)*20], h[:]) >= 0
+	})
+	if i >= hi || !bytes.Equal(p.names[i*20:(i+1)*20], h[:]) {
+		return 0, false
+	}
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	j := int(offset & 0x7fffffff)
+	if len(p.large) < (j+1)*8 {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[j*8:])), true
+}
+
+// readAt decodes the object at offset, applying deltas
+func (p *pack) readAt(r *Repo, offset int64) (int, []byte, error) {
+	if obj, ok := p.bases[offset]; ok {
+		return obj.typ, obj.data, nil
+	}
+	br := bufio.NewReader(io.NewSectionReader(p.file, offset, 1<<62))
+	c, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	typ := int(c>>4) & 7
+	size := uint64(c & 0x0f)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		if c, err = br.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+		size |= uint64(c&0x7f) << shift
+	}
+
+	var baseType int
+	var base []byte
+	switch typ {
+	case objOfsDelta:
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			rel = (rel+1)<<7 | int64(c&0x7f)
+		}
+		if baseType, base, err = p.readAt(r, offset-rel); err != nil {
+			return 0, nil, err
+		}
+	case objRefDelta:
+		var h hash
+		if _, err := io.ReadFull(br, h[:]); err != nil {
+			return 0, nil, err
+		}
+		if baseType, base, err = r.readObject(h); err != nil {
+			return 0, nil, err
+		}
+	case objCommit, objTree, objBlob, objTag:
+	default:
+		return 0, nil, fmt.Errorf("pack object at %d: unknown type %d", offset, typ)
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return 0, nil, fmt.Errorf("pack object at %d: %w", offset, err)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return 0, nil, fmt.Errorf("pack object at %d: %w", offset, err)
+	}
+	if base != nil {
+		if data, err = applyDelta(base, data); err != nil {
+			return 0, nil, fmt.Errorf("pack object at %d: %w", offset, err)
+		}
+		typ = baseType
+	}
+
+	if len(p.bases) >= maxCachedBases {
+		for k := range p.bases { // Evict an arbitrary entry
+			delete(p.bases, k)
+			break
+		}
+	}
+	p.bases[offset] = packObject{typ, data}
+	return typ, data, nil
+}
+
+// applyDelta rebuilds an object from its base and a git delta
+func applyDelta(base, delta []byte) ([]byte, error) {
+	errCorrupt := errors.New("corrupt delta")
+	varint := func() (uint64, bool) {
+		var v uint64
+		for shift := 0; len(delta) > 0; shift += 7 {
+			c := delta[0]
+			delta = delta[1:]
+			v |= uint64(c&0x7f) << shift
+			if c&0x80 == 0 {
+				return v, true
+			}
+		}
+		return 0, false
+	}
+	srcSize, ok1 := varint()
+	dstSize, ok2 := varint()
+	if !ok1 || !ok2 || srcSize != uint64(len(base)) {
+		return nil, errCorrupt
+	}
+
+	out := make([]byte, 0, dstSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0: // Copy from the base
+			var offset, size uint64
+			for i := 0; i < 4; i++ {
+				if op&(1<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errCorrupt
+					}
+					offset |= uint64(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if op&(0x10<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errCorrupt
+					}
+					size |= uint64(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > uint64(len(base)) {
+				return nil, errCorrupt
+			}
+			out = append(out, base[offset:offset+size]...)
+		case op != 0: // Insert the next op bytes
+			if int(op) > len(delta) {
+				return nil, errCorrupt
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errCorrupt
+		}
+	}
+	if uint64(len(out)) != dstSize {
+		return nil, errCorrupt
+	}
+	return out, nil
+}
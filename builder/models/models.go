@@ -59,7 +59,10 @@ type PostMetadata struct {
 	Pinned      bool
 	Draft       bool
 	DateObj     time.Time
-	Version     string // "v2.0", "v1.0", "" for latest
+	LastMod     time.Time     // Last modification; DateObj when unknown
+	Version     string        // "v2.0", "v1.0", "" for latest
+	Summary     template.HTML // Rendered start of the post, up to <!--more-->
+	FeedSummary template.HTML // Summary with its math rendered for feeds
 }
 
 // TagData represents a tag and its frequency.
@@ -81,6 +84,13 @@ type Paginator struct {
 	HasNext     bool
 }
 
+// ImagePlaceholder is the blur-up preview of an image shown while it loads
+type ImagePlaceholder struct {
+	Color  string `json:"color"`          // Dominant colour as #rrggbb
+	LQIP   string `json:"lqip,omitempty"` // Tiny preview as a data URI
+	Opaque bool   `json:"opaque"`         // False if the image has transparency
+}
+
 // PageData is the context passed to HTML templates.
 type PageData struct {
 	Title        string
@@ -97,12 +107,16 @@ type PageData struct {
 	BuildVersion int64
 	Permalink    string
 	Image        string
+	ImagePreview ImagePlaceholder // Placeholder of Image when it is a local file
 	TOC          []TOCEntry
 	SiteTree     []*TreeNode
 	Paginator    Paginator
 	Assets       map[string]string
+	Data         map[string]interface{} // Site data files, e.g. .Data.projects for data/projects.yaml
 	Weight       int
 	ReadingTime  int
+	Date         time.Time // Of the post; zero on other pages
+	LastMod      time.Time
 
 	// Navigation
 	Breadcrumbs []Breadcrumb
@@ -139,10 +153,11 @@ type Rss struct {
 }
 
 type Channel struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	Items       []Item `xml:"item"`
+	Title         string `xml:"title"`
+	Link          string `xml:"link"`
+	Description   string `xml:"description"`
+	LastBuildDate string `xml:"lastBuildDate,omitempty"`
+	Items         []Item `xml:"item"`
 }
 
 type Item struct {
@@ -182,9 +197,10 @@ type PostRecord struct {
 	Link            string   `msgpack:"link"`
 	Description     string   `msgpack:"desc"`
 	Tags            []string `msgpack:"tags"`
-	NormalizedTags  []string `msgpack:"norm_tags"` // Lowercase tags for search
-	Content         string   `msgpack:"content"`   // Raw plain text for snippet extraction
-	Version         string   `msgpack:"ver"`       // Version scoping
+	NormalizedTags  []string `msgpack:"norm_tags"`          // Lowercase tags for search
+	Content         string   `msgpack:"content"`            // Raw plain text for snippet extraction
+	Version         string   `msgpack:"ver"`                // Version scoping
+	Analyzer        string   `msgpack:"analyzer,omitempty"` // Search analyzer name (empty = English)
 }
 
 // IndexedPost bundles a search record with pre-computed word frequencies for BM25
@@ -192,6 +208,11 @@ type IndexedPost struct {
 	Record    PostRecord     `msgpack:"rec"`
 	WordFreqs map[string]int `msgpack:"freqs"`
 	DocLen    int            `msgpack:"len"`
+	PostID    string         `msgpack:"pid,omitempty"` // Cache key, links the post to maintained postings
+	// Derived when the post was analyzed, see cache.SearchRecord
+	Stems       map[string][]string `msgpack:"stems,omitempty"`
+	Synonyms    []string            `msgpack:"syn,omitempty"`
+	SynonymsKey string              `msgpack:"synkey,omitempty"`
 }
 
 type SearchIndex struct {
@@ -200,6 +221,65 @@ type SearchIndex struct {
 	DocLens    map[int]int            `msgpack:"lens"` // postID -> word count
 	AvgDocLen  float64                `msgpack:"avg"`
 	TotalDocs  int                    `msgpack:"total"`
-	StemMap    map[string][]string    `msgpack:"stem,omitempty"`  // stemmed -> original forms
-	NgramIndex map[string][]string    `msgpack:"ngram,omitempty"` // trigram -> terms (for fuzzy search)
+	StemMap    map[string][]string    `msgpack:"stem,omitempty"`      // stemmed -> original forms
+	NgramIndex map[string][]string    `msgpack:"ngram,omitempty"`     // trigram -> terms (for fuzzy search)
+	DocFreqs   map[string]int         `msgpack:"df,omitempty"`        // word -> document frequency (sharded indexes only)
+	Analyzers  []string               `msgpack:"analyzers,omitempty"` // analyzer names used by posts, applied to queries
+	Synonyms   map[string][]string    `msgpack:"syn,omitempty"`       // phrase -> equivalent phrases (query expansion)
+}
+
+// --- Sharded Search Structures ---
+
+// SearchShardFormat identifies the sharded index layout written next to search.bin
+const SearchShardFormat = 2
+
+// SearchManifest is the small entry point of a sharded search index.
+// Clients load it first and fetch version and term shards on demand.
+type SearchManifest struct {
+	Format    int                  `msgpack:"format"`
+	TotalDocs int                  `msgpack:"total"`
+	AvgDocLen float64              `msgpack:"avg"`
+	Versions  []SearchVersionShard `msgpack:"versions"`
+	Analyzers []string             `msgpack:"analyzers,omitempty"`
+	Synonyms  map[string][]string  `msgpack:"syn,omitempty"`
+	Trigrams  []SearchTermRange    `msgpack:"trigrams,omitempty"` // Trigram index of every term, by first rune of the trigram
+}
+
+// SearchVersionShard lists the shards holding the posts of a single version
+type SearchVersionShard struct {
+	Version string            `msgpack:"ver"`
+	Docs    SearchShardRef    `msgpack:"docs"`
+	Terms   []SearchTermRange `msgpack:"terms"`
+}
+
+// SearchTermRange covers all terms whose first rune lies within [Start, End]
+type SearchTermRange struct {
+	Start string         `msgpack:"start"`
+	End   string         `msgpack:"end"`
+	Shard SearchShardRef `msgpack:"shard"`
+}
+
+// SearchShardRef points to a shard file relative to the manifest
+type SearchShardRef struct {
+	File string `msgpack:"file"`
+	Hash string `msgpack:"hash"` // Content hash, used for cache busting and precaching
+}
+
+// SearchDocShard holds the post records of one version; IDs are local to the shard
+type SearchDocShard struct {
+	Posts   []PostRecord `msgpack:"posts"`
+	DocLens []int        `msgpack:"lens"`
+}
+
+// SearchTrigramShard holds a slice of the trigram index over the terms of all
+// versions. A query loads the shards of its own trigrams, which tell whether
+// a term is indexed anywhere and which terms are fuzzy candidates.
+type SearchTrigramShard struct {
+	Ngrams map[string][]string `msgpack:"ngram"` // trigram -> sorted terms containing it
+}
+
+// SearchTermShard holds a slice of the inverted index of one version
+type SearchTermShard struct {
+	Inverted map[string]map[int]int `msgpack:"inv"` // word -> local postID -> frequency
+	DocFreqs map[string]int         `msgpack:"df"`  // word -> document frequency across all versions
 }
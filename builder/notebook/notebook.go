@@ -0,0 +1,477 @@
+// Package notebook converts Jupyter notebooks (.ipynb) to Markdown posts:
+// markdown cells as written, code cells as fenced code and their outputs as
+// text, HTML, LaTeX or images extracted to files
+package notebook
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif" // Decoders for the dimensions of extracted images
+	_ "image/jpeg"
+	_ "image/png"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zeebo/blake3"
+	"gopkg.in/yaml.v3"
+)
+
+// Ext is the file extension of notebooks
+const Ext = ".ipynb"
+
+// DefaultLanguage highlights code cells of notebooks that do not name their kernel language
+const DefaultLanguage = "python"
+
+// IsNotebook reports whether path is a notebook. Jupyter's checkpoint copies are not.
+func IsNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), Ext) && !strings.Contains(filepath.ToSlash(path), ".ipynb_checkpoints/")
+}
+
+// Options configures Convert
+type Options struct {
+	// Language highlights code cells when the notebook metadata names no kernel
+	// language (default: DefaultLanguage)
+	Language string
+	// ImageURL is the URL of the directory the extracted images are served from
+	ImageURL string
+}
+
+// Image is an output image or markdown attachment, decoded from the notebook
+type Image struct {
+	Name string // Content hash plus extension, unique within the notebook
+	Data []byte
+}
+
+// Notebook is a notebook converted to a Markdown post
+type Notebook struct {
+	Markdown []byte  // Front matter and body
+	Images   []Image // Files to write to Options.ImageURL
+	Stable   []byte  // The notebook JSON without execution counts, for cache hashing
+}
+
+type notebookJSON struct {
+	Cells    []cell                 `json:"cells"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Format   int                    `json:"nbformat"`
+}
+
+type cell struct {
+	Type        string                       `json:"cell_type"`
+	Source      multiline                    `json:"source"`
+	Metadata    cellMetadata                 `json:"metadata"`
+	Outputs     []output                     `json:"outputs"`
+	Attachments map[string]map[string]string `json:"attachments"`
+}
+
+type cellMetadata struct {
+	Tags        []string `json:"tags"`
+	RawMimetype string   `json:"raw_mimetype"`
+	Format      string   `json:"format"`
+}
+
+type output struct {
+	Type      string                     `json:"output_type"`
+	Name      string                     `json:"name"` // stdout or stderr
+	Text      multiline                  `json:"text"`
+	Data      map[string]json.RawMessage `json:"data"`
+	Metadata  map[string]json.RawMessage `json:"metadata"`
+	EName     string                     `json:"ename"`
+	EValue    string                     `json:"evalue"`
+	Traceback []string                   `json:"traceback"`
+}
+
+// multiline is notebook text, stored as a string or a list of lines
+type multiline string
+
+func (m *multiline) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*m = multiline(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*m = multiline(s)
+	return nil
+}
+
+// Convert converts a notebook to Markdown. Front matter comes from the "kosh"
+// and "title" notebook metadata, overridden by a YAML block in a leading raw cell.
+func Convert(data []byte, opts Options) (*Notebook, error) {
+	var nb notebookJSON
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("notebook: %w", err)
+	}
+	if nb.Format != 0 && nb.Format < 4 {
+		return nil, fmt.Errorf("notebook: nbformat %d is not supported (use 4 or later)", nb.Format)
+	}
+	stable, err := Stable(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &converter{opts: opts, images: map[string]bool{}}
+	cells := nb.Cells
+	frontMatter := metadataFrontMatter(nb.Metadata)
+	if len(cells) > 0 && cells[0].Type == "raw" {
+		if fm, ok := rawFrontMatter(string(cells[0].Source)); ok {
+			for k, v := range fm {
+				frontMatter[k] = v
+			}
+			cells = cells[1:]
+		}
+	}
+	c.language = kernelLanguage(nb.Metadata, opts.Language)
+
+	var b strings.Builder
+	if len(frontMatter) > 0 {
+		fm, err := yaml.Marshal(frontMatter)
+		if err != nil {
+			return nil, fmt.Errorf("notebook front matter: %w", err)
+		}
+		b.WriteString("---\n")
+		b.Write(fm)
+		b.WriteString("---\n\n")
+	}
+	for _, cl := range cells {
+		c.cell(&b, cl)
+	}
+	return &Notebook{Markdown: []byte(b.String()), Images: c.out, Stable: stable}, nil
+}
+
+// Stable returns the notebook JSON without the execution counts and timings
+// that change on every run, so re-running a notebook does not invalidate it
+func Stable(data []byte) ([]byte, error) {
+	var nb map[string]interface{}
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("notebook: %w", err)
+	}
+	cells, _ := nb["cells"].([]interface{})
+	for _, c := range cells {
+		cell, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(cell, "execution_count")
+		if md, ok := cell["metadata"].(map[string]interface{}); ok {
+			delete(md, "execution") // JupyterLab
+			delete(md, "ExecuteTime")
+		}
+		outputs, _ := cell["outputs"].([]interface{})
+		for _, o := range outputs {
+			if out, ok := o.(map[string]interface{}); ok {
+				delete(out, "execution_count")
+			}
+		}
+	}
+	return json.Marshal(nb) // Map keys are sorted, so equal notebooks marshal alike
+}
+
+// metadataFrontMatter collects front matter from the notebook metadata
+func metadataFrontMatter(md map[string]interface{}) map[string]interface{} {
+	fm := map[string]interface{}{}
+	if kosh, ok := md["kosh"].(map[string]interface{}); ok {
+		for k, v := range kosh {
+			fm[k] = v
+		}
+	}
+	if title, ok := md["title"].(string); ok && fm["title"] == nil {
+		fm["title"] = title
+	}
+	return fm
+}
+
+// rawFrontMatter parses a raw cell holding a "---" delimited YAML block. Values
+// are kept as nodes so dates and numbers are written back as they were typed.
+func rawFrontMatter(src string) (map[string]*yaml.Node, bool) {
+	src = strings.TrimSpace(src)
+	if !strings.HasPrefix(src, "---") {
+		return nil, false
+	}
+	body := strings.TrimPrefix(src, "---")
+	body = strings.TrimSuffix(strings.TrimSpace(body), "---")
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return nil, false
+	}
+	fm := map[string]*yaml.Node{}
+	if len(doc.Content) == 0 {
+		return fm, true // An empty block
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, false
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		fm[mapping.Content[i].Value] = mapping.Content[i+1]
+	}
+	return fm, true
+}
+
+func kernelLanguage(md map[string]interface{}, fallback string) string {
+	if ks, ok := md["kernelspec"].(map[string]interface{}); ok {
+		if lang, ok := ks["language"].(string); ok && lang != "" {
+			return lang
+		}
+	}
+	if li, ok := md["language_info"].(map[string]interface{}); ok {
+		if name, ok := li["name"].(string); ok && name != "" {
+			return name
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return DefaultLanguage
+}
+
+// converter writes cells as Markdown and collects their images
+type converter struct {
+	opts     Options
+	language string
+	images   map[string]bool
+	out      []Image
+}
+
+func (c *converter) cell(b *strings.Builder, cl cell) {
+	tags := map[string]bool{}
+	for _, t := range cl.Metadata.Tags {
+		tags[t] = true
+	}
+	if tags["remove-cell"] {
+		return
+	}
+
+	switch cl.Type {
+	case "markdown":
+		src := string(cl.Source)
+		names := make([]string, 0, len(cl.Attachments))
+		for name := range cl.Attachments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if url, ok := c.attachment(cl.Attachments[name]); ok {
+				src = strings.ReplaceAll(src, "attachment:"+name, url)
+			}
+		}
+		block(b, src)
+	case "code":
+		if !tags["remove-input"] && strings.TrimSpace(string(cl.Source)) != "" {
+			block(b, fenced(c.language, string(cl.Source)))
+		}
+		if !tags["remove-output"] {
+			c.outputs(b, cl.Outputs)
+		}
+	case "raw":
+		// Raw cells are for nbconvert; only Markdown and HTML ones mean anything on a page
+		switch strings.ToLower(cl.Metadata.RawMimetype + cl.Metadata.Format) {
+		case "text/markdown", "markdown", "text/html", "html":
+			block(b, string(cl.Source))
+		}
+	}
+}
+
+// outputDataOrder is Jupyter's preference among an output's representations
+var outputDataOrder = []string{"text/html", "text/markdown", "text/latex", "image/svg+xml", "image/png", "image/jpeg", "image/gif", "text/plain"}
+
+func (c *converter) outputs(b *strings.Builder, outputs []output) {
+	for i := 0; i < len(outputs); i++ {
+		o := outputs[i]
+		switch o.Type {
+		case "stream":
+			text := string(o.Text)
+			for i+1 < len(outputs) && outputs[i+1].Type == "stream" && outputs[i+1].Name == o.Name {
+				i++
+				text += string(outputs[i].Text)
+			}
+			class := "nb-output nb-stream"
+			if o.Name == "stderr" {
+				class += " nb-stderr"
+			}
+			markdownOutput(b, class, fenced("text", stripANSI(text)))
+		case "error":
+			text := strings.Join(o.Traceback, "\n")
+			if text == "" {
+				text = o.EName + ": " + o.EValue
+			}
+			markdownOutput(b, "nb-output nb-error", fenced("text", stripANSI(text)))
+		case "execute_result", "display_data":
+			c.data(b, o)
+		}
+	}
+}
+
+func (c *converter) data(b *strings.Builder, o output) {
+	for _, mime := range outputDataOrder {
+		raw, ok := o.Data[mime]
+		if !ok {
+			continue
+		}
+		var value multiline
+		if err := json.Unmarshal(raw, &value); err != nil {
+			continue
+		}
+		text := string(value)
+		switch mime {
+		case "text/html":
+			htmlOutput(b, "nb-output nb-html", text)
+		case "text/markdown", "text/latex":
+			markdownOutput(b, "nb-output", text)
+		case "image/svg+xml":
+			htmlOutput(b, "nb-output nb-image", svgBody(text))
+		case "text/plain":
+			markdownOutput(b, "nb-output", fenced("text", stripANSI(text)))
+		default:
+			name, data, ok := c.addImage(mime, text)
+			if !ok {
+				continue
+			}
+			htmlOutput(b, "nb-output nb-image", c.imgTag(name, data, o.Metadata[mime], o.Data["text/plain"]))
+		}
+		return
+	}
+}
+
+// attachment extracts the image of a markdown cell attachment
+func (c *converter) attachment(bundle map[string]string) (string, bool) {
+	for _, mime := range []string{"image/png", "image/jpeg", "image/gif"} {
+		if data, ok := bundle[mime]; ok {
+			if name, _, ok := c.addImage(mime, data); ok {
+				return c.imageURL(name), true
+			}
+		}
+	}
+	return "", false
+}
+
+// addImage decodes a base64 image and names it after its content
+func (c *converter) addImage(mime, encoded string) (string, []byte, bool) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return "", nil, false
+	}
+	ext := "." + strings.TrimPrefix(mime, "image/")
+	if ext == ".jpeg" {
+		ext = ".jpg"
+	}
+	sum := blake3.Sum256(data)
+	name := hex.EncodeToString(sum[:8]) + ext
+	if !c.images[name] {
+		c.images[name] = true
+		c.out = append(c.out, Image{Name: name, Data: data})
+	}
+	return name, data, true
+}
+
+func (c *converter) imageURL(name string) string {
+	return strings.TrimSuffix(c.opts.ImageURL, "/") + "/" + name
+}
+
+// imgTag writes an extracted image with its intrinsic size, or the display
+// size Jupyter recorded for high-DPI figures
+func (c *converter) imgTag(name string, data []byte, metadata, plain json.RawMessage) string {
+	var size struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	}
+	_ = json.Unmarshal(metadata, &size)
+	if size.Width == 0 {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			size.Width, size.Height = cfg.Width, cfg.Height
+		}
+	}
+
+	// The text/plain repr of figures, like "<Figure size 640x480 with 1 Axes>", is no alt text
+	var alt multiline
+	_ = json.Unmarshal(plain, &alt)
+	altText := strings.TrimSpace(string(alt))
+	if strings.HasPrefix(altText, "<") {
+		altText = ""
+	}
+
+	tag := fmt.Sprintf(`<img src="%s" alt="%s"`, escapeAttr(c.imageURL(name)), escapeAttr(altText))
+	if size.Width > 0 {
+		tag += fmt.Sprintf(` width="%d"`, size.Width)
+	}
+	if size.Height > 0 {
+		tag += fmt.Sprintf(` height="%d"`, size.Height)
+	}
+	return tag + ` loading="lazy" decoding="async">`
+}
+
+// block writes a Markdown block followed by a blank line
+func block(b *strings.Builder, s string) {
+	s = strings.TrimRight(s, "\n")
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	b.WriteString(s)
+	b.WriteString("\n\n")
+}
+
+// markdownOutput wraps Markdown in an output <div>. The blank lines let
+// goldmark parse the content as Markdown.
+func markdownOutput(b *strings.Builder, class, content string) {
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+	block(b, `<div class="`+class+`">`+"\n\n"+strings.TrimRight(content, "\n")+"\n\n</div>")
+}
+
+// htmlOutput wraps HTML in an output <div>. An HTML block ends at a blank
+// line, so blank lines become empty comments to keep the output in one block.
+func htmlOutput(b *strings.Builder, class, content string) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = "<!-- -->"
+		}
+	}
+	block(b, `<div class="`+class+`">`+"\n"+strings.Join(lines, "\n")+"\n</div>")
+}
+
+// fenced writes code as a fenced block longer than any backtick run inside it
+func fenced(lang, code string) string {
+	code = strings.TrimRight(code, "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// stripANSI removes the terminal colours of tracebacks and progress output
+func stripANSI(s string) string {
+	return ansiRe.ReplaceAllString(s, "")
+}
+
+var svgPrologRe = regexp.MustCompile(`(?s)^\s*(<\?xml.*?\?>\s*)?(<!DOCTYPE.*?>\s*)?`)
+
+// svgBody drops the XML declaration and doctype, which are invalid inline in HTML
+func svgBody(svg string) string {
+	return svgPrologRe.ReplaceAllString(svg, "")
+}
+
+func escapeAttr(s string) string {
+	return strings.NewReplacer(`&`, "&amp;", `"`, "&quot;", `<`, "&lt;", `>`, "&gt;").Replace(s)
+}
@@ -0,0 +1,163 @@
+package notebook
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// A 1x1 PNG
+const testPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+const testNotebook = `{
+ "nbformat": 4, "nbformat_minor": 5,
+ "metadata": {
+  "title": "From metadata",
+  "kosh": {"tags": ["ml"]},
+  "kernelspec": {"language": "julia", "name": "julia-1.9"}
+ },
+ "cells": [
+  {"cell_type": "raw", "metadata": {}, "source": ["---\n", "title: Linear Regression\n", "date: 2024-01-02\n", "---\n"]},
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Intro\n", "\n", "![plot](attachment:plot.png)"],
+   "attachments": {"plot.png": {"image/png": "` + testPNG + `"}}},
+  {"cell_type": "code", "execution_count": 3, "metadata": {"ExecuteTime": {"end_time": "x"}}, "source": "x = 1\nprintln(x)",
+   "outputs": [
+    {"output_type": "stream", "name": "stdout", "text": ["1\n"]},
+    {"output_type": "stream", "name": "stdout", "text": "2 < 3\n"},
+    {"output_type": "stream", "name": "stderr", "text": "warn\n"},
+    {"output_type": "execute_result", "execution_count": 3, "metadata": {}, "data": {"image/png": "` + testPNG + `", "text/plain": "Plot"}},
+    {"output_type": "error", "ename": "E", "evalue": "v", "traceback": ["\u001b[31mBoom\u001b[0m"]}
+   ]},
+  {"cell_type": "code", "execution_count": 4, "metadata": {"tags": ["remove-input"]}, "source": "hidden()",
+   "outputs": [{"output_type": "display_data", "metadata": {}, "data": {"text/html": "<table>\n\n<tr><td>1</td></tr></table>", "text/plain": "df"}}]},
+  {"cell_type": "code", "execution_count": 5, "metadata": {"tags": ["remove-output"]}, "source": "shown()",
+   "outputs": [{"output_type": "stream", "name": "stdout", "text": "gone"}]},
+  {"cell_type": "code", "execution_count": 6, "metadata": {"tags": ["remove-cell"]}, "source": "secret()", "outputs": []},
+  {"cell_type": "code", "execution_count": 7, "metadata": {}, "source": "s = \"` + "```" + `\"", "outputs": []}
+ ]
+}`
+
+func TestConvert(t *testing.T) {
+	nb, err := Convert([]byte(testNotebook), Options{ImageURL: "/posts/nb"})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	md := string(nb.Markdown)
+
+	want := []string{
+		"---\ndate: 2024-01-02\ntags:\n    - ml\ntitle: Linear Regression\n---\n\n# Intro\n",
+		"![plot](/posts/nb/9aab4a27bde3bd1c.png)",
+		"```julia\nx = 1\nprintln(x)\n```",
+		"<div class=\"nb-output nb-stream\">\n\n```text\n1\n2 < 3\n```\n\n</div>",
+		"<div class=\"nb-output nb-stream nb-stderr\">\n\n```text\nwarn\n```",
+		`<img src="/posts/nb/9aab4a27bde3bd1c.png" alt="Plot" width="1" height="1"`,
+		"<div class=\"nb-output nb-error\">\n\n```text\nBoom\n```",
+		"<table>\n<!-- -->\n<tr>",
+		"```julia\nshown()\n```",
+		"````julia\ns = \"```\"\n````",
+	}
+	for _, w := range want {
+		if !strings.Contains(md, w) {
+			t.Errorf("Markdown missing %q\n%s", w, md)
+		}
+	}
+	for _, w := range []string{"From metadata", "hidden()", "gone", "secret()", "\x1b"} {
+		if strings.Contains(md, w) {
+			t.Errorf("Markdown should not contain %q\n%s", w, md)
+		}
+	}
+
+	// The attachment and the output are the same image
+	if len(nb.Images) != 1 || nb.Images[0].Name != "9aab4a27bde3bd1c.png" || !bytes.HasPrefix(nb.Images[0].Data, []byte("\x89PNG")) {
+		t.Errorf("Images = %+v, want one decoded PNG", nb.Images)
+	}
+}
+
+func TestConvertMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		notebook string
+		opts     Options
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "metadata front matter and language_info",
+			notebook: `{"nbformat": 4, "metadata": {"title": "T", "language_info": {"name": "r"}}, "cells": [{"cell_type": "code", "source": "1", "metadata": {}}]}`,
+			want:     "---\ntitle: T\n---\n\n```r\n1\n```\n\n",
+		},
+		{
+			name:     "configured language",
+			notebook: `{"nbformat": 4, "metadata": {}, "cells": [{"cell_type": "code", "source": "1", "metadata": {}}]}`,
+			opts:     Options{Language: "scala"},
+			want:     "```scala\n1\n```\n\n",
+		},
+		{
+			name:     "default language",
+			notebook: `{"nbformat": 4, "metadata": {}, "cells": [{"cell_type": "code", "source": "1", "metadata": {}}]}`,
+			want:     "```python\n1\n```\n\n",
+		},
+		{
+			name:     "markdown raw cell",
+			notebook: `{"nbformat": 4, "metadata": {}, "cells": [{"cell_type": "raw", "source": "*hi*", "metadata": {"raw_mimetype": "text/markdown"}}, {"cell_type": "raw", "source": "\\LaTeX", "metadata": {}}]}`,
+			want:     "*hi*\n\n",
+		},
+		{
+			name:     "nbformat 3",
+			notebook: `{"nbformat": 3, "worksheets": []}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid JSON",
+			notebook: `{"cells": [`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nb, err := Convert([]byte(tt.notebook), tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Convert() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if got := string(nb.Markdown); got != tt.want {
+				t.Errorf("Markdown = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStable(t *testing.T) {
+	rerun := strings.NewReplacer(`"execution_count": 3`, `"execution_count": 9`, `"end_time": "x"`, `"end_time": "y"`).Replace(testNotebook)
+	a, err := Stable([]byte(testNotebook))
+	if err != nil {
+		t.Fatalf("Stable() error = %v", err)
+	}
+	b, _ := Stable([]byte(rerun))
+	if !bytes.Equal(a, b) {
+		t.Error("Stable() should ignore execution counts and timings")
+	}
+	c, _ := Stable([]byte(strings.Replace(testNotebook, "x = 1", "x = 2", 1)))
+	if bytes.Equal(a, c) {
+		t.Error("Stable() should change with the source")
+	}
+}
+
+func TestIsNotebook(t *testing.T) {
+	tests := map[string]bool{
+		"content/intro.ipynb": true,
+		"content/Intro.IPYNB": true,
+		"content/intro.md":    false,
+		"content/.ipynb_checkpoints/intro-checkpoint.ipynb": false,
+	}
+	for path, want := range tests {
+		if got := IsNotebook(path); got != want {
+			t.Errorf("IsNotebook(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
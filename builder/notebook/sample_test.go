@@ -0,0 +1,30 @@
+package notebook
+
+// 1x1 PNG
+const png1 = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+const sample = `{
+ "nbformat": 4, "nbformat_minor": 5,
+ "metadata": {
+  "title": "From metadata",
+  "kosh": {"tags": ["ml"]},
+  "kernelspec": {"language": "julia", "name": "julia-1.9"},
+  "language_info": {"name": "julia"}
+ },
+ "cells": [
+  {"cell_type": "raw", "metadata": {}, "source": ["---\n", "title: Linear Regression\n", "date: 2024-01-02\n", "---\n"]},
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Intro\n", "\n", "![plot](attachment:plot.png)"],
+   "attachments": {"plot.png": {"image/png": "` + png1 + `"}}},
+  {"cell_type": "code", "execution_count": 3, "metadata": {}, "source": "x = 1\nprintln(x)",
+   "outputs": [
+    {"output_type": "stream", "name": "stdout", "text": ["1\n"]},
+    {"output_type": "stream", "name": "stdout", "text": "2 < 3\n"},
+    {"output_type": "stream", "name": "stderr", "text": "warn\n"},
+    {"output_type": "execute_result", "execution_count": 3, "metadata": {}, "data": {"image/png": "` + png1 + `", "text/plain": "Plot"}},
+    {"output_type": "error", "ename": "E", "evalue": "v", "traceback": ["\u001b[31mBoom\u001b[0m"]}
+   ]},
+  {"cell_type": "code", "execution_count": 4, "metadata": {"tags": ["remove-input"]}, "source": "hidden()", "outputs": [{"output_type": "display_data", "metadata": {}, "data": {"text/html": "<table>\n\n<tr><td>1</td></tr></table>", "text/plain": "df"}}]},
+  {"cell_type": "code", "execution_count": 5, "metadata": {"tags": ["remove-output"]}, "source": "shown()", "outputs": [{"output_type": "stream", "name": "stdout", "text": "gone"}]},
+  {"cell_type": "code", "execution_count": 6, "metadata": {"tags": ["remove-cell"]}, "source": "secret()", "outputs": []}
+ ]
+}`
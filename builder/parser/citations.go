@@ -0,0 +1,307 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Kush-Singh-26/kosh/builder/bibliography"
+)
+
+// DefaultReferencesTitle is the heading of the references list
+const DefaultReferencesTitle = "References"
+
+var (
+	// ContextKeyBibliography stores the *bibliography.Library of the post being parsed
+	ContextKeyBibliography = parser.NewContextKey()
+	missingCitationsKey    = parser.NewContextKey()
+
+	// citationRe matches "[@key]", "[@key, p. 5]", "[-@key]" and "[@a; @b]"
+	citationRe = regexp.MustCompile(`^\[(-?@[^\s;,\]]+(?:,[^;\]]*)?(?:;\s*-?@[^\s;,\]]+(?:,[^;\]]*)?)*)\]`)
+)
+
+// PostBibliography is the bibliography file named by a post's "bibliography" front matter
+type PostBibliography struct {
+	Path    string // Resolved file, a dependency of the post
+	Data    []byte
+	Library *bibliography.Library
+}
+
+// LoadBibliography loads the file named by the "bibliography" front matter of
+// source, resolved next to the post first, then against the site root. It
+// returns nil when the post has no bibliography.
+func LoadBibliography(fs afero.Fs, postPath string, source []byte) (*PostBibliography, error) {
+	file := frontMatterString(source, "bibliography")
+	if file == "" {
+		return nil, nil
+	}
+	resolved, err := resolvePostFile(fs, postPath, ".", file)
+	if err != nil {
+		return nil, fmt.Errorf("bibliography %q: %w", file, err)
+	}
+	data, err := afero.ReadFile(fs, resolved)
+	if err != nil {
+		return nil, fmt.Errorf("bibliography %q: %w", file, err)
+	}
+	lib, err := bibliography.Parse(resolved, data)
+	if err != nil {
+		return nil, fmt.Errorf("bibliography %q: %w", file, err)
+	}
+	return &PostBibliography{Path: resolved, Data: data, Library: lib}, nil
+}
+
+// frontMatterString reads a string field of the YAML front matter before the
+// post is parsed
+func frontMatterString(source []byte, key string) string {
+	source = bytes.TrimPrefix(source, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(source, []byte("---")) {
+		return ""
+	}
+	rest := source[3:]
+	end := bytes.Index(rest, []byte("\n---"))
+	if end < 0 {
+		return ""
+	}
+	var fm map[string]interface{}
+	if err := yaml.Unmarshal(rest[:end], &fm); err != nil {
+		return ""
+	}
+	s, _ := fm[key].(string)
+	return s
+}
+
+// GetMissingCitations returns the cited keys the post's bibliography lacks
+func GetMissingCitations(pc parser.Context) []string {
+	if v := pc.Get(missingCitationsKey); v != nil {
+		return v.([]string)
+	}
+	return nil
+}
+
+// KindCitation is the node kind of Citation
+var KindCitation = ast.NewNodeKind("Citation")
+
+// CitationItem is one cited key of a Citation
+type CitationItem struct {
+	Key            string
+	Locator        string // e.g. "p. 5"
+	SuppressAuthor bool   // [-@key] cites the year only
+	Label          string // Set by citationTransformer; empty for unknown keys
+}
+
+// Citation is a bracketed citation of one or more bibliography entries
+type Citation struct {
+	ast.BaseInline
+	Items []CitationItem
+	Style bibliography.Style
+}
+
+func (n *Citation) Kind() ast.NodeKind { return KindCitation }
+
+func (n *Citation) Dump(source []byte, level int) {
+	keys := make([]string, len(n.Items))
+	for i, item := range n.Items {
+		keys[i] = item.Key
+	}
+	ast.DumpHelper(n, source, level, map[string]string{"Keys": strings.Join(keys, ";")}, nil)
+}
+
+// citationParser parses [@key] citations in posts that have a bibliography
+type citationParser struct{}
+
+func (p *citationParser) Trigger() []byte { return []byte{'['} }
+
+func (p *citationParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if pc.Get(ContextKeyBibliography) == nil {
+		return nil
+	}
+	line, _ := block.PeekLine()
+	m := citationRe.FindSubmatch(line)
+	if m == nil {
+		return nil
+	}
+
+	var items []CitationItem
+	for _, part := range strings.Split(string(m[1]), ";") {
+		part = strings.TrimSpace(part)
+		item := CitationItem{}
+		if rest, ok := strings.CutPrefix(part, "-"); ok {
+			item.SuppressAuthor = true
+			part = rest
+		}
+		key, locator, _ := strings.Cut(strings.TrimPrefix(part, "@"), ",")
+		if strings.HasPrefix(key, figurePrefix) || strings.HasPrefix(key, equationPrefix) {
+			return nil // A cross-reference, not a citation
+		}
+		item.Key, item.Locator = key, strings.TrimSpace(locator)
+		items = append(items, item)
+	}
+	block.Advance(len(m[0]))
+	return &Citation{Items: items}
+}
+
+// KindReferences is the node kind of References
+var KindReferences = ast.NewNodeKind("References")
+
+// References is the list of cited entries, appended to the document. Its
+// child is the list's heading.
+type References struct {
+	ast.BaseBlock
+	Entries []*bibliography.Entry
+	Style   bibliography.Style
+}
+
+func (n *References) Kind() ast.NodeKind { return KindReferences }
+
+func (n *References) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Style": string(n.Style)}, nil)
+}
+
+// citationTransformer labels citations and appends the references list. The
+// style and title come from the site, and the "citationStyle" front matter
+// overrides the style. It runs before tocTransformer so the list's heading is
+// part of the TOC.
+type citationTransformer struct {
+	Style bibliography.Style
+	Title string
+}
+
+func (t *citationTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	lib, _ := pc.Get(ContextKeyBibliography).(*bibliography.Library)
+	if lib == nil {
+		return
+	}
+	style := t.Style
+	if s, ok := meta.Get(pc)["citationStyle"].(string); ok {
+		if parsed, ok := bibliography.ParseStyle(s); ok {
+			style = parsed
+		}
+	}
+
+	var citations []*Citation
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if c, ok := n.(*Citation); ok && entering {
+			citations = append(citations, c)
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(citations) == 0 {
+		return
+	}
+
+	numbers := map[string]int{}
+	var cited []*bibliography.Entry
+	var missing []string
+	for _, c := range citations {
+		c.Style = style
+		for i := range c.Items {
+			item := &c.Items[i]
+			entry, ok := lib.Get(item.Key)
+			if !ok {
+				if numbers[item.Key] == 0 {
+					missing = append(missing, item.Key)
+					numbers[item.Key] = -1
+				}
+				continue
+			}
+			if numbers[item.Key] == 0 {
+				cited = append(cited, entry)
+				numbers[item.Key] = len(cited)
+			}
+			item.Label = style.Label(entry, numbers[item.Key], item.Locator, item.SuppressAuthor)
+		}
+	}
+	if len(missing) > 0 {
+		pc.Set(missingCitationsKey, missing)
+	}
+	if len(cited) == 0 {
+		return
+	}
+
+	style.Sort(cited)
+	title := t.Title
+	if title == "" {
+		title = DefaultReferencesTitle
+	}
+	heading := ast.NewHeading(2)
+	heading.SetAttributeString("id", pc.IDs().Generate([]byte(title), ast.KindHeading))
+	heading.AppendChild(heading, ast.NewString([]byte(title)))
+	refs := &References{Entries: cited, Style: style}
+	refs.AppendChild(refs, heading)
+	node.AppendChild(node, refs)
+}
+
+// citationRenderer renders Citation and References nodes
+type citationRenderer struct{}
+
+func newCitationRenderer() renderer.NodeRenderer {
+	return &citationRenderer{}
+}
+
+func (r *citationRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindCitation, r.renderCitation)
+	reg.Register(KindReferences, r.renderReferences)
+}
+
+func (r *citationRenderer) renderCitation(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*Citation)
+	open, sep, close := n.Style.Delimiters()
+	_, _ = w.WriteString(`<span class="citation">` + open)
+	for i, item := range n.Items {
+		if i > 0 {
+			_, _ = w.WriteString(sep)
+		}
+		if item.Label == "" {
+			_, _ = w.WriteString(`<span class="citation-missing">@`)
+			_, _ = w.Write(util.EscapeHTML([]byte(item.Key)))
+			_, _ = w.WriteString(`</span>`)
+			continue
+		}
+		_, _ = w.WriteString(`<a href="#`)
+		_, _ = w.Write(util.EscapeHTML([]byte(referenceID(item.Key))))
+		_, _ = w.WriteString(`" class="citation-ref">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(item.Label)))
+		_, _ = w.WriteString(`</a>`)
+	}
+	_, _ = w.WriteString(close + `</span>`)
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *citationRenderer) renderReferences(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*References)
+	if entering {
+		_, _ = w.WriteString(`<section class="references references-` + string(n.Style) + `">` + "\n")
+		return ast.WalkContinue, nil
+	}
+
+	list := "ul"
+	if n.Style.Numbered() {
+		list = "ol"
+	}
+	_, _ = w.WriteString("<" + list + ` class="references-list">` + "\n")
+	for _, e := range n.Entries {
+		_, _ = w.WriteString(`<li id="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(referenceID(e.Key))))
+		_, _ = w.WriteString(`">` + n.Style.Reference(e) + "</li>\n")
+	}
+	_, _ = w.WriteString("</" + list + ">\n</section>\n")
+	return ast.WalkContinue, nil
+}
+
+// referenceID is the ID of a references list entry
+func referenceID(key string) string {
+	return "ref-" + key
+}
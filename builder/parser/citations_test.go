@@ -0,0 +1,264 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/Kush-Singh-26/kosh/builder/bibliography"
+)
+
+const testBib = `@article{vaswani2017,
+  title = {Attention Is All You Need},
+  author = {Vaswani, Ashish and Shazeer, Noam and Parmar, Niki},
+  journal = {NeurIPS},
+  year = {2017}
+}
+@book{knuth1984,
+  title = {The {\TeX}book},
+  author = {Donald E. Knuth},
+  publisher = {Addison-Wesley},
+  year = 1984
+}`
+
+func renderCitations(t *testing.T, markdown string, withBib bool, opts ...Option) (string, ast.Node, []byte, parser.Context) {
+	t.Helper()
+	md := New("", nil, &sync.Map{}, opts...)
+	source := []byte(markdown)
+	ctx := parser.NewContext()
+	if withBib {
+		lib, err := bibliography.ParseBibTeX([]byte(testBib))
+		if err != nil {
+			t.Fatalf("ParseBibTeX() error = %v", err)
+		}
+		ctx.Set(ContextKeyBibliography, lib)
+	}
+	doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	return buf.String(), doc, source, ctx
+}
+
+func TestCitations(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		opts     []Option
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "apa",
+			markdown: "Transformers [@vaswani2017] and [@knuth1984, p. 5].\n",
+			want: []string{
+				`<span class="citation">(<a href="#ref-vaswani2017" class="citation-ref">Vaswani et al., 2017</a>)</span>`,
+				`>Knuth, 1984, p. 5</a>)`,
+				`<section class="references references-apa">`,
+				`<h2 id="references">References</h2>`,
+				`<ul class="references-list">`,
+				`<li id="ref-knuth1984">Knuth, D. E. (1984). <em>The TeXbook</em>. Addison-Wesley.</li>`,
+			},
+		},
+		{
+			name:     "apa sorts by author",
+			markdown: "[@vaswani2017; @knuth1984]\n",
+			want:     []string{`; <a href="#ref-knuth1984"`, "ref-knuth1984\">Knuth, D. E.", "</li>\n<li id=\"ref-vaswani2017\">"},
+		},
+		{
+			name:     "suppress author",
+			markdown: "Knuth [-@knuth1984] wrote it.\n",
+			want:     []string{`class="citation-ref">1984</a>`},
+		},
+		{
+			name:     "ieee numbers by first citation",
+			markdown: "[@knuth1984] then [@vaswani2017; @knuth1984]\n",
+			opts:     []Option{WithCitations(bibliography.IEEE, "Bibliography")},
+			want: []string{
+				`[<a href="#ref-vaswani2017" class="citation-ref">2</a>, <a href="#ref-knuth1984" class="citation-ref">1</a>]`,
+				`<h2 id="bibliography">Bibliography</h2>`,
+				`<ol class="references-list">`,
+				"<li id=\"ref-knuth1984\">D. E. Knuth, <em>The TeXbook</em>. Addison-Wesley, 1984.</li>\n<li id=\"ref-vaswani2017\">",
+			},
+		},
+		{
+			name:     "front matter overrides the style",
+			markdown: "---\ncitationStyle: ieee\n---\n[@knuth1984]\n",
+			want:     []string{`[<a href="#ref-knuth1984" class="citation-ref">1</a>]`},
+		},
+		{
+			name:     "missing key",
+			markdown: "[@nobody2020]\n",
+			want:     []string{`<span class="citation-missing">@nobody2020</span>`},
+			notWant:  []string{`class="references`},
+		},
+		{
+			name:     "links and cross-references are untouched",
+			markdown: "[a link](/x) and [@fig:plot] and [email@example.com]\n",
+			want:     []string{`<a href="/x">a link</a>`, "[@fig:plot]", "[email@example.com]"},
+			notWant:  []string{`class="citation"`},
+		},
+		{
+			name:     "footnotes keep working",
+			markdown: "Text[^1] [@knuth1984].\n\n[^1]: A note.\n",
+			want:     []string{`class="footnote-ref"`, `class="citation"`, "A note."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, _, _, _ := renderCitations(t, tt.markdown, true, tt.opts...)
+			for _, w := range tt.want {
+				if !strings.Contains(html, w) {
+					t.Errorf("output missing %q\n%s", w, html)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(html, w) {
+					t.Errorf("output should not contain %q\n%s", w, html)
+				}
+			}
+		})
+	}
+}
+
+func TestCitationsWithoutBibliography(t *testing.T) {
+	html, _, _, _ := renderCitations(t, "See [@vaswani2017].\n", false)
+	if !strings.Contains(html, "See [@vaswani2017].") || strings.Contains(html, "citation") {
+		t.Errorf("citations should stay text without a bibliography:\n%s", html)
+	}
+}
+
+func TestCitationsMissingAndTOC(t *testing.T) {
+	_, _, _, ctx := renderCitations(t, "## Intro\n\n[@knuth1984; @gone; @gone]\n", true)
+	if got, want := GetMissingCitations(ctx), []string{"gone"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetMissingCitations() = %v, want %v", got, want)
+	}
+	toc := GetTOC(ctx)
+	if len(toc) != 2 || toc[1].ID != "references" || toc[1].Text != "References" {
+		t.Errorf("TOC = %+v, want the references heading last", toc)
+	}
+}
+
+func TestSidenotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		enabled  bool
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "disabled",
+			markdown: "Text[^a].\n\n[^a]: A note.\n",
+			want:     []string{`class="footnotes"`},
+			notWant:  []string{"sidenote"},
+		},
+		{
+			name:     "enabled",
+			markdown: "Text[^a] and again[^a].\n\n[^a]: A *note*.\n\n    Second paragraph.\n",
+			enabled:  true,
+			want: []string{
+				`Text<label for="sn-1" class="sidenote-number">1</label><input type="checkbox" id="sn-1" class="sidenote-toggle"><span class="sidenote" data-number="1">A <em>note</em>.<br>` + "\nSecond paragraph.</span>",
+				`again<label for="sn-1" class="sidenote-number">1</label>.`,
+			},
+			notWant: []string{`class="footnotes"`, "footnote-backref"},
+		},
+		{
+			name:     "front matter enables",
+			markdown: "---\nsidenotes: true\n---\nText[^a].\n\n[^a]: A note.\n",
+			want:     []string{`<span class="sidenote" data-number="1">A note.</span>`},
+		},
+		{
+			name:     "front matter disables",
+			markdown: "---\nsidenotes: false\n---\nText[^a].\n\n[^a]: A note.\n",
+			enabled:  true,
+			notWant:  []string{"sidenote"},
+		},
+		{
+			name:     "block content stays a footnote",
+			markdown: "One[^a] two[^b].\n\n[^a]: Short.\n[^b]: A list:\n\n    - x\n",
+			enabled:  true,
+			want:     []string{`class="sidenote" data-number="1">Short.</span>`, `class="footnotes"`, `<li id="fn:2">`},
+			notWant:  []string{`<li id="fn:1">`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, _, _, _ := renderCitations(t, tt.markdown, false, WithSidenotes(tt.enabled))
+			for _, w := range tt.want {
+				if !strings.Contains(html, w) {
+					t.Errorf("output missing %q\n%s", w, html)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(html, w) {
+					t.Errorf("output should not contain %q\n%s", w, html)
+				}
+			}
+		})
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	markdown := "---\ntitle: A long title here\n---\n# Heading\n\nOne two three[^n] [@knuth1984].\n\n```go\nfmt.Println(x)\n```\n\n[^n]: Not counted at all.\n"
+	for _, sidenotes := range []bool{false, true} {
+		_, doc, source, _ := renderCitations(t, markdown, true, WithSidenotes(sidenotes))
+		// "Heading", "One two three", the code line and a trailing "."
+		if got := CountWords(doc, source); got != 6 {
+			t.Errorf("CountWords(sidenotes=%v) = %d, want 6", sidenotes, got)
+		}
+	}
+}
+
+func TestLoadBibliography(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "content/posts/refs.bib", []byte(testBib), 0644)
+	_ = afero.WriteFile(fs, "shared.json", []byte(`[{"id": "x", "title": "X"}]`), 0644)
+
+	tests := []struct {
+		name     string
+		source   string
+		wantPath string
+		wantLen  int
+		wantErr  string
+	}{
+		{name: "none", source: "---\ntitle: A\n---\nBody"},
+		{name: "next to the post", source: "---\nbibliography: refs.bib\n---\n", wantPath: "content/posts/refs.bib", wantLen: 2},
+		{name: "site root", source: "---\nbibliography: shared.json\n---\n", wantPath: "shared.json", wantLen: 1},
+		{name: "missing", source: "---\nbibliography: nope.bib\n---\n", wantErr: "file not found"},
+		{name: "outside the site", source: "---\nbibliography: ../../../etc/x.bib\n---\n", wantErr: "outside the site"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bib, err := LoadBibliography(fs, "content/posts/post.md", []byte(tt.source))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadBibliography() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadBibliography() error = %v", err)
+			}
+			if tt.wantPath == "" {
+				if bib != nil {
+					t.Errorf("LoadBibliography() = %+v, want nil", bib)
+				}
+				return
+			}
+			if bib.Path != tt.wantPath || bib.Library.Len() != tt.wantLen {
+				t.Errorf("LoadBibliography() = %q with %d entries, want %q with %d", bib.Path, bib.Library.Len(), tt.wantPath, tt.wantLen)
+			}
+		})
+	}
+}
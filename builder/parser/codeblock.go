@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// DefaultHighlightStyle is the chroma style used when none is configured
+const DefaultHighlightStyle = "nord"
+
+// diffLangPrefix marks fences such as ```diff-go, highlighted as Go with
+// +/- lines marked as added/removed
+const diffLangPrefix = "diff-"
+
+// chromaLineRe matches the span chroma opens for every line of code
+var chromaLineRe = regexp.MustCompile(`<span class="line( hl)?">`)
+
+// codeBlockRenderer renders fenced code blocks. Ordinary blocks go to the
+// highlighting extension's renderer; diff-<lang> blocks are rendered here.
+type codeBlockRenderer struct {
+	highlight renderer.NodeRendererFunc
+	style     string
+}
+
+func newCodeBlockRenderer(style string) renderer.NodeRenderer {
+	if style == "" || styles.Registry[style] == nil {
+		style = DefaultHighlightStyle
+	}
+	hl := highlighting.NewHTMLRenderer(
+		highlighting.WithStyle(style),
+		highlighting.WithFormatOptions(
+			chromahtml.WithClasses(true),
+		),
+		highlighting.WithWrapperRenderer(codeBlockWrapper),
+		highlighting.WithCodeBlockOptions(codeBlockOptions),
+	)
+	funcs := rendererFuncs{}
+	hl.RegisterFuncs(funcs)
+	return &codeBlockRenderer{highlight: funcs[ast.KindFencedCodeBlock], style: style}
+}
+
+// rendererFuncs captures the functions a NodeRenderer registers
+type rendererFuncs map[ast.NodeKind]renderer.NodeRendererFunc
+
+func (f rendererFuncs) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	f[kind] = fn
+}
+
+func (r *codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r *codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.FencedCodeBlock)
+	lang := string(n.Language(source))
+	base, isDiff := strings.CutPrefix(lang, diffLangPrefix)
+	if !isDiff || base == "" {
+		return r.highlight(w, source, node, entering)
+	}
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	// Strip the +/- column, remembering each line's change
+	var code bytes.Buffer
+	var changes []string
+	for i := 0; i < n.Lines().Len(); i++ {
+		segment := n.Lines().At(i)
+		line := segment.Value(source)
+		change := ""
+		if len(line) > 0 {
+			switch line[0] {
+			case '+':
+				change = "diff-add"
+				line = line[1:]
+			case '-':
+				change = "diff-del"
+				line = line[1:]
+			case ' ':
+				line = line[1:]
+			}
+		}
+		changes = append(changes, change)
+		code.Write(line)
+	}
+
+	lexer := lexers.Get(base)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	attrs := ast.NewTextBlock() // Holds the fence attributes, like the highlighting extension
+	for _, attr := range fenceAttributes(n, source) {
+		attrs.SetAttribute(attr.Name, attr.Value)
+	}
+	ctx := &diffBlockContext{language: []byte(lang), attrs: nodeAttributes{attrs}}
+
+	opts := append([]chromahtml.Option{chromahtml.WithClasses(true)}, codeBlockOptions(ctx)...)
+	opts = append(opts, chromahtml.LineNumbersInTable(false)) // One line span per line
+	var out bytes.Buffer
+	if err := chromahtml.New(opts...).Format(&out, styles.Get(r.style), iterator); err != nil {
+		return ast.WalkStop, err
+	}
+
+	codeBlockWrapper(w, ctx, true)
+	_, _ = w.WriteString(markDiffLines(out.String(), changes))
+	codeBlockWrapper(w, ctx, false)
+	return ast.WalkContinue, nil
+}
+
+// markDiffLines adds each line's change class to chroma's line spans
+func markDiffLines(html string, changes []string) string {
+	i := 0
+	return chromaLineRe.ReplaceAllStringFunc(html, func(span string) string {
+		if i >= len(changes) {
+			return span
+		}
+		change := changes[i]
+		i++
+		if change == "" {
+			return span
+		}
+		return strings.Replace(span, `class="line`, `class="line `+change, 1)
+	})
+}
+
+// codeBlockOptions maps the fence attributes linenos=true, start=N and
+// hl_lines="3-5,9" to chroma options. hl_lines counts from the block's first line.
+func codeBlockOptions(ctx highlighting.CodeBlockContext) []chromahtml.Option {
+	attrs := ctx.Attributes()
+	if attrs == nil {
+		return nil
+	}
+
+	var opts []chromahtml.Option
+	if v, ok := attrs.GetString("linenos"); ok {
+		switch v := v.(type) {
+		case bool:
+			opts = append(opts, chromahtml.WithLineNumbers(v))
+		case []byte:
+			opts = append(opts, chromahtml.WithLineNumbers(string(v) != "false"))
+		}
+	}
+
+	start := 1
+	if v, ok := attrs.GetString("start"); ok {
+		if f, ok := v.(float64); ok {
+			start = int(f)
+			opts = append(opts, chromahtml.BaseLineNumber(start))
+		}
+	}
+
+	if v, ok := attrs.GetString("hl_lines"); ok {
+		if spec, ok := v.([]byte); ok {
+			if ranges := lineRanges(string(spec), start); len(ranges) > 0 {
+				opts = append(opts, chromahtml.HighlightLines(ranges))
+			}
+		}
+	}
+	return opts
+}
+
+// lineRanges parses "3-5,9" into chroma line ranges offset to start at start
+func lineRanges(spec string, start int) [][2]int {
+	var ranges [][2]int
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		lo, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			continue
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, [2]int{lo + start - 1, hi + start - 1})
+	}
+	return ranges
+}
+
+// diffBlockContext is the highlighting.CodeBlockContext of a diff block
+type diffBlockContext struct {
+	language []byte
+	attrs    highlighting.ImmutableAttributes
+}
+
+func (c *diffBlockContext) Language() ([]byte, bool)                     { return c.language, true }
+func (c *diffBlockContext) Highlighted() bool                            { return true }
+func (c *diffBlockContext) Attributes() highlighting.ImmutableAttributes { return c.attrs }
+
+// nodeAttributes exposes a node's attributes as highlighting.ImmutableAttributes
+type nodeAttributes struct {
+	n ast.Node
+}
+
+func (a nodeAttributes) Get(name []byte) (interface{}, bool)       { return a.n.Attribute(name) }
+func (a nodeAttributes) GetString(name string) (interface{}, bool) { return a.n.AttributeString(name) }
+func (a nodeAttributes) All() []ast.Attribute                      { return a.n.Attributes() }
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+func renderCodeBlock(t *testing.T, markdown string) string {
+	t.Helper()
+	md := goldmark.New(goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(newCodeBlockRenderer(""), 200)),
+	))
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(markdown), &buf); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestCodeBlockLineOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "plain",
+			markdown: "```go\nx := 1\n```\n",
+			want:     []string{`class="chroma"`, `data-lang="go"`},
+			notWant:  []string{`class="ln"`, `line hl`},
+		},
+		{
+			name:     "line numbers from start",
+			markdown: "```go {linenos=true start=10}\na := 1\nb := 2\n```\n",
+			want:     []string{`<span class="ln">10</span>`, `<span class="ln">11</span>`},
+		},
+		{
+			name:     "highlighted lines count from the block start",
+			markdown: "```go {linenos=true start=10 hl_lines=\"2\"}\na := 1\nb := 2\nc := 3\n```\n",
+			want:     []string{`<span class="line hl"><span class="ln">11</span>`},
+			notWant:  []string{`<span class="line hl"><span class="ln">10</span>`},
+		},
+		{
+			name:     "highlighted range without line numbers",
+			markdown: "```go {hl_lines=\"1-2\"}\na := 1\nb := 2\nc := 3\n```\n",
+			want:     []string{`<span class="line hl">`},
+			notWant:  []string{`class="ln"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := renderCodeBlock(t, tt.markdown)
+			for _, s := range tt.want {
+				if !strings.Contains(html, s) {
+					t.Errorf("output missing %q:\n%s", s, html)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(html, s) {
+					t.Errorf("output contains %q:\n%s", s, html)
+				}
+			}
+		})
+	}
+}
+
+func TestCodeBlockDiff(t *testing.T) {
+	html := renderCodeBlock(t, "```diff-go {title=\"main.go\"}\n func main() {\n-\tprintln(1)\n+\tprintln(2)\n }\n```\n")
+
+	for _, s := range []string{`data-lang="diff-go"`, "main.go", `<span class="line diff-del">`, `<span class="line diff-add">`} {
+		if !strings.Contains(html, s) {
+			t.Errorf("output missing %q:\n%s", s, html)
+		}
+	}
+	if got := strings.Count(html, `<span class="line">`); got != 2 {
+		t.Errorf("got %d unchanged lines, want 2:\n%s", got, html)
+	}
+	// The base language is still highlighted, and the +/- column is gone
+	if !strings.Contains(html, `<span class="kd">func</span>`) {
+		t.Errorf("diff block is not highlighted as Go:\n%s", html)
+	}
+	if strings.Contains(html, "+\t") || strings.Contains(html, ">-") {
+		t.Errorf("diff markers left in output:\n%s", html)
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	tests := []struct {
+		spec  string
+		start int
+		want  [][2]int
+	}{
+		{"3-5,9", 1, [][2]int{{3, 5}, {9, 9}}},
+		{"2", 10, [][2]int{{11, 11}}},
+		{" 1 - 2 , x", 1, [][2]int{{1, 2}}},
+		{"", 1, nil},
+	}
+	for _, tt := range tests {
+		if got := lineRanges(tt.spec, tt.start); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lineRanges(%q, %d) = %v, want %v", tt.spec, tt.start, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/Kush-Singh-26/kosh/builder/utils"
+)
+
+var (
+	// fenceOpenRe matches an opening code fence and captures its container
+	// prefix (indent, blockquote markers and a list marker), marker and info string
+	fenceOpenRe = regexp.MustCompile("^((?:[ \t]*>[ \t]?)*(?:[ \t]*(?:[-*+]|[0-9]{1,9}[.)])[ \t]+)?[ \t]*)(`{3,}|~{3,})(.*)$")
+	// regionMarkerRe matches "#region name" / "#endregion" comments, whatever the comment syntax
+	regionMarkerRe = regexp.MustCompile(`#(region|endregion)\b[ \t]*([\w.-]*)`)
+)
+
+// EmbedCodeFiles fills fenced code blocks that carry a file attribute, e.g.
+// ```go {file="snippets/train.go" lines="10-42" region="train-loop"}, with the
+// contents of that file. A path resolves against the post's directory first,
+// then snippetsDir; a leading "/" resolves against the site root. Either way the
+// file must lie under the post's directory or snippetsDir. Fences inside
+// blockquotes and list items work too. It returns the expanded source, the
+// embedded files and the embeds that failed, whose fences are left untouched.
+func EmbedCodeFiles(fs afero.Fs, postPath, snippetsDir string, source []byte) ([]byte, []string, []error) {
+	if !bytes.Contains(source, []byte("file=")) {
+		return source, nil, nil
+	}
+
+	lines := bytes.SplitAfter(source, []byte("\n"))
+	var out bytes.Buffer
+	var includes []string
+	var errs []error
+
+	for i := 0; i < len(lines); i++ {
+		m := fenceOpenRe.FindSubmatch(bytes.TrimRight(lines[i], "\r\n"))
+		if m == nil || (m[2][0] == '`' && bytes.ContainsRune(m[3], '`')) {
+			out.Write(lines[i])
+			continue
+		}
+		cont := continuation(m[1])
+
+		// Find the closing fence, or the end of the container or document
+		end, closed := len(lines), false
+		for j := i + 1; j < len(lines); j++ {
+			line := bytes.TrimRight(lines[j], "\r\n")
+			inner, ok := bytes.CutPrefix(line, bytes.TrimRight(cont, " \t"))
+			if !ok && len(bytes.TrimSpace(line)) > 0 {
+				for end = j; end > i+1 && len(bytes.TrimSpace(lines[end-1])) == 0; end-- {
+				}
+				break
+			}
+			closing := bytes.TrimSpace(inner)
+			if len(closing) >= len(m[2]) && bytes.Count(closing, m[2][:1]) == len(closing) {
+				end, closed = j, true
+				break
+			}
+		}
+
+		file, code, err := embedFence(fs, postPath, snippetsDir, m[3])
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+			fallthrough
+		case file == "":
+			for _, line := range lines[i:end] {
+				out.Write(line)
+			}
+			if closed {
+				out.Write(lines[end])
+			}
+		default:
+			includes = append(includes, file)
+			// The fence must outgrow every run of its character in the code
+			marker := m[2]
+			if n := longestRun(code, marker[0]); n >= len(marker) {
+				marker = bytes.Repeat(marker[:1], n+1)
+			}
+			out.Write(m[1])
+			out.Write(marker)
+			out.Write(m[3])
+			out.WriteString("\n")
+			for _, line := range strings.SplitAfter(code, "\n") {
+				if line != "" {
+					out.Write(cont)
+					out.WriteString(line)
+				}
+			}
+			if closed {
+				closing := lines[end]
+				out.Write(cont)
+				out.Write(marker)
+				out.Write(closing[len(bytes.TrimRight(closing, "\r\n")):])
+			}
+		}
+		i = end
+		if !closed {
+			i-- // The line that ended the container is processed on its own
+		}
+	}
+	return out.Bytes(), includes, errs
+}
+
+// continuation returns the prefix that continues the container of a line
+// starting with prefix: blockquote markers stay, a list marker becomes spaces
+func continuation(prefix []byte) []byte {
+	cont := make([]byte, len(prefix))
+	for i, c := range prefix {
+		if c == '>' || c == ' ' || c == '\t' {
+			cont[i] = c
+		} else {
+			cont[i] = ' '
+		}
+	}
+	return cont
+}
+
+// longestRun returns the length of the longest run of c in s
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
+			continue
+		}
+		if run++; run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// embedFence returns the embedded file and its selected code for a fence info
+// string, or "" when the fence has no file attribute
+func embedFence(fs afero.Fs, postPath, snippetsDir string, info []byte) (string, string, error) {
+	i := bytes.IndexByte(info, '{')
+	if i < 0 {
+		return "", "", nil
+	}
+	attrs, _ := parser.ParseAttributes(text.NewReader(info[i:]))
+	var file, lines, region string
+	for _, attr := range attrs {
+		var v string
+		switch val := attr.Value.(type) {
+		case []byte:
+			v = string(val)
+		case float64:
+			v = strconv.FormatFloat(val, 'f', -1, 64)
+		default:
+			continue
+		}
+		switch string(attr.Name) {
+		case "file":
+			file = v
+		case "lines":
+			lines = v
+		case "region":
+			region = v
+		}
+	}
+	if file == "" {
+		return "", "", nil
+	}
+
+	resolved, err := resolveEmbed(fs, postPath, snippetsDir, file)
+	if err != nil {
+		return "", "", err
+	}
+	if info, err := fs.Stat(resolved); err == nil && info.Size() > utils.MaxFileSize {
+		return "", "", fmt.Errorf("embed %q: file exceeds size limit", file)
+	}
+	data, err := afero.ReadFile(fs, resolved)
+	if err != nil {
+		return "", "", fmt.Errorf("embed %q: %w", file, err)
+	}
+
+	code := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+	if region != "" {
+		if code, err = codeRegion(code, region); err != nil {
+			return "", "", fmt.Errorf("embed %q: %w", file, err)
+		}
+	}
+	if lines != "" {
+		if code, err = codeLines(code, lines); err != nil {
+			return "", "", fmt.Errorf("embed %q: %w", file, err)
+		}
+	}
+	return filepath.ToSlash(resolved), dedent(code), nil
+}
+
+// resolveEmbed finds an embedded file, refusing paths outside the post's
+// directory and snippetsDir
+func resolveEmbed(fs afero.Fs, postPath, snippetsDir, file string) (string, error) {
+	roots := []string{filepath.Dir(postPath)}
+	if snippetsDir != "" {
+		roots = append(roots, snippetsDir)
+	}
+	resolved, err := resolvePostFile(fs, postPath, snippetsDir, file, roots...)
+	if err != nil {
+		return "", fmt.Errorf("embed %q: %w", file, err)
+	}
+	return resolved, nil
+}
+
+// resolvePostFile resolves a file a post refers to: next to the post first,
+// then in fallbackDir; a leading "/" resolves against the site root. Paths
+// outside the site are refused, and so are paths outside roots when given.
+func resolvePostFile(fs afero.Fs, postPath, fallbackDir, file string, roots ...string) (string, error) {
+	file = filepath.ToSlash(file)
+	var candidates []string
+	if strings.HasPrefix(file, "/") {
+		candidates = []string{strings.TrimPrefix(file, "/")}
+	} else {
+		candidates = []string{path.Join(path.Dir(filepath.ToSlash(postPath)), file)}
+		if fallbackDir != "" {
+			candidates = append(candidates, path.Join(filepath.ToSlash(fallbackDir), file))
+		}
+	}
+
+	refused := false
+	for _, c := range candidates {
+		c = path.Clean(c)
+		if c == ".." || strings.HasPrefix(c, "../") {
+			return "", fmt.Errorf("path is outside the site")
+		}
+		if len(roots) > 0 && !underAny(c, roots) {
+			refused = true
+			continue
+		}
+		if _, err := fs.Stat(filepath.FromSlash(c)); err == nil {
+			return filepath.FromSlash(c), nil
+		}
+	}
+	if refused {
+		return "", fmt.Errorf("path is outside %s", strings.Join(roots, " and "))
+	}
+	return "", fmt.Errorf("file not found")
+}
+
+// underAny reports whether the clean slash path p lies within one of dirs
+func underAny(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = path.Clean(filepath.ToSlash(dir))
+		if dir == "." || p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// codeRegion returns the lines between "#region name" and its "#endregion",
+// without the markers of nested regions
+func codeRegion(code []string, name string) ([]string, error) {
+	start := -1
+	depth := 0
+	var out []string
+	for i, line := range code {
+		m := regionMarkerRe.FindStringSubmatch(line)
+		if start < 0 {
+			if m != nil && m[1] == "region" && m[2] == name {
+				start = i
+			}
+			continue
+		}
+		if m == nil {
+			out = append(out, line)
+			continue
+		}
+		if m[1] == "region" {
+			depth++
+			continue
+		}
+		if depth == 0 || m[2] == name {
+			return out, nil
+		}
+		depth--
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("region %q not found", name)
+	}
+	return nil, fmt.Errorf("region %q has no #endregion", name)
+}
+
+// codeLines selects 1-based line ranges such as "10-42", "10-", "-5" or "1-3,8"
+func codeLines(code []string, spec string) ([]string, error) {
+	var out []string
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		start, end := 1, len(code)
+		var err error
+		if from != "" {
+			if start, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid lines %q", spec)
+			}
+		}
+		switch {
+		case !isRange:
+			end = start
+		case to != "":
+			if end, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid lines %q", spec)
+			}
+		}
+		if start < 1 || end > len(code) || start > end {
+			return nil, fmt.Errorf("lines %q out of range (file has %d lines)", part, len(code))
+		}
+		out = append(out, code[start-1:end]...)
+	}
+	return out, nil
+}
+
+// dedent removes the indentation common to all non-blank lines
+func dedent(code []string) string {
+	prefix := ""
+	first := true
+	for _, line := range code {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range code {
+		b.WriteString(strings.TrimPrefix(line, prefix))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
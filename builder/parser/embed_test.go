@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestEmbedCodeFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	train := "package main\n\nfunc train() {\n\t// #region train-loop\n\tfor epoch := range epochs {\n\t\t// #region step\n\t\tstep(epoch)\n\t\t// #endregion step\n\t}\n\t// #endregion\n}\n"
+	_ = afero.WriteFile(fs, "content/posts/train.go", []byte(train), 0644)
+	_ = afero.WriteFile(fs, "snippets/util.py", []byte("a = 1\nb = 2\nc = 3\n"), 0644)
+	_ = afero.WriteFile(fs, "content/drafts/secret.go", []byte("package drafts\n"), 0644)
+	_ = afero.WriteFile(fs, "content/posts/usage.md", []byte("Run:\n```sh\nmake\n```\n"), 0644)
+	_ = afero.WriteFile(fs, "kosh.yaml", []byte("title: Site\n"), 0644)
+
+	tests := []struct {
+		name     string
+		fence    string
+		want     string
+		includes []string
+		errs     int
+	}{
+		{
+			name:     "whole file next to the post",
+			fence:    "```go {file=\"train.go\"}\n```",
+			want:     "```go {file=\"train.go\"}\n" + train + "```",
+			includes: []string{"content/posts/train.go"},
+		},
+		{
+			name:     "region is dedented without nested markers",
+			fence:    "```go {file=\"train.go\" region=\"train-loop\"}\n```",
+			want:     "```go {file=\"train.go\" region=\"train-loop\"}\nfor epoch := range epochs {\n\tstep(epoch)\n}\n```",
+			includes: []string{"content/posts/train.go"},
+		},
+		{
+			name:     "line ranges from the snippets root",
+			fence:    "~~~python {file=\"util.py\" lines=\"1,3-\"}\nstale\n~~~",
+			want:     "~~~python {file=\"util.py\" lines=\"1,3-\"}\na = 1\nc = 3\n~~~",
+			includes: []string{"snippets/util.py"},
+		},
+		{
+			name:     "site root path",
+			fence:    "```python {file=\"/snippets/util.py\" lines=\"2\"}\n```",
+			want:     "```python {file=\"/snippets/util.py\" lines=\"2\"}\nb = 2\n```",
+			includes: []string{"snippets/util.py"},
+		},
+		{
+			name:  "missing file keeps the fence",
+			fence: "```go {file=\"missing.go\"}\nfallback\n```",
+			want:  "```go {file=\"missing.go\"}\nfallback\n```",
+			errs:  1,
+		},
+		{
+			name:  "path outside the site",
+			fence: "```go {file=\"../../../etc/passwd\"}\n```",
+			want:  "```go {file=\"../../../etc/passwd\"}\n```",
+			errs:  1,
+		},
+		{
+			name:  "sibling directory of the post",
+			fence: "```go {file=\"../drafts/secret.go\"}\n```",
+			want:  "```go {file=\"../drafts/secret.go\"}\n```",
+			errs:  1,
+		},
+		{
+			name:  "site root path outside the allowed directories",
+			fence: "```yaml {file=\"/kosh.yaml\"}\n```",
+			want:  "```yaml {file=\"/kosh.yaml\"}\n```",
+			errs:  1,
+		},
+		{
+			name:  "lines out of range",
+			fence: "```python {file=\"util.py\" lines=\"2-9\"}\n```",
+			want:  "```python {file=\"util.py\" lines=\"2-9\"}\n```",
+			errs:  1,
+		},
+		{
+			name:     "fence outgrows backtick runs in the file",
+			fence:    "```markdown {file=\"usage.md\"}\n```",
+			want:     "````markdown {file=\"usage.md\"}\nRun:\n```sh\nmake\n```\n````",
+			includes: []string{"content/posts/usage.md"},
+		},
+		{
+			name:     "inside a blockquote",
+			fence:    "> ```python {file=\"util.py\" lines=\"1-2\"}\n> ```",
+			want:     "> ```python {file=\"util.py\" lines=\"1-2\"}\n> a = 1\n> b = 2\n> ```",
+			includes: []string{"snippets/util.py"},
+		},
+		{
+			name:     "inside a list item",
+			fence:    "- Step:\n- ```python {file=\"util.py\" lines=\"3\"}\n  ```",
+			want:     "- Step:\n- ```python {file=\"util.py\" lines=\"3\"}\n  c = 3\n  ```",
+			includes: []string{"snippets/util.py"},
+		},
+		{
+			name:     "unclosed fence ends with its blockquote",
+			fence:    "> ```python {file=\"util.py\" lines=\"1\"}\nLazy line",
+			want:     "> ```python {file=\"util.py\" lines=\"1\"}\n> a = 1\nLazy line",
+			includes: []string{"snippets/util.py"},
+		},
+		{
+			name:  "file attribute inside another fence is left alone",
+			fence: "````markdown\n```go {file=\"train.go\"}\n```\n````",
+			want:  "````markdown\n```go {file=\"train.go\"}\n```\n````",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "Intro\n\n" + tt.fence + "\n\nOutro\n"
+			got, includes, errs := EmbedCodeFiles(fs, "content/posts/post.md", "snippets", []byte(source))
+			if want := "Intro\n\n" + tt.want + "\n\nOutro\n"; string(got) != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+			if !reflect.DeepEqual(includes, tt.includes) {
+				t.Errorf("includes = %v, want %v", includes, tt.includes)
+			}
+			if len(errs) != tt.errs {
+				t.Errorf("errs = %v, want %d", errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestCodeRegionErrors(t *testing.T) {
+	code := strings.Split("// #region a\nx\n", "\n")
+	if _, err := codeRegion(code, "b"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing region error = %v", err)
+	}
+	if _, err := codeRegion(code, "a"); err == nil || !strings.Contains(err.Error(), "#endregion") {
+		t.Errorf("unterminated region error = %v", err)
+	}
+}
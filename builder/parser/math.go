@@ -3,7 +3,6 @@ package parser
 import (
 	"fmt"
 	htmlLib "html"
-	"log"
 	"regexp"
 	"strings"
 	"sync"
@@ -30,8 +29,34 @@ var (
 	currencyPattern = regexp.MustCompile(`^\d`)
 )
 
+// MathOptions are the KaTeX settings that apply to one page
+type MathOptions struct {
+	Macros     map[string]string // e.g. \R -> \mathbb{R}
+	Output     string            // native.MathOutputHTML (default), MathOutputMathML or MathOutputHTMLAndMathML
+	Extensions string            // native.Renderer.ExtensionsHash()
+}
+
+func (o MathOptions) hash(kind, latex string) string {
+	settings := o.Extensions
+	if o.Output != "" && o.Output != native.MathOutputHTML {
+		settings += "|" + o.Output
+	}
+	return native.MathHash(kind, latex, o.Macros, settings)
+}
+
+func (o MathOptions) expression(latex string, displayMode bool, hash string) native.MathExpression {
+	return native.MathExpression{LaTeX: latex, DisplayMode: displayMode, Hash: hash, Macros: o.Macros, Output: o.Output}
+}
+
+// mathWrapper opens the element around rendered math. The LaTeX source doubles
+// as the accessible name, since KaTeX's HTML output alone means nothing to a
+// screen reader.
+func mathWrapper(tag, class, latex string) string {
+	return fmt.Sprintf(`<%s class="%s" role="math" aria-label="%s">`, tag, class, htmlLib.EscapeString(latex))
+}
+
 // ExtractMathExpressions finds all LaTeX expressions in HTML and returns them with metadata
-func ExtractMathExpressions(html string) []native.MathExpression {
+func ExtractMathExpressions(html string, opts MathOptions) []native.MathExpression {
 	var expressions []native.MathExpression
 	seen := make(map[string]bool) // Deduplicate
 
@@ -40,10 +65,10 @@ func ExtractMathExpressions(html string) []native.MathExpression {
 		if len(match) >= 2 {
 			latex := htmlLib.UnescapeString(match[1])
 			latex = strings.TrimSpace(latex)
-			hash := native.HashContent("math-block", latex)
+			hash := opts.hash("math-block", latex)
 			if !seen[hash] {
 				seen[hash] = true
-				expressions = append(expressions, native.MathExpression{LaTeX: latex, DisplayMode: true, Hash: hash})
+				expressions = append(expressions, opts.expression(latex, true, hash))
 			}
 		}
 	}
@@ -53,10 +78,10 @@ func ExtractMathExpressions(html string) []native.MathExpression {
 		if len(match) >= 2 {
 			latex := htmlLib.UnescapeString(match[1])
 			latex = strings.TrimSpace(latex)
-			hash := native.HashContent("math-display", latex)
+			hash := opts.hash("math-display", latex)
 			if !seen[hash] {
 				seen[hash] = true
-				expressions = append(expressions, native.MathExpression{LaTeX: latex, DisplayMode: true, Hash: hash})
+				expressions = append(expressions, opts.expression(latex, true, hash))
 			}
 		}
 	}
@@ -68,10 +93,10 @@ func ExtractMathExpressions(html string) []native.MathExpression {
 			if currencyPattern.MatchString(latex) {
 				continue
 			}
-			hash := native.HashContent("math-inline", latex)
+			hash := opts.hash("math-inline", latex)
 			if !seen[hash] {
 				seen[hash] = true
-				expressions = append(expressions, native.MathExpression{LaTeX: latex, DisplayMode: false, Hash: hash})
+				expressions = append(expressions, opts.expression(latex, false, hash))
 			}
 		}
 	}
@@ -80,10 +105,10 @@ func ExtractMathExpressions(html string) []native.MathExpression {
 	for _, match := range inlineParenRegex.FindAllStringSubmatch(html, -1) {
 		if len(match) >= 2 {
 			latex := htmlLib.UnescapeString(match[1])
-			hash := native.HashContent("math-paren", latex)
+			hash := opts.hash("math-paren", latex)
 			if !seen[hash] {
 				seen[hash] = true
-				expressions = append(expressions, native.MathExpression{LaTeX: latex, DisplayMode: false, Hash: hash})
+				expressions = append(expressions, opts.expression(latex, false, hash))
 			}
 		}
 	}
@@ -92,7 +117,7 @@ func ExtractMathExpressions(html string) []native.MathExpression {
 }
 
 // ReplaceMathExpressions replaces LaTeX expressions in HTML with rendered output
-func ReplaceMathExpressions(html string, rendered map[string]string, cache map[string]string, cacheMu *sync.Mutex) string {
+func ReplaceMathExpressions(html string, rendered map[string]string, cache map[string]string, cacheMu *sync.Mutex, opts MathOptions) string {
 	if len(rendered) == 0 && len(cache) == 0 {
 		return html
 	}
@@ -130,9 +155,9 @@ func ReplaceMathExpressions(html string, rendered map[string]string, cache map[s
 		}
 		latex := htmlLib.UnescapeString(submatch[1])
 		latex = strings.TrimSpace(latex)
-		hash := native.HashContent("math-block", latex)
+		hash := opts.hash("math-block", latex)
 		if html, ok := getRendered(hash); ok {
-			return fmt.Sprintf(`<div class="katex-display">%s</div>`, html)
+			return mathWrapper("div", "katex-display", latex) + html + "</div>"
 		}
 		return match
 	})
@@ -145,9 +170,9 @@ func ReplaceMathExpressions(html string, rendered map[string]string, cache map[s
 		}
 		latex := htmlLib.UnescapeString(submatch[1])
 		latex = strings.TrimSpace(latex)
-		hash := native.HashContent("math-display", latex)
+		hash := opts.hash("math-display", latex)
 		if html, ok := getRendered(hash); ok {
-			return fmt.Sprintf(`<div class="katex-display">%s</div>`, html)
+			return mathWrapper("div", "katex-display", latex) + html + "</div>"
 		}
 		return match
 	})
@@ -162,9 +187,9 @@ func ReplaceMathExpressions(html string, rendered map[string]string, cache map[s
 		if currencyPattern.MatchString(latex) {
 			return match
 		}
-		hash := native.HashContent("math-inline", latex)
+		hash := opts.hash("math-inline", latex)
 		if html, ok := getRendered(hash); ok {
-			return fmt.Sprintf(`<span class="katex-inline">%s</span>`, html)
+			return mathWrapper("span", "katex-inline", latex) + html + "</span>"
 		}
 		return match
 	})
@@ -176,9 +201,9 @@ func ReplaceMathExpressions(html string, rendered map[string]string, cache map[s
 			return match
 		}
 		latex := htmlLib.UnescapeString(submatch[1])
-		hash := native.HashContent("math-paren", latex)
+		hash := opts.hash("math-paren", latex)
 		if html, ok := getRendered(hash); ok {
-			return fmt.Sprintf(`<span class="katex-inline">%s</span>`, html)
+			return mathWrapper("span", "katex-inline", latex) + html + "</span>"
 		}
 		return match
 	})
@@ -187,11 +212,13 @@ func ReplaceMathExpressions(html string, rendered map[string]string, cache map[s
 }
 
 // RenderMathForHTML extracts, renders, and replaces all LaTeX in HTML
-// Returns the rendered HTML and a slice of SSR input hashes for cache tracking
-func RenderMathForHTML(html string, renderer *native.Renderer, cache map[string]string, cacheMu *sync.Mutex) (string, []string) {
-	expressions := ExtractMathExpressions(html)
+// Returns the rendered HTML, a slice of SSR input hashes for cache tracking, and
+// the expressions KaTeX rejected (including cached renders of them)
+func RenderMathForHTML(html string, renderer *native.Renderer, cache map[string]string, cacheMu *sync.Mutex, opts MathOptions) (string, []string, []native.MathError) {
+	opts.Extensions = renderer.ExtensionsHash()
+	expressions := ExtractMathExpressions(html, opts)
 	if len(expressions) == 0 {
-		return html, nil
+		return html, nil, nil
 	}
 
 	hashes := make([]string, len(expressions))
@@ -206,10 +233,16 @@ func RenderMathForHTML(html string, renderer *native.Renderer, cache map[string]
 	}
 	cacheMu.Unlock()
 
-	rendered, err := renderer.RenderAllMath(expressions, cachedCopy)
-	if err != nil {
-		log.Printf("   ⚠️  LaTeX batch render failed: %v", err)
+	rendered, mathErrors := renderer.RenderAllMath(expressions, cachedCopy)
+	for _, expr := range expressions {
+		out, ok := rendered[expr.Hash]
+		if !ok {
+			out = cachedCopy[expr.Hash]
+		}
+		if msg, failed := native.RenderedMathError(out); failed {
+			mathErrors = append(mathErrors, native.MathError{LaTeX: expr.LaTeX, Message: msg})
+		}
 	}
 
-	return ReplaceMathExpressions(html, rendered, cache, cacheMu), hashes
+	return ReplaceMathExpressions(html, rendered, cache, cacheMu, opts), hashes, mathErrors
 }
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
+)
+
+func TestRenderMathForHTML(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts KaTeX workers")
+	}
+	renderer := native.New()
+	input := `<p>Inline $a &lt; b$ and</p>
+$$\frac{1}{2}$$
+<p>broken $\frac{1}{$ here</p>`
+
+	tests := []struct {
+		output  string
+		want    []string
+		notWant []string
+	}{
+		{native.MathOutputHTML, []string{`<span class="katex-html"`}, []string{"<math"}},
+		{native.MathOutputMathML, []string{"<math"}, []string{`class="katex-html"`}},
+		{native.MathOutputHTMLAndMathML, []string{"<math", `<span class="katex-html"`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			cache := make(map[string]string)
+			var mu sync.Mutex
+			html, hashes, mathErrors := RenderMathForHTML(input, renderer, cache, &mu, MathOptions{Output: tt.output})
+
+			want := append([]string{
+				`<span class="katex-inline" role="math" aria-label="a &lt; b">`,
+				`<div class="katex-display" role="math" aria-label="\frac{1}{2}">`,
+			}, tt.want...)
+			for _, w := range want {
+				if !strings.Contains(html, w) {
+					t.Errorf("output missing %q\ngot: %s", w, html)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(html, w) {
+					t.Errorf("output should not contain %q", w)
+				}
+			}
+			if len(hashes) == 0 {
+				t.Error("expected SSR hashes")
+			}
+			if len(mathErrors) != 1 || mathErrors[0].LaTeX != `\frac{1}{` || mathErrors[0].Message == "" {
+				t.Errorf("mathErrors = %+v, want one error for \\frac{1}{", mathErrors)
+			}
+
+			// Errors are still reported when the broken expression comes from the cache
+			_, _, cachedErrors := RenderMathForHTML(input, renderer, cache, &mu, MathOptions{Output: tt.output})
+			if len(cachedErrors) != 1 {
+				t.Errorf("cached mathErrors = %+v, want one", cachedErrors)
+			}
+		})
+	}
+}
+
+func TestMathOptionsHash(t *testing.T) {
+	latex := `x \in \R`
+	plain := MathOptions{}.hash("math-inline", latex)
+	if plain != native.HashContent("math-inline", latex) {
+		t.Error("default options should keep the plain content hash")
+	}
+	if h := (MathOptions{Output: native.MathOutputHTML}).hash("math-inline", latex); h != plain {
+		t.Error("explicit html output should match the default")
+	}
+	if h := (MathOptions{Output: native.MathOutputMathML}).hash("math-inline", latex); h == plain {
+		t.Error("output mode should be part of the hash")
+	}
+}
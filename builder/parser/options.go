@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"github.com/Kush-Singh-26/kosh/builder/bibliography"
+	"github.com/Kush-Singh-26/kosh/builder/utils"
+)
+
+// ImageResolver looks up the renditions generated for an image URL
+type ImageResolver interface {
+	ResolveImage(src string) (utils.ImageInfo, bool)
+}
+
+// Option configures optional parser features
+type Option func(*options)
+
+type options struct {
+	images            ImageResolver
+	imageSizes        string
+	equationNumbering string
+	diagrams          *DiagramOptions
+	highlightStyle    string
+	citationStyle     bibliography.Style
+	referencesTitle   string
+	sidenotes         bool
+	summaryWords      int
+}
+
+// WithImages enables responsive <picture> output and intrinsic dimensions for
+// local images. sizes is the default sizes attribute of the generated sources.
+func WithImages(resolver ImageResolver, sizes string) Option {
+	return func(o *options) {
+		o.images = resolver
+		o.imageSizes = sizes
+	}
+}
+
+// WithEquationNumbering sets which display equations are numbered: NumberLabelled
+// (the default), NumberAll or NumberNone
+func WithEquationNumbering(mode string) Option {
+	return func(o *options) {
+		o.equationNumbering = mode
+	}
+}
+
+// WithDiagramDefaults sets the site-wide rendering options of D2, Graphviz and
+// Mermaid diagrams (default: DefaultDiagramOptions)
+func WithDiagramDefaults(defaults DiagramOptions) Option {
+	return func(o *options) {
+		o.diagrams = &defaults
+	}
+}
+
+// WithHighlightStyle sets the chroma style of code blocks (default: DefaultHighlightStyle)
+func WithHighlightStyle(style string) Option {
+	return func(o *options) {
+		o.highlightStyle = style
+	}
+}
+
+// WithCitations sets the site's citation style and the heading of the
+// references list (defaults: bibliography.DefaultStyle, DefaultReferencesTitle)
+func WithCitations(style bibliography.Style, title string) Option {
+	return func(o *options) {
+		o.citationStyle = style
+		o.referencesTitle = title
+	}
+}
+
+// WithSidenotes renders footnotes as margin sidenotes; the "sidenotes" front
+// matter overrides it per post
+func WithSidenotes(enabled bool) Option {
+	return func(o *options) {
+		o.sidenotes = enabled
+	}
+}
+
+// WithSummaryWords makes the summary of a post without a <!--more--> divider
+// its first blocks that reach words words; 0 leaves such posts without one
+func WithSummaryWords(words int) Option {
+	return func(o *options) {
+		o.summaryWords = words
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"strings"
 	"sync"
 
-	chroma_html "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/gohugoio/hugo-goldmark-extensions/passthrough"
 	admonitions "github.com/stefanfritsch/goldmark-admonitions"
 	"github.com/yuin/goldmark"
@@ -14,9 +13,11 @@ import (
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	gm_renderer "github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/util"
 
+	"github.com/Kush-Singh-26/kosh/builder/bibliography"
 	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
 )
 
@@ -74,43 +75,86 @@ func ExtractPlainText(node ast.Node, source []byte) string {
 		case ast.KindHeading:
 			// Ensure headings are separated
 			out.WriteString("\n")
+		case KindTab:
+			// Tab labels are not text nodes
+			out.WriteString(n.(*Tab).Label)
+			out.WriteString(" ")
 		}
 		return ast.WalkContinue, nil
 	})
 	return out.String()
 }
 
+// mathDelimiters keep TeX away from the Markdown parser until it is rendered
+var mathDelimiters = passthrough.Config{
+	InlineDelimiters: []passthrough.Delimiters{{Open: "$", Close: "$"}, {Open: "\\(", Close: "\\)"}},
+	BlockDelimiters:  []passthrough.Delimiters{{Open: "$$", Close: "$$"}, {Open: "\\[", Close: "\\]"}},
+}
+
 // New creates a new Goldmark markdown parser with SSR support for diagrams
-func New(baseURL string, renderer *native.Renderer, diagramCache *sync.Map) goldmark.Markdown {
+func New(baseURL string, renderer *native.Renderer, diagramCache *sync.Map, opts ...Option) goldmark.Markdown {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	diagrams := DefaultDiagramOptions
+	if o.diagrams != nil {
+		diagrams = *o.diagrams
+	}
+	citationStyle := o.citationStyle
+	if citationStyle == "" {
+		citationStyle = bibliography.DefaultStyle
+	}
+
 	return goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
+			extension.Footnote,
 			meta.Meta,
-			highlighting.NewHighlighting(
-				highlighting.WithStyle("nord"),
-				highlighting.WithFormatOptions(
-					chroma_html.WithClasses(true),
-				),
-				highlighting.WithWrapperRenderer(codeBlockWrapper),
-			),
-			passthrough.New(passthrough.Config{
-				InlineDelimiters: []passthrough.Delimiters{{Open: "$", Close: "$"}, {Open: "\\(", Close: "\\)"}},
-				BlockDelimiters:  []passthrough.Delimiters{{Open: "$$", Close: "$$"}, {Open: "\\[", Close: "\\]"}},
-			}),
+			passthrough.New(mathDelimiters),
 			&admonitions.Extender{},
 		),
 		goldmark.WithParserOptions(
+			parser.WithBlockParsers(
+				util.Prioritized(&tabsParser{}, 90),
+				util.Prioritized(&tabParser{}, 90), // Before setext headings ("== Label")
+			),
+			parser.WithInlineParsers(
+				util.Prioritized(&citationParser{}, 150), // After footnote references, before links
+				util.Prioritized(&crossRefParser{}, 160),
+			),
 			// Register Transformers
 			parser.WithASTTransformers(
+				util.Prioritized(&tabsTransformer{}, 60),
+				util.Prioritized(&summaryTransformer{Words: o.summaryWords}, 65), // After tabs regroup the top-level blocks
+				util.Prioritized(&imageTransformer{Resolver: o.images, Sizes: o.imageSizes}, 90),
+				util.Prioritized(&figureTransformer{}, 95),
+				util.Prioritized(&equationTransformer{Numbering: o.equationNumbering}, 96),
 				util.Prioritized(&urlTransformer{BaseURL: baseURL}, 100),
+				util.Prioritized(&citationTransformer{Style: citationStyle, Title: o.referencesTitle}, 190),
 				util.Prioritized(&tocTransformer{}, 200),
+				util.Prioritized(&crossRefTransformer{}, 250),                      // After every label is registered
+				util.Prioritized(&sidenoteTransformer{Enabled: o.sidenotes}, 1000), // After the footnote list is built
 				util.Prioritized(&ssrTransformer{
 					Renderer: renderer,
 					Cache:    diagramCache,
+					Defaults: diagrams,
 				}, 50), // Run SSR early (lower priority = runs first)
 			),
 			parser.WithAutoHeadingID(),
 		),
-		goldmark.WithRendererOptions(html.WithUnsafe()),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+			gm_renderer.WithNodeRenderers(
+				util.Prioritized(newImageRenderer(), 500),
+				util.Prioritized(newFigureRenderer(), 500),
+				util.Prioritized(newEquationRenderer(), 500),
+				util.Prioritized(newTabsRenderer(), 500),
+				util.Prioritized(newCitationRenderer(), 500),
+				util.Prioritized(newSidenoteRenderer(), 500),
+				util.Prioritized(newSummaryRenderer(), 500),
+				util.Prioritized(newCodeBlockRenderer(o.highlightStyle), 200), // Highlighting, plus diff-<lang> blocks
+			),
+		),
 	)
 }
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindSidenote is the node kind of Sidenote
+var KindSidenote = ast.NewNodeKind("Sidenote")
+
+// Sidenote is a footnote shown in the margin next to its reference. Its
+// children are the footnote's inlines; repeated references have none.
+type Sidenote struct {
+	ast.BaseInline
+	Number int
+}
+
+func (n *Sidenote) Kind() ast.NodeKind { return KindSidenote }
+
+func (n *Sidenote) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Number": fmt.Sprint(n.Number)}, nil)
+}
+
+// sidenoteTransformer turns footnotes into sidenotes when the site enables
+// them or the "sidenotes" front matter is true. Footnotes with more than
+// paragraphs, like lists or code, stay in the footnote list. It runs after the
+// footnote extension has numbered the footnotes and appended the list.
+type sidenoteTransformer struct {
+	Enabled bool
+}
+
+func (t *sidenoteTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	enabled := t.Enabled
+	if v, ok := meta.Get(pc)["sidenotes"].(bool); ok {
+		enabled = v
+	}
+	if !enabled {
+		return
+	}
+
+	var list *east.FootnoteList
+	var links []*east.FootnoteLink
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *east.FootnoteList:
+			list = n
+			return ast.WalkSkipChildren, nil
+		case *east.FootnoteLink:
+			links = append(links, n)
+		}
+		return ast.WalkContinue, nil
+	})
+	if list == nil {
+		return
+	}
+
+	footnotes := map[int]*east.Footnote{}
+	for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+		if fn, ok := c.(*east.Footnote); ok && onlyParagraphs(fn) {
+			footnotes[fn.Index] = fn
+		}
+	}
+
+	for _, link := range links {
+		fn, ok := footnotes[link.Index]
+		if !ok {
+			continue
+		}
+		note := &Sidenote{Number: link.Index}
+		if link.RefIndex == 0 {
+			for p := fn.FirstChild(); p != nil; p = p.NextSibling() {
+				if p != fn.FirstChild() {
+					br := ast.NewText()
+					br.SetHardLineBreak(true)
+					note.AppendChild(note, br)
+				}
+				for c := p.FirstChild(); c != nil; {
+					next := c.NextSibling()
+					if c.Kind() != east.KindFootnoteBacklink {
+						note.AppendChild(note, c)
+					}
+					c = next
+				}
+			}
+		}
+		link.Parent().ReplaceChild(link.Parent(), link, note)
+	}
+
+	for _, fn := range footnotes {
+		list.RemoveChild(list, fn)
+	}
+	if !list.HasChildren() {
+		list.Parent().RemoveChild(list.Parent(), list)
+	}
+}
+
+// onlyParagraphs reports whether a footnote fits in a sidenote
+func onlyParagraphs(fn *east.Footnote) bool {
+	for c := fn.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.Kind() != ast.KindParagraph {
+			return false
+		}
+	}
+	return fn.HasChildren()
+}
+
+// sidenoteRenderer renders Sidenote nodes as a numbered marker plus a margin
+// note. The checkbox lets narrow screens toggle the note inline without JavaScript.
+type sidenoteRenderer struct{}
+
+func newSidenoteRenderer() renderer.NodeRenderer {
+	return &sidenoteRenderer{}
+}
+
+func (r *sidenoteRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindSidenote, r.renderSidenote)
+}
+
+func (r *sidenoteRenderer) renderSidenote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Sidenote)
+	id := fmt.Sprintf("sn-%d", n.Number)
+	if entering {
+		_, _ = fmt.Fprintf(w, `<label for="%s" class="sidenote-number">%d</label>`, id, n.Number)
+		if !n.HasChildren() {
+			return ast.WalkSkipChildren, nil
+		}
+		_, _ = fmt.Fprintf(w, `<input type="checkbox" id="%s" class="sidenote-toggle"><span class="sidenote" data-number="%d">`, id, n.Number)
+		return ast.WalkContinue, nil
+	}
+	if n.HasChildren() {
+		_, _ = w.WriteString(`</span>`)
+	}
+	return ast.WalkContinue, nil
+}
+
+// CountWords counts the words a reader reads: prose and code, without
+// citations, the references list and footnotes
+func CountWords(doc ast.Node, source []byte) int {
+	words := 0
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case KindCitation, KindReferences, KindSidenote,
+			east.KindFootnoteLink, east.KindFootnoteBacklink, east.KindFootnoteList, east.KindFootnote:
+			return ast.WalkSkipChildren, nil
+		case ast.KindText:
+			words += len(strings.Fields(string(n.(*ast.Text).Segment.Value(source))))
+		case ast.KindCodeBlock, ast.KindFencedCodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				line := lines.At(i)
+				words += len(strings.Fields(string(line.Value(source))))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return words
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// SummaryDivider ends the summary of a post in Markdown and in rendered HTML
+const SummaryDivider = "<!--more-->"
+
+// KindSummaryBreak is the node kind of SummaryBreak
+var KindSummaryBreak = ast.NewNodeKind("SummaryBreak")
+
+// SummaryBreak marks the end of a post's summary. It is always a child of the
+// document, so the HTML before it is made of whole blocks.
+type SummaryBreak struct {
+	ast.BaseBlock
+}
+
+func (n *SummaryBreak) Kind() ast.NodeKind { return KindSummaryBreak }
+
+func (n *SummaryBreak) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// summaryTransformer places the summary break. A <!--more--> divider ends the
+// summary after the top-level block holding it; without one, the summary is
+// the first blocks that reach Words words (0 turns this off). It runs before
+// footnote and reference lists are appended, so they are never part of it.
+type summaryTransformer struct {
+	Words int
+}
+
+func (t *summaryTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var dividers []ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && isSummaryDivider(n, source) {
+			dividers = append(dividers, n)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if len(dividers) > 0 {
+		end := topLevel(dividers[0])
+		if end == dividers[0] {
+			doc.ReplaceChild(doc, end, &SummaryBreak{})
+			dividers = dividers[1:]
+		} else {
+			doc.InsertAfter(doc, end, &SummaryBreak{})
+		}
+		for _, d := range dividers {
+			d.Parent().RemoveChild(d.Parent(), d)
+		}
+		return
+	}
+
+	if t.Words <= 0 || doc.LastChild() == nil {
+		return
+	}
+	end := doc.LastChild() // A short post is its own summary
+	words := 0
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		if words += CountWords(c, source); words >= t.Words {
+			end = c
+			break
+		}
+	}
+	doc.InsertAfter(doc, end, &SummaryBreak{})
+}
+
+// isSummaryDivider reports whether n is a <!--more--> block or inline HTML
+func isSummaryDivider(n ast.Node, source []byte) bool {
+	var raw []byte
+	switch n := n.(type) {
+	case *ast.HTMLBlock:
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			raw = append(raw, line.Value(source)...)
+		}
+	case *ast.RawHTML:
+		for i := 0; i < n.Segments.Len(); i++ {
+			seg := n.Segments.At(i)
+			raw = append(raw, seg.Value(source)...)
+		}
+	default:
+		return false
+	}
+	return bytes.Equal(bytes.TrimSpace(raw), []byte(SummaryDivider))
+}
+
+// topLevel returns the ancestor of n that is a child of the document
+func topLevel(n ast.Node) ast.Node {
+	for n.Parent() != nil && n.Parent().Kind() != ast.KindDocument {
+		n = n.Parent()
+	}
+	return n
+}
+
+type summaryRenderer struct{}
+
+func newSummaryRenderer() renderer.NodeRenderer { return &summaryRenderer{} }
+
+func (r *summaryRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindSummaryBreak, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString(SummaryDivider + "\n")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+var (
+	// Footnote references point at notes that are not part of the summary
+	footnoteRefRe = regexp.MustCompile(`<sup id="fnref[^"]*"><a href="#fn:[^"]*" class="footnote-ref"[^>]*>[^<]*</a></sup>`)
+	// Heading ids would clash when several summaries share a page
+	headingIDRe = regexp.MustCompile(`(<h[1-6])((?:\s[^>]*?)?)\s+id="[^"]*"`)
+)
+
+// SplitSummary cuts rendered HTML at its summary break. It returns the
+// summary, or "" when the post has none, and the content without the break.
+// The summary loses its footnote references and heading ids, since it is
+// shown away from the post on index pages and in feeds.
+func SplitSummary(html string) (summary, content string) {
+	before, after, found := strings.Cut(html, SummaryDivider+"\n")
+	if !found {
+		return "", html
+	}
+	summary = footnoteRefRe.ReplaceAllString(strings.TrimSpace(before), "")
+	summary = headingIDRe.ReplaceAllString(summary, "$1$2")
+	return summary, before + after
+}
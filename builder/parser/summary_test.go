@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func renderSummary(t *testing.T, markdown string, words int) (summary, content string) {
+	t.Helper()
+	md := New("", nil, &sync.Map{}, WithSummaryWords(words))
+	source := []byte(markdown)
+	doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(parser.NewContext()))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	return SplitSummary(buf.String())
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		words    int
+		want     string
+	}{
+		{
+			name:     "divider",
+			markdown: "First paragraph.\n\n<!--more-->\n\nSecond paragraph.\n",
+			want:     "<p>First paragraph.</p>",
+		},
+		{
+			name:     "divider wins over word count",
+			markdown: "One two three four.\n\nFive.\n\n<!--more-->\n\nSix.\n",
+			words:    2,
+			want:     "<p>One two three four.</p>\n<p>Five.</p>",
+		},
+		{
+			name:     "inline divider ends after its block",
+			markdown: "- one <!--more--> two\n- three\n\nAfter.\n",
+			want:     "<ul>\n<li>one  two</li>\n<li>three</li>\n</ul>",
+		},
+		{
+			name:     "code stays whole",
+			markdown: "Intro.\n\n```go\nfunc main() {}\n```\n<!--more-->\nRest.\n",
+			want:     `<span class="kd">func</span>`,
+		},
+		{
+			name:     "first blocks that reach the word count",
+			markdown: "One two.\n\nThree four five.\n\nSix seven.\n",
+			words:    4,
+			want:     "<p>One two.</p>\n<p>Three four five.</p>",
+		},
+		{
+			name:     "short post is its own summary",
+			markdown: "One two.\n\nThree.\n",
+			words:    70,
+			want:     "<p>One two.</p>\n<p>Three.</p>",
+		},
+		{
+			name:     "no summary without divider or word count",
+			markdown: "One two.\n",
+			want:     "",
+		},
+		{
+			name:     "footnotes and their references are not part of the summary",
+			markdown: "Text[^1] here.\n\n[^1]: A note.\n",
+			words:    70,
+			want:     "<p>Text here.</p>",
+		},
+		{
+			name:     "headings lose their ids",
+			markdown: "## Intro\n\nText.\n\n<!--more-->\n\nRest.\n",
+			want:     "<h2>Intro</h2>\n<p>Text.</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, content := renderSummary(t, tt.markdown, tt.words)
+			if tt.want == "" {
+				if summary != "" {
+					t.Errorf("summary = %q, want none", summary)
+				}
+			} else if !strings.Contains(summary, tt.want) {
+				t.Errorf("summary = %q, want %q", summary, tt.want)
+			}
+			if strings.Contains(summary, "footnotes") || strings.Contains(content, SummaryDivider) {
+				t.Errorf("summary = %q, content = %q", summary, content)
+			}
+			if strings.Count(summary, "<div") != strings.Count(summary, "</div>") {
+				t.Errorf("summary has unbalanced blocks: %q", summary)
+			}
+		})
+	}
+}
+
+func TestSplitSummary(t *testing.T) {
+	summary, content := SplitSummary("<p>a</p>\n" + SummaryDivider + "\n<p>b</p>\n")
+	if summary != "<p>a</p>" || content != "<p>a</p>\n<p>b</p>\n" {
+		t.Errorf("SplitSummary() = %q, %q", summary, content)
+	}
+	if summary, content := SplitSummary("<p>a</p>\n"); summary != "" || content != "<p>a</p>\n" {
+		t.Errorf("SplitSummary() without break = %q, %q", summary, content)
+	}
+
+	html := `<h2 class="x" id="intro">Intro</h2>` + "\n" +
+		`<p>a<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup></p>` + "\n" + SummaryDivider + "\n"
+	summary, content = SplitSummary(html)
+	if want := `<h2 class="x">Intro</h2>` + "\n<p>a</p>"; summary != want {
+		t.Errorf("SplitSummary() summary = %q, want %q", summary, want)
+	}
+	if !strings.Contains(content, `id="intro"`) || !strings.Contains(content, `href="#fn:1"`) {
+		t.Errorf("content should keep ids and footnote references: %q", content)
+	}
+}
@@ -0,0 +1,340 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Tabbed content:
+//
+//	:::tabs
+//	== Bash
+//	...
+//	== PowerShell
+//	...
+//	:::
+//
+// Consecutive fenced code blocks with the same {group="name"} become a code
+// group, one tab per block.
+
+var (
+	// KindTabs is the node kind of Tabs
+	KindTabs = ast.NewNodeKind("Tabs")
+	// KindTab is the node kind of Tab
+	KindTab = ast.NewNodeKind("Tab")
+)
+
+// Tabs is a set of tabs. Its children are Tab nodes.
+type Tabs struct {
+	ast.BaseBlock
+	Name  string // Optional ":::tabs name", or the group of a code group
+	ID    string // Stable ID, derived from Name or the tab labels
+	fence int    // Number of colons of the opening line
+}
+
+func (n *Tabs) Kind() ast.NodeKind { return KindTabs }
+
+func (n *Tabs) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.Name, "ID": n.ID}, nil)
+}
+
+// Tab is one labelled panel of Tabs
+type Tab struct {
+	ast.BaseBlock
+	Label string
+	ID    string
+}
+
+func (n *Tab) Kind() ast.NodeKind { return KindTab }
+
+func (n *Tab) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": n.Label, "ID": n.ID}, nil)
+}
+
+// tabsParser parses ":::tabs [name]" ... ":::" containers. A longer colon
+// fence (::::tabs) can hold nested tabs.
+type tabsParser struct{}
+
+func (b *tabsParser) Trigger() []byte { return []byte{':'} }
+
+func (b *tabsParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	fence := colonFence(line[pos:])
+	if fence < 3 {
+		return nil, parser.NoChildren
+	}
+	rest := bytes.TrimSpace(line[pos+fence:])
+	name, ok := bytes.CutPrefix(rest, []byte("tabs"))
+	if !ok || (len(name) > 0 && !util.IsSpace(name[0])) {
+		return nil, parser.NoChildren
+	}
+	// NoChildren keeps the rest of the line out of the tabs; Continue accepts
+	// children from the next line on
+	return &Tabs{Name: string(bytes.TrimSpace(name)), fence: fence}, parser.NoChildren
+}
+
+func (b *tabsParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, _ := reader.PeekLine()
+	if openInside(pc, node, ast.KindFencedCodeBlock) {
+		return parser.Continue | parser.HasChildren
+	}
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w < 4 {
+		rest := line[pos:]
+		if fence := colonFence(rest); fence >= node.(*Tabs).fence && util.IsBlank(rest[fence:]) {
+			advanceToEOL(reader)
+			return parser.Close
+		}
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (b *tabsParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *tabsParser) CanInterruptParagraph() bool { return true }
+
+func (b *tabsParser) CanAcceptIndentedLine() bool { return false }
+
+// tabParser parses the "== Label" lines that start each tab of a Tabs container
+type tabParser struct{}
+
+func (b *tabParser) Trigger() []byte { return []byte{'='} }
+
+func (b *tabParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	if parent.Kind() != KindTabs {
+		return nil, parser.NoChildren
+	}
+	line, _ := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	label, ok := tabLabel(line[pos:])
+	if !ok {
+		return nil, parser.NoChildren
+	}
+	return &Tab{Label: label}, parser.NoChildren
+}
+
+func (b *tabParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	// Labels inside a code block or nested tabs belong to them
+	if openInside(pc, node, ast.KindFencedCodeBlock) || openInside(pc, node, KindTabs) {
+		return parser.Continue | parser.HasChildren
+	}
+	line, _ := reader.PeekLine()
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if _, ok := tabLabel(line[pos:]); ok && w < 4 {
+		return parser.Close // The next tab opens from this line
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (b *tabParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *tabParser) CanInterruptParagraph() bool { return true }
+
+func (b *tabParser) CanAcceptIndentedLine() bool { return false }
+
+// colonFence returns the number of leading colons of line
+func colonFence(line []byte) int {
+	i := 0
+	for i < len(line) && line[i] == ':' {
+		i++
+	}
+	return i
+}
+
+// tabLabel returns the label of a "== Label" line
+func tabLabel(line []byte) (string, bool) {
+	rest, ok := bytes.CutPrefix(line, []byte("=="))
+	if !ok || len(rest) == 0 || !util.IsSpace(rest[0]) {
+		return "", false
+	}
+	label := strings.TrimSpace(string(rest))
+	return label, label != ""
+}
+
+// advanceToEOL consumes the rest of the current line, except its newline
+func advanceToEOL(reader text.Reader) {
+	line, _ := reader.PeekLine()
+	n := len(line)
+	for n > 0 && (line[n-1] == '\n' || line[n-1] == '\r') {
+		n--
+	}
+	reader.Advance(n)
+}
+
+// openInside reports whether a block of kind is open inside node
+func openInside(pc parser.Context, node ast.Node, kind ast.NodeKind) bool {
+	inside := false
+	for _, b := range pc.OpenedBlocks() {
+		if inside && b.Node.Kind() == kind {
+			return true
+		}
+		inside = inside || b.Node == node
+	}
+	return false
+}
+
+// tabsTransformer builds code groups from fenced code blocks with a group
+// attribute, and gives every set of tabs stable IDs
+type tabsTransformer struct{}
+
+func (t *tabsTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	// Code groups: runs of sibling fences with the same group
+	var fences []*ast.FencedCodeBlock
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fcb, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			fences = append(fences, fcb)
+		}
+		return ast.WalkContinue, nil
+	})
+	var group *Tabs
+	for _, fcb := range fences {
+		name, label := codeGroupAttributes(fcb, source)
+		if name == "" {
+			group = nil
+			continue
+		}
+		if group == nil || group.Name != name || fcb.PreviousSibling() != group {
+			group = &Tabs{Name: name}
+			fcb.Parent().InsertBefore(fcb.Parent(), fcb, group)
+		}
+		tab := &Tab{Label: label}
+		fcb.Parent().RemoveChild(fcb.Parent(), fcb)
+		tab.AppendChild(tab, fcb)
+		group.AppendChild(group, tab)
+	}
+
+	var sets []*Tabs
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if tabs, ok := n.(*Tabs); ok && entering {
+			sets = append(sets, tabs)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, tabs := range sets {
+		// Content before the first tab is rendered above the tabs
+		for c := tabs.FirstChild(); c != nil && c.Kind() != KindTab; c = tabs.FirstChild() {
+			tabs.RemoveChild(tabs, c)
+			tabs.Parent().InsertBefore(tabs.Parent(), tabs, c)
+		}
+		if !tabs.HasChildren() {
+			tabs.Parent().RemoveChild(tabs.Parent(), tabs)
+			continue
+		}
+
+		name := tabs.Name
+		if name == "" {
+			var labels []string
+			for c := tabs.FirstChild(); c != nil; c = c.NextSibling() {
+				labels = append(labels, c.(*Tab).Label)
+			}
+			name = strings.Join(labels, " ")
+		}
+		tabs.ID = string(pc.IDs().Generate([]byte("tabs "+name), KindTabs))
+		for c := tabs.FirstChild(); c != nil; c = c.NextSibling() {
+			tab := c.(*Tab)
+			tab.ID = string(pc.IDs().Generate([]byte(tabs.ID+" "+tab.Label), KindTab))
+		}
+	}
+}
+
+// codeGroupAttributes returns the group of a fenced code block and its tab
+// label: the tab attribute, else its title, else its language
+func codeGroupAttributes(fcb *ast.FencedCodeBlock, source []byte) (string, string) {
+	var group, tab, title string
+	for _, attr := range fenceAttributes(fcb, source) {
+		v, ok := attr.Value.([]byte)
+		if !ok {
+			continue
+		}
+		switch string(attr.Name) {
+		case "group":
+			group = string(v)
+		case "tab":
+			tab = string(v)
+		case "title":
+			title = string(v)
+		}
+	}
+	if group == "" {
+		return "", ""
+	}
+	switch {
+	case tab != "":
+		return group, tab
+	case title != "":
+		return group, title
+	}
+	if lang := fcb.Language(source); len(lang) > 0 {
+		return group, string(lang)
+	}
+	return group, "text"
+}
+
+// tabsRenderer renders Tabs and Tab nodes as WAI-ARIA tabs. The first tab is
+// selected; the theme's script switches tabs.
+type tabsRenderer struct{}
+
+func newTabsRenderer() renderer.NodeRenderer {
+	return &tabsRenderer{}
+}
+
+func (r *tabsRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTabs, r.renderTabs)
+	reg.Register(KindTab, r.renderTab)
+}
+
+func (r *tabsRenderer) renderTabs(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Tabs)
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString(`<div class="tabs" id="` + n.ID + `">` + "\n")
+	_, _ = w.WriteString(`<div class="tab-list" role="tablist">`)
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		tab := c.(*Tab)
+		selected, tabindex := "false", "-1"
+		if c == n.FirstChild() {
+			selected, tabindex = "true", "0"
+		}
+		_, _ = w.WriteString(`<button type="button" class="tab" role="tab" id="` + tab.ID +
+			`" aria-controls="` + tab.ID + `-panel" aria-selected="` + selected +
+			`" tabindex="` + tabindex + `" data-tab="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(strings.ToLower(tab.Label))))
+		_, _ = w.WriteString(`">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(tab.Label)))
+		_, _ = w.WriteString(`</button>`)
+	}
+	_, _ = w.WriteString("</div>\n")
+	return ast.WalkContinue, nil
+}
+
+func (r *tabsRenderer) renderTab(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Tab)
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+	_, _ = w.WriteString(`<div class="tab-panel" role="tabpanel" id="` + n.ID + `-panel" aria-labelledby="` + n.ID + `" tabindex="0"`)
+	if node.PreviousSibling() != nil {
+		_, _ = w.WriteString(" hidden")
+	}
+	_, _ = w.WriteString(">\n")
+	return ast.WalkContinue, nil
+}
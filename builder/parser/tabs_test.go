@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func parseTabs(t *testing.T, markdown string) (ast.Node, []byte, string) {
+	t.Helper()
+	md := New("", nil, &sync.Map{})
+	source := []byte(markdown)
+	doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(parser.NewContext()))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	return doc, source, buf.String()
+}
+
+func TestTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "tabs",
+			markdown: ":::tabs\n== Bash\nRun `make`\n== PowerShell\nRun `.\\make.ps1`\n:::\n\nAfter\n",
+			want: []string{
+				`<div class="tabs" id="tabs-bash-powershell">`,
+				`<button type="button" class="tab" role="tab" id="tabs-bash-powershell-bash" aria-controls="tabs-bash-powershell-bash-panel" aria-selected="true" tabindex="0" data-tab="bash">Bash</button>`,
+				`aria-selected="false" tabindex="-1" data-tab="powershell">PowerShell</button>`,
+				`<div class="tab-panel" role="tabpanel" id="tabs-bash-powershell-powershell-panel" aria-labelledby="tabs-bash-powershell-powershell" tabindex="0" hidden>`,
+				"</div>\n<p>After</p>",
+			},
+		},
+		{
+			name:     "named tabs",
+			markdown: ":::tabs framework\n== PyTorch\nx\n== JAX\ny\n:::\n",
+			want:     []string{`id="tabs-framework"`, `id="tabs-framework-pytorch"`, `id="tabs-framework-jax"`},
+		},
+		{
+			name:     "labels and fences inside code are code",
+			markdown: ":::tabs\n== A\n```text\n== B\n:::\n```\n:::\n",
+			want:     []string{"== B\n", ":::\n"},
+			notWant:  []string{`data-tab="b"`},
+		},
+		{
+			name:     "nested tabs",
+			markdown: "::::tabs\n== A\n:::tabs\n== X\nx\n:::\n== B\nb\n::::\n",
+			want:     []string{`id="tabs-a-b"`, `id="tabs-x"`, `data-tab="b"`},
+		},
+		{
+			name:     "inside an admonition",
+			markdown: "!!!note Install\n:::tabs\n== Bash\nx\n:::\n!!!\n",
+			want:     []string{`<div class="adm-body">` + "\n" + `<div class="tabs" id="tabs-bash">`},
+		},
+		{
+			name:     "content before the first tab stays above",
+			markdown: ":::tabs\nIntro\n== A\nx\n:::\n",
+			want:     []string{"<p>Intro</p>\n<div class=\"tabs\""},
+		},
+		{
+			name:     "IDs do not collide with headings",
+			markdown: "# Tabs A\n\n:::tabs\n== A\nx\n:::\n",
+			want:     []string{`<h1 id="tabs-a">`, `<div class="tabs" id="tabs-a-1">`},
+		},
+		{
+			name:     "code group",
+			markdown: "```bash {group=\"install\"}\npip install kosh\n```\n```bash {group=\"install\" tab=\"uv\"}\nuv add kosh\n```\n\n```go\nx\n```\n",
+			want: []string{
+				`<div class="tabs" id="tabs-install">`,
+				`data-tab="bash">bash</button>`,
+				`data-tab="uv">uv</button>`,
+			},
+		},
+		{
+			name:     "code groups need adjacent fences",
+			markdown: "```bash {group=\"a\"}\nx\n```\n\ntext\n\n```bash {group=\"a\"}\ny\n```\n",
+			want:     []string{`id="tabs-a"`, `id="tabs-a-1"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, html := parseTabs(t, tt.markdown)
+			for _, s := range tt.want {
+				if !strings.Contains(html, s) {
+					t.Errorf("output missing %q:\n%s", s, html)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(html, s) {
+					t.Errorf("output contains %q:\n%s", s, html)
+				}
+			}
+		})
+	}
+}
+
+func TestTabsPlainText(t *testing.T) {
+	doc, source, _ := parseTabs(t, ":::tabs\n== Bash\nchmod\n== PowerShell\nUnblock-File\n:::\n")
+	text := ExtractPlainText(doc, source)
+	for _, s := range []string{"Bash", "chmod", "PowerShell", "Unblock-File"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("plain text missing %q: %q", s, text)
+		}
+	}
+}
@@ -67,9 +67,11 @@ func (t *tocTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 				if !entering {
 					return ast.WalkContinue, nil
 				}
-				if child.Kind() == ast.KindText {
-					textNode := child.(*ast.Text)
-					headerText.Write(textNode.Segment.Value(reader.Source()))
+				switch child := child.(type) {
+				case *ast.Text:
+					headerText.Write(child.Segment.Value(reader.Source()))
+				case *ast.String: // Headings added by transformers, like References
+					headerText.Write(child.Value)
 				}
 				return ast.WalkContinue, nil
 			}
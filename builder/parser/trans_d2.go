@@ -2,11 +2,13 @@ package parser
 
 import (
 	"fmt"
+	htmlLib "html"
 	"regexp"
 )
 
-// d2PreRegex matches d2 code blocks (matches the div wrapper)
-var d2PreRegex = regexp.MustCompile(`(?s)<div class="code-wrapper" data-lang="d2">.*?</div>`)
+// d2PreRegex matches d2, dot/graphviz and mermaid code blocks, including the
+// container and title header written by codeBlockWrapper
+var d2PreRegex = regexp.MustCompile(`(?s)<div class="code-block-container">(?:<div class="code-header">.*?</div>)?<div class="code-wrapper" data-lang="(?i:d2|dot|graphviz|mermaid)">.*?</div></div>`)
 
 // ReplaceD2BlocksWithThemeSupport replaces d2 blocks with both light and dark SVGs
 // The browser will show/hide based on the data-theme attribute
@@ -25,11 +27,18 @@ func ReplaceD2BlocksWithThemeSupport(html string, pairs []D2SVGPair) string {
 
 		pair := pairs[pairIndex]
 		pairIndex++
+		if pair.Light == "" || pair.Dark == "" {
+			return match // Render failed, keep the source visible
+		}
 
 		// Output container with both light and dark versions
 		// CSS will show/hide based on data-theme attribute
-		return fmt.Sprintf(`<div class="d2-container" data-diagram="true"><div class="d2-light">%s</div><div class="d2-dark">%s</div><span class="zoom-hint">🔍 Click to zoom</span></div>`,
+		container := fmt.Sprintf(`<div class="d2-container" data-diagram="true"><div class="d2-light">%s</div><div class="d2-dark">%s</div><span class="zoom-hint">🔍 Click to zoom</span></div>`,
 			pair.Light, pair.Dark)
+		if pair.Title == "" {
+			return container
+		}
+		return `<figure class="diagram">` + container + `<figcaption>` + htmlLib.EscapeString(pair.Title) + `</figcaption></figure>`
 	})
 }
 
@@ -37,4 +46,5 @@ func ReplaceD2BlocksWithThemeSupport(html string, pairs []D2SVGPair) string {
 type D2SVGPair struct {
 	Light string
 	Dark  string
+	Title string // Caption from the fence's title attribute
 }
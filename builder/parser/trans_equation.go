@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gohugoio/hugo-goldmark-extensions/passthrough"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const equationPrefix = "eq:"
+
+// Equation numbering modes for math.numbering
+const (
+	NumberLabelled = "labelled" // Only equations with a \label (default)
+	NumberAll      = "all"      // Every display equation without \notag or \nonumber
+	NumberNone     = "none"     // Only explicit \tag{}s
+)
+
+var (
+	eqLabelRe = regexp.MustCompile(`\\label\{([^}]*)\}`)
+	eqTagRe   = regexp.MustCompile(`\\tag\*?\{([^}]*)\}`)
+	eqNoTagRe = regexp.MustCompile(`\\(?:notag|nonumber)\b`)
+)
+
+// KindEquation is the node kind of Equation
+var KindEquation = ast.NewNodeKind("Equation")
+
+// Equation is a display math block with its number and anchor. TeX is the block
+// with \label and numbering commands removed, so the KaTeX input (and its SSR hash)
+// does not depend on where the equation sits in the post.
+type Equation struct {
+	ast.BaseBlock
+	Number string // "1", or the \tag text; empty for unnumbered equations
+	Label  string // e.g. "eq:energy"
+	Open   string
+	Close  string
+	TeX    string
+}
+
+func (n *Equation) Kind() ast.NodeKind { return KindEquation }
+
+func (n *Equation) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Number": n.Number, "Label": n.Label}, nil)
+}
+
+// equationLabel normalises a \label or \eqref argument to an "eq:" anchor
+func equationLabel(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, equationPrefix) {
+		return name
+	}
+	return equationPrefix + name
+}
+
+// equationTransformer numbers display math ($$...$$ and \[...\]) per post and
+// registers \label{eq:...} names for cross-references. \tag{} sets the number
+// explicitly; \notag and \nonumber opt an equation out.
+// It runs after the passthrough extension has split block math out of paragraphs.
+type equationTransformer struct {
+	Numbering string
+}
+
+func (t *equationTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	numbering := valueOr(t.Numbering, NumberLabelled)
+	source := reader.Source()
+
+	var blocks []*passthrough.PassthroughBlock
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == passthrough.KindPassthroughBlock {
+			blocks = append(blocks, n.(*passthrough.PassthroughBlock))
+		}
+		return ast.WalkContinue, nil
+	})
+
+	number := 0
+	for _, block := range blocks {
+		var raw strings.Builder
+		for i := 0; i < block.Lines().Len(); i++ {
+			line := block.Lines().At(i)
+			raw.Write(line.Value(source))
+		}
+		value := raw.String()
+		open, close := block.Delimiters.Open, block.Delimiters.Close
+		if !strings.HasPrefix(value, open) || !strings.HasSuffix(value, close) || len(value) < len(open)+len(close) {
+			continue
+		}
+		tex := value[len(open) : len(value)-len(close)]
+
+		eq := &Equation{Open: open, Close: close}
+		if m := eqLabelRe.FindStringSubmatch(tex); m != nil && strings.TrimSpace(m[1]) != "" {
+			eq.Label = equationLabel(m[1])
+		}
+		if m := eqTagRe.FindStringSubmatch(tex); m != nil {
+			eq.Number = strings.TrimSpace(m[1])
+		}
+		suppressed := eqNoTagRe.MatchString(tex)
+
+		if eq.Number == "" && !suppressed {
+			if numbering == NumberAll || (numbering == NumberLabelled && eq.Label != "") {
+				number++
+				eq.Number = strconv.Itoa(number)
+			}
+		}
+		if eq.Label == "" && eq.Number == "" && !suppressed {
+			// Nothing to number or anchor: keep the plain passthrough block
+			continue
+		}
+
+		// Numbers are rendered outside KaTeX, so drop everything that carries them
+		tex = eqLabelRe.ReplaceAllString(tex, "")
+		tex = eqTagRe.ReplaceAllString(tex, "")
+		eq.TeX = strings.TrimSpace(eqNoTagRe.ReplaceAllString(tex, ""))
+
+		block.Parent().ReplaceChild(block.Parent(), block, eq)
+		if eq.Label != "" && eq.Number != "" {
+			addRefTarget(pc, eq.Label, refTarget{Name: "Equation", Number: eq.Number})
+		}
+	}
+}
+
+// equationRenderer renders Equation nodes. The math itself is left delimited for
+// RenderMathForHTML; the number and anchor sit around it.
+type equationRenderer struct{}
+
+func newEquationRenderer() renderer.NodeRenderer {
+	return &equationRenderer{}
+}
+
+func (r *equationRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindEquation, r.renderEquation)
+}
+
+func (r *equationRenderer) renderEquation(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*Equation)
+	if n.Number == "" && n.Label == "" {
+		_, _ = w.WriteString(n.Open + n.TeX + n.Close + "\n")
+		return ast.WalkSkipChildren, nil
+	}
+
+	_, _ = w.WriteString(`<div class="equation"`)
+	if n.Label != "" {
+		_, _ = w.WriteString(` id="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.Label)))
+		_, _ = w.WriteString(`"`)
+	}
+	_, _ = w.WriteString(">\n" + n.Open + n.TeX + n.Close + "\n")
+	if n.Number != "" {
+		_, _ = w.WriteString(`<span class="eq-number">(`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.Number)))
+		_, _ = w.WriteString(")</span>\n")
+	}
+	_, _ = w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
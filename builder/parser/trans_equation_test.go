@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func renderEquations(t *testing.T, markdown, numbering string) (string, parser.Context) {
+	t.Helper()
+	md := New("", nil, &sync.Map{}, WithEquationNumbering(numbering))
+	ctx := parser.NewContext()
+	source := []byte(markdown)
+	doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	return buf.String(), ctx
+}
+
+func TestEquationNumbering(t *testing.T) {
+	markdown := `$$E = mc^2 \label{eq:energy}$$
+
+$$a^2 + b^2 = c^2$$
+
+$$F = ma \tag{N2} \label{newton}$$
+
+$$p = mv \label{eq:momentum}$$
+
+By @eq:energy and \eqref{eq:momentum}, with $\eqref{newton}$; not \eqref{eq:missing}.
+`
+	html, ctx := renderEquations(t, markdown, NumberLabelled)
+
+	for _, want := range []string{
+		"<div class=\"equation\" id=\"eq:energy\">\n$$E = mc^2$$\n<span class=\"eq-number\">(1)</span>\n</div>",
+		"<div class=\"equation\" id=\"eq:newton\">\n$$F = ma$$\n<span class=\"eq-number\">(N2)</span>\n</div>",
+		`id="eq:momentum">` + "\n$$p = mv$$\n" + `<span class="eq-number">(2)</span>`,
+		"$$a^2 + b^2 = c^2$$\n",
+		`By <a href="#eq:energy" class="xref">Equation 1</a>`,
+		`and <a href="#eq:momentum" class="xref">(2)</a>`,
+		`with <a href="#eq:newton" class="xref">(N2)</a>;`,
+		`not \eqref{eq:missing}.`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("output missing %q\ngot: %s", want, html)
+		}
+	}
+	if strings.Contains(html, `\label`) {
+		t.Errorf("\\label should not reach KaTeX\ngot: %s", html)
+	}
+
+	if got := GetBrokenRefs(ctx); !reflect.DeepEqual(got, []string{`\eqref{eq:missing}`}) {
+		t.Errorf("GetBrokenRefs() = %v, want [\\eqref{eq:missing}]", got)
+	}
+}
+
+func TestEquationNumberingModes(t *testing.T) {
+	markdown := "$$a = 1$$\n\n$$b = 2 \\notag$$\n\n$$c = 3 \\label{eq:c}$$\n\n$$d = 4 \\tag{*}$$\n"
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{NumberLabelled, []string{"(1)", "(*)"}},
+		{NumberAll, []string{"(1)", "(2)", "(*)"}},
+		{NumberNone, []string{"(*)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			html, _ := renderEquations(t, markdown, tt.mode)
+			var got []string
+			for _, part := range strings.Split(html, `<span class="eq-number">`)[1:] {
+				got = append(got, part[:strings.Index(part, "</span>")])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("numbers = %v, want %v\ngot: %s", got, tt.want, html)
+			}
+			if strings.Contains(html, `\notag`) || strings.Contains(html, `\tag`) {
+				t.Errorf("numbering commands should not reach KaTeX\ngot: %s", html)
+			}
+		})
+	}
+}
+
+// Moving or relabelling an equation must not change the math handed to KaTeX,
+// so cached renders stay valid
+func TestEquationMathHashStable(t *testing.T) {
+	plain, _ := renderEquations(t, "$$x^2 + y^2 = 1$$\n", NumberAll)
+	labelled, _ := renderEquations(t, "Text.\n\n$$x^2 + y^2 = 1 \\label{eq:circle}$$\n\n$$z = 0 \\label{eq:z}$$\n", NumberLabelled)
+
+	display := func(html string) []string {
+		var hashes []string
+		for _, expr := range ExtractMathExpressions(html, MathOptions{}) {
+			if expr.DisplayMode {
+				hashes = append(hashes, expr.Hash)
+			}
+		}
+		return hashes
+	}
+	want, got := display(plain), display(labelled)
+	if len(want) != 1 || len(got) != 2 || got[0] != want[0] {
+		t.Errorf("hashes differ: plain %v, labelled %v", want, got)
+	}
+}
@@ -0,0 +1,295 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+
+	"github.com/gohugoio/hugo-goldmark-extensions/passthrough"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const figurePrefix = "fig:"
+
+// figureLabelAttr carries a {#fig:label} from imageTransformer to figureTransformer
+var figureLabelAttr = []byte("kosh-figure-label")
+
+// KindFigure is the node kind of Figure
+var KindFigure = ast.NewNodeKind("Figure")
+
+// Figure is a numbered image with an optional caption
+type Figure struct {
+	ast.BaseBlock
+	Number  int
+	Label   string // e.g. "fig:pipeline"; empty for unlabelled figures
+	Caption []byte
+}
+
+func (n *Figure) Kind() ast.NodeKind { return KindFigure }
+
+func (n *Figure) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Number": strconv.Itoa(n.Number), "Label": n.Label}, nil)
+}
+
+// figureTransformer turns a paragraph holding only an image with a title or a
+// {#fig:label} into a numbered <figure>, and registers its label for cross-references.
+// It runs after imageTransformer, which consumes the brace block.
+type figureTransformer struct{}
+
+func (t *figureTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	var paragraphs []*ast.Paragraph
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if p, ok := n.(*ast.Paragraph); ok {
+			paragraphs = append(paragraphs, p)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	number := 0
+	for _, p := range paragraphs {
+		img, ok := p.FirstChild().(*ast.Image)
+		if !ok || p.ChildCount() != 1 {
+			continue
+		}
+		var label string
+		if v, ok := img.Attribute(figureLabelAttr); ok {
+			label, _ = v.(string)
+		}
+		if img.Title == nil && label == "" {
+			continue
+		}
+
+		number++
+		fig := &Figure{Number: number, Label: label, Caption: img.Title}
+		img.Title = nil // The caption replaces the tooltip
+		p.Parent().ReplaceChild(p.Parent(), p, fig)
+		fig.AppendChild(fig, img)
+
+		if label != "" {
+			addRefTarget(pc, label, refTarget{Name: "Figure", Number: strconv.Itoa(number)})
+		}
+	}
+}
+
+// Cross-references: "@fig:label" or "@eq:label" in text becomes a link to the
+// labelled element; "\eqref{eq:label}" becomes a parenthesised equation number
+
+var (
+	refTargetsKey = parser.NewContextKey()
+	brokenRefsKey = parser.NewContextKey()
+
+	atRefRe = regexp.MustCompile(`^@((?:fig|eq):[\w-]*\w)`)
+	eqrefRe = regexp.MustCompile(`^\\eqref\{([^}]+)\}`)
+)
+
+// refTarget is what a cross-reference label points at
+type refTarget struct {
+	Name   string // "Figure", "Equation"
+	Number string // Auto number, or the \tag of an equation
+}
+
+// addRefTarget registers a cross-reference label
+func addRefTarget(pc parser.Context, label string, target refTarget) {
+	targets, _ := pc.Get(refTargetsKey).(map[string]refTarget)
+	if targets == nil {
+		targets = make(map[string]refTarget)
+		pc.Set(refTargetsKey, targets)
+	}
+	targets[label] = target
+}
+
+// GetBrokenRefs returns the cross-references that did not match any label, e.g. "@fig:missing"
+func GetBrokenRefs(pc parser.Context) []string {
+	if v := pc.Get(brokenRefsKey); v != nil {
+		return v.([]string)
+	}
+	return nil
+}
+
+// KindCrossRef is the node kind of CrossRef
+var KindCrossRef = ast.NewNodeKind("CrossRef")
+
+// CrossRef is a cross-reference as written. crossRefTransformer replaces it
+// with a link, or with its text when the label is unknown.
+type CrossRef struct {
+	ast.BaseInline
+	Label   string       // e.g. "fig:pipeline", or the argument of \eqref
+	Eqref   bool         // Written as \eqref{...}
+	Segment text.Segment // The whole reference
+}
+
+func (n *CrossRef) Kind() ast.NodeKind { return KindCrossRef }
+
+func (n *CrossRef) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": n.Label}, nil)
+}
+
+// crossRefParser reads "@fig:label", "@eq:label" and "\eqref{label}" from the
+// raw line, so labels keep characters such as "_" that split text nodes
+type crossRefParser struct{}
+
+func (p *crossRefParser) Trigger() []byte { return []byte{'@', '\\'} }
+
+func (p *crossRefParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, seg := block.PeekLine()
+	ref := &CrossRef{}
+	var m [][]byte
+	if line[0] == '@' {
+		if isWordChar(block.PrecendingCharacter()) { // e.g. an email address
+			return nil
+		}
+		m = atRefRe.FindSubmatch(line)
+	} else {
+		m = eqrefRe.FindSubmatch(line)
+		ref.Eqref = true
+	}
+	if m == nil {
+		return nil
+	}
+	ref.Label = string(m[1])
+	ref.Segment = text.NewSegment(seg.Start, seg.Start+len(m[0]))
+	block.Advance(len(m[0]))
+	return ref
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// refLink builds the link a cross-reference resolves to
+func refLink(label, text string) *ast.Link {
+	link := ast.NewLink()
+	link.Destination = []byte("#" + label)
+	link.SetAttributeString("class", []byte("xref"))
+	link.AppendChild(link, ast.NewString([]byte(text)))
+	return link
+}
+
+// crossRefTransformer resolves cross-references once every label is registered
+type crossRefTransformer struct{}
+
+func (t *crossRefTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	targets, _ := pc.Get(refTargetsKey).(map[string]refTarget)
+
+	var refs []*CrossRef
+	var inlineMath []*passthrough.PassthroughInline
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *CrossRef:
+			refs = append(refs, n)
+		case *passthrough.PassthroughInline:
+			inlineMath = append(inlineMath, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var broken []string
+	resolve := func(rawLabel string, eqref bool) (string, string, bool) {
+		if !eqref {
+			target, ok := targets[rawLabel]
+			if !ok {
+				broken = append(broken, "@"+rawLabel)
+				return "", "", false
+			}
+			return rawLabel, target.Name + " " + target.Number, true
+		}
+		label := equationLabel(rawLabel)
+		target, ok := targets[label]
+		if !ok {
+			broken = append(broken, `\eqref{`+rawLabel+"}")
+			return "", "", false
+		}
+		return label, "(" + target.Number + ")", true
+	}
+
+	for _, ref := range refs {
+		parent := ref.Parent()
+		if insideLink(ref) { // Links cannot nest, and image alt text is plain
+			parent.ReplaceChild(parent, ref, ast.NewTextSegment(ref.Segment))
+			continue
+		}
+		if label, refText, ok := resolve(ref.Label, ref.Eqref); ok {
+			parent.ReplaceChild(parent, ref, refLink(label, refText))
+		} else {
+			parent.ReplaceChild(parent, ref, ast.NewTextSegment(ref.Segment))
+		}
+	}
+
+	// Inline math holding nothing but \eqref{...}, e.g. $\eqref{eq:energy}$
+	for _, math := range inlineMath {
+		value := math.Segment.Value(source)
+		inner := bytes.TrimSpace(value[len(math.Delimiters.Open) : len(value)-len(math.Delimiters.Close)])
+		m := eqrefRe.FindSubmatch(inner)
+		if m == nil || len(m[0]) != len(inner) {
+			continue
+		}
+		if label, refText, ok := resolve(string(m[1]), true); ok {
+			math.Parent().ReplaceChild(math.Parent(), math, refLink(label, refText))
+		}
+	}
+
+	if len(broken) > 0 {
+		pc.Set(brokenRefsKey, append(GetBrokenRefs(pc), broken...))
+	}
+}
+
+// insideLink reports whether n is part of the text of a link or an image
+func insideLink(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		switch p.Kind() {
+		case ast.KindLink, ast.KindAutoLink, ast.KindImage:
+			return true
+		}
+	}
+	return false
+}
+
+// figureRenderer renders Figure nodes
+type figureRenderer struct {
+	html.Config
+}
+
+func newFigureRenderer() renderer.NodeRenderer {
+	return &figureRenderer{Config: html.NewConfig()}
+}
+
+func (r *figureRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindFigure, r.renderFigure)
+}
+
+func (r *figureRenderer) renderFigure(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Figure)
+	if entering {
+		_, _ = w.WriteString(`<figure class="figure"`)
+		if n.Label != "" {
+			_, _ = w.WriteString(` id="`)
+			_, _ = w.Write(util.EscapeHTML([]byte(n.Label)))
+			_, _ = w.WriteString(`"`)
+		}
+		_, _ = w.WriteString(">\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString("\n<figcaption><span class=\"figure-number\">Figure " + strconv.Itoa(n.Number))
+	if len(n.Caption) > 0 {
+		_, _ = w.WriteString(":</span> ")
+		_, _ = w.Write(util.EscapeHTML(n.Caption))
+	} else {
+		_, _ = w.WriteString("</span>")
+	}
+	_, _ = w.WriteString("</figcaption>\n</figure>\n")
+	return ast.WalkContinue, nil
+}
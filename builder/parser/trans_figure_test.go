@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func renderWithContext(t *testing.T, markdown string) (string, parser.Context) {
+	t.Helper()
+	md := New("", nil, &sync.Map{})
+	ctx := parser.NewContext()
+	source := []byte(markdown)
+	doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	return buf.String(), ctx
+}
+
+func TestFigures(t *testing.T) {
+	markdown := `Intro text.
+
+![Encoder](/a.svg "The encoder stack"){#fig:encoder}
+
+![Plain](/b.svg)
+
+![Decoder](/c.svg "The decoder")
+
+As @fig:encoder shows, and unlike @fig:missing, see (@fig:encoder).
+Inline ![x](/d.svg "not a figure") stays inline. Mail me@fig:encoder and ` + "`@fig:encoder`" + `.
+`
+	html, ctx := renderWithContext(t, markdown)
+
+	for _, want := range []string{
+		"<figure class=\"figure\" id=\"fig:encoder\">\n<img src=\"/a.svg\" alt=\"Encoder\"",
+		`<figcaption><span class="figure-number">Figure 1:</span> The encoder stack</figcaption>`,
+		`<figcaption><span class="figure-number">Figure 2:</span> The decoder</figcaption>`,
+		`As <a href="#fig:encoder" class="xref">Figure 1</a> shows`,
+		`see (<a href="#fig:encoder" class="xref">Figure 1</a>).`,
+		`unlike @fig:missing`,
+		`title="not a figure"`,
+		`me@fig:encoder`,
+		`<code>@fig:encoder</code>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("output missing %q\ngot: %s", want, html)
+		}
+	}
+	if strings.Contains(html, `title="The encoder stack"`) {
+		t.Error("figure caption should replace the image title")
+	}
+	if strings.Count(html, "<figure") != 2 {
+		t.Errorf("expected 2 figures, got %d", strings.Count(html, "<figure"))
+	}
+
+	if got := GetBrokenRefs(ctx); !reflect.DeepEqual(got, []string{"@fig:missing"}) {
+		t.Errorf("GetBrokenRefs() = %v, want [@fig:missing]", got)
+	}
+}
+
+func TestLabelledFigureWithoutCaption(t *testing.T) {
+	html, _ := renderWithContext(t, "![Chart](/chart.svg){#fig:chart}\n\nSee @fig:chart.\n")
+
+	if !strings.Contains(html, `<figcaption><span class="figure-number">Figure 1</span></figcaption>`) {
+		t.Errorf("missing numbered caption\ngot: %s", html)
+	}
+	if !strings.Contains(html, `<a href="#fig:chart" class="xref">Figure 1</a>.`) {
+		t.Errorf("missing cross-reference\ngot: %s", html)
+	}
+}
+
+func TestCrossRefLabelsWithUnderscores(t *testing.T) {
+	markdown := `![Loss](/loss.svg "Training loss"){#fig:train_loss}
+
+$$L = -\sum y \log p \label{eq:cross_entropy}$$
+
+See @fig:train_loss and @eq:cross_entropy, \eqref{eq:cross_entropy} or @fig:val_loss_2.
+[Not @fig:train_loss](/x.html) and \@fig:train_loss stay text.
+`
+	md := New("", nil, &sync.Map{}, WithEquationNumbering(NumberLabelled))
+	ctx := parser.NewContext()
+	source := []byte(markdown)
+	doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		`id="fig:train_loss"`,
+		`See <a href="#fig:train_loss" class="xref">Figure 1</a>`,
+		`and <a href="#eq:cross_entropy" class="xref">Equation 1</a>,`,
+		`<a href="#eq:cross_entropy" class="xref">(1)</a> or @fig:val_loss_2.`,
+		`<a href="/x.html">Not @fig:train_loss</a>`,
+		`and @fig:train_loss stay text.`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("output missing %q\ngot: %s", want, html)
+		}
+	}
+	if got := GetBrokenRefs(ctx); !reflect.DeepEqual(got, []string{"@fig:val_loss_2"}) {
+		t.Errorf("GetBrokenRefs() = %v, want [@fig:val_loss_2]", got)
+	}
+}
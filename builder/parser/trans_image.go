@@ -0,0 +1,376 @@
+package parser
+
+import (
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gohugoio/hugo-goldmark-extensions/passthrough"
+	"github.com/yuin/goldmark"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/utils"
+)
+
+// pictureAttr carries the responsive sources of an image from the transformer to
+// the renderer. It is not in html.ImageAttributeFilter, so it is never written out.
+var pictureAttr = []byte("kosh-picture")
+
+// pictureSources are the <source> renditions of one image
+type pictureSources struct {
+	Widths  []int
+	Formats []string
+	Ext     string
+	Sizes   string
+}
+
+// imageTransformer adds intrinsic dimensions, lazy/async defaults and responsive
+// sources to images. Per-image overrides follow the image in braces:
+//
+//	![Chart](/static/images/chart.png){width=600 sizes="50vw" loading=eager .wide}
+//
+// responsive=false opts a single image out of <picture> output and
+// placeholder=false drops its blur-up placeholder.
+// It chooses every URL an image is rendered with: the sources of its
+// <picture>, or its .webp rendition, or the original when there is neither.
+// It runs before urlTransformer, which only adds the base URL.
+type imageTransformer struct {
+	Resolver ImageResolver
+	Sizes    string
+}
+
+func (t *imageTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	walkImages(node, func(img *ast.Image) {
+		t.processImage(img, imageOverrides(img, source))
+	})
+}
+
+// walkImages calls fn for every image in the document
+func walkImages(node ast.Node, fn func(*ast.Image)) {
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			fn(img)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// scanParser parses Markdown like New but runs no transformers, so scanning
+// resolves and renders nothing
+var scanParser = goldmark.New(goldmark.WithExtensions(
+	extension.GFM,
+	extension.Footnote,
+	meta.Meta,
+	passthrough.New(mathDelimiters),
+)).Parser()
+
+// ImageRefs parses a post and returns its front matter and the destinations
+// of the images in its body, before any transformer rewrites them
+func ImageRefs(source []byte) (map[string]interface{}, []string, error) {
+	pc := parser.NewContext()
+	doc := scanParser.Parse(text.NewReader(source), parser.WithContext(pc))
+	front, err := meta.TryGet(pc)
+	if err != nil {
+		return nil, nil, err
+	}
+	var refs []string
+	walkImages(doc, func(img *ast.Image) {
+		refs = append(refs, string(img.Destination))
+	})
+	return front, refs, nil
+}
+
+func (t *imageTransformer) processImage(img *ast.Image, overrides map[string]string) {
+	img.SetAttributeString("loading", []byte(valueOr(overrides["loading"], "lazy")))
+	img.SetAttributeString("decoding", []byte(valueOr(overrides["decoding"], "async")))
+	// {#fig:label} names the figure rather than the image
+	if label := overrides["id"]; strings.HasPrefix(label, figurePrefix) {
+		img.SetAttribute(figureLabelAttr, label)
+		delete(overrides, "id")
+	}
+	for _, key := range []string{"class", "id", "style"} {
+		if v, ok := overrides[key]; ok {
+			img.SetAttributeString(key, []byte(v))
+		}
+	}
+
+	var info utils.ImageInfo
+	var resolved bool
+	if t.Resolver != nil {
+		info, resolved = t.Resolver.ResolveImage(string(img.Destination))
+	}
+
+	// Explicit dimensions win; a lone width or height keeps the intrinsic aspect ratio
+	width, height := info.Width, info.Height
+	w, hasW := positiveInt(overrides["width"])
+	h, hasH := positiveInt(overrides["height"])
+	switch {
+	case hasW && hasH:
+		width, height = w, h
+	case hasW:
+		width, height = w, scaleDimension(info.Height, w, info.Width)
+	case hasH:
+		width, height = scaleDimension(info.Width, h, info.Height), h
+	}
+	if width > 0 {
+		img.SetAttributeString("width", []byte(strconv.Itoa(width)))
+	}
+	if height > 0 {
+		img.SetAttributeString("height", []byte(strconv.Itoa(height)))
+	}
+
+	if ph := info.Placeholder; ph.Color != "" && overrides["placeholder"] != "false" {
+		setPlaceholder(img, ph)
+	}
+
+	if !resolved || len(info.Widths) == 0 || len(info.Formats) == 0 || overrides["responsive"] == "false" {
+		if sizes, ok := overrides["sizes"]; ok {
+			img.SetAttributeString("sizes", []byte(sizes))
+		}
+		if info.WebP {
+			dest := string(img.Destination)
+			img.Destination = []byte(strings.TrimSuffix(dest, path.Ext(dest)) + ".webp")
+		}
+		return
+	}
+	img.SetAttribute(pictureAttr, &pictureSources{
+		Widths:  info.Widths,
+		Formats: info.Formats,
+		Ext:     info.Ext,
+		Sizes:   valueOr(overrides["sizes"], t.Sizes),
+	})
+}
+
+// setPlaceholder exposes the blur-up preview as data attributes and, for opaque
+// images, as an inline background that the loaded image then covers
+func setPlaceholder(img *ast.Image, ph models.ImagePlaceholder) {
+	img.SetAttributeString("data-dominant-color", []byte(ph.Color))
+	style := "background-color:" + ph.Color
+	if ph.LQIP != "" {
+		img.SetAttributeString("data-lqip", []byte(ph.LQIP))
+		style += ";background-image:url(" + ph.LQIP + ");background-size:cover"
+	}
+	if ph.Opaque {
+		if existing, ok := img.AttributeString("style"); ok {
+			if b, ok := existing.([]byte); ok {
+				style = string(b) + ";" + style
+			}
+		}
+		img.SetAttributeString("style", []byte(style))
+	}
+}
+
+// imageOverrides consumes a "{key=value ...}" block directly after an image.
+// Inline parsing may split the block over several adjacent text nodes.
+func imageOverrides(img *ast.Image, source []byte) map[string]string {
+	first, ok := img.NextSibling().(*ast.Text)
+	if !ok || !bytes.HasPrefix(first.Segment.Value(source), []byte("{")) {
+		return nil
+	}
+
+	var block []byte
+	var nodes []*ast.Text
+	for n := ast.Node(first); n != nil; n = n.NextSibling() {
+		t, ok := n.(*ast.Text)
+		if !ok {
+			return nil
+		}
+		value := t.Segment.Value(source)
+		nodes = append(nodes, t)
+		end := bytes.IndexByte(value, '}')
+		if end < 0 {
+			block = append(block, value...)
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				return nil
+			}
+			continue
+		}
+		block = append(block, value[:end]...)
+
+		// Drop the consumed nodes, keeping any text after the closing brace
+		parent := img.Parent()
+		for _, consumed := range nodes[:len(nodes)-1] {
+			parent.RemoveChild(parent, consumed)
+		}
+		if end+1 == len(value) && !t.SoftLineBreak() && !t.HardLineBreak() {
+			parent.RemoveChild(parent, t)
+		} else {
+			t.Segment = t.Segment.WithStart(t.Segment.Start + end + 1)
+		}
+		return parseOverrides(string(block[1:]))
+	}
+	return nil
+}
+
+// parseOverrides parses space separated key=value pairs, with optional quoted
+// values, plus ".class" and "#id" shorthands
+func parseOverrides(s string) map[string]string {
+	overrides := make(map[string]string)
+	var classes []string
+	for _, field := range splitFields(s) {
+		switch {
+		case strings.HasPrefix(field, "."):
+			classes = append(classes, field[1:])
+		case strings.HasPrefix(field, "#"):
+			overrides["id"] = field[1:]
+		default:
+			key, value, _ := strings.Cut(field, "=")
+			overrides[strings.ToLower(key)] = strings.Trim(value, `"'`)
+		}
+	}
+	if len(classes) > 0 {
+		overrides["class"] = strings.TrimSpace(overrides["class"] + " " + strings.Join(classes, " "))
+	}
+	return overrides
+}
+
+// splitFields splits on spaces outside of quotes
+func splitFields(s string) []string {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			current.WriteRune(r)
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+func positiveInt(s string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "px"))
+	return n, err == nil && n > 0
+}
+
+// scaleDimension returns other scaled by target/base, or 0 if unknown
+func scaleDimension(other, target, base int) int {
+	if base == 0 {
+		return 0
+	}
+	return (other*target + base/2) / base
+}
+
+func valueOr(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
+
+// imageRenderer renders images, wrapping those with responsive sources in <picture>
+type imageRenderer struct {
+	html.Config
+}
+
+func newImageRenderer() renderer.NodeRenderer {
+	return &imageRenderer{Config: html.NewConfig()}
+}
+
+func (r *imageRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindImage, r.renderImage)
+}
+
+func (r *imageRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	dest := n.Destination
+
+	var pic *pictureSources
+	if v, ok := n.Attribute(pictureAttr); ok {
+		pic, _ = v.(*pictureSources)
+	}
+
+	if pic != nil {
+		// Destination is the original image; variants sit beside it
+		base := strings.TrimSuffix(string(dest), path.Ext(string(dest)))
+		_, _ = w.WriteString("<picture>")
+		for _, format := range pic.Formats {
+			srcset := make([]string, len(pic.Widths))
+			for i, width := range pic.Widths {
+				url := util.URLEscape([]byte(base+"-"+strconv.Itoa(width)+"w."+format), true)
+				srcset[i] = string(url) + " " + strconv.Itoa(width) + "w"
+			}
+			_, _ = w.WriteString(`<source type="image/` + format + `" srcset="`)
+			_, _ = w.Write(util.EscapeHTML([]byte(strings.Join(srcset, ", "))))
+			_, _ = w.WriteString(`"`)
+			if pic.Sizes != "" {
+				_, _ = w.WriteString(` sizes="`)
+				_, _ = w.Write(util.EscapeHTML([]byte(pic.Sizes)))
+				_, _ = w.WriteString(`"`)
+			}
+			_, _ = w.WriteString(">")
+		}
+		dest = []byte(base + pic.Ext)
+	}
+
+	_, _ = w.WriteString(`<img src="`)
+	if r.Unsafe || !html.IsDangerousURL(dest) {
+		_, _ = w.Write(util.EscapeHTML(util.URLEscape(dest, true)))
+	}
+	_, _ = w.WriteString(`" alt="`)
+	_, _ = w.Write(util.EscapeHTML(imageAlt(n, source)))
+	_ = w.WriteByte('"')
+	if n.Title != nil {
+		_, _ = w.WriteString(` title="`)
+		r.Writer.Write(w, n.Title)
+		_ = w.WriteByte('"')
+	}
+	if n.Attributes() != nil {
+		html.RenderAttributes(w, n, html.ImageAttributeFilter)
+	}
+	if r.XHTML {
+		_, _ = w.WriteString(" />")
+	} else {
+		_, _ = w.WriteString(">")
+	}
+
+	if pic != nil {
+		_, _ = w.WriteString("</picture>")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// imageAlt concatenates the text of an image's children
+func imageAlt(n ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(source))
+		case *ast.String:
+			buf.Write(t.Value)
+		default:
+			buf.Write(imageAlt(c, source))
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/utils"
+)
+
+type stubResolver map[string]utils.ImageInfo
+
+func (r stubResolver) ResolveImage(src string) (utils.ImageInfo, bool) {
+	info, ok := r[src]
+	return info, ok
+}
+
+func TestImageRendering(t *testing.T) {
+	resolver := stubResolver{
+		"/static/images/chart.png": {Width: 1200, Height: 600, Widths: []int{480, 800}, Formats: []string{"avif", "webp"}, Ext: ".png", WebP: true},
+		"/static/images/icon.png":  {Width: 64, Height: 32, Ext: ".png"},
+		"/static/images/photo.jpg": {Width: 800, Height: 600, Ext: ".jpg", Placeholder: models.ImagePlaceholder{Color: "#336699", LQIP: "data:image/webp;base64,AAAA", Opaque: true}},
+		"/static/images/logo.png":  {Width: 100, Height: 100, Ext: ".png", Placeholder: models.ImagePlaceholder{Color: "#112233"}},
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "responsive picture",
+			markdown: "![A chart](/static/images/chart.png)",
+			want: []string{
+				`<picture><source type="image/avif" srcset="https://example.com/static/images/chart-480w.avif 480w, https://example.com/static/images/chart-800w.avif 800w" sizes="100vw">`,
+				`<source type="image/webp" srcset="https://example.com/static/images/chart-480w.webp 480w, https://example.com/static/images/chart-800w.webp 800w" sizes="100vw">`,
+				`<img src="https://example.com/static/images/chart.png" alt="A chart" loading="lazy" decoding="async" width="1200" height="600"></picture>`,
+			},
+		},
+		{
+			name:     "overrides",
+			markdown: "![A chart](/static/images/chart.png){width=600 sizes=\"50vw\" loading=eager .wide}",
+			want:     []string{`sizes="50vw"`, `loading="eager"`, `width="600" height="300"`, `class="wide"`},
+			notWant:  []string{"{", "100vw"},
+		},
+		{
+			name:     "opt out of picture",
+			markdown: "![A chart](/static/images/chart.png){responsive=false}",
+			want:     []string{`<img src="https://example.com/static/images/chart.webp"`},
+			notWant:  []string{"<picture>"},
+		},
+		{
+			name:     "intrinsic dimensions without variants",
+			markdown: "![Icon](/static/images/icon.png) text",
+			want:     []string{`width="64" height="32"`, `</p>`, ` text`},
+			notWant:  []string{"<picture>"},
+		},
+		{
+			name:     "uncompressed image keeps its original",
+			markdown: "![Icon](/static/images/icon.png)",
+			want:     []string{`<img src="https://example.com/static/images/icon.png"`},
+			notWant:  []string{".webp"},
+		},
+		{
+			name:     "raw html image is left alone",
+			markdown: "<img src=\"/static/images/chart.png\" alt=\"Raw\">",
+			want:     []string{`<img src="/static/images/chart.png" alt="Raw">`},
+			notWant:  []string{".webp"},
+		},
+		{
+			name:     "lqip placeholder",
+			markdown: "![Photo](/static/images/photo.jpg)",
+			want: []string{
+				`data-dominant-color="#336699"`,
+				`data-lqip="data:image/webp;base64,AAAA"`,
+				`style="background-color:#336699;background-image:url(data:image/webp;base64,AAAA);background-size:cover"`,
+			},
+		},
+		{
+			name:     "transparent image keeps only data attributes",
+			markdown: "![Logo](/static/images/logo.png)",
+			want:     []string{`data-dominant-color="#112233"`},
+			notWant:  []string{"style=", "data-lqip"},
+		},
+		{
+			name:     "placeholder opt out",
+			markdown: "![Photo](/static/images/photo.jpg){placeholder=false}",
+			notWant:  []string{"data-dominant-color", "style="},
+		},
+		{
+			name:     "external image",
+			markdown: "![Remote](https://cdn.example.com/a.png)",
+			want:     []string{`<img src="https://cdn.example.com/a.png" alt="Remote" loading="lazy" decoding="async">`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := New("https://example.com", nil, &sync.Map{}, WithImages(resolver, "100vw"))
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.markdown), &buf); err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output missing %s\ngot: %s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("output contains %s\ngot: %s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestImageRefs(t *testing.T) {
+	source := "---\ntitle: Launch\nimageProfile: hero\n---\n\n" +
+		"![UI](</static/images/ui shot.png>)\n\n" +
+		"> ![Quoted](/static/images/quoted.png){width=300}\n\n" +
+		"- ![Listed][ref]\n\n" +
+		"```markdown\n![Code](/static/images/code.png)\n```\n\n" +
+		"$![Math](/static/images/math.png)$\n\n" +
+		"[ref]: /static/images/listed.png\n"
+	front, refs, err := ImageRefs([]byte(source))
+	if err != nil {
+		t.Fatalf("ImageRefs() error = %v", err)
+	}
+	if front["imageProfile"] != "hero" {
+		t.Errorf("front matter = %v, want imageProfile hero", front)
+	}
+	want := []string{"/static/images/ui shot.png", "/static/images/quoted.png", "/static/images/listed.png"}
+	if strings.Join(refs, "|") != strings.Join(want, "|") {
+		t.Errorf("refs = %q, want %q", refs, want)
+	}
+
+	if _, _, err := ImageRefs([]byte("---\ntitle: [\n---\n")); err == nil {
+		t.Error("ImageRefs() with broken front matter: want an error")
+	}
+}
@@ -2,7 +2,7 @@ package parser
 
 import (
 	"bytes"
-	"log"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -13,17 +13,126 @@ import (
 	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
 )
 
-// ssrTransformer handles server-side rendering of D2 diagrams and LaTeX math
+// ssrTransformer handles server-side rendering of D2, Graphviz and Mermaid diagrams
 type ssrTransformer struct {
 	Renderer *native.Renderer
-	Cache    *sync.Map // Thread-safe cache for D2 diagrams
+	Cache    *sync.Map      // Thread-safe cache for rendered diagrams
+	Defaults DiagramOptions // Site defaults, overridden by fence attributes
+}
+
+// DiagramOptions are the rendering options of a diagram, set site-wide and per
+// fence, e.g. ```d2 {layout=elk theme=3 sketch=true pad=20 scale=0.8 title="..."}
+type DiagramOptions struct {
+	Layout    string  // native.LayoutDagre or native.LayoutELK
+	Theme     int64   // D2 theme ID of the light variant
+	DarkTheme int64   // D2 theme ID of the dark variant
+	Sketch    bool    // Hand-drawn look
+	Pad       int64   // Padding in pixels
+	Scale     float64 // 0 fits the SVG to its container
+	Title     string  // Figure caption and accessible SVG title
+}
+
+// DefaultDiagramOptions match the original fixed rendering settings
+var DefaultDiagramOptions = DiagramOptions{Layout: native.LayoutDagre, DarkTheme: 200}
+
+// withAttributes overrides the options with fence attributes
+func (o DiagramOptions) withAttributes(attrs parser.Attributes) DiagramOptions {
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case []byte:
+			switch string(attr.Name) {
+			case "layout":
+				o.Layout = strings.ToLower(string(v))
+			case "title":
+				o.Title = string(v)
+			case "sketch":
+				o.Sketch = string(v) == "true"
+			}
+		case float64:
+			switch string(attr.Name) {
+			case "theme":
+				o.Theme = int64(v)
+			case "dark-theme":
+				o.DarkTheme = int64(v)
+			case "pad":
+				o.Pad = int64(v)
+			case "scale":
+				o.Scale = v
+			}
+		case bool:
+			if string(attr.Name) == "sketch" {
+				o.Sketch = v
+			}
+		}
+	}
+	return o
+}
+
+// hash identifies a diagram render; default options keep the plain content hash
+func (o DiagramOptions) hash(kind, code string) string {
+	if o == DefaultDiagramOptions {
+		return native.HashContent(kind, code)
+	}
+	return native.HashContent(kind, fmt.Sprintf("%s\x00%+v", code, o))
+}
+
+// variant returns the native options of the light or dark render
+func (o DiagramOptions) variant(dark bool) native.D2Options {
+	opts := native.D2Options{
+		ThemeID: o.Theme,
+		Layout:  o.Layout,
+		Sketch:  o.Sketch,
+		Pad:     o.Pad,
+		Scale:   o.Scale,
+		Title:   o.Title,
+	}
+	if dark {
+		opts.ThemeID = o.DarkTheme
+	}
+	return opts
+}
+
+// fenceAttributes parses the {...} attributes of a fenced code block's info string
+func fenceAttributes(fcb *ast.FencedCodeBlock, source []byte) parser.Attributes {
+	if fcb.Info == nil {
+		return nil
+	}
+	info := fcb.Info.Segment.Value(source)
+	i := bytes.IndexByte(info, '{')
+	if i < 0 {
+		return nil
+	}
+	attrs, _ := parser.ParseAttributes(text.NewReader(info[i:]))
+	return attrs
+}
+
+// diagramKinds maps fence languages to the hash kind of the diagram they hold
+var diagramKinds = map[string]string{
+	"d2":       "d2",
+	"dot":      "dot",
+	"graphviz": "dot",
+	"mermaid":  "mermaid",
+}
+
+// render renders a diagram of the given kind
+func (t *ssrTransformer) render(kind, code string, opts native.D2Options) (string, error) {
+	switch kind {
+	case "dot":
+		return t.Renderer.RenderDOT(code, opts)
+	case "mermaid":
+		return t.Renderer.RenderMermaid(code, opts)
+	default:
+		return t.Renderer.RenderD2(code, opts)
+	}
 }
 
 func (t *ssrTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
 	source := reader.Source()
 	var d2Blocks []struct {
+		kind string
 		code string
 		hash string
+		opts DiagramOptions
 	}
 
 	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
@@ -35,7 +144,7 @@ func (t *ssrTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 			fcb := n.(*ast.FencedCodeBlock)
 			lang := strings.ToLower(strings.TrimSpace(string(fcb.Language(source))))
 
-			if lang == "d2" {
+			if kind, ok := diagramKinds[lang]; ok {
 				var codeBuilder bytes.Buffer
 				lines := fcb.Lines()
 				for i := 0; i < lines.Len(); i++ {
@@ -44,11 +153,14 @@ func (t *ssrTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 				}
 				code := strings.TrimSpace(codeBuilder.String())
 				if code != "" {
-					hash := native.HashContent("d2", code)
+					opts := t.Defaults.withAttributes(fenceAttributes(fcb, source))
+					hash := opts.hash(kind, code)
 					d2Blocks = append(d2Blocks, struct {
+						kind string
 						code string
 						hash string
-					}{code: code, hash: hash})
+						opts DiagramOptions
+					}{kind: kind, code: code, hash: hash, opts: opts})
 					AddSSRHash(pc, hash)
 				}
 			}
@@ -62,13 +174,16 @@ func (t *ssrTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 
 	// 2. Render all blocks in parallel (or use cache)
 	results := make([]D2SVGPair, len(d2Blocks))
+	failures := make([]error, len(d2Blocks))
 	var wg sync.WaitGroup
 
 	for i, block := range d2Blocks {
 		wg.Add(1)
 		go func(idx int, b struct {
+			kind string
 			code string
 			hash string
+			opts DiagramOptions
 		}) {
 			defer wg.Done()
 
@@ -80,23 +195,25 @@ func (t *ssrTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 			darkCached, darkExists := t.Cache.Load(darkHash)
 
 			if lightExists && darkExists {
-				results[idx] = D2SVGPair{Light: lightCached.(string), Dark: darkCached.(string)}
+				results[idx] = D2SVGPair{Light: lightCached.(string), Dark: darkCached.(string), Title: b.opts.Title}
 				return
 			}
 
 			// Render
-			lightSVG, err := t.Renderer.RenderD2(b.code, 0)
+			// Failed renders leave an empty pair, which keeps the code block,
+			// and are reported through GetDiagramErrors
+			lightSVG, err := t.render(b.kind, b.code, b.opts.variant(false))
 			if err != nil {
-				log.Printf("   ⚠️  D2 light theme render failed: %v", err)
+				failures[idx] = err
 				return
 			}
-			darkSVG, err := t.Renderer.RenderD2(b.code, 200)
+			darkSVG, err := t.render(b.kind, b.code, b.opts.variant(true))
 			if err != nil {
-				log.Printf("   ⚠️  D2 dark theme render failed: %v", err)
+				failures[idx] = err
 				return
 			}
 
-			pair := D2SVGPair{Light: lightSVG, Dark: darkSVG}
+			pair := D2SVGPair{Light: lightSVG, Dark: darkSVG, Title: b.opts.Title}
 			results[idx] = pair
 
 			// Store in cache (sync.Map is thread-safe)
@@ -108,4 +225,27 @@ func (t *ssrTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 
 	// 3. Store in context
 	pc.Set(d2OrderedKey, results)
+	var errs []DiagramError
+	for i, err := range failures {
+		if err != nil {
+			errs = append(errs, DiagramError{Kind: d2Blocks[i].kind, Err: err})
+		}
+	}
+	pc.Set(diagramErrorsKey, errs)
+}
+
+// DiagramError is a diagram that failed to render and was left as a code block
+type DiagramError struct {
+	Kind string // "d2", "dot" or "mermaid"
+	Err  error
+}
+
+var diagramErrorsKey = parser.NewContextKey()
+
+// GetDiagramErrors returns the diagrams of the document that failed to render
+func GetDiagramErrors(pc parser.Context) []DiagramError {
+	if v := pc.Get(diagramErrorsKey); v != nil {
+		return v.([]DiagramError)
+	}
+	return nil
 }
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
+)
+
+func TestDiagramSSR(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts D2 workers")
+	}
+	markdown := "```dot\ndigraph { a -> b }\n```\n\n" +
+		"```mermaid\nsequenceDiagram\n  A->>B: hi\n```\n\n" +
+		"```mermaid\npie\n  \"a\": 1\n```\n"
+
+	cache := &sync.Map{}
+	md := New("", native.New(), cache)
+	ctx := parser.NewContext()
+	source := []byte(markdown)
+	doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	pairs := GetD2SVGPairSlice(ctx)
+	if len(pairs) != 3 {
+		t.Fatalf("got %d diagram pairs, want 3", len(pairs))
+	}
+	for i, pair := range pairs[:2] {
+		if !strings.Contains(pair.Light, "<svg") || !strings.Contains(pair.Dark, "<svg") {
+			t.Errorf("diagram %d was not rendered", i)
+		}
+	}
+	if pairs[2].Light != "" {
+		t.Error("unsupported mermaid diagram should not render")
+	}
+	errs := GetDiagramErrors(ctx)
+	if len(errs) != 1 || errs[0].Kind != "mermaid" || !strings.Contains(errs[0].Err.Error(), `unsupported diagram type "pie"`) {
+		t.Errorf("GetDiagramErrors() = %v, want the pie chart", errs)
+	}
+	if len(GetSSRHashes(ctx)) != 3 {
+		t.Errorf("got %d SSR hashes, want 3", len(GetSSRHashes(ctx)))
+	}
+	if _, ok := cache.Load(native.HashContent("dot", "digraph { a -> b }") + "_light"); !ok {
+		t.Error("dot render should be cached under its content hash")
+	}
+
+	html := ReplaceD2BlocksWithThemeSupport(buf.String(), pairs)
+	if n := strings.Count(html, `class="d2-container"`); n != 2 {
+		t.Errorf("got %d diagram containers, want 2", n)
+	}
+	if !strings.Contains(html, `data-lang="mermaid">`) {
+		t.Error("failed diagram should keep its code block")
+	}
+}
+
+func TestDiagramOptionsWithAttributes(t *testing.T) {
+	markdown := "```d2 {layout=elk theme=3 dark-theme=201 sketch=true pad=20 scale=0.8 title=\"Request flow\"}\na -> b\n```\n"
+	source := []byte(markdown)
+	doc := goldmark.New().Parser().Parse(text.NewReader(source))
+	fcb, ok := doc.FirstChild().(*ast.FencedCodeBlock)
+	if !ok {
+		t.Fatalf("first node is %T, want fenced code block", doc.FirstChild())
+	}
+
+	got := DefaultDiagramOptions.withAttributes(fenceAttributes(fcb, source))
+	want := DiagramOptions{Layout: "elk", Theme: 3, DarkTheme: 201, Sketch: true, Pad: 20, Scale: 0.8, Title: "Request flow"}
+	if got != want {
+		t.Errorf("withAttributes() = %+v, want %+v", got, want)
+	}
+	if v := got.variant(true); v.ThemeID != 201 || v.Layout != "elk" {
+		t.Errorf("dark variant = %+v", v)
+	}
+
+	plain := native.HashContent("d2", "a -> b")
+	if h := DefaultDiagramOptions.hash("d2", "a -> b"); h != plain {
+		t.Error("default options should keep the plain content hash")
+	}
+	if h := got.hash("d2", "a -> b"); h == plain {
+		t.Error("fence options should be part of the hash")
+	}
+}
+
+func TestReplaceDiagramWithTitle(t *testing.T) {
+	input := `<p>x</p><div class="code-block-container"><div class="code-header">Flow & more</div><div class="code-wrapper" data-lang="d2"><pre>a -&gt; b</pre></div></div>`
+	html := ReplaceD2BlocksWithThemeSupport(input, []D2SVGPair{{Light: "<svg>l</svg>", Dark: "<svg>d</svg>", Title: "Flow & more"}})
+
+	want := `<p>x</p><figure class="diagram"><div class="d2-container" data-diagram="true"><div class="d2-light"><svg>l</svg></div><div class="d2-dark"><svg>d</svg></div><span class="zoom-hint">🔍 Click to zoom</span></div><figcaption>Flow &amp; more</figcaption></figure>`
+	if html != want {
+		t.Errorf("got  %s\nwant %s", html, want)
+	}
+}
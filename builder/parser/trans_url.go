@@ -42,22 +42,20 @@ func (t *urlTransformer) processDestination(n ast.Node, dest []byte, pc parser.C
 			n.SetAttribute([]byte("rel"), []byte("noopener noreferrer"))
 		}
 	} else {
+		// Images get their renditions from imageTransformer
 		ext := strings.ToLower(filepath.Ext(href))
-		if ext == ".jpg" || ext == ".jpeg" || ext == ".png" {
+		if link, isLink := n.(*ast.Link); isLink && (ext == ".jpg" || ext == ".jpeg" || ext == ".png") {
 			href = href[:len(href)-len(ext)] + ".webp"
-			switch node := n.(type) {
-			case *ast.Link:
-				node.Destination = []byte(href)
-			case *ast.Image:
-				node.Destination = []byte(href)
-			}
+			link.Destination = []byte(href)
 		}
 	}
 
-	// Convert .md to .html
+	// Convert .md and .ipynb to .html
 	if strings.HasSuffix(href, ".md") && !strings.HasPrefix(href, "http") {
 		href = strings.Replace(href, ".md", ".html", 1)
 		href = strings.ToLower(href)
+	} else if strings.HasSuffix(href, ".ipynb") && !strings.HasPrefix(href, "http") {
+		href = strings.ToLower(strings.TrimSuffix(href, ".ipynb") + ".html")
 	}
 
 	// Clean up ./ prefix which is redundant
@@ -99,7 +97,9 @@ func (t *urlTransformer) processDestination(n ast.Node, dest []byte, pc parser.C
 	}
 
 	if _, isImage := n.(*ast.Image); isImage {
-		n.SetAttribute([]byte("loading"), []byte("lazy"))
+		if _, ok := n.AttributeString("loading"); !ok {
+			n.SetAttribute([]byte("loading"), []byte("lazy"))
+		}
 	}
 
 	if strings.HasPrefix(href, "/") && t.BaseURL != "" {
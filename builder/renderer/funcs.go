@@ -0,0 +1,482 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	xhtml "golang.org/x/net/html"
+
+	"github.com/Kush-Singh-26/kosh/builder/frontmatter"
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/utils"
+)
+
+// PostGroup is a group of posts made by groupBy
+type PostGroup struct {
+	Key   string
+	Posts []models.PostMetadata
+}
+
+// siteFuncs holds the site settings that absURL, relURL and asset read
+type siteFuncs struct {
+	mu      sync.RWMutex
+	baseURL string
+	assets  map[string]string
+}
+
+func (s *siteFuncs) setBaseURL(baseURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (s *siteFuncs) setAssets(assets map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assets = assets
+}
+
+// funcMap returns the template functions. set returns the template being
+// executed, in which partials are looked up; tc holds partialCached output
+// and the site settings.
+func funcMap(set func() *template.Template, tc *templateCache) template.FuncMap {
+	site := &tc.site
+	return template.FuncMap{
+		"lower":     strings.ToLower,
+		"hasPrefix": strings.HasPrefix,
+		"replace": func(from, to, input string) string {
+			return strings.ReplaceAll(input, from, to)
+		},
+		"now": time.Now,
+		"partial": func(name string, data interface{}) (template.HTML, error) {
+			return executePartial(set(), name, data)
+		},
+		// partialCached renders a partial once per build for each name and
+		// keys, e.g. {{ partialCached "footer" . .CurrentVersion }}
+		"partialCached": func(name string, data interface{}, keys ...interface{}) (template.HTML, error) {
+			key := name + "\x00" + fmt.Sprint(keys...)
+			if out, ok := tc.partials.Load(key); ok {
+				return out.(template.HTML), nil
+			}
+			out, err := executePartial(set(), name, data)
+			if err != nil {
+				return "", err
+			}
+			tc.partials.Store(key, out)
+			return out, nil
+		},
+
+		"dateFormat":  dateFormat,
+		"markdownify": markdownify,
+		"truncate":    truncate,
+		"slugify":     slugify,
+		"readingTime": readingTime,
+		"safeHTML":    func(v interface{}) template.HTML { return template.HTML(toString(v)) },
+		"jsonify":     jsonify,
+		"dict":        dict,
+		"slice":       func(values ...interface{}) []interface{} { return values },
+
+		"where":   where,
+		"sortBy":  sortBy,
+		"first":   first,
+		"after":   after,
+		"groupBy": groupBy,
+
+		"absURL": func(v interface{}) string {
+			site.mu.RLock()
+			defer site.mu.RUnlock()
+			return absURL(site.baseURL, toString(v))
+		},
+		"relURL": func(v interface{}) string {
+			site.mu.RLock()
+			defer site.mu.RUnlock()
+			return relURL(site.baseURL, toString(v))
+		},
+		// asset returns the URL of a hashed asset: {{ asset "/static/css/theme.css" }}
+		"asset": func(name string) (string, error) {
+			site.mu.RLock()
+			defer site.mu.RUnlock()
+			key := "/" + strings.TrimPrefix(name, "/")
+			hashed, ok := site.assets[key]
+			if !ok {
+				return "", fmt.Errorf("asset %q not found", key)
+			}
+			return absURL(site.baseURL, hashed), nil
+		},
+	}
+}
+
+// dateFormat formats a time or a front matter date string with a Go layout.
+// The zero time is "".
+func dateFormat(layout string, v interface{}) (string, error) {
+	var t time.Time
+	switch v := v.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v != nil {
+			t = *v
+		}
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		parsed, err := frontmatter.ParseDate(v, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		t = parsed
+	default:
+		return "", fmt.Errorf("dateFormat: %T is not a date", v)
+	}
+	if t.IsZero() {
+		return "", nil
+	}
+	return t.Format(layout), nil
+}
+
+var inlineMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// markdownify renders Markdown, leaving out the paragraph around a single line
+func markdownify(v interface{}) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := inlineMarkdown.Convert([]byte(toString(v)), &buf); err != nil {
+		return "", err
+	}
+	out := strings.TrimSpace(buf.String())
+	if inner, ok := strings.CutPrefix(out, "<p>"); ok && strings.HasSuffix(inner, "</p>") && !strings.Contains(inner, "<p>") {
+		out = strings.TrimSuffix(inner, "</p>")
+	}
+	return template.HTML(out), nil
+}
+
+// truncate shortens text to at most n characters, cutting at a word boundary
+// when it can and ending with "…". HTML such as .Summary is reduced to its text.
+func truncate(n int, v interface{}) string {
+	text := []rune(toText(v))
+	if len(text) <= n {
+		return string(text)
+	}
+	cut := text[:max(n, 0)]
+	if !unicode.IsSpace(text[len(cut)]) {
+		if i := lastSpace(cut); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRightFunc(string(cut), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// slugify lowercases text and joins its words with hyphens
+func slugify(v interface{}) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(toText(v)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+// readingTime estimates the minutes it takes to read text or HTML
+func readingTime(v interface{}) int {
+	return utils.ReadingTime(len(strings.Fields(toText(v))))
+}
+
+func jsonify(v interface{}) (template.JS, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(out), nil
+}
+
+// dict makes a map from key and value pairs, e.g. for passing several values
+// to a partial: {{ partial "card" (dict "post" . "wide" true) }}
+func dict(values ...interface{}) (map[string]interface{}, error) {
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("dict: odd number of arguments")
+	}
+	m := make(map[string]interface{}, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", values[i])
+		}
+		m[key] = values[i+1]
+	}
+	return m, nil
+}
+
+// where returns the posts whose field equals value. For list fields such as
+// Tags, the list must contain value.
+func where(posts []models.PostMetadata, field string, value interface{}) ([]models.PostMetadata, error) {
+	want := fmt.Sprint(value)
+	var out []models.PostMetadata
+	for _, p := range posts {
+		f, err := postField(p, field)
+		if err != nil {
+			return nil, err
+		}
+		if f.Kind() == reflect.Slice {
+			for i := 0; i < f.Len(); i++ {
+				if fmt.Sprint(f.Index(i).Interface()) == want {
+					out = append(out, p)
+					break
+				}
+			}
+		} else if fmt.Sprint(f.Interface()) == want {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// sortBy returns the posts sorted by a field, "asc" (the default) or "desc".
+// Posts with equal fields keep their order.
+func sortBy(posts []models.PostMetadata, field string, order ...string) ([]models.PostMetadata, error) {
+	desc := false
+	if len(order) > 0 {
+		switch strings.ToLower(order[0]) {
+		case "asc":
+		case "desc":
+			desc = true
+		default:
+			return nil, fmt.Errorf("sortBy: unknown order %q (use asc or desc)", order[0])
+		}
+	}
+	keys := make([]reflect.Value, len(posts))
+	for i, p := range posts {
+		f, err := postField(p, field)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = f
+	}
+	if len(keys) > 0 {
+		if _, err := less(keys[0], keys[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	idx := make([]int, len(posts))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		if desc {
+			a, b = b, a
+		}
+		lt, _ := less(keys[idx[a]], keys[idx[b]])
+		return lt
+	})
+	out := make([]models.PostMetadata, len(posts))
+	for i, j := range idx {
+		out[i] = posts[j]
+	}
+	return out, nil
+}
+
+func less(a, b reflect.Value) (bool, error) {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String(), nil
+	case reflect.Int:
+		return a.Int() < b.Int(), nil
+	case reflect.Bool:
+		return !a.Bool() && b.Bool(), nil
+	}
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Before(b.Interface().(time.Time)), nil
+	}
+	return false, fmt.Errorf("sortBy: cannot sort by a %s", a.Type())
+}
+
+// first returns the first n posts
+func first(n int, posts []models.PostMetadata) ([]models.PostMetadata, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("first: negative count %d", n)
+	}
+	return posts[:min(n, len(posts))], nil
+}
+
+// after returns the posts after the first n
+func after(n int, posts []models.PostMetadata) ([]models.PostMetadata, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("after: negative count %d", n)
+	}
+	return posts[min(n, len(posts)):], nil
+}
+
+// groupBy groups posts by a field, in the order the keys first appear. Dates
+// are grouped by a Go layout, the year by default: {{ groupBy .Posts "Date" "January 2006" }}.
+// A post is in the group of each of its Tags.
+func groupBy(posts []models.PostMetadata, field string, layout ...string) ([]PostGroup, error) {
+	dateLayout := "2006"
+	if len(layout) > 0 {
+		dateLayout = layout[0]
+	}
+	var groups []PostGroup
+	index := map[string]int{}
+	add := func(key string, p models.PostMetadata) {
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, PostGroup{Key: key})
+		}
+		groups[i].Posts = append(groups[i].Posts, p)
+	}
+	for _, p := range posts {
+		f, err := postField(p, field)
+		if err != nil {
+			return nil, err
+		}
+		switch v := f.Interface().(type) {
+		case time.Time:
+			add(v.Format(dateLayout), p)
+		case []string:
+			for _, s := range v {
+				add(s, p)
+			}
+		default:
+			add(fmt.Sprint(v), p)
+		}
+	}
+	return groups, nil
+}
+
+// postField returns a field of a post by its case-insensitive name. Date is
+// DateObj.
+func postField(p models.PostMetadata, field string) (reflect.Value, error) {
+	if strings.EqualFold(field, "Date") {
+		field = "DateObj"
+	}
+	f := reflect.ValueOf(p).FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, field) })
+	if !f.IsValid() {
+		return reflect.Value{}, fmt.Errorf("posts have no field %q", field)
+	}
+	return f, nil
+}
+
+// absURL joins a path to the base URL. URLs with a scheme or host and
+// fragments are returned unchanged.
+func absURL(baseURL, path string) string {
+	if isAbsoluteURL(path) {
+		return path
+	}
+	return utils.BuildURL(baseURL, "", path)
+}
+
+// relURL joins a path to the path of the base URL, so "css/a.css" is
+// "/blog/css/a.css" on a site at https://example.com/blog
+func relURL(baseURL, path string) string {
+	if isAbsoluteURL(path) {
+		return path
+	}
+	basePath := ""
+	if u, err := url.Parse(baseURL); err == nil {
+		basePath = u.Path
+	}
+	return utils.BuildURL(basePath, "", path)
+}
+
+func isAbsoluteURL(path string) bool {
+	if strings.HasPrefix(path, "//") || strings.HasPrefix(path, "#") {
+		return true
+	}
+	u, err := url.Parse(path)
+	return err == nil && u.Scheme != ""
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case template.HTML:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// toText returns the text of v, without tags when v is template.HTML, with
+// runs of white space collapsed
+func toText(v interface{}) string {
+	s := toString(v)
+	if _, ok := v.(template.HTML); ok {
+		s = plainText(s)
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// plainText returns the text of HTML. Block elements are separated by spaces
+// and the content of scripts and styles is left out.
+func plainText(src string) string {
+	var b strings.Builder
+	z := xhtml.NewTokenizer(strings.NewReader(src))
+	skip := 0
+	for {
+		switch tt := z.Next(); tt {
+		case xhtml.ErrorToken:
+			return b.String()
+		case xhtml.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tag == "script" || tag == "style" {
+				if tt == xhtml.StartTagToken {
+					skip++
+				} else if tt == xhtml.EndTagToken && skip > 0 {
+					skip--
+				}
+			}
+			if !inlineTags[tag] {
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+var inlineTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "cite": true, "code": true,
+	"data": true, "del": true, "dfn": true, "em": true, "i": true, "ins": true, "kbd": true,
+	"mark": true, "q": true, "s": true, "samp": true, "small": true, "span": true,
+	"strong": true, "sub": true, "sup": true, "time": true, "u": true, "var": true,
+}
@@ -0,0 +1,152 @@
+package renderer
+
+import (
+	"flag"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/funcs")
+
+func samplePosts() []models.PostMetadata {
+	date := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02", s)
+		return t
+	}
+	return []models.PostMetadata{
+		{Title: "Transformers", Link: "/transformers.html", Tags: []string{"ai", "nlp"}, Weight: 2, ReadingTime: 9, DateObj: date("2025-03-14")},
+		{Title: "Attention", Link: "/attention.html", Tags: []string{"ai"}, Weight: 5, ReadingTime: 4, DateObj: date("2024-11-02"), Pinned: true},
+		{Title: "Go Generics", Link: "/generics.html", Tags: []string{"go"}, Weight: 2, ReadingTime: 6, DateObj: date("2025-01-20")},
+		{Title: "Bolt Internals", Link: "/bolt.html", Tags: []string{"go", "databases"}, Weight: 1, ReadingTime: 12, DateObj: date("2024-06-30"), Version: "v1.0"},
+	}
+}
+
+// TestFuncs renders one template per function and compares it with
+// testdata/funcs/<name>.golden. Run with -update to rewrite the files.
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		data interface{}
+	}{
+		{"lower", `{{ lower "Kosh SSG" }}`, nil},
+		{"hasPrefix", `{{ hasPrefix "/v2.0/intro" "/v2.0" }} {{ hasPrefix "/intro" "/v2.0" }}`, nil},
+		{"replace", `{{ replace ".html" ".md" "/posts/intro.html" }}`, nil},
+		{"now", `{{ printf "%T" now }} {{ gt now.Year 2000 }}`, nil},
+		{"dateFormat", `{{ dateFormat "Jan 2, 2006" .Date }}|{{ dateFormat "2006-01-02" "2026-01-14T09:30:00Z" }}|{{ dateFormat "2006" .Zero }}`,
+			map[string]interface{}{"Date": time.Date(2026, 2, 3, 18, 0, 0, 0, time.UTC), "Zero": time.Time{}}},
+		{"markdownify", `{{ markdownify "Uses **bold** and a [link](/a.html)" }}
+{{ markdownify "One\n\nTwo" }}
+{{ markdownify .Summary }}`, map[string]interface{}{"Summary": template.HTML("`code` <b>&</b>")}},
+		{"truncate", `{{ truncate 24 "The quick brown fox jumps over the lazy dog" }}
+{{ truncate 50 "Short enough" }}
+{{ truncate 12 .HTML }}`, map[string]interface{}{"HTML": template.HTML("<p>Tokens, <em>heads</em> &amp; layers</p><p>Residuals</p>")}},
+		{"slugify", `{{ slugify "Hello, World!" }} {{ slugify "  Go 1.25 — Release Notes " }} {{ slugify "Café Münster" }}`, nil},
+		{"readingTime", `{{ readingTime .Short }} {{ readingTime .Long }}`,
+			map[string]interface{}{"Short": "a few words", "Long": template.HTML(strings.Repeat("<p>word word word</p>", 50))}},
+		{"safeHTML", `{{ safeHTML "<em>kept</em>" }} {{ "<em>escaped</em>" }}`, nil},
+		{"jsonify", `<script>var posts = {{ jsonify (first 2 .) }};</script>
+<div data-tags="{{ jsonify (slice "a" "<b>") }}"></div>`, samplePosts()},
+		{"dict", `{{ $d := dict "title" "Card" "wide" true }}{{ $d.title }} {{ $d.wide }}`, nil},
+		{"slice", `{{ range slice "a" 2 true }}[{{ . }}]{{ end }}`, nil},
+		{"where", `{{ range where . "Tags" "go" }}{{ .Title }};{{ end }}
+{{ range where . "Pinned" true }}{{ .Title }};{{ end }}
+{{ range where . "version" "v1.0" }}{{ .Title }};{{ end }}`, samplePosts()},
+		{"sortBy", `{{ range sortBy . "Title" }}{{ .Title }};{{ end }}
+{{ range sortBy . "Date" "desc" }}{{ .Title }};{{ end }}
+{{ range sortBy . "Weight" "desc" }}{{ .Title }};{{ end }}`, samplePosts()},
+		{"first", `{{ range first 2 . }}{{ .Title }};{{ end }}|{{ len (first 10 .) }}|{{ len (first 0 .) }}`, samplePosts()},
+		{"after", `{{ range after 2 . }}{{ .Title }};{{ end }}|{{ len (after 10 .) }}`, samplePosts()},
+		{"groupBy", `{{ range groupBy . "Date" }}{{ .Key }}: {{ range .Posts }}{{ .Title }};{{ end }}
+{{ end }}{{ range groupBy . "Tags" }}{{ .Key }}: {{ len .Posts }}
+{{ end }}{{ range groupBy . "Date" "Jan 2006" }}{{ .Key }} {{ end }}`, samplePosts()},
+		{"absURL", `{{ absURL "/static/a.png" }} {{ absURL "posts/b.html" }} {{ absURL "https://cdn.example.org/x.js" }} {{ absURL "#top" }}`, nil},
+		{"relURL", `{{ relURL "/static/a.png" }} {{ relURL "posts/b.html" }} {{ relURL "//cdn.example.org/x.js" }}`, nil},
+		{"asset", `<link rel="stylesheet" href="{{ asset "/static/css/theme.css" }}"><script src="{{ asset "static/js/main.js" }}"></script>`, nil},
+		{"partial", `{{ partial "title" "Hello" }}`, nil},
+		{"partialCached", `{{ partialCached "title" "First" }}{{ partialCached "title" "Second" }}{{ partialCached "title" "Third" 3 }}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := executeFuncTemplate(t, tt.tmpl, tt.data)
+			golden := filepath.Join("testdata", "funcs", tt.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestFuncErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"missing asset", `{{ asset "/static/css/missing.css" }}`, `asset "/static/css/missing.css" not found`},
+		{"unknown field", `{{ where . "Author" "me" }}`, `posts have no field "Author"`},
+		{"unsortable field", `{{ sortBy . "Tags" }}`, "cannot sort by a []string"},
+		{"bad order", `{{ sortBy . "Title" "up" }}`, `unknown order "up"`},
+		{"odd dict", `{{ dict "a" }}`, "odd number of arguments"},
+		{"negative first", `{{ first -1 . }}`, "negative count"},
+		{"bad date", `{{ dateFormat "2006" "yesterday" }}`, `date "yesterday" does not match`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderFuncTemplate(tt.tmpl, samplePosts())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func executeFuncTemplate(t *testing.T, text string, data interface{}) string {
+	t.Helper()
+	out, err := renderFuncTemplate(text, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func renderFuncTemplate(text string, data interface{}) (string, error) {
+	tc := &templateCache{}
+	tc.site.setBaseURL("https://example.com/blog/")
+	tc.site.setAssets(map[string]string{
+		"/static/css/theme.css": "/static/css/theme.3f2a9c.css",
+		"/static/js/main.js":    "/static/js/main.b71e04.js",
+	})
+	var tmpl *template.Template
+	tmpl = template.New("test").Funcs(funcMap(func() *template.Template { return tmpl }, tc))
+	if _, err := tmpl.New(PartialsDir + "/title.html").Parse(`<h1>{{ . }}</h1>`); err != nil {
+		return "", err
+	}
+	if _, err := tmpl.Parse(text); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -3,39 +3,72 @@ package native
 import (
 	"context"
 	"fmt"
+	"html"
+	"strings"
+
 	"oss.terrastruct.com/d2/d2graph"
 	"oss.terrastruct.com/d2/d2layouts/d2dagrelayout"
+	"oss.terrastruct.com/d2/d2layouts/d2elklayout"
 	"oss.terrastruct.com/d2/d2lib"
 	"oss.terrastruct.com/d2/d2renderers/d2svg"
 	d2log "oss.terrastruct.com/d2/lib/log"
 	"oss.terrastruct.com/util-go/go2"
 )
 
-// RenderD2 renders a D2 diagram to SVG with the specified theme ID.
-func (r *Renderer) RenderD2(code string, themeID int64) (string, error) {
+// D2 layout engines
+const (
+	LayoutDagre = "dagre"
+	LayoutELK   = "elk"
+)
+
+// D2Options controls how a diagram is laid out and drawn
+type D2Options struct {
+	ThemeID int64
+	Layout  string  // LayoutDagre (default) or LayoutELK
+	Sketch  bool    // Hand-drawn look
+	Pad     int64   // Padding around the diagram in pixels
+	Scale   float64 // SVG scale factor; 0 fits the SVG to its container
+	Title   string  // Accessible <title> of the SVG
+}
+
+// RenderD2 renders a D2 diagram to SVG with the specified options.
+func (r *Renderer) RenderD2(code string, opts D2Options) (string, error) {
 	r.ensureInitialized()
 
 	// Acquire worker
 	instance := <-r.pool
 	defer func() { r.pool <- instance }() // Release worker
 
-	// Configure layout
-	layout := func(ctx context.Context, g *d2graph.Graph) error {
-		return d2dagrelayout.Layout(ctx, g, nil)
-	}
-
+	// Configure layout; a fence option wins over the diagram's own layout-engine
 	compileOpts := &d2lib.CompileOptions{
-		Layout: nil,
-		Ruler:  instance.ruler,
+		Ruler: instance.ruler,
+	}
+	if opts.Layout != "" {
+		compileOpts.Layout = &opts.Layout
 	}
-
 	compileOpts.LayoutResolver = func(engine string) (d2graph.LayoutGraph, error) {
-		return layout, nil
+		switch engine {
+		case LayoutELK:
+			return func(ctx context.Context, g *d2graph.Graph) error {
+				return d2elklayout.Layout(ctx, g, nil)
+			}, nil
+		case "", LayoutDagre:
+			return func(ctx context.Context, g *d2graph.Graph) error {
+				return d2dagrelayout.Layout(ctx, g, nil)
+			}, nil
+		default:
+			return nil, fmt.Errorf("unknown d2 layout %q (supported: dagre, elk)", engine)
+		}
 	}
 
+	themeID := opts.ThemeID
 	renderOpts := &d2svg.RenderOpts{
 		ThemeID: &themeID,
-		Pad:     go2.Pointer(int64(0)),
+		Pad:     go2.Pointer(opts.Pad),
+		Sketch:  go2.Pointer(opts.Sketch),
+	}
+	if opts.Scale > 0 {
+		renderOpts.Scale = go2.Pointer(opts.Scale)
 	}
 
 	ctx := d2log.WithDefault(context.Background())
@@ -50,5 +83,22 @@ func (r *Renderer) RenderD2(code string, themeID int64) (string, error) {
 		return "", fmt.Errorf("d2 render failed: %w", err)
 	}
 
-	return string(out), nil
+	return withSVGTitle(string(out), opts.Title), nil
+}
+
+// withSVGTitle adds an accessible <title> as the first child of the root <svg>
+func withSVGTitle(svg, title string) string {
+	if title == "" {
+		return svg
+	}
+	start := strings.Index(svg, "<svg")
+	if start < 0 {
+		return svg
+	}
+	end := strings.IndexByte(svg[start:], '>')
+	if end < 0 {
+		return svg
+	}
+	end += start + 1
+	return svg[:end] + "<title>" + html.EscapeString(title) + "</title>" + svg[end:]
 }
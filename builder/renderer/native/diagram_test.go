@@ -0,0 +1,172 @@
+package native
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDOTToD2(t *testing.T) {
+	tests := []struct {
+		name string
+		dot  string
+		want []string
+	}{
+		{
+			name: "directed with attributes",
+			dot:  `digraph { rankdir=LR; a [label="Start\nhere", shape=box, style=filled, fillcolor="#eee"]; a -> b [label="go", style=dashed] }`,
+			want: []string{
+				"direction: right",
+				`"a": {shape: rectangle; label: "Start\nhere"; style.fill: "#eee"}`,
+				`"b": {shape: oval}`,
+				`"a" -> "b": "go" {style.stroke-dash: 5}`,
+			},
+		},
+		{
+			name: "undirected",
+			dot:  `graph { a -- b }`,
+			want: []string{`"a" -- "b"`},
+		},
+		{
+			name: "edge direction",
+			dot:  `digraph { a -> b [dir=both] }`,
+			want: []string{`"a" <-> "b"`},
+		},
+		{
+			name: "nested clusters",
+			dot:  `digraph { subgraph cluster_outer { label="Outer"; subgraph cluster_inner { x } } x -> y }`,
+			want: []string{
+				`"cluster_outer": {`,
+				`label: "Outer"`,
+				`"cluster_outer"."cluster_inner": {`,
+				`"cluster_outer"."cluster_inner"."x" -> "y"`,
+			},
+		},
+		{
+			name: "defaults, chains and subgraph operands",
+			dot: "strict digraph G {\n  // comment\n  node [shape=box]; edge [style=dotted]\n" +
+				"  a:p1:n -> { b; c } -> d /* end */\n  e [label=\"one \" + \"two\"]\n}",
+			want: []string{
+				`"a": {shape: rectangle}`,
+				`"e": {shape: rectangle; label: "one two"}`,
+				`"a" -> "b" {style.stroke-dash: 2}`,
+				`"a" -> "c" {style.stroke-dash: 2}`,
+				`"c" -> "d" {style.stroke-dash: 2}`,
+			},
+		},
+		{
+			name: "scoped defaults",
+			dot:  `digraph { subgraph { node [shape=circle]; x } y }`,
+			want: []string{`"x": {shape: circle}`, `"y": {shape: oval}`},
+		},
+		{
+			name: "html label",
+			dot:  `digraph { a [label=<<b>Bold</b> text>] }`,
+			want: []string{`label: "Bold text"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DOTToD2(tt.dot)
+			if err != nil {
+				t.Fatalf("DOTToD2() error = %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output missing %q\ngot:\n%s", w, got)
+				}
+			}
+		})
+	}
+
+	for _, bad := range []string{`digraph {`, `digraph { a -> }`, `digraph { a [label="open] }`, `digraph { a } b`, `flow { a }`} {
+		if _, err := DOTToD2(bad); err == nil {
+			t.Errorf("DOTToD2(%q) expected an error", bad)
+		}
+	}
+}
+
+func TestMermaidToD2(t *testing.T) {
+	tests := []struct {
+		name    string
+		mermaid string
+		want    []string
+	}{
+		{
+			name:    "flowchart shapes and links",
+			mermaid: "graph LR\n  A[Start] --> B{Is it?}\n  B -->|Yes| C([Done])\n  B -- No --> D((Retry))\n  D -.-> A\n  C ==> E[(DB)]:::db\n  E --- A",
+			want: []string{
+				"direction: right",
+				`"A": {shape: rectangle; label: "Start"; style.border-radius: 8}`,
+				`"B": {shape: diamond; label: "Is it?"}`,
+				`"A" -> "B"`,
+				`"B" -> "C": "Yes"`,
+				`"B" -> "D": "No"`,
+				`"D" -> "A" {style.stroke-dash: 3}`,
+				`"C" -> "E" {style.stroke-width: 3}`,
+				`"E": {shape: cylinder; label: "DB"}`,
+				`"E" -- "A"`,
+			},
+		},
+		{
+			name:    "chains, groups and subgraphs",
+			mermaid: "flowchart TD\n  subgraph api [API Layer]\n    X & Y --> Z\n  end\n  Z <--> W",
+			want: []string{
+				"direction: down",
+				`"api": {label: "API Layer"}`,
+				`"api"."X" -> "api"."Z"`,
+				`"api"."Y" -> "api"."Z"`,
+				`"api"."Z" <-> "W"`,
+			},
+		},
+		{
+			name:    "sequence diagram",
+			mermaid: "sequenceDiagram\n  participant A as Alice\n  actor B\n  A->>+B: Hello\n  B-->>-A: Hi\n  Note over A: thinking\n  alt ok\n    A-)B: yes\n  else\n    A-xB: no\n  end",
+			want: []string{
+				"shape: sequence_diagram",
+				`"A": {label: "Alice"}`,
+				`"B": {shape: person}`,
+				`"A" -> "B": "Hello"`,
+				`"B" -> "A": "Hi" {style.stroke-dash: 3}`,
+				`"A"."note 1": {label: "thinking"; shape: page}`,
+				"label: \"alt ok\"\n  \"A\" -> \"B\": \"yes\"",
+				"label: \"else\"\n  \"A\" -> \"B\": \"no\"",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MermaidToD2(tt.mermaid)
+			if err != nil {
+				t.Fatalf("MermaidToD2() error = %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output missing %q\ngot:\n%s", w, got)
+				}
+			}
+		})
+	}
+
+	for _, bad := range []string{"pie\n  \"a\": 1", "classDiagram\n  A <|-- B", "gantt\n  title Plan", "graph TD\n  A ~> B", ""} {
+		if _, err := MermaidToD2(bad); err == nil {
+			t.Errorf("MermaidToD2(%q) expected error", bad)
+		}
+	}
+}
+
+func TestRenderD2Options(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts D2 workers")
+	}
+	r := New()
+	svg, err := r.RenderD2("a -> b", D2Options{Layout: LayoutELK, Sketch: true, Pad: 20, Title: "A & B"})
+	if err != nil {
+		t.Fatalf("RenderD2() error = %v", err)
+	}
+	if !strings.Contains(svg, "<title>A &amp; B</title><svg") {
+		t.Errorf("title should be the first child of the root svg: %.300s", svg)
+	}
+	if _, err := r.RenderD2("a -> b", D2Options{Layout: "circo"}); err == nil {
+		t.Error("expected error for unknown layout")
+	}
+}
@@ -0,0 +1,523 @@
+package native
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// dotGraph is a parsed Graphviz graph. It keeps what DOTToD2 translates:
+// attributes, nodes and edges in declaration order, and cluster nesting.
+// Attribute values keep their DOT quoting so HTML-like labels stay distinct
+// from quoted strings.
+type dotGraph struct {
+	directed bool
+	attrs    map[string]string
+	nodes    []*dotNode
+	edges    []*dotEdge
+	clusters []*dotSubgraph
+	byID     map[string]*dotNode
+	subs     map[string]*dotSubgraph
+}
+
+type dotNode struct {
+	id      string
+	attrs   map[string]string
+	cluster *dotSubgraph // Deepest cluster the node was written in, or nil
+}
+
+type dotEdge struct {
+	src, dst *dotNode
+	directed bool
+	attrs    map[string]string
+}
+
+// dotSubgraph is a subgraph body; parent is the enclosing cluster, or nil
+type dotSubgraph struct {
+	id      string
+	attrs   map[string]string
+	parent  *dotSubgraph
+	cluster bool
+	depth   int // Number of clusters enclosing this one, itself included
+}
+
+// dotScope holds the defaults in force inside a graph or subgraph body and
+// the nodes written in it, which an edge to the subgraph connects
+type dotScope struct {
+	sub       *dotSubgraph // nil at the root
+	outer     *dotScope
+	nodeAttrs map[string]string
+	edgeAttrs map[string]string
+	nodes     []*dotNode
+	seen      map[*dotNode]bool
+}
+
+type dotToken struct {
+	kind byte // 'i' for IDs, 'e' for edge operators, otherwise the punctuation itself
+	text string
+	line int
+}
+
+// parseDOT parses a graph in the Graphviz DOT language
+func parseDOT(code string) (*dotGraph, error) {
+	tokens, err := lexDOT(code)
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{tokens: tokens, g: &dotGraph{
+		attrs: make(map[string]string),
+		byID:  make(map[string]*dotNode),
+		subs:  make(map[string]*dotSubgraph),
+	}}
+	if err := p.parseGraph(); err != nil {
+		return nil, err
+	}
+	return p.g, nil
+}
+
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+	g      *dotGraph
+}
+
+func (p *dotParser) peek() dotToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	line := 1
+	if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return dotToken{kind: 0, line: line}
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given case-insensitive keyword
+func (p *dotParser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == 'i' && strings.EqualFold(t.text, word)
+}
+
+func (p *dotParser) expect(kind byte) (dotToken, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.unexpected(t, string(kind))
+	}
+	return t, nil
+}
+
+func (p *dotParser) unexpected(t dotToken, want string) error {
+	got := t.text
+	switch {
+	case t.kind == 0:
+		got = "end of input"
+	case t.kind != 'i' && t.kind != 'e':
+		got = string(t.kind)
+	}
+	if want == "i" {
+		want = "an ID"
+	}
+	return fmt.Errorf("line %d: expected %s, got %q", t.line, want, got)
+}
+
+func (p *dotParser) parseGraph() error {
+	if p.keyword("strict") {
+		p.next()
+	}
+	switch {
+	case p.keyword("digraph"):
+		p.g.directed = true
+	case p.keyword("graph"):
+	default:
+		return p.unexpected(p.peek(), `"graph" or "digraph"`)
+	}
+	p.next()
+	if p.peek().kind == 'i' {
+		p.next() // The graph name has no D2 counterpart
+	}
+	if _, err := p.expect('{'); err != nil {
+		return err
+	}
+	root := &dotScope{nodeAttrs: map[string]string{}, edgeAttrs: map[string]string{}}
+	if err := p.parseStmts(root); err != nil {
+		return err
+	}
+	if t := p.peek(); t.kind != 0 {
+		return p.unexpected(t, "end of input")
+	}
+	return nil
+}
+
+// parseStmts parses statements up to and including the closing brace
+func (p *dotParser) parseStmts(scope *dotScope) error {
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == '}':
+			p.next()
+			return nil
+		case t.kind == ';':
+			p.next()
+		case t.kind == 0:
+			return p.unexpected(t, "}")
+		case p.keyword("graph") || p.keyword("node") || p.keyword("edge"):
+			p.next()
+			attrs, err := p.parseAttrLists()
+			if err != nil {
+				return err
+			}
+			target := scope.edgeAttrs
+			switch strings.ToLower(t.text) {
+			case "graph":
+				target = p.graphAttrs(scope)
+			case "node":
+				target = scope.nodeAttrs
+			}
+			for k, v := range attrs {
+				target[k] = v
+			}
+		case t.kind == '{' || p.keyword("subgraph"):
+			nodes, err := p.parseSubgraph(scope)
+			if err != nil {
+				return err
+			}
+			if err := p.parseEdges(scope, nodes); err != nil {
+				return err
+			}
+		case t.kind == 'i':
+			p.next()
+			if p.peek().kind == '=' {
+				p.next()
+				value, err := p.expect('i')
+				if err != nil {
+					return err
+				}
+				p.graphAttrs(scope)[t.text] = value.text
+				continue
+			}
+			if err := p.skipPort(); err != nil {
+				return err
+			}
+			node := p.node(t.text, scope)
+			if p.peek().kind == 'e' {
+				if err := p.parseEdges(scope, []*dotNode{node}); err != nil {
+					return err
+				}
+				continue
+			}
+			attrs, err := p.parseAttrLists()
+			if err != nil {
+				return err
+			}
+			for k, v := range attrs {
+				node.attrs[k] = v
+			}
+		default:
+			return p.unexpected(t, "a statement")
+		}
+	}
+}
+
+// graphAttrs returns the attributes graph statements in scope assign to
+func (p *dotParser) graphAttrs(scope *dotScope) map[string]string {
+	if scope.sub != nil {
+		return scope.sub.attrs
+	}
+	return p.g.attrs
+}
+
+// parseSubgraph parses a subgraph and returns the nodes written inside it
+func (p *dotParser) parseSubgraph(scope *dotScope) ([]*dotNode, error) {
+	var name string
+	if p.keyword("subgraph") {
+		p.next()
+		if p.peek().kind == 'i' {
+			name = p.next().text
+		}
+	}
+	if _, err := p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	parent := scope.sub
+	for parent != nil && !parent.cluster {
+		parent = parent.parent
+	}
+	sub := p.g.subs[dotUnquote(name)]
+	if sub == nil || name == "" {
+		sub = &dotSubgraph{id: dotUnquote(name), attrs: make(map[string]string), parent: parent}
+		sub.cluster = isDOTCluster(sub.id)
+		if parent != nil {
+			sub.depth = parent.depth
+		}
+		if sub.cluster {
+			sub.depth++
+			p.g.clusters = append(p.g.clusters, sub)
+		}
+		if name != "" {
+			p.g.subs[sub.id] = sub
+		}
+	}
+
+	inner := &dotScope{sub: sub, outer: scope, nodeAttrs: copyDOTAttrs(scope.nodeAttrs), edgeAttrs: copyDOTAttrs(scope.edgeAttrs)}
+	if err := p.parseStmts(inner); err != nil {
+		return nil, err
+	}
+	return inner.nodes, nil
+}
+
+// parseEdges parses an edge chain whose first operand is from, then its attributes
+func (p *dotParser) parseEdges(scope *dotScope, from []*dotNode) error {
+	type hop struct {
+		directed bool
+		to       []*dotNode
+	}
+	var hops []hop
+	for p.peek().kind == 'e' {
+		op := p.next()
+		var to []*dotNode
+		if p.peek().kind == '{' || p.keyword("subgraph") {
+			nodes, err := p.parseSubgraph(scope)
+			if err != nil {
+				return err
+			}
+			to = nodes
+		} else {
+			t, err := p.expect('i')
+			if err != nil {
+				return err
+			}
+			if err := p.skipPort(); err != nil {
+				return err
+			}
+			to = []*dotNode{p.node(t.text, scope)}
+		}
+		hops = append(hops, hop{directed: op.text == "->", to: to})
+	}
+	attrs, err := p.parseAttrLists()
+	if err != nil {
+		return err
+	}
+	for _, h := range hops {
+		for _, src := range from {
+			for _, dst := range h.to {
+				e := &dotEdge{src: src, dst: dst, directed: h.directed, attrs: copyDOTAttrs(scope.edgeAttrs)}
+				for k, v := range attrs {
+					e.attrs[k] = v
+				}
+				p.g.edges = append(p.g.edges, e)
+			}
+		}
+		from = h.to
+	}
+	return nil
+}
+
+// node returns the node with the given ID, creating it with the scope's
+// defaults on first use, and records the deepest cluster it appears in
+func (p *dotParser) node(id string, scope *dotScope) *dotNode {
+	key := dotUnquote(id)
+	n := p.g.byID[key]
+	if n == nil {
+		n = &dotNode{id: key, attrs: copyDOTAttrs(scope.nodeAttrs)}
+		p.g.byID[key] = n
+		p.g.nodes = append(p.g.nodes, n)
+	}
+	for sc := scope; sc != nil; sc = sc.outer {
+		if sc.seen == nil {
+			sc.seen = make(map[*dotNode]bool)
+		}
+		if !sc.seen[n] {
+			sc.seen[n] = true
+			sc.nodes = append(sc.nodes, n)
+		}
+	}
+	cluster := scope.sub
+	for cluster != nil && !cluster.cluster {
+		cluster = cluster.parent
+	}
+	if cluster != nil && (n.cluster == nil || cluster.depth > n.cluster.depth) {
+		n.cluster = cluster
+	}
+	return n
+}
+
+// skipPort skips a node port (":port" or ":port:compass"), which D2 has no use for
+func (p *dotParser) skipPort() error {
+	for i := 0; i < 2 && p.peek().kind == ':'; i++ {
+		p.next()
+		if _, err := p.expect('i'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseAttrLists parses zero or more bracketed attribute lists
+func (p *dotParser) parseAttrLists() (map[string]string, error) {
+	attrs := make(map[string]string)
+	for p.peek().kind == '[' {
+		p.next()
+		for p.peek().kind != ']' {
+			key, err := p.expect('i')
+			if err != nil {
+				return nil, err
+			}
+			value := dotToken{text: "true"}
+			if p.peek().kind == '=' {
+				p.next()
+				if value, err = p.expect('i'); err != nil {
+					return nil, err
+				}
+			}
+			attrs[key.text] = value.text
+			if k := p.peek().kind; k == ',' || k == ';' {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return attrs, nil
+}
+
+func copyDOTAttrs(attrs map[string]string) map[string]string {
+	out := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		out[k] = v
+	}
+	return out
+}
+
+// lexDOT splits DOT source into tokens. Quoted strings keep their quotes and
+// "a" + "b" concatenations are joined; HTML strings keep their angle brackets.
+func lexDOT(code string) ([]dotToken, error) {
+	var tokens []dotToken
+	line := 1
+	atLineStart := true
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '\n':
+			line++
+			atLineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' && atLineStart:
+			// C preprocessor output lines are ignored
+			for i < len(code) && code[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		atLineStart = false
+
+		switch {
+		case strings.HasPrefix(code[i:], "//"):
+			for i < len(code) && code[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(code[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(code[i:], "->") || strings.HasPrefix(code[i:], "--"):
+			tokens = append(tokens, dotToken{kind: 'e', text: code[i : i+2], line: line})
+			i += 2
+		case strings.IndexByte("{}[];,=:", c) >= 0:
+			tokens = append(tokens, dotToken{kind: c, text: string(c), line: line})
+			i++
+		case c == '"':
+			start, startLine := i, line
+			for i++; i < len(code) && code[i] != '"'; i++ {
+				if code[i] == '\\' && i+1 < len(code) {
+					i++
+				}
+				if code[i] == '\n' {
+					line++
+				}
+			}
+			if i >= len(code) {
+				return nil, fmt.Errorf("line %d: unterminated string", startLine)
+			}
+			i++
+			text := strings.ReplaceAll(code[start:i], "\\\n", "")
+			if n := len(tokens); n >= 2 && tokens[n-1].kind == '+' && strings.HasPrefix(tokens[n-2].text, `"`) {
+				prev := tokens[n-2].text
+				tokens[n-2].text = prev[:len(prev)-1] + text[1:]
+				tokens = tokens[:n-1]
+				continue
+			}
+			tokens = append(tokens, dotToken{kind: 'i', text: text, line: startLine})
+		case c == '+':
+			tokens = append(tokens, dotToken{kind: '+', text: "+", line: line})
+			i++
+		case c == '<':
+			start, startLine, depth := i, line, 0
+		html:
+			for ; i < len(code); i++ {
+				switch code[i] {
+				case '<':
+					depth++
+				case '>':
+					if depth--; depth == 0 {
+						break html
+					}
+				case '\n':
+					line++
+				}
+			}
+			if i >= len(code) {
+				return nil, fmt.Errorf("line %d: unterminated HTML string", startLine)
+			}
+			i++
+			tokens = append(tokens, dotToken{kind: 'i', text: code[start:i], line: startLine})
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			if c == '-' {
+				i++
+			}
+			for i < len(code) && (code[i] == '.' || (code[i] >= '0' && code[i] <= '9')) {
+				i++
+			}
+			if i == start || code[start:i] == "-" {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, code[start:start+1])
+			}
+			tokens = append(tokens, dotToken{kind: 'i', text: code[start:i], line: line})
+		case isDOTIDByte(c):
+			start := i
+			for i < len(code) && (isDOTIDByte(code[i]) || (code[i] >= '0' && code[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, dotToken{kind: 'i', text: code[start:i], line: line})
+		default:
+			r, _ := utf8.DecodeRuneInString(code[i:])
+			return nil, fmt.Errorf("line %d: unexpected %q", line, r)
+		}
+	}
+	for _, t := range tokens {
+		if t.kind == '+' {
+			return nil, fmt.Errorf("line %d: '+' must join two quoted strings", t.line)
+		}
+	}
+	return tokens, nil
+}
+
+// isDOTIDByte reports whether c can start an unquoted DOT ID; bytes of
+// multi-byte UTF-8 characters count as letters
+func isDOTIDByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
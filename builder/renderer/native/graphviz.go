@@ -0,0 +1,170 @@
+package native
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RenderDOT renders a Graphviz DOT graph to SVG with the specified options.
+// The graph is translated to D2 and laid out by the same pure-Go pipeline as
+// D2 diagrams, so no Graphviz install is needed.
+func (r *Renderer) RenderDOT(code string, opts D2Options) (string, error) {
+	d2, err := DOTToD2(code)
+	if err != nil {
+		return "", err
+	}
+	return r.RenderD2(d2, opts)
+}
+
+var (
+	dotHTMLTagRe = regexp.MustCompile(`<[^>]*>`)
+	dotColorRe   = regexp.MustCompile(`^#?[A-Za-z0-9]+$`)
+)
+
+// dotShapes maps Graphviz node shapes to D2 shapes; unlisted shapes become rectangles
+var dotShapes = map[string]string{
+	"box": "rectangle", "rect": "rectangle", "rectangle": "rectangle", "record": "rectangle", "Mrecord": "rectangle",
+	"square": "square", "ellipse": "oval", "oval": "oval", "circle": "circle", "doublecircle": "circle",
+	"point": "circle", "diamond": "diamond", "cylinder": "cylinder", "note": "page", "tab": "package",
+	"folder": "package", "hexagon": "hexagon", "parallelogram": "parallelogram",
+	"plaintext": "text", "plain": "text", "none": "text",
+}
+
+var dotDirections = map[string]string{"TB": "down", "LR": "right", "BT": "up", "RL": "left"}
+
+// DOTToD2 translates a Graphviz graph into D2. Clusters become containers; node
+// labels, shapes, colours and dashed/dotted/bold styles carry over. Plain
+// subgraphs, rank constraints, ports and HTML label markup are dropped.
+func DOTToD2(code string) (string, error) {
+	g, err := parseDOT(code)
+	if err != nil {
+		return "", fmt.Errorf("dot parse failed: %w", err)
+	}
+
+	var b strings.Builder
+	if dir, ok := dotDirections[dotUnquote(g.attrs["rankdir"])]; ok {
+		b.WriteString("direction: " + dir + "\n")
+	}
+
+	// Clusters are declared before their children, so parents come first
+	clusterPath := make(map[*dotSubgraph]string)
+	for _, c := range g.clusters {
+		p := d2String(c.id)
+		if c.parent != nil {
+			p = clusterPath[c.parent] + "." + p
+		}
+		clusterPath[c] = p
+		b.WriteString(p + ": {\n")
+		if label := dotLabel(c.attrs["label"]); label != "" {
+			b.WriteString("  label: " + d2String(label) + "\n")
+		}
+		b.WriteString("}\n")
+	}
+
+	nodePath := func(n *dotNode) string {
+		p := d2String(n.id)
+		if n.cluster != nil {
+			p = clusterPath[n.cluster] + "." + p
+		}
+		return p
+	}
+
+	for _, n := range g.nodes {
+		shape := "oval" // Graphviz's default
+		if s := dotUnquote(n.attrs["shape"]); s != "" {
+			if shape = dotShapes[s]; shape == "" {
+				shape = "rectangle"
+			}
+		}
+		fields := []string{"shape: " + shape}
+		if label := dotLabel(n.attrs["label"]); label != "" {
+			fields = append(fields, "label: "+d2String(label))
+		}
+		fields = append(fields, dotStyles(n.attrs, true)...)
+		b.WriteString(nodePath(n) + ": {" + strings.Join(fields, "; ") + "}\n")
+	}
+
+	for _, e := range g.edges {
+		arrow := "--"
+		if e.directed {
+			arrow = "->"
+		}
+		switch dotUnquote(e.attrs["dir"]) {
+		case "both":
+			arrow = "<->"
+		case "back":
+			arrow = "<-"
+		case "none":
+			arrow = "--"
+		}
+		b.WriteString(nodePath(e.src) + " " + arrow + " " + nodePath(e.dst))
+		if label := dotLabel(e.attrs["label"]); label != "" {
+			b.WriteString(": " + d2String(label))
+		}
+		if styles := dotStyles(e.attrs, false); len(styles) > 0 {
+			b.WriteString(" {" + strings.Join(styles, "; ") + "}")
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+func isDOTCluster(name string) bool {
+	return strings.HasPrefix(dotUnquote(name), "cluster")
+}
+
+// dotStyles maps colour and line style attributes to D2 style fields
+func dotStyles(attrs map[string]string, node bool) []string {
+	var styles []string
+	color := func(key, field string) {
+		if c := dotUnquote(attrs[key]); dotColorRe.MatchString(c) {
+			styles = append(styles, "style."+field+": "+d2String(c))
+		}
+	}
+	if node {
+		color("fillcolor", "fill")
+	}
+	color("color", "stroke")
+	color("fontcolor", "font-color")
+
+	for _, style := range strings.Split(dotUnquote(attrs["style"]), ",") {
+		switch strings.TrimSpace(style) {
+		case "dashed":
+			styles = append(styles, "style.stroke-dash: 5")
+		case "dotted":
+			styles = append(styles, "style.stroke-dash: 2")
+		case "bold":
+			styles = append(styles, "style.stroke-width: 3")
+		case "rounded":
+			if node {
+				styles = append(styles, "style.border-radius: 8")
+			}
+		}
+	}
+	return styles
+}
+
+// dotUnquote strips DOT string quotes
+func dotUnquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strings.ReplaceAll(s[1:len(s)-1], `\"`, `"`)
+	}
+	return s
+}
+
+// dotLabel converts a DOT label to plain text: escape-sequence line breaks
+// become newlines and HTML-like labels lose their markup
+func dotLabel(s string) string {
+	if len(s) >= 2 && s[0] == '<' && s[len(s)-1] == '>' {
+		s = dotHTMLTagRe.ReplaceAllString(s[1:len(s)-1], " ")
+		return strings.Join(strings.Fields(s), " ")
+	}
+	s = dotUnquote(s)
+	return strings.NewReplacer(`\n`, "\n", `\l`, "\n", `\r`, "\n").Replace(s)
+}
+
+// d2String quotes a D2 key or string value
+func d2String(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -2,12 +2,24 @@ package native
 
 import (
 	"fmt"
-	"log"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// KaTeX output modes for math.output
+const (
+	MathOutputHTML          = "html"          // Visual HTML only (default)
+	MathOutputMathML        = "mathml"        // MathML only, for feeds and readers without KaTeX CSS
+	MathOutputHTMLAndMathML = "htmlAndMathml" // Visual HTML plus hidden MathML for screen readers
 )
 
-// RenderMath renders a single LaTeX expression to HTML using KaTeX via goja
-func (r *Renderer) RenderMath(latex string, displayMode bool) (string, error) {
+// RenderMath renders a single LaTeX expression using KaTeX via goja
+func (r *Renderer) RenderMath(expr MathExpression) (string, error) {
 	r.ensureInitialized()
 
 	// Acquire worker
@@ -18,12 +30,7 @@ func (r *Renderer) RenderMath(latex string, displayMode bool) (string, error) {
 		return "", fmt.Errorf("KaTeX not initialized in worker")
 	}
 
-	opts := instance.vm.NewObject()
-	_ = opts.Set("displayMode", displayMode)
-	_ = opts.Set("throwOnError", false)
-	_ = opts.Set("output", "html")
-
-	result, err := instance.renderFn(instance.katex, instance.vm.ToValue(latex), opts)
+	result, err := instance.renderFn(instance.katex, instance.vm.ToValue(expr.LaTeX), katexOptions(instance.vm, expr))
 	if err != nil {
 		return "", fmt.Errorf("KaTeX render failed: %w", err)
 	}
@@ -31,15 +38,125 @@ func (r *Renderer) RenderMath(latex string, displayMode bool) (string, error) {
 	return result.String(), nil
 }
 
+// katexOptions builds the options object of katex.renderToString. KaTeX writes
+// \gdef definitions back into macros, so each call gets a fresh object.
+func katexOptions(vm *goja.Runtime, expr MathExpression) *goja.Object {
+	opts := vm.NewObject()
+	_ = opts.Set("displayMode", expr.DisplayMode)
+	_ = opts.Set("throwOnError", false)
+	output := expr.Output
+	if output == "" {
+		output = MathOutputHTML
+	}
+	_ = opts.Set("output", output)
+	if len(expr.Macros) > 0 {
+		m := vm.NewObject()
+		for name, expansion := range expr.Macros {
+			_ = m.Set(name, expansion)
+		}
+		_ = opts.Set("macros", m)
+	}
+	return opts
+}
+
 // MathExpression represents a LaTeX expression with its metadata
 type MathExpression struct {
 	LaTeX       string
 	DisplayMode bool
 	Hash        string
+	Macros      map[string]string // Macros in effect; see MathHash
+	Output      string            // KaTeX output mode; "" means MathOutputHTML
+}
+
+// MathError is an expression KaTeX could not render
+type MathError struct {
+	LaTeX   string
+	Message string
+}
+
+var katexErrorRe = regexp.MustCompile(`<span class="katex-error" title="([^"]*)"`)
+
+// RenderedMathError reports the parse error KaTeX embedded in rendered output.
+// With throwOnError off, KaTeX renders the source in red instead of failing.
+func RenderedMathError(rendered string) (string, bool) {
+	m := katexErrorRe.FindStringSubmatch(rendered)
+	if m == nil {
+		return "", false
+	}
+	return html.UnescapeString(m[1]), true
+}
+
+// MacroName adds the leading backslash KaTeX expects to a macro name
+func MacroName(name string) string {
+	if strings.HasPrefix(name, "\\") {
+		return name
+	}
+	return "\\" + name
+}
+
+// MergeMacros combines macro tables into one keyed by MacroName; later tables
+// win on conflicts
+func MergeMacros(tables ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, table := range tables {
+		for name, expansion := range table {
+			merged[MacroName(name)] = expansion
+		}
+	}
+	return merged
 }
 
-// RenderAllMath renders multiple LaTeX expressions in parallel using the worker pool
-func (r *Renderer) RenderAllMath(expressions []MathExpression, cache map[string]string) (map[string]string, error) {
+var macroNameRe = regexp.MustCompile(`\\(?:[A-Za-z]+|.)`)
+
+// UsedMacros returns the macros an expression depends on, following macros
+// that expand to other macros
+func UsedMacros(latex string, macros map[string]string) map[string]string {
+	if len(macros) == 0 {
+		return nil
+	}
+	used := make(map[string]string)
+	pending := []string{latex}
+	for len(pending) > 0 {
+		src := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, name := range macroNameRe.FindAllString(src, -1) {
+			expansion, ok := macros[name]
+			if _, seen := used[name]; !ok || seen {
+				continue
+			}
+			used[name] = expansion
+			pending = append(pending, expansion)
+		}
+	}
+	return used
+}
+
+// MathHash is the SSR cache key of an expression. Only the macros the expression
+// uses are hashed, so editing a macro re-renders just the expressions that use it.
+// settings fingerprints any other non-default KaTeX settings ("" when none).
+func MathHash(kind, latex string, macros map[string]string, settings string) string {
+	used := UsedMacros(latex, macros)
+	if len(used) == 0 && settings == "" {
+		return HashContent(kind, latex)
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(latex)
+	for _, name := range names {
+		b.WriteString("\x00" + name + "=" + used[name])
+	}
+	b.WriteString("\x00" + settings)
+	return HashContent(kind, b.String())
+}
+
+// RenderAllMath renders multiple LaTeX expressions in parallel using the worker pool.
+// Expressions that KaTeX throws on are returned as errors and left unrendered.
+func (r *Renderer) RenderAllMath(expressions []MathExpression, cache map[string]string) (map[string]string, []MathError) {
 	if len(expressions) == 0 {
 		return make(map[string]string), nil
 	}
@@ -47,6 +164,7 @@ func (r *Renderer) RenderAllMath(expressions []MathExpression, cache map[string]
 	r.ensureInitialized()
 
 	results := make(map[string]string)
+	var errs []MathError
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
@@ -67,14 +185,11 @@ func (r *Renderer) RenderAllMath(expressions []MathExpression, cache map[string]
 				return
 			}
 
-			opts := instance.vm.NewObject()
-			_ = opts.Set("displayMode", e.DisplayMode)
-			_ = opts.Set("throwOnError", false)
-			_ = opts.Set("output", "html")
-
-			res, err := instance.renderFn(instance.katex, instance.vm.ToValue(e.LaTeX), opts)
+			res, err := instance.renderFn(instance.katex, instance.vm.ToValue(e.LaTeX), katexOptions(instance.vm, e))
 			if err != nil {
-				log.Printf("   ⚠️  LaTeX render failed for %s: %v", e.Hash[:8], err)
+				mu.Lock()
+				errs = append(errs, MathError{LaTeX: e.LaTeX, Message: err.Error()})
+				mu.Unlock()
 				return
 			}
 
@@ -85,5 +200,5 @@ func (r *Renderer) RenderAllMath(expressions []MathExpression, cache map[string]
 	}
 
 	wg.Wait()
-	return results, nil
+	return results, errs
 }
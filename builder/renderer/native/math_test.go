@@ -0,0 +1,103 @@
+package native
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUsedMacros(t *testing.T) {
+	macros := map[string]string{
+		`\R`:    `\mathbb{R}`,
+		`\Rn`:   `\R^n`,
+		`\norm`: `\lVert #1 \rVert`,
+		`\E`:    `\mathbb{E}`,
+	}
+
+	tests := []struct {
+		latex string
+		want  map[string]string
+	}{
+		{`x \in \R`, map[string]string{`\R`: `\mathbb{R}`}},
+		{`x \in \Rn`, map[string]string{`\Rn`: `\R^n`, `\R`: `\mathbb{R}`}},
+		{`\norm{x}`, map[string]string{`\norm`: `\lVert #1 \rVert`}},
+		{`\Real`, map[string]string{}},
+		{`a + b`, map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := UsedMacros(tt.latex, macros); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("UsedMacros(%q) = %v, want %v", tt.latex, got, tt.want)
+		}
+	}
+}
+
+func TestMergeMacros(t *testing.T) {
+	site := map[string]string{"R": `\mathbb{R}`, `\E`: `\mathbb{E}`}
+	post := map[string]string{`\R`: `\mathcal{R}`, "norm": `\lVert #1 \rVert`}
+
+	got := MergeMacros(site, post)
+	want := map[string]string{`\R`: `\mathcal{R}`, `\E`: `\mathbb{E}`, `\norm`: `\lVert #1 \rVert`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeMacros() = %v, want %v", got, want)
+	}
+	if got := MergeMacros(nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("MergeMacros(nil, nil) = %v, want an empty table", got)
+	}
+}
+
+func TestMathHash(t *testing.T) {
+	base := MathHash("math-block", `x \in \R`, nil, "")
+	if base != HashContent("math-block", `x \in \R`) {
+		t.Error("hash without macros should match the plain content hash")
+	}
+
+	withR := MathHash("math-block", `x \in \R`, map[string]string{`\R`: `\mathbb{R}`}, "")
+	if withR == base {
+		t.Error("a used macro should change the hash")
+	}
+	if other := MathHash("math-block", `x \in \R`, map[string]string{`\R`: `\mathbf{R}`}, ""); other == withR {
+		t.Error("editing a used macro should change the hash")
+	}
+	if unused := MathHash("math-block", `x \in \R`, map[string]string{`\R`: `\mathbb{R}`, `\E`: `\mathbb{E}`}, ""); unused != withR {
+		t.Error("an unused macro should not change the hash")
+	}
+	if ext := MathHash("math-block", `x \in \R`, nil, "abc"); ext == base {
+		t.Error("loaded extensions should change the hash")
+	}
+}
+
+func TestRenderMathMacros(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts KaTeX workers")
+	}
+	r := New()
+	html, err := r.RenderMath(MathExpression{LaTeX: `\R`, Macros: map[string]string{`\R`: `\mathbb{R}`}})
+	if err != nil {
+		t.Fatalf("RenderMath() error = %v", err)
+	}
+	if !strings.Contains(html, "mathbb") {
+		t.Errorf("macro not expanded: %s", html)
+	}
+}
+
+func TestRenderMathExtensions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts KaTeX workers")
+	}
+	r := New()
+	// Same registration hook that contrib/mhchem uses for \ce
+	ext := Extension{Name: "foo.js", Source: `katex.__defineMacro("\\foo", "\\mathbb{F}");`}
+	if err := r.LoadExtensions([]Extension{ext}); err != nil {
+		t.Fatalf("LoadExtensions() error = %v", err)
+	}
+	if r.ExtensionsHash() == "" {
+		t.Error("ExtensionsHash() should be set once extensions load")
+	}
+	html, err := r.RenderMath(MathExpression{LaTeX: `\foo`})
+	if err != nil {
+		t.Fatalf("RenderMath() error = %v", err)
+	}
+	if !strings.Contains(html, "mathbb") {
+		t.Errorf("extension macro not defined: %s", html)
+	}
+}
@@ -0,0 +1,356 @@
+package native
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RenderMermaid renders a Mermaid flowchart or sequence diagram to SVG with the
+// specified options. Mermaid itself needs a browser DOM, so the diagram is
+// translated to D2 and rendered by the D2 pipeline instead. Other diagram
+// types are errors rather than silent fallbacks, so callers can report them.
+func (r *Renderer) RenderMermaid(code string, opts D2Options) (string, error) {
+	d2, err := MermaidToD2(code)
+	if err != nil {
+		return "", err
+	}
+	return r.RenderD2(d2, opts)
+}
+
+// MermaidToD2 translates the flowchart (graph/flowchart) and sequenceDiagram
+// subsets of Mermaid into D2. Other diagram types return an error. Styling
+// directives (classDef, style, linkStyle, click) and sequence activations,
+// numbering and titles have no D2 counterpart here and are dropped.
+func MermaidToD2(code string) (string, error) {
+	lines := mermaidLines(code)
+	if len(lines) == 0 {
+		return "", fmt.Errorf("mermaid: empty diagram")
+	}
+	header := strings.Fields(lines[0])
+	switch header[0] {
+	case "graph", "flowchart":
+		dir := ""
+		if len(header) > 1 {
+			dir = header[1]
+		}
+		return mermaidFlowchart(dir, lines[1:])
+	case "sequenceDiagram":
+		return mermaidSequence(lines[1:])
+	default:
+		return "", fmt.Errorf("mermaid: unsupported diagram type %q (supported: flowchart, sequenceDiagram)", header[0])
+	}
+}
+
+// mermaidLines splits a diagram into trimmed statements, dropping %% comments
+func mermaidLines(code string) []string {
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		if i := strings.Index(line, "%%"); i >= 0 {
+			line = line[:i]
+		}
+		for _, stmt := range strings.Split(line, ";") {
+			if stmt = strings.TrimSpace(stmt); stmt != "" {
+				lines = append(lines, stmt)
+			}
+		}
+	}
+	return lines
+}
+
+// mermaidText unquotes a Mermaid label and turns <br> into line breaks
+func mermaidText(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n").Replace(s)
+}
+
+// Flowcharts
+
+var mermaidDirections = map[string]string{"TB": "down", "TD": "down", "LR": "right", "BT": "up", "RL": "left"}
+
+// Node shapes by opening bracket, longest first
+var mermaidShapes = []struct{ open, close, shape string }{
+	{"([", "])", "oval"},
+	{"[[", "]]", "rectangle"},
+	{"[(", ")]", "cylinder"},
+	{"((", "))", "circle"},
+	{"{{", "}}", "hexagon"},
+	{"[/", "/]", "parallelogram"},
+	{`[\`, `\]`, "parallelogram"},
+	{"[", "]", "rectangle"},
+	{"(", ")", "rectangle"}, // Rounded
+	{"{", "}", "diamond"},
+	{">", "]", "page"},
+}
+
+var (
+	mermaidIDRe    = regexp.MustCompile(`^[\p{L}\p{N}_]+`)
+	mermaidClassRe = regexp.MustCompile(`^:::[\w-]+`)
+	// "-->", "---", "-.->", "==>", "<-->", "--o", "--x", each with an optional |label|
+	mermaidLinkRe = regexp.MustCompile(`^(<?)(-{2,}|={2,}|-\.+-)([>ox]?)\s*(?:\|([^|]*)\|)?`)
+	// "-- label -->", "-. label .->", "== label ==>"
+	mermaidTextLinkRe = regexp.MustCompile(`^(<?)(--|==|-\.)\s+(.+?)\s+(-{2,}|={2,}|\.-+)([>ox]?)`)
+)
+
+type mermaidFlow struct {
+	b     strings.Builder
+	path  []string          // Enclosing subgraph keys
+	nodes map[string]string // Node id -> D2 path
+}
+
+func mermaidFlowchart(dir string, lines []string) (string, error) {
+	f := &mermaidFlow{nodes: make(map[string]string)}
+	if d, ok := mermaidDirections[dir]; ok {
+		f.b.WriteString("direction: " + d + "\n")
+	}
+
+	for _, line := range lines {
+		switch keyword := strings.Fields(line)[0]; keyword {
+		case "subgraph":
+			rest := strings.TrimSpace(strings.TrimPrefix(line, "subgraph"))
+			id, label := rest, rest
+			if i := strings.Index(rest, "["); i > 0 && strings.HasSuffix(rest, "]") {
+				id, label = strings.TrimSpace(rest[:i]), rest[i+1:len(rest)-1]
+			}
+			f.path = append(f.path, d2String(mermaidText(id)))
+			f.b.WriteString(strings.Join(f.path, ".") + ": {label: " + d2String(mermaidText(label)) + "}\n")
+		case "end":
+			if len(f.path) > 0 {
+				f.path = f.path[:len(f.path)-1]
+			}
+		case "direction", "classDef", "class", "style", "linkStyle", "click":
+			// Presentation details without a D2 equivalent here
+		default:
+			if err := f.statement(line); err != nil {
+				return "", err
+			}
+		}
+	}
+	return f.b.String(), nil
+}
+
+// statement handles chains such as "A[Text] --> B & C -->|label| D"
+func (f *mermaidFlow) statement(line string) error {
+	from, rest, err := f.nodeGroup(line)
+	if err != nil {
+		return err
+	}
+	for rest != "" {
+		edge, after, ok := mermaidLink(rest)
+		if !ok {
+			return fmt.Errorf("mermaid: cannot parse link in %q", line)
+		}
+		to, remaining, err := f.nodeGroup(after)
+		if err != nil {
+			return err
+		}
+		for _, a := range from {
+			for _, b := range to {
+				f.b.WriteString(a + " " + edge.arrow + " " + b + edge.suffix + "\n")
+			}
+		}
+		from, rest = to, remaining
+	}
+	return nil
+}
+
+// nodeGroup parses "A[text] & B" at the start of s, declaring the nodes
+func (f *mermaidFlow) nodeGroup(s string) ([]string, string, error) {
+	var paths []string
+	for {
+		s = strings.TrimSpace(s)
+		id := mermaidIDRe.FindString(s)
+		if id == "" {
+			return nil, "", fmt.Errorf("mermaid: expected node id at %q", s)
+		}
+		s = s[len(id):]
+
+		shape, label := "", ""
+		for _, sh := range mermaidShapes {
+			if !strings.HasPrefix(s, sh.open) {
+				continue
+			}
+			end := strings.Index(s[len(sh.open):], sh.close)
+			if end < 0 {
+				continue
+			}
+			shape, label = sh.shape, s[len(sh.open):len(sh.open)+end]
+			s = s[len(sh.open)+end+len(sh.close):]
+			break
+		}
+		s = strings.TrimPrefix(s, mermaidClassRe.FindString(s))
+		paths = append(paths, f.declare(id, shape, label))
+
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, "&") {
+			return paths, s, nil
+		}
+		s = s[1:]
+	}
+}
+
+// declare returns the D2 path of a node, writing its shape and label when given.
+// A node belongs to the subgraph it first appears in.
+func (f *mermaidFlow) declare(id, shape, label string) string {
+	p, ok := f.nodes[id]
+	if !ok {
+		p = d2String(id)
+		if len(f.path) > 0 {
+			p = strings.Join(f.path, ".") + "." + p
+		}
+		f.nodes[id] = p
+		if shape == "" {
+			f.b.WriteString(p + "\n")
+		}
+	}
+	if shape != "" {
+		fields := []string{"shape: " + shape, "label: " + d2String(mermaidText(label))}
+		if shape == "rectangle" {
+			fields = append(fields, "style.border-radius: 8")
+		}
+		f.b.WriteString(p + ": {" + strings.Join(fields, "; ") + "}\n")
+	}
+	return p
+}
+
+type mermaidEdge struct {
+	arrow  string // "->", "<->" or "--"
+	suffix string // ": label {styles}"
+}
+
+// mermaidLink parses the link at the start of s
+func mermaidLink(s string) (mermaidEdge, string, bool) {
+	var back, line, head, label string
+	if m := mermaidTextLinkRe.FindStringSubmatch(s); m != nil {
+		back, line, head, label = m[1], m[2]+m[4], m[5], m[3]
+		s = s[len(m[0]):]
+	} else if m := mermaidLinkRe.FindStringSubmatch(s); m != nil {
+		back, line, head, label = m[1], m[2], m[3], m[4]
+		s = s[len(m[0]):]
+	} else {
+		return mermaidEdge{}, s, false
+	}
+
+	edge := mermaidEdge{arrow: "--"}
+	switch {
+	case back != "" && head != "":
+		edge.arrow = "<->"
+	case head != "":
+		edge.arrow = "->"
+	}
+	if label = mermaidText(label); label != "" {
+		edge.suffix = ": " + d2String(label)
+	}
+	switch {
+	case strings.Contains(line, "."):
+		edge.suffix += " {style.stroke-dash: 3}"
+	case strings.HasPrefix(line, "="):
+		edge.suffix += " {style.stroke-width: 3}"
+	}
+	return edge, s, true
+}
+
+// Sequence diagrams
+
+var (
+	mermaidParticipantRe = regexp.MustCompile(`^(participant|actor)\s+(.+?)(?:\s+as\s+(.+))?$`)
+	mermaidMessageRe     = regexp.MustCompile(`^(.+?)\s*(--?)(>>|>|x|\))\s*[+-]?\s*(.+?)\s*:\s*(.*)$`)
+	mermaidNoteRe        = regexp.MustCompile(`^(?i:note)\s+(?:left of|right of|over)\s+([^:,]+?)\s*(?:,[^:]+)?:\s*(.*)$`)
+)
+
+// mermaidGroups are the sequence blocks that become D2 groups; else/and start a sibling
+var mermaidGroups = map[string]bool{"loop": true, "alt": true, "opt": true, "par": true, "critical": true, "break": true, "rect": true}
+
+func mermaidSequence(lines []string) (string, error) {
+	var head, body strings.Builder
+	head.WriteString("shape: sequence_diagram\n")
+
+	// Actors are declared up front, in order of appearance, so groups refer to them
+	declared := make(map[string]bool)
+	actor := func(name, label, shape string) string {
+		key := d2String(strings.TrimSpace(name))
+		if declared[key] {
+			return key
+		}
+		declared[key] = true
+		var fields []string
+		if label != "" {
+			fields = append(fields, "label: "+d2String(mermaidText(label)))
+		}
+		if shape != "" {
+			fields = append(fields, "shape: "+shape)
+		}
+		head.WriteString(key)
+		if len(fields) > 0 {
+			head.WriteString(": {" + strings.Join(fields, "; ") + "}")
+		}
+		head.WriteString("\n")
+		return key
+	}
+
+	depth, groups, notes := 0, 0, 0
+	indent := func() string { return strings.Repeat("  ", depth) }
+	openGroup := func(label string) {
+		groups++
+		body.WriteString(indent() + `"group ` + strconv.Itoa(groups) + `": {` + "\n")
+		depth++
+		body.WriteString(indent() + "label: " + d2String(mermaidText(label)) + "\n")
+	}
+
+	for _, line := range lines {
+		keyword := strings.Fields(line)[0]
+		switch {
+		case mermaidGroups[keyword]:
+			openGroup(line)
+		case keyword == "else" || keyword == "and":
+			if depth > 0 {
+				depth--
+				body.WriteString(indent() + "}\n")
+			}
+			openGroup(line)
+		case keyword == "end":
+			if depth > 0 {
+				depth--
+				body.WriteString(indent() + "}\n")
+			}
+		case keyword == "autonumber" || keyword == "activate" || keyword == "deactivate" || keyword == "title":
+			// No D2 equivalent
+		default:
+			if m := mermaidParticipantRe.FindStringSubmatch(line); m != nil {
+				shape := ""
+				if m[1] == "actor" {
+					shape = "person"
+				}
+				actor(m[2], m[3], shape)
+			} else if m := mermaidNoteRe.FindStringSubmatch(line); m != nil {
+				notes++
+				key := actor(m[1], "", "")
+				body.WriteString(indent() + key + `."note ` + strconv.Itoa(notes) + `": {label: ` + d2String(mermaidText(m[2])) + "; shape: page}\n")
+			} else if m := mermaidMessageRe.FindStringSubmatch(line); m != nil {
+				from, to := actor(m[1], "", ""), actor(m[4], "", "")
+				arrow := "->"
+				if m[3] == ">" {
+					arrow = "--" // Mermaid's "->" is a line without an arrowhead
+				}
+				body.WriteString(indent() + from + " " + arrow + " " + to)
+				if msg := mermaidText(m[5]); msg != "" {
+					body.WriteString(": " + d2String(msg))
+				}
+				if m[2] == "--" {
+					body.WriteString(" {style.stroke-dash: 3}")
+				}
+				body.WriteString("\n")
+			} else {
+				return "", fmt.Errorf("mermaid: cannot parse %q", line)
+			}
+		}
+	}
+	for ; depth > 0; depth-- {
+		body.WriteString(strings.Repeat("  ", depth-1) + "}\n")
+	}
+	return head.String() + body.String(), nil
+}
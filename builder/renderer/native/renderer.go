@@ -4,7 +4,9 @@ package native
 import (
 	_ "embed"
 	"encoding/hex"
+	"fmt"
 	"log"
+	"path/filepath"
 	"runtime"
 	"sync"
 
@@ -31,6 +33,20 @@ type Renderer struct {
 	numWorkers int
 	initOnce   sync.Once
 	katexProg  *goja.Program // Pre-compiled program to share across workers
+
+	extensions    []compiledExtension // KaTeX extensions (e.g. mhchem), run after KaTeX in every worker
+	extensionHash string
+}
+
+// Extension is a KaTeX extension script
+type Extension struct {
+	Name   string
+	Source string
+}
+
+type compiledExtension struct {
+	name string
+	prog *goja.Program
 }
 
 // New creates a new Renderer - workers are lazy-initialized
@@ -69,7 +85,7 @@ func (r *Renderer) ensureInitialized() {
 				instance := newinstance()
 				if instance != nil {
 					// Pass the program to the instance (we could store it in instance or pass it during ensureInitialized)
-					instance.ensureInitialized(r.katexProg)
+					instance.ensureInitialized(r.katexProg, r.extensions)
 					r.pool <- instance
 				} else {
 					log.Printf("⚠️ Failed to initialize worker %d", id)
@@ -82,6 +98,33 @@ func (r *Renderer) ensureInitialized() {
 	})
 }
 
+// LoadExtensions compiles KaTeX extension scripts, such as KaTeX's
+// contrib/mhchem.min.js, to load into every worker. It must be called before
+// the first render.
+func (r *Renderer) LoadExtensions(extensions []Extension) error {
+	h := blake3.New()
+	for _, ext := range extensions {
+		prog, err := goja.Compile(filepath.Base(ext.Name), ext.Source, true)
+		if err != nil {
+			return fmt.Errorf("compile KaTeX extension %s: %w", ext.Name, err)
+		}
+		r.extensions = append(r.extensions, compiledExtension{name: ext.Name, prog: prog})
+		_, _ = h.WriteString(ext.Source)
+	}
+	if len(extensions) > 0 {
+		r.extensionHash = hex.EncodeToString(h.Sum(nil))[:16]
+	}
+	return nil
+}
+
+// ExtensionsHash fingerprints the loaded extensions for math cache keys, "" if none
+func (r *Renderer) ExtensionsHash() string {
+	if r == nil {
+		return ""
+	}
+	return r.extensionHash
+}
+
 func newinstance() *instance {
 	ruler, err := textmeasure.NewRuler()
 	if err != nil {
@@ -94,7 +137,7 @@ func newinstance() *instance {
 }
 
 // ensureInitialized performs lazy initialization of the JS engine
-func (i *instance) ensureInitialized(prog *goja.Program) {
+func (i *instance) ensureInitialized(prog *goja.Program, extensions []compiledExtension) {
 	i.initOnce.Do(func() {
 		// Initialize goja VM with KaTeX
 		vm := goja.New()
@@ -122,6 +165,13 @@ func (i *instance) ensureInitialized(prog *goja.Program) {
 			return
 		}
 
+		// Extensions register themselves on the global katex object
+		for _, ext := range extensions {
+			if _, err := vm.RunProgram(ext.prog); err != nil {
+				log.Printf("⚠️ Failed to load KaTeX extension %s: %v", ext.name, err)
+			}
+		}
+
 		katex := vm.Get("katex")
 		if katex == nil || goja.IsUndefined(katex) {
 			log.Printf("⚠️ KaTeX not found in VM")
@@ -0,0 +1,40 @@
+package renderer
+
+import (
+	"bytes"
+	"html/template"
+	"path"
+	"strings"
+)
+
+const (
+	// DefaultLayout is the template of pages that do not choose a layout
+	DefaultLayout = "layout.html"
+	// PartialsDir holds the templates shared by every layout, relative to the templates directory
+	PartialsDir = "partials"
+)
+
+// LayoutFile returns the template file, relative to the templates directory,
+// that the "layout" front matter selects: "wide" is wide.html. Names that
+// leave the templates directory or point into partials select DefaultLayout.
+func LayoutFile(meta map[string]interface{}) string {
+	name, _ := meta["layout"].(string)
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".html")
+	if name == "" || name != path.Clean(name) || path.IsAbs(name) || strings.HasPrefix(name, "..") ||
+		strings.Contains(name, "\\") || name == PartialsDir || strings.HasPrefix(name, PartialsDir+"/") {
+		return DefaultLayout
+	}
+	return name + ".html"
+}
+
+// executePartial renders partials/<name>, adding .html when name has no extension
+func executePartial(set *template.Template, name string, data interface{}) (template.HTML, error) {
+	if path.Ext(name) == "" {
+		name += ".html"
+	}
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, PartialsDir+"/"+name, data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
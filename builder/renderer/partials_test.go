@@ -0,0 +1,146 @@
+package renderer
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+func TestLayoutFile(t *testing.T) {
+	tests := []struct {
+		layout interface{}
+		want   string
+	}{
+		{nil, "layout.html"},
+		{"", "layout.html"},
+		{"wide", "wide.html"},
+		{"wide.html", "wide.html"},
+		{"docs/api", "docs/api.html"},
+		{"../secret", "layout.html"},
+		{"/etc/page", "layout.html"},
+		{"docs/../wide", "layout.html"},
+		{"partials/header", "layout.html"},
+		{42, "layout.html"},
+	}
+	for _, tt := range tests {
+		if got := LayoutFile(map[string]interface{}{"layout": tt.layout}); got != tt.want {
+			t.Errorf("LayoutFile(%v) = %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+}
+
+func writeTemplates(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRenderPartialsAndLayouts(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplates(t, dir, map[string]string{
+		"layout.html":              `{{ partial "header" . }}<main>{{ .Title }}</main>{{ partialCached "footer.html" . }}`,
+		"wide.html":                `<div class="wide">{{ template "partials/header.html" . }}{{ .Content }}</div>`,
+		"index.html":               `{{ partial "nav/menu" . }}`,
+		"partials/header.html":     `<header>{{ .Title | lower }}</header>`,
+		"partials/footer.html":     `<footer>{{ .Title }}</footer>`,
+		"partials/nav/menu.html":   `<nav>{{ len .Posts }}</nav>`,
+		"partials/escaped.html":    `<p>{{ .Title }}</p>`,
+		"partials/notemplate.text": `ignored`,
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	fs := afero.NewMemMapFs()
+	r := New(false, fs, dir, logger)
+
+	tests := []struct {
+		name string
+		path string
+		data models.PageData
+		want string
+	}{
+		{
+			name: "default layout",
+			path: "a.html",
+			data: models.PageData{Title: "First"},
+			want: "<header>first</header><main>First</main><footer>First</footer>",
+		},
+		{
+			name: "partialCached renders once per build",
+			path: "b.html",
+			data: models.PageData{Title: "Second"},
+			want: "<header>second</header><main>Second</main><footer>First</footer>",
+		},
+		{
+			name: "layout from front matter",
+			path: "c.html",
+			data: models.PageData{Title: "Wide", Content: "<p>body</p>", Meta: map[string]interface{}{"layout": "wide"}},
+			want: `<div class="wide"><header>wide</header><p>body</p></div>`,
+		},
+		{
+			name: "missing layout falls back",
+			path: "d.html",
+			data: models.PageData{Title: "Gone", Meta: map[string]interface{}{"layout": "missing"}},
+			want: "<header>gone</header><main>Gone</main><footer>First</footer>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.RenderPage(tt.path, tt.data)
+			got, err := afero.ReadFile(fs, tt.path)
+			if err != nil {
+				t.Fatalf("page not written: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	r.RenderIndex("index.html", models.PageData{Posts: make([]models.PostMetadata, 3)})
+	if got, _ := afero.ReadFile(fs, "index.html"); string(got) != "<nav>3</nav>" {
+		t.Errorf("index = %q, want nested partial", got)
+	}
+
+	// A new build forgets cached partials and picks up edited templates
+	future := time.Now().Add(time.Minute)
+	writeTemplates(t, dir, map[string]string{"partials/header.html": `<header class="new">{{ .Title }}</header>`})
+	_ = os.Chtimes(filepath.Join(dir, "partials/header.html"), future, future)
+	r.Reload()
+	r.RenderPage("e.html", models.PageData{Title: "Third"})
+	if got, _ := afero.ReadFile(fs, "e.html"); string(got) != `<header class="new">Third</header><main>Third</main><footer>Third</footer>` {
+		t.Errorf("after Reload got %q", got)
+	}
+}
+
+func TestPartialEscaping(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplates(t, dir, map[string]string{
+		"layout.html":          `{{ partial "p" . }}`,
+		"partials/p.html":      `<p title="{{ .Title }}">{{ .Title }}</p>`,
+		"partials/broken.html": `{{ partial "missing" . }}`,
+	})
+	fs := afero.NewMemMapFs()
+	r := New(false, fs, dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r.RenderPage("x.html", models.PageData{Title: `<b>"x"</b>`})
+	got, _ := afero.ReadFile(fs, "x.html")
+	if want := `<p title="&lt;b&gt;&#34;x&#34;&lt;/b&gt;">&lt;b&gt;&#34;x&#34;&lt;/b&gt;</p>`; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !strings.Contains(string(got), "&lt;b&gt;") {
+		t.Error("partial output is not escaped")
+	}
+}
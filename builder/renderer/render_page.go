@@ -10,6 +10,7 @@ import (
 
 func (r *Renderer) RenderPage(path string, data models.PageData) {
 	data.Assets = r.GetAssets()
+	data.Data = r.GetData()
 
 	if err := r.DestFs.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		r.logger.Error("Failed to create directory", "path", path, "error", err)
@@ -37,7 +38,7 @@ func (r *Renderer) RenderPage(path string, data models.PageData) {
 		w = mw
 	}
 
-	if err := r.Layout.Execute(w, data); err != nil {
+	if err := r.pageTemplate(data.Meta).Execute(w, data); err != nil {
 		r.logger.Error("Failed to render layout", "path", path, "error", err)
 	} else {
 		r.RegisterFile(path)
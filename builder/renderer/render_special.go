@@ -11,6 +11,7 @@ import (
 
 func (r *Renderer) RenderIndex(path string, data models.PageData) {
 	data.Assets = r.Assets
+	data.Data = r.GetData()
 
 	if err := r.DestFs.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		r.logger.Error("Failed to create directory", "path", path, "error", err)
@@ -52,6 +53,7 @@ func (r *Renderer) RenderGraph(path string, data models.PageData) {
 		return
 	}
 	data.Assets = r.Assets
+	data.Data = r.GetData()
 
 	if err := r.DestFs.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		r.logger.Error("Failed to create directory", "path", path, "error", err)
@@ -84,6 +86,7 @@ func (r *Renderer) RenderGraph(path string, data models.PageData) {
 
 func (r *Renderer) Render404(path string, data models.PageData) {
 	data.Assets = r.Assets
+	data.Data = r.GetData()
 
 	if err := r.DestFs.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		r.logger.Error("Failed to create directory", "path", path, "error", err)
@@ -1,13 +1,14 @@
 package renderer
 
 import (
+	"fmt"
 	"html/template"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/spf13/afero"
 )
@@ -19,103 +20,176 @@ type Renderer struct {
 	NotFound    *template.Template
 	Assets      map[string]string
 	AssetsMu    sync.RWMutex
+	data        map[string]interface{} // Site data files, shared by every page
+	dataMu      sync.RWMutex
 	Compress    bool
 	DestFs      afero.Fs
 	RenderedMu  sync.RWMutex
 	RenderedSet map[string]bool
+	templateDir string
+	layouts     map[string]*template.Template // Per-page layouts by file, nil when missing
+	layoutsMu   sync.Mutex
 	logger      *slog.Logger
 }
 
 func New(compress bool, destFs afero.Fs, templateDir string, logger *slog.Logger) *Renderer {
-	funcMap := template.FuncMap{
-		"lower":     strings.ToLower,
-		"hasPrefix": strings.HasPrefix,
-		"replace": func(from, to, input string) string {
-			return strings.ReplaceAll(input, from, to)
-		},
-		"now": time.Now,
+	r := &Renderer{
+		Compress:    compress,
+		DestFs:      destFs,
+		RenderedSet: make(map[string]bool),
+		templateDir: templateDir,
+		logger:      logger,
 	}
+	r.loadTemplates()
+	return r
+}
 
-	tc := getGlobalCache(templateDir)
+// Reload re-parses the templates when a file in the templates directory has
+// changed, and forgets the output of partialCached. Call it before each build.
+func (r *Renderer) Reload() {
+	if r.templateDir == "" {
+		return
+	}
+	tc := getGlobalCache(r.templateDir)
+	tc.clearPartials()
+	if !tc.hasTemplatesChanged() {
+		return
+	}
+	r.loadTemplates()
+	r.layoutsMu.Lock()
+	r.layouts = nil
+	r.layoutsMu.Unlock()
+}
+
+func (r *Renderer) loadTemplates() {
+	tc := getGlobalCache(r.templateDir)
 
 	tc.mu.RLock()
-	cacheValid := len(tc.templates) > 0 && !tc.hasTemplatesChanged()
+	cacheValid := len(tc.templates) > 0 && !tc.hasTemplatesChangedLocked()
 	if cacheValid {
-		r := &Renderer{
-			Layout:      tc.templates["layout"],
-			Index:       tc.templates["index"],
-			Graph:       tc.templates["graph"],
-			NotFound:    tc.templates["404"],
-			Compress:    compress,
-			DestFs:      destFs,
-			RenderedSet: make(map[string]bool),
-			logger:      logger,
-		}
+		r.Layout = tc.templates["layout"]
+		r.Index = tc.templates["index"]
+		r.Graph = tc.templates["graph"]
+		r.NotFound = tc.templates["404"]
 		tc.mu.RUnlock()
-		return r
+		return
 	}
 	tc.mu.RUnlock()
 
-	layoutPath := filepath.Join(templateDir, "layout.html")
-	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles(layoutPath)
+	stamp := templateStamp(r.templateDir)
+	layoutPath := filepath.Join(r.templateDir, DefaultLayout)
+	tmpl, err := r.parse(layoutPath)
 	if err != nil {
-		logger.Error("Failed to parse layout template", "path", layoutPath, "error", err)
+		r.logger.Error("Failed to parse layout template", "path", layoutPath, "error", err)
 		// Check if error might be due to template cycle
 		if strings.Contains(err.Error(), "template") && strings.Contains(err.Error(), "not defined") {
-			logger.Error("Possible template cycle detected - check for circular {{ template }} references")
+			r.logger.Error("Possible template cycle detected - check for circular {{ template }} references")
 		}
 		os.Exit(1)
 	}
-	layoutInfo, _ := os.Stat(layoutPath)
-	if layoutInfo != nil {
-		tc.setTemplate("layout", tmpl, layoutInfo.ModTime())
-	}
 
-	indexPath := filepath.Join(templateDir, "index.html")
-	indexTmpl, err := template.New("index.html").Funcs(funcMap).ParseFiles(indexPath)
+	indexTmpl, err := r.parse(filepath.Join(r.templateDir, "index.html"))
 	if err != nil {
-		logger.Warn("Index template not found, falling back to layout", "dir", templateDir, "error", err)
+		r.logger.Warn("Index template not found, falling back to layout", "dir", r.templateDir, "error", err)
 		indexTmpl = nil
-	} else {
-		indexInfo, _ := os.Stat(indexPath)
-		if indexInfo != nil {
-			tc.setTemplate("index", indexTmpl, indexInfo.ModTime())
-		}
 	}
 
-	graphPath := filepath.Join(templateDir, "graph.html")
-	graphTmpl, err := template.ParseFiles(graphPath)
+	graphTmpl, err := r.parse(filepath.Join(r.templateDir, "graph.html"))
 	if err != nil {
-		logger.Warn("Graph template not found, skipping graph page", "dir", templateDir, "error", err)
-	} else {
-		graphInfo, _ := os.Stat(graphPath)
-		if graphInfo != nil {
-			tc.setTemplate("graph", graphTmpl, graphInfo.ModTime())
-		}
+		r.logger.Warn("Graph template not found, skipping graph page", "dir", r.templateDir, "error", err)
+		graphTmpl = nil
 	}
 
-	notFoundPath := filepath.Join(templateDir, "404.html")
-	notFoundTmpl, err := template.New("404.html").Funcs(funcMap).ParseFiles(notFoundPath)
+	notFoundTmpl, err := r.parse(filepath.Join(r.templateDir, "404.html"))
 	if err != nil {
-		logger.Warn("404 template not found, falling back to layout", "dir", templateDir, "error", err)
+		r.logger.Warn("404 template not found, falling back to layout", "dir", r.templateDir, "error", err)
 		notFoundTmpl = nil
-	} else {
-		notFoundInfo, _ := os.Stat(notFoundPath)
-		if notFoundInfo != nil {
-			tc.setTemplate("404", notFoundTmpl, notFoundInfo.ModTime())
+	}
+
+	tc.set(map[string]*template.Template{
+		"layout": tmpl,
+		"index":  indexTmpl,
+		"graph":  graphTmpl,
+		"404":    notFoundTmpl,
+	}, stamp)
+	r.Layout, r.Index, r.Graph, r.NotFound = tmpl, indexTmpl, graphTmpl, notFoundTmpl
+}
+
+// parse reads a template file together with the partials, which are defined
+// as "partials/<file>" in every template
+func (r *Renderer) parse(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tmpl *template.Template
+	set := func() *template.Template { return tmpl }
+	tmpl = template.New(filepath.Base(path)).Funcs(funcMap(set, getGlobalCache(r.templateDir)))
+	if err := r.parsePartials(tmpl); err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.Parse(string(content)); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func (r *Renderer) parsePartials(tmpl *template.Template) error {
+	dir := filepath.Join(r.templateDir, PartialsDir)
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".html" {
+			return nil
+		}
+		rel, err := filepath.Rel(r.templateDir, path)
+		if err != nil {
+			return err
 		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := tmpl.New(filepath.ToSlash(rel)).Parse(string(content)); err != nil {
+			return fmt.Errorf("partial %s: %w", filepath.ToSlash(rel), err)
+		}
+		return nil
+	})
+}
+
+// pageTemplate returns the layout a page's "layout" front matter selects,
+// falling back to layout.html
+func (r *Renderer) pageTemplate(meta map[string]interface{}) *template.Template {
+	file := LayoutFile(meta)
+	if file == DefaultLayout {
+		if name, _ := meta["layout"].(string); name != "" && strings.TrimSuffix(name, ".html") != "layout" {
+			r.logger.Warn("Invalid layout name, using layout.html", "layout", name)
+		}
+		return r.Layout
 	}
 
-	return &Renderer{
-		Layout:      tmpl,
-		Index:       indexTmpl,
-		Graph:       graphTmpl,
-		NotFound:    notFoundTmpl,
-		Compress:    compress,
-		DestFs:      destFs,
-		RenderedSet: make(map[string]bool),
-		logger:      logger,
+	r.layoutsMu.Lock()
+	defer r.layoutsMu.Unlock()
+	if r.layouts == nil {
+		r.layouts = make(map[string]*template.Template)
+	}
+	tmpl, ok := r.layouts[file]
+	if !ok {
+		var err error
+		if tmpl, err = r.parse(filepath.Join(r.templateDir, file)); err != nil {
+			r.logger.Warn("Failed to load layout, using layout.html", "layout", file, "error", err)
+			tmpl = nil
+		}
+		r.layouts[file] = tmpl // Remember failures too, so they are reported once
 	}
+	if tmpl == nil {
+		return r.Layout
+	}
+	return tmpl
 }
 
 func (r *Renderer) RegisterFile(path string) {
@@ -144,6 +218,12 @@ func (r *Renderer) SetAssets(assets map[string]string) {
 	r.AssetsMu.Lock()
 	defer r.AssetsMu.Unlock()
 	r.Assets = assets
+	getGlobalCache(r.templateDir).site.setAssets(assets)
+}
+
+// SetBaseURL sets the site URL that absURL, relURL and asset build on
+func (r *Renderer) SetBaseURL(baseURL string) {
+	getGlobalCache(r.templateDir).site.setBaseURL(baseURL)
 }
 
 func (r *Renderer) GetAssets() map[string]string {
@@ -155,3 +235,17 @@ func (r *Renderer) GetAssets() map[string]string {
 	}
 	return copy
 }
+
+// SetData sets the site data passed to every template as .Data
+func (r *Renderer) SetData(data map[string]interface{}) {
+	r.dataMu.Lock()
+	defer r.dataMu.Unlock()
+	r.data = data
+}
+
+// GetData returns the site data. Templates only read it, so it is not copied.
+func (r *Renderer) GetData() map[string]interface{} {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	return r.data
+}
@@ -1,76 +1,83 @@
 package renderer
 
 import (
+	"fmt"
 	"html/template"
-	"os"
+	"io/fs"
 	"path/filepath"
+	"strings"
 	"sync"
-	"time"
 )
 
 type templateCache struct {
 	templates   map[string]*template.Template
-	mtimes      map[string]time.Time
+	stamp       string    // templateStamp of the parsed files
+	partials    sync.Map  // Output of partialCached by name and keys
+	site        siteFuncs // Settings read by the URL and asset functions
 	templateDir string
 	mu          sync.RWMutex
-	lastCheck   time.Time
-	checkTTL    time.Duration // How often to re-check mtimes
 }
 
 var (
-	globalCache     *templateCache
-	globalCacheOnce sync.Once
+	globalCaches   = make(map[string]*templateCache)
+	globalCachesMu sync.Mutex
 )
 
+// getGlobalCache returns the parsed templates of a directory, shared by the
+// renderers of the process
 func getGlobalCache(templateDir string) *templateCache {
-	globalCacheOnce.Do(func() {
-		globalCache = &templateCache{
+	globalCachesMu.Lock()
+	defer globalCachesMu.Unlock()
+	tc, ok := globalCaches[templateDir]
+	if !ok {
+		tc = &templateCache{
 			templates:   make(map[string]*template.Template),
-			mtimes:      make(map[string]time.Time),
 			templateDir: templateDir,
-			checkTTL:    2 * time.Second, // Only check mtimes every 2s
 		}
-	})
-	return globalCache
+		globalCaches[templateDir] = tc
+	}
+	return tc
 }
 
+// hasTemplatesChanged reports whether a template or partial was edited,
+// added or removed since the templates were parsed
 func (tc *templateCache) hasTemplatesChanged() bool {
-	now := time.Now()
-
 	tc.mu.RLock()
-	if now.Sub(tc.lastCheck) < tc.checkTTL {
-		tc.mu.RUnlock()
-		return false // Skip check, assume unchanged within TTL
-	}
-	tc.mu.RUnlock()
-
-	templateFiles := []string{"layout.html", "index.html", "graph.html", "404.html"}
-	changed := false
-
-	for _, fname := range templateFiles {
-		path := filepath.Join(tc.templateDir, fname)
-		info, err := os.Stat(path)
-		if err != nil {
-			continue
-		}
-
-		cachedMtime, exists := tc.mtimes[fname]
-		if !exists || info.ModTime().After(cachedMtime) {
-			changed = true
-			break
-		}
-	}
+	defer tc.mu.RUnlock()
+	return tc.hasTemplatesChangedLocked()
+}
 
-	tc.mu.Lock()
-	tc.lastCheck = now
-	tc.mu.Unlock()
+func (tc *templateCache) hasTemplatesChangedLocked() bool {
+	return templateStamp(tc.templateDir) != tc.stamp
+}
 
-	return changed
+// clearPartials forgets the output of partialCached
+func (tc *templateCache) clearPartials() {
+	tc.partials.Range(func(key, _ any) bool {
+		tc.partials.Delete(key)
+		return true
+	})
 }
 
-func (tc *templateCache) setTemplate(name string, tmpl *template.Template, mtime time.Time) {
+func (tc *templateCache) set(templates map[string]*template.Template, stamp string) {
 	tc.mu.Lock()
 	defer tc.mu.Unlock()
-	tc.templates[name] = tmpl
-	tc.mtimes[name] = mtime
+	tc.templates = templates
+	tc.stamp = stamp
+}
+
+// templateStamp lists the HTML files under dir with their sizes and
+// modification times
+func templateStamp(dir string) string {
+	var b strings.Builder
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".html" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			fmt.Fprintf(&b, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return b.String()
 }
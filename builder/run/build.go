@@ -3,6 +3,8 @@ package run
 import (
 	"context"
 	"fmt"
+	"html/template"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/Kush-Singh-26/kosh/builder/cache"
 	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/sitedata"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 )
 
@@ -54,16 +57,19 @@ func (b *Builder) Build(ctx context.Context) error {
 		}
 	}()
 
-	globalDependencies := []string{
-		filepath.Join(cfg.TemplateDir, "layout.html"),
-		filepath.Join(cfg.TemplateDir, "index.html"),
-		filepath.Join(cfg.TemplateDir, "404.html"),
-		filepath.Join(cfg.TemplateDir, "graph.html"),
+	b.renderService.ReloadTemplates()
+
+	// Every template counts: layouts chosen in front matter and partials too
+	globalDependencies := templateFiles(cfg.TemplateDir)
+	globalDependencies = append(globalDependencies,
 		filepath.Join(cfg.StaticDir, "css/layout.css"),
 		filepath.Join(cfg.StaticDir, "css/theme.css"),
 		"kosh.yaml",
 		"builder/generators/pwa.go",
-	}
+	)
+	// Data files are read by templates only, so like templates they re-render
+	// pages without reprocessing posts; directories change when files come and go
+	globalDependencies = append(globalDependencies, sitedata.Paths(b.SourceFs, cfg.DataDir)...)
 	forceSocialRebuild := false
 	shouldForce := b.cfg.ForceRebuild
 	var affectedPosts []string
@@ -115,6 +121,7 @@ func (b *Builder) Build(ctx context.Context) error {
 	// 2. Static Assets (MUST complete before posts to populate Assets map)
 	fmt.Println("📦 Building assets...")
 	b.copyStaticAndBuildAssets(ctx)
+	b.loadData()
 	_ = utils.WriteFileVFS(b.DestFs, filepath.Join(b.cfg.OutputDir, ".nojekyll"), []byte(""))
 
 	if len(affectedPosts) > 0 && b.cacheService != nil {
@@ -147,7 +154,7 @@ func (b *Builder) Build(ctx context.Context) error {
 			if info, err := os.Stat(dep); err == nil {
 				if info.ModTime().After(lastBuildTime) {
 					// Check if it's a template
-					if strings.HasSuffix(dep, ".html") || strings.HasSuffix(dep, ".css") {
+					if strings.HasSuffix(dep, ".html") || strings.HasSuffix(dep, ".css") || b.isDataPath(dep) {
 						isTemplateOnly = true
 					} else {
 						isTemplateOnly = false
@@ -197,6 +204,9 @@ func (b *Builder) Build(ctx context.Context) error {
 				Pinned:      cached.Pinned,
 				Draft:       cached.Draft,
 				DateObj:     cached.Date,
+				LastMod:     cached.LastMod,
+				Summary:     template.HTML(cached.Summary),
+				FeedSummary: template.HTML(cached.FeedSummary),
 				Version:     cached.Version,
 			}
 
@@ -211,32 +221,7 @@ func (b *Builder) Build(ctx context.Context) error {
 
 			// Indexed Posts - use batch-fetched search records
 			if searchMeta, ok := searchRecords[id]; ok && searchMeta != nil {
-				// Reconstruct PostRecord with relative link (not full URL)
-				relLink := strings.ToLower(strings.Replace(cached.Path, ".md", ".html", 1))
-
-				// Pre-compute normalized fields
-				normalizedTags := make([]string, len(cached.Tags))
-				for i, t := range cached.Tags {
-					normalizedTags[i] = strings.ToLower(t)
-				}
-
-				rec := models.PostRecord{
-					Title:           searchMeta.Title,
-					NormalizedTitle: searchMeta.NormalizedTitle,
-					Link:            relLink,
-					Description:     cached.Description,
-					Tags:            cached.Tags,
-					NormalizedTags:  normalizedTags,
-					Content:         searchMeta.Content,
-					Version:         cached.Version,
-				}
-				rec.ID = len(indexedPosts)
-
-				indexedPosts = append(indexedPosts, models.IndexedPost{
-					Record:    rec,
-					WordFreqs: searchMeta.BM25Data,
-					DocLen:    searchMeta.DocLen,
-				})
+				indexedPosts = append(indexedPosts, indexedPostFromCache(id, cached, searchMeta, len(indexedPosts)))
 			}
 		}
 
@@ -312,6 +297,8 @@ func (b *Builder) Build(ctx context.Context) error {
 }
 
 func (b *Builder) copyStaticAndBuildAssets(ctx context.Context) {
+	// Posts can pick an image profile in front matter; resolve those before encoding
+	b.imageProfiles.SetOverrides(b.postService.ImageProfileOverrides())
 	if err := b.assetService.Build(ctx); err != nil {
 		b.logger.Error("Failed to build assets", "error", err)
 	}
@@ -329,3 +316,64 @@ func (b *Builder) processPosts(ctx context.Context, shouldForce, forceSocialRebu
 func (b *Builder) renderCachedPosts() {
 	b.postService.RenderCachedPosts()
 }
+
+// indexedPostFromCache reconstructs a search index entry from cached records
+func indexedPostFromCache(id string, cached *cache.PostMeta, searchMeta *cache.SearchRecord, pos int) models.IndexedPost {
+	// Reconstruct PostRecord with relative link (not full URL)
+	relLink := utils.PostHTMLPath(cached.Path)
+
+	// Pre-compute normalized fields
+	normalizedTags := make([]string, len(cached.Tags))
+	for i, t := range cached.Tags {
+		normalizedTags[i] = strings.ToLower(t)
+	}
+
+	return models.IndexedPost{
+		Record: models.PostRecord{
+			ID:              pos,
+			Title:           searchMeta.Title,
+			NormalizedTitle: searchMeta.NormalizedTitle,
+			Link:            relLink,
+			Description:     cached.Description,
+			Tags:            cached.Tags,
+			NormalizedTags:  normalizedTags,
+			Content:         searchMeta.Content,
+			Version:         cached.Version,
+			Analyzer:        searchMeta.Analyzer,
+		},
+		WordFreqs:   searchMeta.BM25Data,
+		DocLen:      searchMeta.DocLen,
+		PostID:      id,
+		Stems:       searchMeta.Stems,
+		Synonyms:    searchMeta.Synonyms,
+		SynonymsKey: searchMeta.SynonymsKey,
+	}
+}
+
+// loadData reads the data files for templates and the search synonyms
+func (b *Builder) loadData() {
+	data, err := sitedata.Load(b.SourceFs, b.cfg.DataDir)
+	if err != nil {
+		b.logger.Warn("Failed to load data files", "dir", b.cfg.DataDir, "error", err)
+	}
+	b.renderService.SetData(data)
+
+	synonyms, err := b.cfg.SearchSynonyms(b.SourceFs)
+	if err != nil {
+		b.logger.Warn("Failed to load search synonyms", "error", err)
+	}
+	b.synonyms = synonyms
+	b.postService.SetSearchSynonyms(synonyms)
+}
+
+// templateFiles returns the HTML files under the templates directory
+func templateFiles(dir string) []string {
+	var files []string
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".html" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
@@ -8,12 +8,15 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/spf13/afero"
 	"github.com/yuin/goldmark"
 	"gopkg.in/yaml.v3"
 
+	"github.com/Kush-Singh-26/kosh/builder/bibliography"
 	"github.com/Kush-Singh-26/kosh/builder/cache"
 	"github.com/Kush-Singh-26/kosh/builder/config"
 	"github.com/Kush-Singh-26/kosh/builder/metrics"
@@ -51,6 +54,15 @@ type Builder struct {
 	// Shared markdown parser for reuse in incremental builds
 	md goldmark.Markdown
 
+	// KaTeX/D2 worker pool, also used for math in feeds
+	nativeRenderer *native.Renderer
+
+	// Image encoding profiles shared by the asset pipeline and the parser
+	imageProfiles *utils.ImageProfiles
+
+	// Search synonyms from the config and the data dir, reloaded with the data files
+	synonyms map[string][]string
+
 	// Build coordination - prevents concurrent builds during watch mode
 	buildMu sync.Mutex
 }
@@ -104,6 +116,10 @@ func newBuilderWithConfig(cfg *config.Config) *Builder {
 		_ = os.MkdirAll(staticPath, 0755)
 	}
 
+	if err := cfg.FrontMatter.Schema.Check(); err != nil {
+		logger.Warn("Invalid front matter schema", "error", err)
+	}
+
 	// Initialize build metrics
 	buildMetrics := metrics.NewBuildMetrics()
 
@@ -181,12 +197,45 @@ func newBuilderWithConfig(cfg *config.Config) *Builder {
 	}
 	cfg.ThemeMetadata = themeMetadata
 
+	// KaTeX extensions must be loaded before the first math render
+	var katexExtensions []native.Extension
+	for _, name := range cfg.Math.Extensions {
+		src, err := afero.ReadFile(sourceFs, name)
+		if err != nil {
+			logger.Warn("Failed to read KaTeX extension", "path", name, "error", err)
+			continue
+		}
+		katexExtensions = append(katexExtensions, native.Extension{Name: name, Source: string(src)})
+	}
+	if err := nativeRenderer.LoadExtensions(katexExtensions); err != nil {
+		logger.Warn("Failed to load KaTeX extensions", "error", err)
+	}
+
 	// Create sync.Map for diagram cache (thread-safe, no mutex needed)
 	diagramCache := &sync.Map{}
 
 	// Create core components
-	md := mdParser.New(cfg.BaseURL, nativeRenderer, diagramCache)
+	imageProfiles := cfg.ImageProfiles()
+	imageResolver := utils.NewImageResolver(sourceFs, []string{"static", cfg.StaticDir}, cfg.CompressImages, imageProfiles)
+	imageResolver.EnablePlaceholders(cfg.Images.Placeholder, cfg.CacheDir+"/images")
+	md := mdParser.New(cfg.BaseURL, nativeRenderer, diagramCache,
+		mdParser.WithImages(imageResolver, cfg.Images.Sizes),
+		mdParser.WithEquationNumbering(cfg.Math.Numbering),
+		mdParser.WithDiagramDefaults(mdParser.DiagramOptions{
+			Layout:    cfg.Diagrams.Layout,
+			Theme:     cfg.Diagrams.Theme,
+			DarkTheme: cfg.Diagrams.DarkTheme,
+			Sketch:    cfg.Diagrams.Sketch,
+			Pad:       cfg.Diagrams.Pad,
+			Scale:     cfg.Diagrams.Scale,
+		}),
+		mdParser.WithHighlightStyle(cfg.Highlight.Style),
+		mdParser.WithCitations(citationStyle(cfg.Citations.Style, logger), cfg.Citations.Title),
+		mdParser.WithSidenotes(cfg.Footnotes.Sidenotes),
+		mdParser.WithSummaryWords(cfg.Summary.Words),
+	)
 	rnd := renderer.New(cfg.CompressImages, destFs, cfg.TemplateDir, logger)
+	rnd.SetBaseURL(cfg.BaseURL)
 
 	// Create Services
 	var cacheSvc services.CacheService
@@ -195,8 +244,8 @@ func newBuilderWithConfig(cfg *config.Config) *Builder {
 	}
 
 	renderSvc := services.NewRenderService(rnd, logger)
-	assetSvc := services.NewAssetService(sourceFs, destFs, cfg, renderSvc, logger)
-	postSvc := services.NewPostService(cfg, cacheSvc, renderSvc, logger, buildMetrics, md, nativeRenderer, sourceFs, destFs, diagramAdapter)
+	assetSvc := services.NewAssetService(sourceFs, destFs, cfg, imageProfiles, renderSvc, logger)
+	postSvc := services.NewPostService(cfg, cacheSvc, renderSvc, logger, buildMetrics, md, nativeRenderer, sourceFs, destFs, diagramAdapter, imageResolver)
 
 	builder := &Builder{
 		cfg:            cfg,
@@ -210,6 +259,8 @@ func newBuilderWithConfig(cfg *config.Config) *Builder {
 		SourceFs:       sourceFs,
 		DestFs:         destFs,
 		md:             md,
+		nativeRenderer: nativeRenderer,
+		imageProfiles:  imageProfiles,
 	}
 
 	return builder
@@ -223,6 +274,19 @@ func generateCacheID(cfg *config.Config) string {
 		"goldmark:1.7",
 		"d2:0.7",
 		"katex:embedded",
+		"analyzers:2",      // CJK text indexes unigrams next to bigrams
+		"search-records:2", // Search records carry stems and synonym terms
+		mathFingerprint(cfg.Math),
+		imagesFingerprint(cfg.Images),
+		fmt.Sprintf("placeholder:%s", cfg.Images.Placeholder),
+		fmt.Sprintf("diagrams:%+v", cfg.Diagrams),
+		fmt.Sprintf("citations:%+v", cfg.Citations),
+		fmt.Sprintf("footnotes:%+v", cfg.Footnotes),
+		fmt.Sprintf("notebooks:%+v", cfg.Notebooks),
+		fmt.Sprintf("frontmatter:%+v", cfg.FrontMatter),
+		fmt.Sprintf("timezone:%s", cfg.Timezone),
+		fmt.Sprintf("dates:%+v", cfg.Dates),
+		fmt.Sprintf("summary:%+v", cfg.Summary),
 	}
 
 	combined := ""
@@ -233,6 +297,44 @@ func generateCacheID(cfg *config.Config) string {
 	return cache.HashString(combined)
 }
 
+// citationStyle parses citations.style, warning about unknown styles
+func citationStyle(name string, logger *slog.Logger) bibliography.Style {
+	style, ok := bibliography.ParseStyle(name)
+	if !ok {
+		logger.Warn("Unknown citation style, using the default", "style", name, "default", bibliography.DefaultStyle)
+		return bibliography.DefaultStyle
+	}
+	return style
+}
+
+// mathFingerprint covers the math settings baked into cached post HTML
+func mathFingerprint(m config.MathConfig) string {
+	names := make([]string, 0, len(m.Macros))
+	for name := range m.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := []string{"math", m.Numbering, m.Output}
+	for _, name := range names {
+		parts = append(parts, name+"="+m.Macros[name])
+	}
+	parts = append(parts, m.Extensions...)
+	return strings.Join(parts, ":")
+}
+
+// imagesFingerprint covers the image settings baked into cached post HTML:
+// the widths, formats and sizes of srcset and <picture> markup, and the
+// profiles and rules that choose them
+func imagesFingerprint(img config.ImagesConfig) string {
+	data, _ := yaml.Marshal(struct {
+		Default  config.ImageProfile
+		Sizes    string
+		Profiles map[string]config.ImageProfile
+		Rules    []config.ImageRule
+	}{img.ImageProfile, img.Sizes, img.Profiles, img.Rules})
+	return "images:" + string(data)
+}
+
 // Config returns the builder's configuration
 func (b *Builder) Config() *config.Config {
 	return b.cfg
@@ -0,0 +1,47 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/Kush-Singh-26/kosh/builder/config"
+)
+
+// TestGenerateCacheID checks that settings baked into cached post HTML change
+// the cache ID and that equal configs give equal IDs
+func TestGenerateCacheID(t *testing.T) {
+	base := func() *config.Config {
+		lossless := true
+		return &config.Config{Images: config.ImagesConfig{
+			ImageProfile: config.ImageProfile{Widths: []int{480, 800}, Formats: []string{"webp"}},
+			Sizes:        "100vw",
+			Profiles:     map[string]config.ImageProfile{"screenshot": {Lossless: &lossless}},
+		}}
+	}
+	id := generateCacheID(base())
+	if again := generateCacheID(base()); again != id {
+		t.Fatalf("equal configs give different cache IDs: %s, %s", id, again)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+	}{
+		{"image widths", func(c *config.Config) { c.Images.Widths = []int{640} }},
+		{"image formats", func(c *config.Config) { c.Images.Formats = []string{"avif", "webp"} }},
+		{"image sizes", func(c *config.Config) { c.Images.Sizes = "50vw" }},
+		{"image profiles", func(c *config.Config) { c.Images.Profiles["hero"] = config.ImageProfile{MaxWidth: 2400} }},
+		{"placeholder mode", func(c *config.Config) { c.Images.Placeholder = "lqip" }},
+		{"image rules", func(c *config.Config) {
+			c.Images.Rules = []config.ImageRule{{Match: "**/*.png", Profile: "screenshot"}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.change(cfg)
+			if generateCacheID(cfg) == id {
+				t.Errorf("changing %s keeps the cache ID", tt.name)
+			}
+		})
+	}
+}
@@ -3,12 +3,16 @@ package run
 import (
 	"context"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	gParser "github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
 
+	"github.com/Kush-Singh-26/kosh/builder/frontmatter"
+	"github.com/Kush-Singh-26/kosh/builder/notebook"
 	mdParser "github.com/Kush-Singh-26/kosh/builder/parser"
+	"github.com/Kush-Singh-26/kosh/builder/renderer"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 
 	"github.com/spf13/afero"
@@ -22,9 +26,12 @@ func (b *Builder) invalidateForTemplate(templatePath string) []string {
 		relTmpl, _ := utils.SafeRel(b.cfg.TemplateDir, tp)
 		relTmpl = filepath.ToSlash(relTmpl)
 
-		if relTmpl == "layout.html" {
+		if relTmpl == renderer.DefaultLayout {
 			return nil // Layout changes affect everything
 		}
+		if strings.HasPrefix(relTmpl, renderer.PartialsDir+"/") {
+			return []string{} // Any template may use a partial, so every page is re-rendered from the cache
+		}
 
 		if b.cacheService != nil {
 			ids, err := b.cacheService.GetPostsByTemplate(relTmpl)
@@ -33,7 +40,7 @@ func (b *Builder) invalidateForTemplate(templatePath string) []string {
 				if err == nil && len(posts) > 0 {
 					paths := make([]string, 0, len(posts))
 					for _, post := range posts {
-						paths = append(paths, post.Path)
+						paths = append(paths, filepath.Join(b.cfg.ContentDir, post.Path)) // Cached paths are relative to the content directory
 					}
 					return paths
 				}
@@ -44,6 +51,9 @@ func (b *Builder) invalidateForTemplate(templatePath string) []string {
 	if strings.HasPrefix(tp, filepath.ToSlash(b.cfg.StaticDir)) {
 		return nil
 	}
+	if b.isDataPath(tp) {
+		return []string{} // Pages are re-rendered from the cache
+	}
 
 	switch tp {
 	case "kosh.yaml":
@@ -69,8 +79,9 @@ func (b *Builder) BuildChanged(ctx context.Context, changedPath string) {
 
 	b.logger.Info("⚡ Change detected", "path", changedPath)
 
-	// Handle markdown files - single post rebuild
-	if strings.HasSuffix(changedPath, ".md") && strings.HasPrefix(changedPath, b.cfg.ContentDir) {
+	// Handle markdown and notebook files - single post rebuild
+	isPost := strings.HasSuffix(changedPath, ".md") || notebook.IsNotebook(changedPath)
+	if isPost && strings.HasPrefix(changedPath, b.cfg.ContentDir) {
 		b.buildSinglePost(ctx, changedPath)
 		if err := utils.SyncVFS(b.DestFs, b.cfg.OutputDir, b.renderService.GetRenderedFiles()); err != nil {
 			b.logger.Error("Sync failed", "error", err)
@@ -80,6 +91,24 @@ func (b *Builder) BuildChanged(ctx context.Context, changedPath string) {
 		return
 	}
 
+	// Handle embedded code files - rebuild only the posts that embed them
+	if posts := b.postsIncluding(changedPath); len(posts) > 0 {
+		b.logger.Info("📎 Embedded file changed, rebuilding posts...", "posts", len(posts))
+		for _, postPath := range posts {
+			if err := b.postService.ProcessSingle(ctx, postPath); err != nil {
+				b.logger.Error("Failed to process single post", "path", postPath, "error", err)
+			}
+		}
+		b.regenerateSearchIndexFromCache()
+		b.SaveCaches()
+		if err := utils.SyncVFS(b.DestFs, b.cfg.OutputDir, b.renderService.GetRenderedFiles()); err != nil {
+			b.logger.Error("Sync failed", "error", err)
+			return
+		}
+		b.renderService.ClearRenderedFiles()
+		return
+	}
+
 	// Handle CSS/JS changes - do full rebuild to update HTML with new asset hashes
 	ext := strings.ToLower(filepath.Ext(changedPath))
 	if (ext == ".css" || ext == ".js") && b.isAssetPath(changedPath) {
@@ -100,6 +129,34 @@ func (b *Builder) BuildChanged(ctx context.Context, changedPath string) {
 	b.SaveCaches()
 }
 
+// postsIncluding returns the source paths of the posts that embed a file
+func (b *Builder) postsIncluding(path string) []string {
+	if b.cacheService == nil || strings.HasSuffix(path, ".md") || notebook.IsNotebook(path) {
+		return nil
+	}
+	ids, err := b.cacheService.GetPostsByInclude(filepath.ToSlash(filepath.Clean(path)))
+	if err != nil || len(ids) == 0 {
+		return nil
+	}
+	posts, err := b.cacheService.GetPostsByIDs(ids)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(posts))
+	for _, post := range posts {
+		paths = append(paths, filepath.Join(b.cfg.ContentDir, post.Path))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// isDataPath checks if a path is the data directory or within it
+func (b *Builder) isDataPath(path string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+	dataDir := filepath.ToSlash(filepath.Clean(b.cfg.DataDir))
+	return path == dataDir || strings.HasPrefix(path, dataDir+"/")
+}
+
 // isAssetPath checks if a path is within the static assets directories
 func (b *Builder) isAssetPath(path string) bool {
 	path = filepath.ToSlash(path)
@@ -120,13 +177,38 @@ func (b *Builder) buildSinglePost(ctx context.Context, path string) {
 		return
 	}
 
+	// Notebooks are compared by their content, not by image URLs in the
+	// converted Markdown, so the URL is left out here
+	var nb *notebook.Notebook
+	if notebook.IsNotebook(path) {
+		if nb, err = notebook.Convert(source, notebook.Options{Language: b.cfg.Notebooks.Language}); err != nil {
+			b.logger.Error("Failed to convert notebook", "path", path, "error", err)
+			return
+		}
+		source = nb.Markdown
+	}
+	if source, err = frontmatter.Normalize(source); err != nil {
+		b.logger.Error("Invalid front matter", "path", path, "error", err)
+		return
+	}
+
+	source, _, _ = mdParser.EmbedCodeFiles(b.SourceFs, path, b.cfg.SnippetsDir, source)
+	bib, _ := mdParser.LoadBibliography(b.SourceFs, path, source)
+	var bibData []byte
+	if bib != nil {
+		bibData = bib.Data
+	}
+
 	context := gParser.NewContext()
 	context.Set(mdParser.ContextKeyFilePath, path)
 	reader := text.NewReader(source)
 	b.md.Parser().Parse(reader, gParser.WithContext(context))
 	metaData := meta.Get(context)
 	newFrontmatterHash, _ := utils.GetFrontmatterHash(metaData)
-	newBodyHash := utils.GetBodyHash(source)
+	newBodyHash := utils.GetBodyHash(source, bibData)
+	if nb != nil {
+		newBodyHash = utils.GetContentHash(nb.Stable, bibData)
+	}
 
 	relPath, _ := utils.SafeRel(b.cfg.ContentDir, path)
 
@@ -169,6 +251,8 @@ func (b *Builder) buildSinglePost(ctx context.Context, path string) {
 				b.logger.Error("Build failed", "error", err)
 				return
 			}
+		} else {
+			b.regenerateSearchIndexFromCache()
 		}
 		b.SaveCaches()
 	} else {
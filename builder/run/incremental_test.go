@@ -92,6 +92,13 @@ func TestInvalidateForTemplate(t *testing.T) {
 			staticDir:    staticDir,
 			wantNil:      true,
 		},
+		{
+			name:         "partial changes return empty",
+			templatePath: "themes/test-theme/templates/partials/header.html",
+			templateDir:  templateDir,
+			staticDir:    staticDir,
+			wantNil:      false,
+		},
 		{
 			name:         "pwa.go changes return empty",
 			templatePath: "builder/generators/pwa.go",
@@ -116,3 +123,31 @@ func TestInvalidateForTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestIsDataPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		dataDir string
+		want    bool
+	}{
+		{"data file", "data/projects.yaml", "data", true},
+		{"nested data file", "data/books/2024.csv", "data", true},
+		{"data directory", "data", "data", true},
+		{"directory with the same prefix", "database/x.yaml", "data", false},
+		{"content", "content/post.md", "data", false},
+		{"custom directory", "site/data/a.json", "./site/data", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{cfg: &config.Config{DataDir: tt.dataDir}}
+			if got := b.isDataPath(tt.path); got != tt.want {
+				t.Errorf("isDataPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+			if tt.want && b.invalidateForTemplate(tt.path) == nil {
+				t.Errorf("invalidateForTemplate(%q) forces a full rebuild", tt.path)
+			}
+		})
+	}
+}
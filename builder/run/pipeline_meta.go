@@ -1,15 +1,47 @@
 package run
 
 import (
+	"fmt"
+	"html"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
+	"github.com/Kush-Singh-26/kosh/builder/cache"
 	"github.com/Kush-Singh-26/kosh/builder/generators"
 	"github.com/Kush-Singh-26/kosh/builder/models"
+	mdParser "github.com/Kush-Singh-26/kosh/builder/parser"
+	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
+	"github.com/Kush-Singh-26/kosh/builder/search"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 )
 
+// feedDescription renders the math in feed item descriptions with math.feedOutput,
+// since feed readers do not load KaTeX's CSS
+func (b *Builder) feedDescription() func(string) string {
+	if b.nativeRenderer == nil {
+		return nil
+	}
+	var mu sync.Mutex
+	cache := make(map[string]string)
+	if b.diagramAdapter != nil {
+		cache = b.diagramAdapter.AsMap()
+	}
+	opts := mdParser.MathOptions{Macros: native.MergeMacros(b.cfg.Math.Macros), Output: b.cfg.Math.FeedOutput}
+
+	return func(desc string) string {
+		if !strings.Contains(desc, "$") && !strings.Contains(desc, "\\(") {
+			return desc
+		}
+		rendered, _, mathErrors := mdParser.RenderMathForHTML(html.EscapeString(desc), b.nativeRenderer, cache, &mu, opts)
+		for _, e := range mathErrors {
+			b.logger.Warn("Failed to render math in feed description", "expression", e.LaTeX, "error", e.Message)
+		}
+		return rendered
+	}
+}
+
 func (b *Builder) generateMetadata(allContent []models.PostMetadata, tagMap map[string][]models.PostMetadata, indexedPosts []models.IndexedPost, shouldForce bool) {
 	cfg := b.cfg
 	var genWg sync.WaitGroup
@@ -27,7 +59,7 @@ func (b *Builder) generateMetadata(allContent []models.PostMetadata, tagMap map[
 		genWg.Add(1)
 		go func() {
 			defer genWg.Done()
-			generators.GenerateRSS(b.DestFs, cfg.BaseURL, allContent, cfg.Title, cfg.Description, filepath.Join(outputDir, "rss.xml"))
+			generators.GenerateRSS(b.DestFs, cfg.BaseURL, allContent, cfg.Title, cfg.Description, filepath.Join(outputDir, "rss.xml"), b.feedDescription())
 		}()
 	}
 
@@ -35,9 +67,7 @@ func (b *Builder) generateMetadata(allContent []models.PostMetadata, tagMap map[
 		genWg.Add(1)
 		go func() {
 			defer genWg.Done()
-			if err := generators.GenerateSearchIndex(b.DestFs, outputDir, indexedPosts); err != nil {
-				b.logger.Error("Failed to generate search index", "error", err)
-			}
+			b.generateSearchIndex(indexedPosts, shouldForce)
 		}()
 	}
 
@@ -67,3 +97,100 @@ func (b *Builder) generateMetadata(allContent []models.PostMetadata, tagMap map[
 	}
 	genWg.Wait()
 }
+
+// generateSearchIndex writes the search index, serializing from the inverted
+// and trigram indexes maintained in the build cache when one is available.
+// Nothing is written when the cache reports the same inputs as the last index.
+func (b *Builder) generateSearchIndex(indexedPosts []models.IndexedPost, shouldForce bool) {
+	opts := generators.SearchIndexOptions{
+		Synonyms:      b.synonyms,
+		IndexSynonyms: b.cfg.Search.IndexSynonyms,
+	}
+	var searchHash string
+	if b.cacheService != nil {
+		searchHash = b.searchIndexHash(indexedPosts)
+		if !shouldForce && searchHash != "" && b.searchIndexExists() {
+			if cachedHash, _ := b.cacheService.GetSearchHash(); cachedHash == searchHash {
+				return
+			}
+		}
+
+		if postings, err := b.cacheService.GetSearchPostings(); err == nil {
+			opts.Postings = postings
+		} else {
+			b.logger.Warn("Failed to load cached search postings, rebuilding index from posts", "error", err)
+		}
+		if opts.Postings != nil {
+			if grams, err := b.cacheService.GetSearchGrams(); err == nil {
+				opts.Grams = grams
+			} else {
+				b.logger.Warn("Failed to load cached search trigrams, rebuilding them", "error", err)
+			}
+		}
+	}
+
+	if err := generators.GenerateSearchIndex(b.DestFs, b.cfg.OutputDir, indexedPosts, opts); err != nil {
+		b.logger.Error("Failed to generate search index", "error", err)
+		return
+	}
+	if searchHash != "" {
+		_ = b.cacheService.SetSearchHash(searchHash)
+	}
+}
+
+// searchIndexHash fingerprints the inputs of the search index: the cache's
+// search generation, the index format, the synonyms and the indexed posts.
+// Returns "" when the generation is unavailable.
+func (b *Builder) searchIndexHash(indexedPosts []models.IndexedPost) string {
+	gen, err := b.cacheService.SearchGeneration()
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d|%d|%v|%v", gen, models.SearchShardFormat, b.cfg.Search.IndexSynonyms, search.NormalizeSynonyms(b.synonyms))
+	for _, ip := range indexedPosts {
+		sb.WriteString("|" + ip.PostID)
+	}
+	return cache.HashString(sb.String())
+}
+
+// searchIndexExists reports whether the last search index is still on disk
+func (b *Builder) searchIndexExists() bool {
+	for _, path := range []string{
+		filepath.Join(b.cfg.OutputDir, "search.bin"),
+		filepath.Join(b.cfg.OutputDir, generators.SearchShardDir, generators.SearchManifestFile),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// regenerateSearchIndexFromCache rewrites the search index after a single-post
+// rebuild, using only cached records and the maintained postings
+func (b *Builder) regenerateSearchIndexFromCache() {
+	if b.cacheService == nil || !b.cfg.Features.Generators.Search {
+		return
+	}
+
+	ids, err := b.cacheService.ListAllPosts()
+	if err != nil {
+		b.logger.Error("Failed to list cached posts for search index", "error", err)
+		return
+	}
+	cachedPosts, _ := b.cacheService.GetPostsByIDs(ids)
+	searchRecords, _ := b.cacheService.GetSearchRecords(ids)
+
+	indexedPosts := make([]models.IndexedPost, 0, len(ids))
+	for _, id := range ids {
+		cached, ok := cachedPosts[id]
+		searchMeta, hasSearch := searchRecords[id]
+		if !ok || cached == nil || !hasSearch || searchMeta == nil {
+			continue
+		}
+		indexedPosts = append(indexedPosts, indexedPostFromCache(id, cached, searchMeta, len(indexedPosts)))
+	}
+
+	b.generateSearchIndex(indexedPosts, false)
+}
@@ -0,0 +1,74 @@
+package run
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/config"
+	"github.com/Kush-Singh-26/kosh/builder/generators"
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/services/mocks"
+)
+
+// TestGenerateSearchIndexSkipsUnchanged checks that the search index is only
+// written again once the cache reports new search inputs
+func TestGenerateSearchIndexSkipsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	cacheSvc := mocks.NewMockCacheService()
+	b := &Builder{
+		cfg:          &config.Config{OutputDir: dir},
+		cacheService: cacheSvc,
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	posts := []models.IndexedPost{{
+		Record:    models.PostRecord{Title: "Attention", Link: "attention.html"},
+		WordFreqs: map[string]int{"attent": 1},
+		DocLen:    1,
+		PostID:    "a",
+	}}
+
+	// build generates the index and syncs it to disk, reporting whether it was written
+	build := func(force bool) bool {
+		b.DestFs = afero.NewMemMapFs()
+		b.generateSearchIndex(posts, force)
+		written := false
+		for _, rel := range []string{"search.bin", filepath.Join(generators.SearchShardDir, generators.SearchManifestFile)} {
+			data, err := afero.ReadFile(b.DestFs, filepath.Join(dir, rel))
+			if err != nil {
+				continue
+			}
+			written = true
+			_ = os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0755)
+			if err := os.WriteFile(filepath.Join(dir, rel), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return written
+	}
+
+	steps := []struct {
+		name  string
+		setup func()
+		force bool
+		want  bool
+	}{
+		{"first build", func() {}, false, true},
+		{"unchanged", func() {}, false, false},
+		{"forced", func() {}, true, true},
+		{"posts written", func() { cacheSvc.SearchGen++ }, false, true},
+		{"synonyms changed", func() { b.synonyms = map[string][]string{"cnn": {"convolution"}} }, false, true},
+		{"post filtered out", func() { posts = posts[:0] }, false, true},
+		{"output removed", func() { _ = os.Remove(filepath.Join(dir, "search.bin")) }, false, true},
+	}
+	for _, step := range steps {
+		step.setup()
+		if got := build(step.force); got != step.want {
+			t.Errorf("%s: written = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
@@ -20,7 +20,15 @@ func (b *Builder) generatePWA(shouldForce bool) {
 		if b.cfg.IsDev {
 			return
 		}
-		_ = generators.GenerateSW(b.DestFs, b.cfg.OutputDir, b.cfg.BuildVersion, shouldForce, b.cfg.BaseURL, b.renderService.GetAssets())
+		var precache []string
+		if b.cfg.Features.Generators.Search {
+			// Prefer the index generated in this build, fall back to the one on disk
+			precache = generators.SearchShardURLs(b.DestFs, b.cfg.OutputDir)
+			if precache == nil {
+				precache = generators.SearchShardURLs(b.SourceFs, b.cfg.OutputDir)
+			}
+		}
+		_ = generators.GenerateSW(b.DestFs, b.cfg.OutputDir, b.cfg.BuildVersion, shouldForce, b.cfg.BaseURL, b.renderService.GetAssets(), precache)
 	}()
 	go func() {
 		defer wg.Done()
@@ -36,25 +36,65 @@ var stopWords = map[string]bool{
 	"dare": true, "ought": true, "used": true, "nor": true,
 }
 
-// Analyzer provides text analysis for search indexing
-type Analyzer struct {
+// Analyzer turns text into normalized index terms.
+// The index records the Name of every analyzer used, so queries run through the same pipeline.
+type Analyzer interface {
+	Name() string
+	Analyze(text string) []string
+	// AnalyzeWithOriginals returns both stemmed and original forms
+	// This enables fuzzy matching on original forms while using stemmed forms for indexing
+	AnalyzeWithOriginals(text string) (stemmed []string, originals []string)
+}
+
+// QueryAnalyzer is implemented by analyzers that index more terms than a
+// query should be split into, such as the CJK unigrams kept next to bigrams
+type QueryAnalyzer interface {
+	AnalyzeQuery(text string) []string
+}
+
+// analyzeQuery returns the query terms of text for an analyzer
+func analyzeQuery(a Analyzer, text string) []string {
+	if qa, ok := a.(QueryAnalyzer); ok {
+		return qa.AnalyzeQuery(text)
+	}
+	return a.Analyze(text)
+}
+
+// WordAnalyzer splits text on letters and numbers, drops stop words and stems each token
+type WordAnalyzer struct {
+	name         string
+	stopWords    map[string]bool
+	stem         func(string) string
 	useStopWords bool
 	useStemming  bool
 }
 
-// NewAnalyzer creates a new analyzer with specified options
-func NewAnalyzer(useStopWords, useStemming bool) *Analyzer {
-	return &Analyzer{
+// NewAnalyzer creates a new English analyzer with specified options
+func NewAnalyzer(useStopWords, useStemming bool) *WordAnalyzer {
+	return NewWordAnalyzer(EnglishAnalyzerName, stopWords, StemCached, useStopWords, useStemming)
+}
+
+// NewWordAnalyzer creates an analyzer for a space-delimited language
+func NewWordAnalyzer(name string, stops map[string]bool, stem func(string) string, useStopWords, useStemming bool) *WordAnalyzer {
+	return &WordAnalyzer{
+		name:         name,
+		stopWords:    stops,
+		stem:         stem,
 		useStopWords: useStopWords,
 		useStemming:  useStemming,
 	}
 }
 
 // DefaultAnalyzer is the default analyzer with stemming and stop words enabled
-var DefaultAnalyzer = NewAnalyzer(true, true)
+var DefaultAnalyzer Analyzer = NewAnalyzer(true, true)
+
+// Name returns the identifier stored in the search index
+func (a *WordAnalyzer) Name() string {
+	return a.name
+}
 
 // Analyze processes text and returns normalized tokens
-func (a *Analyzer) Analyze(text string) []string {
+func (a *WordAnalyzer) Analyze(text string) []string {
 	tokens := TokenizeWithUnicode(text)
 	result := make([]string, 0, len(tokens))
 
@@ -63,11 +103,11 @@ func (a *Analyzer) Analyze(text string) []string {
 		if len(token) < 2 {
 			continue
 		}
-		if a.useStopWords && stopWords[token] {
+		if a.useStopWords && a.stopWords[token] {
 			continue
 		}
 		if a.useStemming {
-			token = StemCached(token)
+			token = a.stem(token)
 		}
 		if token != "" {
 			result = append(result, token)
@@ -77,8 +117,7 @@ func (a *Analyzer) Analyze(text string) []string {
 }
 
 // AnalyzeWithOriginals returns both stemmed and original forms
-// This enables fuzzy matching on original forms while using stemmed forms for indexing
-func (a *Analyzer) AnalyzeWithOriginals(text string) (stemmed []string, originals []string) {
+func (a *WordAnalyzer) AnalyzeWithOriginals(text string) (stemmed []string, originals []string) {
 	tokens := TokenizeWithUnicode(text)
 
 	for _, token := range tokens {
@@ -86,14 +125,14 @@ func (a *Analyzer) AnalyzeWithOriginals(text string) (stemmed []string, original
 		if len(token) < 2 {
 			continue
 		}
-		if a.useStopWords && stopWords[token] {
+		if a.useStopWords && a.stopWords[token] {
 			continue
 		}
 
 		originals = append(originals, token)
 
 		if a.useStemming {
-			stemmed = append(stemmed, StemCached(token))
+			stemmed = append(stemmed, a.stem(token))
 		} else {
 			stemmed = append(stemmed, token)
 		}
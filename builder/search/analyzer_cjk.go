@@ -0,0 +1,110 @@
+package search
+
+import (
+	"unicode"
+)
+
+// CJKAnalyzer indexes Chinese, Japanese and Korean text as overlapping character
+// bigrams plus single characters, since those scripts do not separate words with
+// spaces. Queries use bigrams, so a one-character query matches any text with
+// that character and longer queries stay precise.
+// Runs of other scripts go through DefaultAnalyzer.
+type CJKAnalyzer struct{}
+
+// NewCJKAnalyzer creates a bigram analyzer for CJK text
+func NewCJKAnalyzer() *CJKAnalyzer {
+	return &CJKAnalyzer{}
+}
+
+// Name returns the identifier stored in the search index
+func (a *CJKAnalyzer) Name() string {
+	return CJKAnalyzerName
+}
+
+// Analyze returns the index terms of text: CJK bigrams and unigrams, and
+// analyzed tokens of any other text
+func (a *CJKAnalyzer) Analyze(text string) []string {
+	stemmed, _ := a.AnalyzeWithOriginals(text)
+	return stemmed
+}
+
+// AnalyzeQuery returns the query terms of text: CJK bigrams, a unigram for a
+// lone character, and analyzed tokens of any other text
+func (a *CJKAnalyzer) AnalyzeQuery(text string) []string {
+	stemmed, _ := analyzeCJK(text, cjkBigrams)
+	return stemmed
+}
+
+// AnalyzeWithOriginals returns CJK terms as their own originals alongside the
+// stemmed and original forms of non-CJK words
+func (a *CJKAnalyzer) AnalyzeWithOriginals(text string) (stemmed []string, originals []string) {
+	return analyzeCJK(text, cjkIndexGrams)
+}
+
+// analyzeCJK splits text into CJK runs, turned into terms by grams, and
+// other text, analyzed by DefaultAnalyzer
+func analyzeCJK(text string, grams func([]rune) []string) (stemmed []string, originals []string) {
+	var run, other []rune
+
+	flushOther := func() {
+		if len(other) == 0 {
+			return
+		}
+		s, o := DefaultAnalyzer.AnalyzeWithOriginals(string(other))
+		stemmed = append(stemmed, s...)
+		originals = append(originals, o...)
+		other = other[:0]
+	}
+	flushRun := func() {
+		for _, gram := range grams(run) {
+			stemmed = append(stemmed, gram)
+			originals = append(originals, gram)
+		}
+		run = run[:0]
+	}
+
+	for _, r := range text {
+		if isCJK(r) {
+			flushOther()
+			run = append(run, r)
+			continue
+		}
+		flushRun()
+		other = append(other, r)
+	}
+	flushRun()
+	flushOther()
+	return stemmed, originals
+}
+
+// cjkBigrams splits a run of CJK characters into overlapping bigrams.
+// A lone character is kept as a unigram so single-character words stay searchable.
+func cjkBigrams(run []rune) []string {
+	if len(run) == 0 {
+		return nil
+	}
+	if len(run) == 1 {
+		return []string{string(run)}
+	}
+	grams := make([]string, 0, len(run)-1)
+	for i := 0; i < len(run)-1; i++ {
+		grams = append(grams, string(run[i:i+2]))
+	}
+	return grams
+}
+
+// cjkIndexGrams returns the bigrams of a run followed by each of its characters
+func cjkIndexGrams(run []rune) []string {
+	if len(run) < 2 {
+		return cjkBigrams(run)
+	}
+	grams := cjkBigrams(run)
+	for _, r := range run {
+		grams = append(grams, string(r))
+	}
+	return grams
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -40,6 +40,8 @@ const (
 	ScoreTitleMatch    = 10.0
 	ScoreTagMatch      = 5.0
 	ScoreFuzzyModifier = 0.7
+	// ScoreSynonymModifier scales terms added by synonym expansion
+	ScoreSynonymModifier = 0.5
 )
 
 type Result struct {
@@ -50,31 +52,45 @@ type Result struct {
 	Snippet     string
 	Version     string
 	Score       float64
+	Explain     []TermScore // Only set by ExplainSearch
 }
 
-// PerformSearch executes a search query against the index with fuzzy and phrase support
-func PerformSearch(index *models.SearchIndex, query string, versionFilter string) []Result {
-	// Apply NFC normalization and Unicode-aware lowercasing
-	query = norm.NFC.String(query)
-	query = lowerCaser.String(strings.TrimSpace(query))
-	if query == "" {
-		return nil
-	}
+// TermScore is one contribution to a result's score, as reported by ExplainSearch
+type TermScore struct {
+	Kind  string  `json:"kind"` // term, fuzzy, synonym, phrase, title or tag
+	Term  string  `json:"term"`
+	Freq  int     `json:"freq,omitempty"`
+	DF    int     `json:"df,omitempty"`
+	IDF   float64 `json:"idf,omitempty"`
+	Score float64 `json:"score"`
+}
 
-	// Parse query for tag filter
-	tagFilter := ""
-	if strings.HasPrefix(query, "tag:") {
-		parts := strings.SplitN(query, " ", 2)
-		tagFilter = strings.TrimPrefix(parts[0], "tag:")
-		if len(parts) > 1 {
-			query = parts[1]
-		} else {
-			query = ""
-		}
+// PerformSearch executes a search query against the index with fuzzy and phrase support.
+// A non-empty tagFilter keeps only posts with that tag, compared case-insensitively
+// with the whole tag, and replaces any "tag:" prefix of the query.
+func PerformSearch(index *models.SearchIndex, query string, versionFilter string, tagFilter string) []Result {
+	return performSearch(index, query, versionFilter, tagFilter, false)
+}
+
+// ExplainSearch runs PerformSearch and fills each result's Explain with the
+// BM25 contribution of every matched term plus phrase, title and tag boosts
+func ExplainSearch(index *models.SearchIndex, query string, versionFilter string, tagFilter string) []Result {
+	return performSearch(index, query, versionFilter, tagFilter, true)
+}
+
+func performSearch(index *models.SearchIndex, query string, versionFilter string, tagFilter string, explain bool) []Result {
+	query, queryTag, ok := normalizeQuery(query)
+	// Indexed tags are lowercased whole, spaces included
+	if tagFilter = strings.ToLower(strings.TrimSpace(tagFilter)); tagFilter == "" {
+		tagFilter = queryTag
+	}
+	if !ok && tagFilter == "" {
+		return nil
 	}
 
 	// Parse query for phrases and terms
-	parsed := ParseQuery(query)
+	analyzers := queryAnalyzers(index.Analyzers)
+	parsed := ParseQueryWith(query, analyzers...)
 	queryTerms := parsed.Terms
 
 	maxResults := len(index.Posts)
@@ -88,31 +104,47 @@ func PerformSearch(index *models.SearchIndex, query string, versionFilter string
 
 	postCache := make(map[int]*models.PostRecord, maxResults)
 
-	// Process individual terms with BM25
-	for _, term := range queryTerms {
-		if posts, ok := index.Inverted[term]; ok {
-			df := len(posts)
-			idf := math.Log(1 + (float64(index.TotalDocs)-float64(df)+0.5)/(float64(df)+0.5))
-
-			for postID, freq := range posts {
-				post, cached := postCache[postID]
-				if !cached {
-					post = &index.Posts[postID]
-					postCache[postID] = post
-				}
+	var explanations map[int][]TermScore
+	if explain {
+		explanations = make(map[int][]TermScore, maxResults)
+	}
+	addScore := func(postID int, contribution TermScore) {
+		scores[postID] += contribution.Score
+		if explanations != nil {
+			explanations[postID] = append(explanations[postID], contribution)
+		}
+	}
 
-				if versionFilter != "all" && post.Version != versionFilter {
-					continue
-				}
+	// scoreTerm adds the BM25 contribution of one term, scaled by modifier
+	scoreTerm := func(kind, term string, posts map[int]int, modifier float64) {
+		df := documentFrequency(index, term, posts)
+		idf := math.Log(1 + (float64(index.TotalDocs)-float64(df)+0.5)/(float64(df)+0.5))
 
-				if tagFilter != "" && !HasTagNormalized(post.NormalizedTags, tagFilter) {
-					continue
-				}
+		for postID, freq := range posts {
+			post, cached := postCache[postID]
+			if !cached {
+				post = &index.Posts[postID]
+				postCache[postID] = post
+			}
 
-				docLen := float64(index.DocLens[postID])
-				score := idf * (float64(freq) * (k1 + 1)) / (float64(freq) + k1*(1-b+b*(docLen/index.AvgDocLen)))
-				scores[postID] += score
+			if versionFilter != "all" && post.Version != versionFilter {
+				continue
 			}
+
+			if tagFilter != "" && !HasTagNormalized(post.NormalizedTags, tagFilter) {
+				continue
+			}
+
+			docLen := float64(index.DocLens[postID])
+			score := idf * (float64(freq) * (k1 + 1)) / (float64(freq) + k1*(1-b+b*(docLen/index.AvgDocLen)))
+			addScore(postID, TermScore{Kind: kind, Term: term, Freq: freq, DF: df, IDF: idf, Score: score * modifier})
+		}
+	}
+
+	// Process individual terms with BM25
+	for _, term := range queryTerms {
+		if posts, ok := index.Inverted[term]; ok {
+			scoreTerm("term", term, posts, 1)
 		} else {
 			// Try fuzzy matching if exact term not found
 			// Use ngram index for fast candidate generation if available
@@ -124,34 +156,20 @@ func PerformSearch(index *models.SearchIndex, query string, versionFilter string
 			}
 			for _, fuzzyTerm := range fuzzyCandidates {
 				if posts, ok := index.Inverted[fuzzyTerm]; ok {
-					df := len(posts)
-					idf := math.Log(1 + (float64(index.TotalDocs)-float64(df)+0.5)/(float64(df)+0.5))
-
-					for postID, freq := range posts {
-						post, cached := postCache[postID]
-						if !cached {
-							post = &index.Posts[postID]
-							postCache[postID] = post
-						}
-
-						if versionFilter != "all" && post.Version != versionFilter {
-							continue
-						}
-
-						if tagFilter != "" && !HasTagNormalized(post.NormalizedTags, tagFilter) {
-							continue
-						}
-
-						docLen := float64(index.DocLens[postID])
-						score := idf * (float64(freq) * (k1 + 1)) / (float64(freq) + k1*(1-b+b*(docLen/index.AvgDocLen)))
-						// Reduce score for fuzzy matches
-						scores[postID] += score * ScoreFuzzyModifier
-					}
+					// Reduce score for fuzzy matches
+					scoreTerm("fuzzy", fuzzyTerm, posts, ScoreFuzzyModifier)
 				}
 			}
 		}
 	}
 
+	// Synonyms and acronym expansions count less than the words actually typed
+	for _, term := range ExpandSynonyms(parsed.Text, index.Synonyms, analyzers, queryTerms) {
+		if posts, ok := index.Inverted[term]; ok {
+			scoreTerm("synonym", term, posts, ScoreSynonymModifier)
+		}
+	}
+
 	// Process phrase matches (higher score)
 	for _, phrase := range parsed.Phrases {
 		for i, post := range index.Posts {
@@ -161,13 +179,13 @@ func PerformSearch(index *models.SearchIndex, query string, versionFilter string
 
 			// Check if phrase appears in title (highest score)
 			if strings.Contains(post.NormalizedTitle, phrase) {
-				scores[i] += ScorePhraseMatch * 2
+				addScore(i, TermScore{Kind: "phrase", Term: phrase, Score: ScorePhraseMatch * 2})
 				continue
 			}
 
 			// Check if phrase appears in content
 			if strings.Contains(strings.ToLower(post.Content), phrase) {
-				scores[i] += ScorePhraseMatch
+				addScore(i, TermScore{Kind: "phrase", Term: phrase, Score: ScorePhraseMatch})
 			}
 		}
 	}
@@ -180,7 +198,7 @@ func PerformSearch(index *models.SearchIndex, query string, versionFilter string
 				continue
 			}
 			if HasTagNormalized(post.NormalizedTags, tagFilter) {
-				scores[i] = 1.0
+				addScore(i, TermScore{Kind: "tag", Term: tagFilter, Score: 1.0})
 			}
 		}
 	}
@@ -192,13 +210,13 @@ func PerformSearch(index *models.SearchIndex, query string, versionFilter string
 
 		// Title match boost
 		if originalQuery != "" && strings.Contains(post.NormalizedTitle, originalQuery) {
-			scores[id] += ScoreTitleMatch
+			addScore(id, TermScore{Kind: "title", Term: originalQuery, Score: ScoreTitleMatch})
 		}
 
 		// Tag match boost
 		for _, tag := range post.NormalizedTags {
 			if tag == originalQuery || tag == tagFilter {
-				scores[id] += ScoreTagMatch
+				addScore(id, TermScore{Kind: "tag", Term: tag, Score: ScoreTagMatch})
 			}
 		}
 	}
@@ -220,6 +238,7 @@ func PerformSearch(index *models.SearchIndex, query string, versionFilter string
 			Snippet:     ExtractSnippet(post.Content, queryTerms),
 			Version:     post.Version,
 			Score:       score,
+			Explain:     explanations[id],
 		})
 	}
 
@@ -236,6 +255,37 @@ func PerformSearch(index *models.SearchIndex, query string, versionFilter string
 	return results
 }
 
+// normalizeQuery applies NFC normalization and lowercasing, then splits off a
+// leading "tag:" filter. ok is false when the query is empty.
+func normalizeQuery(query string) (rest string, tagFilter string, ok bool) {
+	query = norm.NFC.String(query)
+	query = lowerCaser.String(strings.TrimSpace(query))
+	if query == "" {
+		return "", "", false
+	}
+
+	if strings.HasPrefix(query, "tag:") {
+		parts := strings.SplitN(query, " ", 2)
+		tagFilter = strings.TrimPrefix(parts[0], "tag:")
+		if len(parts) > 1 {
+			query = parts[1]
+		} else {
+			query = ""
+		}
+	}
+	return query, tagFilter, true
+}
+
+// documentFrequency returns the number of documents containing term.
+// Sharded indexes only hold the postings of loaded versions, so they carry
+// the corpus-wide frequency separately to keep BM25 scores stable.
+func documentFrequency(index *models.SearchIndex, term string, posts map[int]int) int {
+	if df, ok := index.DocFreqs[term]; ok {
+		return df
+	}
+	return len(posts)
+}
+
 // Tokenize splits text into tokens (legacy function for compatibility)
 func Tokenize(text string) []string {
 	if len(text) == 0 {
@@ -68,7 +68,7 @@ func TestPerformSearch(t *testing.T) {
 			NormalizedTitle: "rust guide",
 			Content:         "A guide to Rust programming",
 			Version:         "v1",
-			NormalizedTags:  []string{"rust", "programming"},
+			NormalizedTags:  []string{"rust", "programming", "systems programming"},
 		},
 		{
 			ID:              2,
@@ -115,6 +115,7 @@ func TestPerformSearch(t *testing.T) {
 		name          string
 		query         string
 		versionFilter string
+		tagFilter     string
 		wantIDs       []int
 	}{
 		{
@@ -153,11 +154,31 @@ func TestPerformSearch(t *testing.T) {
 			versionFilter: "all",
 			wantIDs:       nil,
 		},
+		{
+			name:          "tag filter with space",
+			query:         "guide",
+			versionFilter: "all",
+			tagFilter:     "Systems Programming",
+			wantIDs:       []int{1},
+		},
+		{
+			name:          "tag filter only",
+			versionFilter: "all",
+			tagFilter:     " systems programming ",
+			wantIDs:       []int{1},
+		},
+		{
+			name:          "tag filter is not hyphenated",
+			query:         "guide",
+			versionFilter: "all",
+			tagFilter:     "systems-programming",
+			wantIDs:       nil,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			results := PerformSearch(index, tt.query, tt.versionFilter)
+			results := PerformSearch(index, tt.query, tt.versionFilter, tt.tagFilter)
 
 			// Extract IDs
 			var gotIDs []int
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -88,7 +89,7 @@ func FuzzyExpand(term string, inverted map[string]map[int]int, maxDist int) []st
 // FuzzyExpandWithNgrams uses n-gram index for faster fuzzy candidate generation
 func FuzzyExpandWithNgrams(term string, ngramIndex map[string][]string, maxDist int) []string {
 	// Generate trigrams for the term
-	trigrams := generateTrigrams(term)
+	trigrams := Trigrams(term)
 
 	// Count how many trigrams each candidate shares
 	candidateScores := make(map[string]int)
@@ -111,12 +112,13 @@ func FuzzyExpandWithNgrams(term string, ngramIndex map[string][]string, maxDist
 			}
 		}
 	}
+	sort.Strings(results) // Scores are summed in this order
 
 	return results
 }
 
-// generateTrigrams creates trigram (3-character) sequences from a word
-func generateTrigrams(word string) []string {
+// Trigrams creates trigram (3-character) sequences from a word
+func Trigrams(word string) []string {
 	runes := []rune(word)
 	n := len(runes)
 
@@ -138,15 +140,19 @@ func BuildNgramIndex(inverted map[string]map[int]int) map[string][]string {
 	ngramIndex := make(map[string][]string)
 
 	for term := range inverted {
-		trigrams := generateTrigrams(term)
-		for _, tg := range trigrams {
-			ngramIndex[tg] = append(ngramIndex[tg], term)
-		}
+		addTermTrigrams(ngramIndex, term)
 	}
 
 	return ngramIndex
 }
 
+// addTermTrigrams registers a single term in an existing trigram index
+func addTermTrigrams(ngramIndex map[string][]string, term string) {
+	for _, tg := range Trigrams(term) {
+		ngramIndex[tg] = append(ngramIndex[tg], term)
+	}
+}
+
 // min3 returns the minimum of three integers
 func min3(a, b, c int) int {
 	if a < b {
@@ -167,10 +173,17 @@ type ParsedQuery struct {
 	Terms   []string // Individual terms
 	Phrases []string // Quoted phrases
 	Raw     string   // Original query
+	Text    string   // Query with quoted phrases removed
 }
 
-// ParseQuery extracts terms and phrases from a query string
+// ParseQuery extracts terms and phrases from a query string using DefaultAnalyzer
 func ParseQuery(query string) ParsedQuery {
+	return ParseQueryWith(query, DefaultAnalyzer)
+}
+
+// ParseQueryWith extracts terms and phrases, running the remaining text through
+// every given analyzer so a query matches posts indexed in any of their languages
+func ParseQueryWith(query string, analyzers ...Analyzer) ParsedQuery {
 	result := ParsedQuery{
 		Raw: query,
 	}
@@ -201,8 +214,18 @@ func ParseQuery(query string) ParsedQuery {
 		cleaned = strings.ReplaceAll(cleaned, `"`+phrase+`"`, " ")
 	}
 
-	// Tokenize remaining terms
-	result.Terms = DefaultAnalyzer.Analyze(cleaned)
+	result.Text = cleaned
+
+	// Tokenize remaining terms, de-duplicating across analyzers
+	seen := make(map[string]bool)
+	for _, analyzer := range analyzers {
+		for _, term := range analyzeQuery(analyzer, cleaned) {
+			if !seen[term] {
+				seen[term] = true
+				result.Terms = append(result.Terms, term)
+			}
+		}
+	}
 
 	return result
 }
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Analyzer names recorded in the search index
+const (
+	EnglishAnalyzerName = "en"
+	GermanAnalyzerName  = "de"
+	FrenchAnalyzerName  = "fr"
+	SpanishAnalyzerName = "es"
+	CJKAnalyzerName     = "cjk"
+)
+
+var germanStopWords = map[string]bool{
+	"aber": true, "alle": true, "als": true, "also": true, "am": true, "an": true,
+	"auch": true, "auf": true, "aus": true, "bei": true, "bin": true, "bis": true,
+	"bist": true, "da": true, "damit": true, "dann": true, "das": true, "dass": true,
+	"dein": true, "dem": true, "den": true, "der": true, "des": true, "die": true,
+	"dies": true, "diese": true, "dieser": true, "doch": true, "du": true, "durch": true,
+	"ein": true, "eine": true, "einem": true, "einen": true, "einer": true, "eines": true,
+	"er": true, "es": true, "für": true, "hat": true, "hatte": true, "ich": true,
+	"ihr": true, "im": true, "in": true, "ist": true, "ja": true, "kann": true,
+	"man": true, "mit": true, "nach": true, "nicht": true, "noch": true, "nur": true,
+	"ob": true, "oder": true, "sich": true, "sie": true, "sind": true, "so": true,
+	"um": true, "und": true, "uns": true, "von": true, "vor": true, "war": true,
+	"was": true, "wenn": true, "werden": true, "wie": true, "wir": true, "wird": true,
+	"zu": true, "zum": true, "zur": true, "über": true,
+}
+
+var frenchStopWords = map[string]bool{
+	"au": true, "aux": true, "avec": true, "ce": true, "ces": true, "cette": true,
+	"dans": true, "de": true, "des": true, "du": true, "elle": true, "en": true,
+	"est": true, "et": true, "eux": true, "il": true, "ils": true, "je": true,
+	"la": true, "le": true, "les": true, "leur": true, "lui": true, "ma": true,
+	"mais": true, "me": true, "mes": true, "moi": true, "mon": true, "ne": true,
+	"nos": true, "notre": true, "nous": true, "on": true, "ou": true, "par": true,
+	"pas": true, "pour": true, "qu": true, "que": true, "qui": true, "sa": true,
+	"se": true, "ses": true, "son": true, "sont": true, "sur": true, "ta": true,
+	"te": true, "tes": true, "toi": true, "ton": true, "tu": true, "un": true,
+	"une": true, "vos": true, "votre": true, "vous": true, "été": true, "être": true,
+}
+
+var spanishStopWords = map[string]bool{
+	"al": true, "algo": true, "como": true, "con": true, "de": true, "del": true,
+	"el": true, "ella": true, "ellos": true, "en": true, "entre": true, "era": true,
+	"es": true, "esta": true, "este": true, "esto": true, "fue": true, "ha": true,
+	"hay": true, "la": true, "las": true, "le": true, "les": true, "lo": true,
+	"los": true, "más": true, "me": true, "mi": true, "muy": true, "no": true,
+	"nos": true, "o": true, "para": true, "pero": true, "por": true, "que": true,
+	"se": true, "sin": true, "sobre": true, "son": true, "su": true, "sus": true,
+	"también": true, "te": true, "tu": true, "un": true, "una": true, "uno": true,
+	"y": true, "ya": true, "yo": true, "él": true,
+}
+
+// analyzers maps analyzer names to their implementation
+var analyzers = map[string]Analyzer{
+	EnglishAnalyzerName: DefaultAnalyzer,
+	GermanAnalyzerName:  NewWordAnalyzer(GermanAnalyzerName, germanStopWords, cachedStemmer(StemGerman), true, true),
+	FrenchAnalyzerName:  NewWordAnalyzer(FrenchAnalyzerName, frenchStopWords, cachedStemmer(StemFrench), true, true),
+	SpanishAnalyzerName: NewWordAnalyzer(SpanishAnalyzerName, spanishStopWords, cachedStemmer(StemSpanish), true, true),
+	CJKAnalyzerName:     NewCJKAnalyzer(),
+}
+
+// languageAnalyzers maps language codes to analyzer names where they differ
+var languageAnalyzers = map[string]string{
+	"zh": CJKAnalyzerName,
+	"ja": CJKAnalyzerName,
+	"ko": CJKAnalyzerName,
+}
+
+// AnalyzerFor selects the analyzer for a language code such as "de" or "pt-BR".
+// Unknown or empty languages fall back to DefaultAnalyzer.
+func AnalyzerFor(lang string) Analyzer {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if name, ok := languageAnalyzers[lang]; ok {
+		lang = name
+	}
+	return AnalyzerByName(lang)
+}
+
+// AnalyzerByName returns the analyzer recorded under name in an index.
+// Empty or unknown names fall back to DefaultAnalyzer.
+func AnalyzerByName(name string) Analyzer {
+	if a, ok := analyzers[name]; ok {
+		return a
+	}
+	return DefaultAnalyzer
+}
+
+// AnalyzerNames returns the sorted, de-duplicated analyzer names used by posts.
+// Posts without a recorded analyzer count as DefaultAnalyzer.
+func AnalyzerNames(posts []string) []string {
+	seen := make(map[string]bool, len(posts))
+	for _, name := range posts {
+		seen[AnalyzerByName(name).Name()] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// queryAnalyzers resolves the analyzers recorded in an index.
+// Indexes written before analyzers were recorded use DefaultAnalyzer.
+func queryAnalyzers(names []string) []Analyzer {
+	if len(names) == 0 {
+		return []Analyzer{DefaultAnalyzer}
+	}
+	result := make([]Analyzer, 0, len(names))
+	for _, name := range names {
+		result = append(result, AnalyzerByName(name))
+	}
+	return result
+}
+
+// cachedStemmer wraps a stemmer with its own memoization cache
+func cachedStemmer(stem func(string) string) func(string) string {
+	var cache sync.Map
+	return func(word string) string {
+		if len(word) <= 2 {
+			return word
+		}
+		if cached, ok := cache.Load(word); ok {
+			return cached.(string)
+		}
+		result := stem(word)
+		cache.Store(word, result)
+		return result
+	}
+}
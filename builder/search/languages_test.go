@@ -0,0 +1,144 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+func TestSnowballStemmers(t *testing.T) {
+	tests := []struct {
+		lang  string
+		stem  func(string) string
+		group []string
+	}{
+		{"de", StemGerman, []string{"haus", "hauses", "häuser"}},
+		{"de", StemGerman, []string{"kategorie", "kategorien"}},
+		{"de", StemGerman, []string{"möglichkeit", "möglichkeiten"}},
+		{"fr", StemFrench, []string{"national", "nationale", "nationales"}},
+		{"fr", StemFrench, []string{"chanter", "chantaient"}},
+		{"fr", StemFrench, []string{"continuel", "continuellement"}},
+		{"es", StemSpanish, []string{"canción", "canciones"}},
+		{"es", StemSpanish, []string{"correr", "corriendo", "corrió"}},
+		{"es", StemSpanish, []string{"biblioteca", "bibliotecas"}},
+	}
+
+	for _, tt := range tests {
+		stems := make(map[string]bool)
+		for _, word := range tt.group {
+			stems[tt.stem(word)] = true
+		}
+		if len(stems) != 1 {
+			t.Errorf("%s: words %v produced different stems: %v", tt.lang, tt.group, stems)
+		}
+	}
+}
+
+func TestAnalyzerFor(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"", EnglishAnalyzerName},
+		{"en-US", EnglishAnalyzerName},
+		{"de", GermanAnalyzerName},
+		{"fr_CA", FrenchAnalyzerName},
+		{"ES", SpanishAnalyzerName},
+		{"zh-Hans", CJKAnalyzerName},
+		{"ja", CJKAnalyzerName},
+		{"pt-BR", EnglishAnalyzerName},
+	}
+
+	for _, tt := range tests {
+		if got := AnalyzerFor(tt.lang).Name(); got != tt.want {
+			t.Errorf("AnalyzerFor(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestCJKAnalyzer(t *testing.T) {
+	a := NewCJKAnalyzer()
+	got := a.Analyze("機械学習 and transformers 日")
+	want := []string{"機械", "械学", "学習", "機", "械", "学", "習", "transform", "日"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Analyze() = %v, want %v", got, want)
+	}
+	got = a.AnalyzeQuery("機械学習 and transformers 日")
+	want = []string{"機械", "械学", "学習", "transform", "日"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AnalyzeQuery() = %v, want %v", got, want)
+	}
+}
+
+// TestSearchSingleCJKCharacter checks that a one-character query finds the
+// character inside longer runs, while a two-character query stays a bigram
+func TestSearchSingleCJKCharacter(t *testing.T) {
+	a := NewCJKAnalyzer()
+	contents := []string{"今日は晴れ", "日本語の文章", "機械学習"}
+	index := &models.SearchIndex{
+		Inverted:  map[string]map[int]int{},
+		DocLens:   map[int]int{},
+		TotalDocs: len(contents),
+		Analyzers: []string{CJKAnalyzerName},
+	}
+	for i, content := range contents {
+		index.Posts = append(index.Posts, models.PostRecord{ID: i, Content: content, Analyzer: CJKAnalyzerName})
+		terms := a.Analyze(content)
+		for _, term := range terms {
+			if index.Inverted[term] == nil {
+				index.Inverted[term] = map[int]int{}
+			}
+			index.Inverted[term][i]++
+		}
+		index.DocLens[i] = len(terms)
+		index.AvgDocLen += float64(len(terms)) / float64(len(contents))
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"日", []string{"今日は晴れ", "日本語の文章"}},
+		{"日本", []string{"日本語の文章"}},
+		{"学", []string{"機械学習"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range PerformSearch(index, tt.query, "all", "") {
+			got = append(got, index.Posts[r.ID].Content)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PerformSearch(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryWithAnalyzers(t *testing.T) {
+	de := AnalyzerByName(GermanAnalyzerName)
+	parsed := ParseQueryWith("Häuser network", DefaultAnalyzer, de)
+
+	want := []string{"häuser", "network", "haus"}
+	if !reflect.DeepEqual(parsed.Terms, want) {
+		t.Errorf("Terms = %v, want %v", parsed.Terms, want)
+	}
+}
+
+func TestPerformSearchUsesIndexAnalyzers(t *testing.T) {
+	index := &models.SearchIndex{
+		Posts: []models.PostRecord{
+			{ID: 0, Title: "Häuser", Link: "haeuser.html", Content: "Alte Häuser", Analyzer: GermanAnalyzerName},
+		},
+		Inverted:  map[string]map[int]int{"haus": {0: 2}, "alt": {0: 1}},
+		DocLens:   map[int]int{0: 3},
+		AvgDocLen: 3,
+		TotalDocs: 1,
+		Analyzers: []string{GermanAnalyzerName},
+	}
+
+	if results := PerformSearch(index, "hauses", "all", ""); len(results) != 1 {
+		t.Fatalf("PerformSearch() returned %d results, want 1", len(results))
+	}
+}
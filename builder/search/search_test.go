@@ -292,14 +292,14 @@ func TestTrigramGeneration(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
-			result := generateTrigrams(tt.input)
+			result := Trigrams(tt.input)
 			if len(result) != len(tt.expected) {
-				t.Errorf("generateTrigrams(%q) returned %d trigrams, want %d", tt.input, len(result), len(tt.expected))
+				t.Errorf("Trigrams(%q) returned %d trigrams, want %d", tt.input, len(result), len(tt.expected))
 				return
 			}
 			for i, exp := range tt.expected {
 				if result[i] != exp {
-					t.Errorf("generateTrigrams(%q)[%d] = %q, want %q", tt.input, i, result[i], exp)
+					t.Errorf("Trigrams(%q)[%d] = %q, want %q", tt.input, i, result[i], exp)
 				}
 			}
 		})
@@ -0,0 +1,219 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+// ShardFetcher returns the decompressed bytes of a shard referenced by the manifest
+type ShardFetcher func(ref models.SearchShardRef) ([]byte, error)
+
+// ShardedIndex lazily assembles a SearchIndex from the shards listed in a manifest.
+// Shards are fetched on first use and kept in memory for later queries.
+type ShardedIndex struct {
+	manifest models.SearchManifest
+	fetch    ShardFetcher
+
+	mu      sync.Mutex
+	index   models.SearchIndex
+	loaded  map[string]bool // shard file -> merged
+	docBase map[int]int     // version position in manifest -> offset of its posts
+}
+
+// NewShardedIndex creates an empty index backed by the given manifest. Fuzzy
+// candidates come from the manifest's trigram shards, loaded per query, so they
+// do not depend on which term shards earlier queries loaded.
+func NewShardedIndex(manifest models.SearchManifest, fetch ShardFetcher) *ShardedIndex {
+	s := &ShardedIndex{
+		manifest: manifest,
+		fetch:    fetch,
+		index: models.SearchIndex{
+			Inverted:   make(map[string]map[int]int),
+			DocLens:    make(map[int]int),
+			DocFreqs:   make(map[string]int),
+			NgramIndex: make(map[string][]string),
+			TotalDocs:  manifest.TotalDocs,
+			AvgDocLen:  manifest.AvgDocLen,
+			Analyzers:  manifest.Analyzers,
+			Synonyms:   manifest.Synonyms,
+		},
+		loaded:  make(map[string]bool),
+		docBase: make(map[int]int),
+	}
+	return s
+}
+
+// Search loads the shards required by the query and runs PerformSearch over them
+func (s *ShardedIndex) Search(query string, versionFilter string, tagFilter string) ([]Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rest, _, ok := normalizeQuery(query)
+	if !ok && tagFilter == "" {
+		return nil, nil
+	}
+	analyzers := queryAnalyzers(s.index.Analyzers)
+	parsed := ParseQueryWith(rest, analyzers...)
+	terms := append(parsed.Terms, ExpandSynonyms(parsed.Text, s.index.Synonyms, analyzers, parsed.Terms)...)
+	trigrams := len(s.manifest.Trigrams) > 0
+	if trigrams {
+		// A typo may not share the first rune of the term it matches, so its
+		// candidates can live in any shard
+		for _, term := range parsed.Terms {
+			if err := s.loadTrigrams(term); err != nil {
+				return nil, err
+			}
+			if !s.known(term) {
+				terms = append(terms, FuzzyExpandWithNgrams(term, s.index.NgramIndex, MaxEditDistance)...)
+			}
+		}
+	}
+
+	for vi, ver := range s.manifest.Versions {
+		if versionFilter != "all" && ver.Version != versionFilter {
+			continue
+		}
+		if err := s.loadDocs(vi); err != nil {
+			return nil, err
+		}
+		for _, term := range terms {
+			if tr := findTermRange(ver.Terms, term); tr != nil {
+				if err := s.loadTerms(vi, tr.Shard); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	// A term indexed only in other versions is still an exact match, as in
+	// the full index, rather than a reason to try fuzzy candidates
+	for _, term := range parsed.Terms {
+		if _, ok := s.index.Inverted[term]; !ok && trigrams && s.known(term) {
+			s.index.Inverted[term] = make(map[int]int)
+		}
+	}
+
+	return PerformSearch(&s.index, query, versionFilter, tagFilter), nil
+}
+
+// LoadedShards returns the number of shards merged so far
+func (s *ShardedIndex) LoadedShards() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.loaded)
+}
+
+// loadDocs merges the post records of a version, assigning global IDs
+func (s *ShardedIndex) loadDocs(vi int) error {
+	ref := s.manifest.Versions[vi].Docs
+	if s.loaded[ref.File] {
+		return nil
+	}
+
+	var shard models.SearchDocShard
+	if err := s.decode(ref, &shard); err != nil {
+		return err
+	}
+
+	base := len(s.index.Posts)
+	for i, post := range shard.Posts {
+		post.ID = base + i
+		s.index.Posts = append(s.index.Posts, post)
+		if i < len(shard.DocLens) {
+			s.index.DocLens[base+i] = shard.DocLens[i]
+		}
+	}
+	s.docBase[vi] = base
+	s.loaded[ref.File] = true
+	return nil
+}
+
+// loadTerms merges a term range shard of a version whose docs are already loaded
+func (s *ShardedIndex) loadTerms(vi int, ref models.SearchShardRef) error {
+	if s.loaded[ref.File] {
+		return nil
+	}
+
+	var shard models.SearchTermShard
+	if err := s.decode(ref, &shard); err != nil {
+		return err
+	}
+
+	base := s.docBase[vi]
+	for term, postings := range shard.Inverted {
+		postMap, ok := s.index.Inverted[term]
+		if !ok {
+			postMap = make(map[int]int, len(postings))
+			s.index.Inverted[term] = postMap
+			if len(s.manifest.Trigrams) == 0 { // Manifests without trigram shards grow them as term shards load
+				addTermTrigrams(s.index.NgramIndex, term)
+			}
+		}
+		for localID, freq := range postings {
+			postMap[base+localID] = freq
+		}
+		if df, ok := shard.DocFreqs[term]; ok {
+			s.index.DocFreqs[term] = df
+		}
+	}
+	s.loaded[ref.File] = true
+	return nil
+}
+
+// loadTrigrams merges the trigram shards covering the trigrams of term
+func (s *ShardedIndex) loadTrigrams(term string) error {
+	for _, tg := range Trigrams(term) {
+		tr := findTermRange(s.manifest.Trigrams, tg)
+		if tr == nil || s.loaded[tr.Shard.File] {
+			continue
+		}
+		var shard models.SearchTrigramShard
+		if err := s.decode(tr.Shard, &shard); err != nil {
+			return err
+		}
+		for key, terms := range shard.Ngrams {
+			s.index.NgramIndex[key] = terms // Each trigram lives in exactly one shard
+		}
+		s.loaded[tr.Shard.File] = true
+	}
+	return nil
+}
+
+// known reports whether term is indexed in any version. Its first trigram's
+// shard must already be loaded.
+func (s *ShardedIndex) known(term string) bool {
+	terms := s.index.NgramIndex[Trigrams(term)[0]]
+	i := sort.SearchStrings(terms, term)
+	return i < len(terms) && terms[i] == term
+}
+
+func (s *ShardedIndex) decode(ref models.SearchShardRef, v interface{}) error {
+	data, err := s.fetch(ref)
+	if err != nil {
+		return fmt.Errorf("fetch shard %s: %w", ref.File, err)
+	}
+	if err := msgpack.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode shard %s: %w", ref.File, err)
+	}
+	return nil
+}
+
+// findTermRange returns the range whose first-rune bounds contain term
+func findTermRange(ranges []models.SearchTermRange, term string) *models.SearchTermRange {
+	r, size := utf8.DecodeRuneInString(term)
+	if size == 0 {
+		return nil
+	}
+	first := string(r)
+	for i := range ranges {
+		if ranges[i].Start <= first && first <= ranges[i].End {
+			return &ranges[i]
+		}
+	}
+	return nil
+}
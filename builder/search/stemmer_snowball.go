@@ -0,0 +1,576 @@
+package search
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Snowball stemmers for German, French and Spanish
+// Based on the Snowball algorithm descriptions: https://snowballstem.org/algorithms/
+
+// snowballWord holds a word being stemmed with its R1, R2 and RV regions (rune offsets)
+type snowballWord struct {
+	r          []rune
+	r1, r2, rv int
+}
+
+func (w *snowballWord) String() string {
+	return string(w.r)
+}
+
+// suffixStart returns the rune offset at which suffix s would start
+func (w *snowballWord) suffixStart(s string) int {
+	return len(w.r) - utf8.RuneCountInString(s)
+}
+
+func (w *snowballWord) hasSuffix(s string) bool {
+	start := w.suffixStart(s)
+	return start >= 0 && string(w.r[start:]) == s
+}
+
+// longest returns the longest suffix from the list that ends the word, or ""
+func (w *snowballWord) longest(suffixes ...string) string {
+	best := ""
+	for _, s := range suffixes {
+		if utf8.RuneCountInString(s) > utf8.RuneCountInString(best) && w.hasSuffix(s) {
+			best = s
+		}
+	}
+	return best
+}
+
+// in reports whether suffix s lies within the region starting at offset region
+func (w *snowballWord) in(s string, region int) bool {
+	return w.suffixStart(s) >= region
+}
+
+func (w *snowballWord) cut(s string) {
+	w.r = w.r[:w.suffixStart(s)]
+}
+
+func (w *snowballWord) replace(s, with string) {
+	w.cut(s)
+	w.r = append(w.r, []rune(with)...)
+}
+
+// before returns the rune preceding suffix s, or 0 if there is none
+func (w *snowballWord) before(s string) rune {
+	if i := w.suffixStart(s) - 1; i >= 0 {
+		return w.r[i]
+	}
+	return 0
+}
+
+// nextRegion returns the offset after the first non-vowel following a vowel,
+// searching from offset from (the standard R1/R2 definition)
+func nextRegion(r []rune, from int, isVowel func(rune) bool) int {
+	for i := from + 1; i < len(r); i++ {
+		if isVowel(r[i-1]) && !isVowel(r[i]) {
+			return i + 1
+		}
+	}
+	return len(r)
+}
+
+// --- German ---
+
+func isGermanVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyäöü", r)
+}
+
+func isGermanSEnding(r rune) bool {
+	return strings.ContainsRune("bdfghklmnrt", r)
+}
+
+func isGermanSTEnding(r rune) bool {
+	return strings.ContainsRune("bdfghklmnt", r)
+}
+
+// StemGerman applies the Snowball German stemming algorithm to a lowercase word
+func StemGerman(word string) string {
+	word = strings.ReplaceAll(word, "ß", "ss")
+	r := []rune(word)
+
+	// Mark u and y between vowels as consonants
+	for i := 1; i < len(r)-1; i++ {
+		if isGermanVowel(r[i-1]) && isGermanVowel(r[i+1]) {
+			switch r[i] {
+			case 'u':
+				r[i] = 'U'
+			case 'y':
+				r[i] = 'Y'
+			}
+		}
+	}
+
+	w := &snowballWord{r: r}
+	w.r1 = nextRegion(r, 0, isGermanVowel)
+	w.r2 = nextRegion(r, w.r1, isGermanVowel)
+	if w.r1 < 3 {
+		w.r1 = 3
+	}
+
+	// Step 1
+	switch s := w.longest("em", "ern", "er", "e", "en", "es", "s"); s {
+	case "em", "ern", "er":
+		if w.in(s, w.r1) {
+			w.cut(s)
+		}
+	case "e", "en", "es":
+		if w.in(s, w.r1) {
+			w.cut(s)
+			if w.hasSuffix("niss") {
+				w.cut("s")
+			}
+		}
+	case "s":
+		if w.in(s, w.r1) && isGermanSEnding(w.before(s)) {
+			w.cut(s)
+		}
+	}
+
+	// Step 2
+	switch s := w.longest("en", "er", "est", "st"); s {
+	case "en", "er", "est":
+		if w.in(s, w.r1) {
+			w.cut(s)
+		}
+	case "st":
+		if w.in(s, w.r1) && isGermanSTEnding(w.before(s)) && w.suffixStart(s) >= 4 {
+			w.cut(s)
+		}
+	}
+
+	// Step 3: derivational suffixes
+	switch s := w.longest("end", "ung", "ig", "ik", "isch", "lich", "heit", "keit"); s {
+	case "end", "ung":
+		if w.in(s, w.r2) {
+			w.cut(s)
+			if w.hasSuffix("ig") && w.in("ig", w.r2) && w.before("ig") != 'e' {
+				w.cut("ig")
+			}
+		}
+	case "ig", "ik", "isch":
+		if w.in(s, w.r2) && w.before(s) != 'e' {
+			w.cut(s)
+		}
+	case "lich", "heit":
+		if w.in(s, w.r2) {
+			w.cut(s)
+			if p := w.longest("er", "en"); p != "" && w.in(p, w.r1) {
+				w.cut(p)
+			}
+		}
+	case "keit":
+		if w.in(s, w.r2) {
+			w.cut(s)
+			if p := w.longest("lich", "ig"); p != "" && w.in(p, w.r2) {
+				w.cut(p)
+			}
+		}
+	}
+
+	return strings.NewReplacer("U", "u", "Y", "y", "ä", "a", "ö", "o", "ü", "u").Replace(w.String())
+}
+
+// --- Spanish ---
+
+func isSpanishVowel(r rune) bool {
+	return strings.ContainsRune("aeiouáéíóúü", r)
+}
+
+var spanishAccents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u")
+
+var spanishVerbSuffixes = []string{
+	"arían", "arías", "arán", "arás", "aríais", "aría", "aréis", "aríamos", "aremos", "ará", "aré",
+	"erían", "erías", "erán", "erás", "eríais", "ería", "eréis", "eríamos", "eremos", "erá", "eré",
+	"irían", "irías", "irán", "irás", "iríais", "iría", "iréis", "iríamos", "iremos", "irá", "iré",
+	"aba", "ada", "ida", "ía", "ara", "iera", "ad", "ed", "id", "ase", "iese", "aste", "iste",
+	"an", "aban", "ían", "aran", "ieran", "asen", "iesen", "aron", "ieron", "ado", "ido",
+	"ando", "iendo", "ió", "ar", "er", "ir", "as", "abas", "adas", "idas", "ías", "aras",
+	"ieras", "ases", "ieses", "ís", "áis", "abais", "íais", "arais", "ierais", "aseis",
+	"ieseis", "asteis", "isteis", "ados", "idos", "amos", "ábamos", "íamos", "imos",
+	"áramos", "iéramos", "iésemos", "ásemos",
+}
+
+// spanishRV computes RV: after the next vowel if the second letter is a consonant,
+// after the next consonant if the word starts with two vowels, otherwise after the third letter
+func spanishRV(r []rune) int {
+	if len(r) < 2 {
+		return len(r)
+	}
+	switch {
+	case !isSpanishVowel(r[1]):
+		for i := 2; i < len(r); i++ {
+			if isSpanishVowel(r[i]) {
+				return i + 1
+			}
+		}
+		return len(r)
+	case isSpanishVowel(r[0]):
+		for i := 2; i < len(r); i++ {
+			if !isSpanishVowel(r[i]) {
+				return i + 1
+			}
+		}
+		return len(r)
+	default:
+		return min(3, len(r))
+	}
+}
+
+// StemSpanish applies the Snowball Spanish stemming algorithm to a lowercase word
+func StemSpanish(word string) string {
+	r := []rune(word)
+	w := &snowballWord{r: r, rv: spanishRV(r)}
+	w.r1 = nextRegion(r, 0, isSpanishVowel)
+	w.r2 = nextRegion(r, w.r1, isSpanishVowel)
+
+	// Step 0: attached pronouns
+	if p := w.longest("me", "se", "sela", "selo", "selas", "selos", "la", "le", "lo", "las", "les", "los", "nos"); p != "" && w.in(p, w.rv) {
+		stem := &snowballWord{r: w.r[:w.suffixStart(p)], rv: w.rv}
+		if e := stem.longest("iéndo", "ándo", "ár", "ér", "ír"); e != "" && stem.in(e, w.rv) {
+			w.cut(p)
+			w.replace(e, spanishAccents.Replace(e))
+		} else if e := stem.longest("ando", "iendo", "ar", "er", "ir"); e != "" && stem.in(e, w.rv) {
+			w.cut(p)
+		} else if stem.hasSuffix("yendo") && stem.in("yendo", w.rv) && stem.before("yendo") == 'u' {
+			w.cut(p)
+		}
+	}
+
+	// Step 1: standard suffix removal
+	step1 := true
+	switch s := w.longest(
+		"anza", "anzas", "ico", "ica", "icos", "icas", "ismo", "ismos", "able", "ables", "ible",
+		"ibles", "ista", "istas", "oso", "osa", "osos", "osas", "amiento", "amientos", "imiento", "imientos",
+		"adora", "ador", "ación", "adoras", "adores", "aciones", "ante", "antes", "ancia", "ancias",
+		"logía", "logías", "ución", "uciones", "encia", "encias", "amente", "mente",
+		"idad", "idades", "iva", "ivo", "ivas", "ivos",
+	); s {
+	case "anza", "anzas", "ico", "ica", "icos", "icas", "ismo", "ismos", "able", "ables", "ible",
+		"ibles", "ista", "istas", "oso", "osa", "osos", "osas", "amiento", "amientos", "imiento", "imientos":
+		step1 = w.cutIn(s, w.r2)
+	case "adora", "ador", "ación", "adoras", "adores", "aciones", "ante", "antes", "ancia", "ancias":
+		if step1 = w.cutIn(s, w.r2); step1 && w.hasSuffix("ic") {
+			w.cutIn("ic", w.r2)
+		}
+	case "logía", "logías":
+		step1 = w.replaceIn(s, "log", w.r2)
+	case "ución", "uciones":
+		step1 = w.replaceIn(s, "u", w.r2)
+	case "encia", "encias":
+		step1 = w.replaceIn(s, "ente", w.r2)
+	case "amente":
+		if step1 = w.cutIn(s, w.r1); step1 {
+			if w.hasSuffix("iv") {
+				if w.cutIn("iv", w.r2) && w.hasSuffix("at") {
+					w.cutIn("at", w.r2)
+				}
+			} else if p := w.longest("os", "ic", "ad"); p != "" {
+				w.cutIn(p, w.r2)
+			}
+		}
+	case "mente":
+		if step1 = w.cutIn(s, w.r2); step1 {
+			if p := w.longest("ante", "able", "ible"); p != "" {
+				w.cutIn(p, w.r2)
+			}
+		}
+	case "idad", "idades":
+		if step1 = w.cutIn(s, w.r2); step1 {
+			if p := w.longest("abil", "ic", "iv"); p != "" {
+				w.cutIn(p, w.r2)
+			}
+		}
+	case "iva", "ivo", "ivas", "ivos":
+		if step1 = w.cutIn(s, w.r2); step1 && w.hasSuffix("at") {
+			w.cutIn("at", w.r2)
+		}
+	default:
+		step1 = false
+	}
+
+	if !step1 {
+		// Step 2a: verb suffixes beginning with y
+		s := w.longest("ya", "ye", "yan", "yen", "yeron", "yendo", "yo", "yó", "yas", "yes", "yais", "yamos")
+		if s != "" && w.in(s, w.rv) && w.before(s) == 'u' {
+			w.cut(s)
+		} else {
+			// Step 2b: other verb suffixes
+			switch s := w.longest(append([]string{"en", "es", "éis", "emos"}, spanishVerbSuffixes...)...); s {
+			case "":
+			case "en", "es", "éis", "emos":
+				if w.cutIn(s, w.rv) && w.hasSuffix("gu") {
+					w.cut("u")
+				}
+			default:
+				w.cutIn(s, w.rv)
+			}
+		}
+	}
+
+	// Step 3: residual suffix
+	switch s := w.longest("os", "a", "o", "á", "í", "ó", "e", "é"); s {
+	case "os", "a", "o", "á", "í", "ó":
+		w.cutIn(s, w.rv)
+	case "e", "é":
+		if w.cutIn(s, w.rv) && w.hasSuffix("gu") && w.in("u", w.rv) {
+			w.cut("u")
+		}
+	}
+
+	return spanishAccents.Replace(w.String())
+}
+
+// cutIn removes suffix s if it lies in region, reporting whether it did
+func (w *snowballWord) cutIn(s string, region int) bool {
+	if !w.in(s, region) {
+		return false
+	}
+	w.cut(s)
+	return true
+}
+
+// replaceIn replaces suffix s if it lies in region, reporting whether it did
+func (w *snowballWord) replaceIn(s, with string, region int) bool {
+	if !w.in(s, region) {
+		return false
+	}
+	w.replace(s, with)
+	return true
+}
+
+// --- French ---
+
+func isFrenchVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyâàëéêèïîôûù", r)
+}
+
+var frenchVerbSuffixesI = []string{
+	"îmes", "ît", "îtes", "i", "ie", "ies", "ir", "ira", "irai", "iraIent", "irais", "irait",
+	"iras", "irent", "irez", "iriez", "irions", "irons", "iront", "is", "issaIent", "issais",
+	"issait", "issant", "issante", "issantes", "issants", "isse", "issent", "isses", "issez",
+	"issiez", "issions", "issons", "it",
+}
+
+var frenchVerbSuffixesE = []string{
+	"é", "ée", "ées", "és", "èrent", "er", "era", "erai", "eraIent", "erais", "erait", "eras",
+	"erez", "eriez", "erions", "erons", "eront", "ez", "iez",
+}
+
+var frenchVerbSuffixesA = []string{
+	"âmes", "ât", "âtes", "a", "ai", "aIent", "ais", "ait", "ant", "ante", "antes", "ants",
+	"as", "asse", "assent", "asses", "assiez", "assions",
+}
+
+// frenchRV computes RV: after the third letter for words starting with two vowels
+// or with par/col/tap, otherwise after the first vowel not at the start
+func frenchRV(r []rune) int {
+	if len(r) < 2 {
+		return len(r)
+	}
+	s := string(r)
+	if strings.HasPrefix(s, "par") || strings.HasPrefix(s, "col") || strings.HasPrefix(s, "tap") {
+		return 3
+	}
+	if isFrenchVowel(r[0]) && isFrenchVowel(r[1]) {
+		return min(3, len(r))
+	}
+	for i := 1; i < len(r); i++ {
+		if isFrenchVowel(r[i]) {
+			return i + 1
+		}
+	}
+	return len(r)
+}
+
+// StemFrench applies the Snowball French stemming algorithm to a lowercase word
+func StemFrench(word string) string {
+	r := []rune(word)
+
+	// Mark vowels that behave as consonants
+	for i := range r {
+		prevVowel := i > 0 && isFrenchVowel(r[i-1])
+		nextVowel := i < len(r)-1 && isFrenchVowel(r[i+1])
+		switch {
+		case (r[i] == 'u' || r[i] == 'i') && prevVowel && nextVowel:
+			r[i] = r[i] - 'a' + 'A'
+		case r[i] == 'y' && (prevVowel || nextVowel):
+			r[i] = 'Y'
+		case r[i] == 'u' && i > 0 && r[i-1] == 'q':
+			r[i] = 'U'
+		}
+	}
+
+	w := &snowballWord{r: r, rv: frenchRV(r)}
+	w.r1 = nextRegion(r, 0, isFrenchVowel)
+	w.r2 = nextRegion(r, w.r1, isFrenchVowel)
+	original := w.String()
+
+	// Step 1: standard suffix removal
+	step1 := true
+	tryVerbs := false
+	switch s := w.longest(
+		"ance", "iqUe", "isme", "able", "iste", "eux", "ances", "iqUes", "ismes", "ables", "istes",
+		"atrice", "ateur", "ation", "atrices", "ateurs", "ations", "logie", "logies",
+		"usion", "ution", "usions", "utions", "ence", "ences", "ement", "ements", "ité", "ités",
+		"if", "ive", "ifs", "ives", "eaux", "aux", "euse", "euses", "issement", "issements",
+		"amment", "emment", "ment", "ments",
+	); s {
+	case "ance", "iqUe", "isme", "able", "iste", "eux", "ances", "iqUes", "ismes", "ables", "istes":
+		step1 = w.cutIn(s, w.r2)
+	case "atrice", "ateur", "ation", "atrices", "ateurs", "ations":
+		if step1 = w.cutIn(s, w.r2); step1 && w.hasSuffix("ic") {
+			if !w.cutIn("ic", w.r2) {
+				w.replace("ic", "iqU")
+			}
+		}
+	case "logie", "logies":
+		step1 = w.replaceIn(s, "log", w.r2)
+	case "usion", "ution", "usions", "utions":
+		step1 = w.replaceIn(s, "u", w.r2)
+	case "ence", "ences":
+		step1 = w.replaceIn(s, "ent", w.r2)
+	case "ement", "ements":
+		if step1 = w.cutIn(s, w.rv); step1 {
+			switch {
+			case w.hasSuffix("iv"):
+				if w.cutIn("iv", w.r2) && w.hasSuffix("at") {
+					w.cutIn("at", w.r2)
+				}
+			case w.hasSuffix("eus"):
+				if !w.cutIn("eus", w.r2) {
+					w.replaceIn("eus", "eux", w.r1)
+				}
+			case w.hasSuffix("abl"):
+				w.cutIn("abl", w.r2)
+			case w.hasSuffix("iqU"):
+				w.cutIn("iqU", w.r2)
+			case w.hasSuffix("ièr"):
+				w.replaceIn("ièr", "i", w.rv)
+			case w.hasSuffix("Ièr"):
+				w.replaceIn("Ièr", "i", w.rv)
+			}
+		}
+	case "ité", "ités":
+		if step1 = w.cutIn(s, w.r2); step1 {
+			switch {
+			case w.hasSuffix("abil"):
+				if !w.cutIn("abil", w.r2) {
+					w.replace("abil", "abl")
+				}
+			case w.hasSuffix("ic"):
+				if !w.cutIn("ic", w.r2) {
+					w.replace("ic", "iqU")
+				}
+			case w.hasSuffix("iv"):
+				w.cutIn("iv", w.r2)
+			}
+		}
+	case "if", "ive", "ifs", "ives":
+		if step1 = w.cutIn(s, w.r2); step1 && w.hasSuffix("at") && w.cutIn("at", w.r2) && w.hasSuffix("ic") {
+			if !w.cutIn("ic", w.r2) {
+				w.replace("ic", "iqU")
+			}
+		}
+	case "eaux":
+		w.replace(s, "eau")
+	case "aux":
+		step1 = w.replaceIn(s, "al", w.r1)
+	case "euse", "euses":
+		if step1 = w.cutIn(s, w.r2); !step1 {
+			step1 = w.replaceIn(s, "eux", w.r1)
+		}
+	case "issement", "issements":
+		step1 = w.in(s, w.r1) && !isFrenchVowel(w.before(s)) && w.cutIn(s, w.r1)
+	case "amment":
+		step1 = w.replaceIn(s, "ant", w.rv)
+		tryVerbs = true
+	case "emment":
+		step1 = w.replaceIn(s, "ent", w.rv)
+		tryVerbs = true
+	case "ment", "ments":
+		step1 = isFrenchVowel(w.before(s)) && w.suffixStart(s)-1 >= w.rv && w.cutIn(s, w.rv)
+		tryVerbs = true
+	default:
+		step1 = false
+	}
+
+	verbChanged := false
+	if !step1 || tryVerbs {
+		// Step 2a: verb suffixes beginning with i, after a non-vowel in RV
+		if s := w.longest(frenchVerbSuffixesI...); s != "" && w.suffixStart(s) > w.rv && !isFrenchVowel(w.before(s)) {
+			w.cut(s)
+			verbChanged = true
+		} else {
+			// Step 2b: other verb suffixes
+			s := w.longest(append(append([]string{"ions"}, frenchVerbSuffixesE...), frenchVerbSuffixesA...)...)
+			switch {
+			case s == "":
+			case s == "ions":
+				verbChanged = w.cutIn(s, w.r2)
+			case w.inList(s, frenchVerbSuffixesE):
+				verbChanged = w.cutIn(s, w.rv)
+			default:
+				if verbChanged = w.cutIn(s, w.rv); verbChanged && w.hasSuffix("e") {
+					w.cutIn("e", w.rv)
+				}
+			}
+		}
+	}
+
+	if w.String() != original && (step1 || verbChanged) {
+		// Step 3
+		if w.hasSuffix("Y") {
+			w.replace("Y", "i")
+		} else if w.hasSuffix("ç") {
+			w.replace("ç", "c")
+		}
+	} else {
+		// Step 4: residual suffixes
+		if w.hasSuffix("s") && !strings.ContainsRune("aiouès", w.before("s")) {
+			w.cut("s")
+		}
+		switch s := w.longest("ion", "ier", "ière", "Ier", "Ière", "e", "ë"); s {
+		case "ion":
+			if w.in(s, w.r2) && w.in(s, w.rv) && strings.ContainsRune("st", w.before(s)) {
+				w.cut(s)
+			}
+		case "ier", "ière", "Ier", "Ière":
+			w.replaceIn(s, "i", w.rv)
+		case "e":
+			w.cutIn(s, w.rv)
+		case "ë":
+			if w.in(s, w.rv) && w.hasSuffix("guë") {
+				w.cut(s)
+			}
+		}
+	}
+
+	// Step 5: undouble
+	if w.longest("enn", "onn", "ett", "ell", "eill") != "" {
+		w.r = w.r[:len(w.r)-1]
+	}
+
+	// Step 6: un-accent é/è followed only by non-vowels
+	for i := len(w.r) - 1; i >= 0; i-- {
+		if isFrenchVowel(w.r[i]) {
+			if i < len(w.r)-1 && (w.r[i] == 'é' || w.r[i] == 'è') {
+				w.r[i] = 'e'
+			}
+			break
+		}
+	}
+
+	return strings.ToLower(w.String())
+}
+
+func (w *snowballWord) inList(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
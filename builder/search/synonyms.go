@@ -0,0 +1,108 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NormalizeSynonyms lowercases a synonym table and makes it symmetric, so that
+// "mlp: [multilayer perceptron]" also expands "multilayer perceptron" to "mlp".
+// Keys and values may be single words or multi-word phrases.
+func NormalizeSynonyms(table map[string][]string) map[string][]string {
+	if len(table) == 0 {
+		return nil
+	}
+
+	sets := make(map[string]map[string]bool)
+	link := func(from, to string) {
+		if from == "" || to == "" || from == to {
+			return
+		}
+		if sets[from] == nil {
+			sets[from] = make(map[string]bool)
+		}
+		sets[from][to] = true
+	}
+
+	for key, values := range table {
+		group := []string{normalizePhrase(key)}
+		for _, v := range values {
+			group = append(group, normalizePhrase(v))
+		}
+		for _, a := range group {
+			for _, b := range group {
+				link(a, b)
+			}
+		}
+	}
+
+	result := make(map[string][]string, len(sets))
+	for key, set := range sets {
+		values := make([]string, 0, len(set))
+		for v := range set {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		result[key] = values
+	}
+	return result
+}
+
+// SynonymsKey identifies a normalized synonym table, so that terms expanded
+// with an older table can be told apart. Empty for an empty table.
+func SynonymsKey(synonyms map[string][]string) string {
+	if len(synonyms) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprint(synonyms))) // Maps print in key order
+	return hex.EncodeToString(sum[:8])
+}
+
+// ExpandSynonyms finds every synonym key appearing as whole words in text and
+// returns the analyzed terms of its equivalents. Terms listed in exclude
+// (typically the query's own terms) are left out so they are not scored twice.
+func ExpandSynonyms(text string, synonyms map[string][]string, analyzers []Analyzer, exclude []string) []string {
+	if len(synonyms) == 0 || text == "" {
+		return nil
+	}
+
+	padded := " " + normalizePhrase(text) + " "
+	seen := make(map[string]bool, len(exclude))
+	for _, term := range exclude {
+		seen[term] = true
+	}
+
+	// Iterate keys in order so expansion is deterministic
+	keys := make([]string, 0, len(synonyms))
+	for key := range synonyms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var terms []string
+	for _, key := range keys {
+		if !strings.Contains(padded, " "+key+" ") {
+			continue
+		}
+		for _, equivalent := range synonyms[key] {
+			for _, analyzer := range analyzers {
+				for _, term := range analyzeQuery(analyzer, equivalent) {
+					if !seen[term] {
+						seen[term] = true
+						terms = append(terms, term)
+					}
+				}
+			}
+		}
+	}
+	return terms
+}
+
+// normalizePhrase lowercases text and joins its tokens with single spaces
+func normalizePhrase(text string) string {
+	tokens := TokenizeWithUnicode(strings.ToLower(text))
+	return strings.Join(tokens, " ")
+}
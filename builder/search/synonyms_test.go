@@ -0,0 +1,79 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+func TestNormalizeSynonyms(t *testing.T) {
+	got := NormalizeSynonyms(map[string][]string{
+		"MLP": {"Multilayer Perceptron", "multi-layer perceptron"},
+	})
+	want := map[string][]string{
+		"mlp":                    {"multi layer perceptron", "multilayer perceptron"},
+		"multilayer perceptron":  {"mlp", "multi layer perceptron"},
+		"multi layer perceptron": {"mlp", "multilayer perceptron"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeSynonyms() = %v, want %v", got, want)
+	}
+
+	if got := NormalizeSynonyms(nil); got != nil {
+		t.Errorf("NormalizeSynonyms(nil) = %v, want nil", got)
+	}
+}
+
+func TestExpandSynonyms(t *testing.T) {
+	synonyms := NormalizeSynonyms(map[string][]string{
+		"svd": {"singular value decomposition"},
+		"moe": {"mixture of experts"},
+	})
+	analyzers := []Analyzer{DefaultAnalyzer}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SVD", []string{"singular", "valu", "decomposit"}},
+		{"singular value decomposition", []string{"svd"}},
+		{"MoE routing", []string{"mixtur", "expert"}},
+		{"svdx", nil},
+	}
+
+	for _, tt := range tests {
+		exclude := DefaultAnalyzer.Analyze(tt.query)
+		got := ExpandSynonyms(tt.query, synonyms, analyzers, exclude)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandSynonyms(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPerformSearchSynonyms(t *testing.T) {
+	index := &models.SearchIndex{
+		Posts: []models.PostRecord{
+			{ID: 0, Title: "MLP", NormalizedTitle: "mlp", Link: "mlp.html", Content: "mlp basics"},
+			{ID: 1, Title: "Perceptrons", NormalizedTitle: "perceptrons", Link: "perceptron.html", Content: "multilayer perceptron"},
+		},
+		Inverted: map[string]map[int]int{
+			"mlp":        {0: 2},
+			"multilay":   {1: 1},
+			"perceptron": {1: 2},
+			"basic":      {0: 1},
+		},
+		DocLens:   map[int]int{0: 3, 1: 3},
+		AvgDocLen: 3,
+		TotalDocs: 2,
+		Synonyms:  NormalizeSynonyms(map[string][]string{"mlp": {"multilayer perceptron"}}),
+	}
+
+	results := PerformSearch(index, "mlp", "all", "")
+	if len(results) != 2 {
+		t.Fatalf("PerformSearch() returned %d results, want 2", len(results))
+	}
+	if results[0].Link != "mlp.html" {
+		t.Errorf("exact match should rank first, got %q", results[0].Link)
+	}
+}
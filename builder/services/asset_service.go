@@ -17,15 +17,17 @@ type assetServiceImpl struct {
 	sourceFs afero.Fs
 	destFs   afero.Fs
 	cfg      *config.Config
+	images   *utils.ImageProfiles
 	renderer RenderService
 	logger   *slog.Logger
 }
 
-func NewAssetService(sourceFs, destFs afero.Fs, cfg *config.Config, renderer RenderService, logger *slog.Logger) AssetService {
+func NewAssetService(sourceFs, destFs afero.Fs, cfg *config.Config, images *utils.ImageProfiles, renderer RenderService, logger *slog.Logger) AssetService {
 	return &assetServiceImpl{
 		sourceFs: sourceFs,
 		destFs:   destFs,
 		cfg:      cfg,
+		images:   images,
 		renderer: renderer,
 		logger:   logger,
 	}
@@ -51,7 +53,7 @@ func (s *assetServiceImpl) Build(ctx context.Context) error {
 		if exists, _ := afero.Exists(s.sourceFs, s.cfg.StaticDir); exists {
 			// Exclude .css and .js files from raw copy (they're handled by esbuild)
 			destStaticDir := filepath.Join(s.cfg.OutputDir, "static")
-			if err := utils.CopyDirVFS(s.sourceFs, s.destFs, s.cfg.StaticDir, destStaticDir, s.cfg.CompressImages, []string{".css", ".js"}, s.renderer.RegisterFile, s.cfg.CacheDir+"/images", s.cfg.ImageWorkers); err != nil {
+			if err := utils.CopyDirVFS(s.sourceFs, s.destFs, s.cfg.StaticDir, destStaticDir, s.cfg.CompressImages, []string{".css", ".js"}, s.renderer.RegisterFile, s.cfg.CacheDir+"/images", s.cfg.ImageWorkers, s.images); err != nil {
 				s.logger.Warn("Failed to copy theme static assets", "error", err)
 			}
 		}
@@ -66,7 +68,7 @@ func (s *assetServiceImpl) Build(ctx context.Context) error {
 		// Site Static (Root 'static' folder)
 		if exists, _ := afero.Exists(s.sourceFs, "static"); exists {
 			destStaticDir := filepath.Join(s.cfg.OutputDir, "static")
-			if err := utils.CopyDirVFS(s.sourceFs, s.destFs, "static", destStaticDir, s.cfg.CompressImages, []string{".css", ".js"}, s.renderer.RegisterFile, s.cfg.CacheDir+"/images", s.cfg.ImageWorkers); err != nil {
+			if err := utils.CopyDirVFS(s.sourceFs, s.destFs, "static", destStaticDir, s.cfg.CompressImages, []string{".css", ".js"}, s.renderer.RegisterFile, s.cfg.CacheDir+"/images", s.cfg.ImageWorkers, s.images); err != nil {
 				s.logger.Warn("Failed to copy site static assets", "error", err)
 			}
 		}
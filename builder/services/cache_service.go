@@ -43,6 +43,10 @@ func (s *cacheServiceImpl) GetPostsByTemplate(templatePath string) ([]string, er
 	return s.manager.GetPostsByTemplate(templatePath)
 }
 
+func (s *cacheServiceImpl) GetPostsByInclude(includePath string) ([]string, error) {
+	return s.manager.GetPostsByInclude(includePath)
+}
+
 func (s *cacheServiceImpl) GetSearchRecords(ids []string) (map[string]*cache.SearchRecord, error) {
 	return s.manager.GetSearchRecords(ids)
 }
@@ -51,6 +55,26 @@ func (s *cacheServiceImpl) GetSearchRecord(id string) (*cache.SearchRecord, erro
 	return s.manager.GetSearchRecord(id)
 }
 
+func (s *cacheServiceImpl) GetSearchPostings() (cache.SearchPostings, error) {
+	return s.manager.GetSearchPostings()
+}
+
+func (s *cacheServiceImpl) GetSearchGrams() (map[string][]string, error) {
+	return s.manager.GetSearchGrams()
+}
+
+func (s *cacheServiceImpl) SearchGeneration() (uint64, error) {
+	return s.manager.SearchGeneration()
+}
+
+func (s *cacheServiceImpl) GetSearchHash() (string, error) {
+	return s.manager.GetSearchHash()
+}
+
+func (s *cacheServiceImpl) SetSearchHash(hash string) error {
+	return s.manager.SetSearchHash(hash)
+}
+
 func (s *cacheServiceImpl) GetHTMLContent(post *cache.PostMeta) ([]byte, error) {
 	return s.manager.GetHTMLContent(post)
 }
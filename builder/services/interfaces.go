@@ -21,6 +21,8 @@ type PostService interface {
 	Process(ctx context.Context, shouldForce, forceSocialRebuild, outputMissing bool) (*PostResult, error)
 	ProcessSingle(ctx context.Context, path string) error
 	RenderCachedPosts()
+	ImageProfileOverrides() map[string]string
+	SetSearchSynonyms(synonyms map[string][]string)
 }
 
 // CacheService abstracts the caching layer
@@ -30,8 +32,14 @@ type CacheService interface {
 	GetPostByPath(path string) (*cache.PostMeta, error)
 	GetPostsByIDs(ids []string) (map[string]*cache.PostMeta, error)
 	GetPostsByTemplate(templatePath string) ([]string, error)
+	GetPostsByInclude(includePath string) ([]string, error)
 	GetSearchRecords(ids []string) (map[string]*cache.SearchRecord, error)
 	GetSearchRecord(id string) (*cache.SearchRecord, error)
+	GetSearchPostings() (cache.SearchPostings, error)
+	GetSearchGrams() (map[string][]string, error)
+	SearchGeneration() (uint64, error)
+	GetSearchHash() (string, error)
+	SetSearchHash(hash string) error
 	GetHTMLContent(post *cache.PostMeta) ([]byte, error)
 	GetSocialCardHash(path string) (string, error)
 	SetSocialCardHash(path, hash string) error
@@ -71,6 +79,8 @@ type RenderService interface {
 	RegisterFile(path string)
 	SetAssets(assets map[string]string)
 	GetAssets() map[string]string
+	SetData(data map[string]interface{})
+	ReloadTemplates()
 	GetRenderedFiles() map[string]bool
 	ClearRenderedFiles()
 }
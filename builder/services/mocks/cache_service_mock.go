@@ -2,7 +2,10 @@
 package mocks
 
 import (
+	"sort"
+
 	"github.com/Kush-Singh-26/kosh/builder/cache"
+	"github.com/Kush-Singh-26/kosh/builder/search"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 )
 
@@ -16,6 +19,8 @@ type MockCacheService struct {
 	SocialCardHashes   map[string]string
 	GraphHash          string
 	WasmHash           string
+	SearchGen          uint64
+	SearchHash         string
 	Err                error
 	CallCount          map[string]int
 	BatchCommitPosts   []*cache.PostMeta
@@ -100,6 +105,15 @@ func (m *MockCacheService) GetPostsByTemplate(templatePath string) ([]string, er
 	return []string{}, nil
 }
 
+// GetPostsByInclude returns posts embedding a file
+func (m *MockCacheService) GetPostsByInclude(includePath string) ([]string, error) {
+	m.recordCall("GetPostsByInclude")
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return []string{}, nil
+}
+
 // GetSearchRecords returns multiple search records
 func (m *MockCacheService) GetSearchRecords(ids []string) (map[string]*cache.SearchRecord, error) {
 	m.recordCall("GetSearchRecords")
@@ -124,6 +138,71 @@ func (m *MockCacheService) GetSearchRecord(id string) (*cache.SearchRecord, erro
 	return m.SearchRecords[id], nil
 }
 
+// GetSearchPostings returns the inverted index built from the search records
+func (m *MockCacheService) GetSearchPostings() (cache.SearchPostings, error) {
+	m.recordCall("GetSearchPostings")
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	postings := make(cache.SearchPostings)
+	for id, rec := range m.SearchRecords {
+		for term, freq := range rec.BM25Data {
+			if postings[term] == nil {
+				postings[term] = make(map[string]int)
+			}
+			postings[term][id] = freq
+		}
+	}
+	return postings, nil
+}
+
+// GetSearchGrams returns the trigram index of the postings
+func (m *MockCacheService) GetSearchGrams() (map[string][]string, error) {
+	m.recordCall("GetSearchGrams")
+	postings, err := m.GetSearchPostings()
+	if err != nil {
+		return nil, err
+	}
+	grams := make(map[string][]string)
+	for term := range postings {
+		for _, tg := range search.Trigrams(term) {
+			grams[tg] = append(grams[tg], term)
+		}
+	}
+	for _, terms := range grams {
+		sort.Strings(terms)
+	}
+	return grams, nil
+}
+
+// SearchGeneration returns the number of writes so far
+func (m *MockCacheService) SearchGeneration() (uint64, error) {
+	m.recordCall("SearchGeneration")
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	return m.SearchGen, nil
+}
+
+// GetSearchHash returns the search index hash
+func (m *MockCacheService) GetSearchHash() (string, error) {
+	m.recordCall("GetSearchHash")
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return m.SearchHash, nil
+}
+
+// SetSearchHash sets the search index hash
+func (m *MockCacheService) SetSearchHash(hash string) error {
+	m.recordCall("SetSearchHash")
+	if m.Err != nil {
+		return m.Err
+	}
+	m.SearchHash = hash
+	return nil
+}
+
 // GetHTMLContent returns HTML content for a post
 func (m *MockCacheService) GetHTMLContent(post *cache.PostMeta) ([]byte, error) {
 	m.recordCall("GetHTMLContent")
@@ -238,6 +317,12 @@ func (m *MockCacheService) BatchCommit(posts []*cache.PostMeta, records map[stri
 	for _, post := range posts {
 		m.Posts[post.PostID] = post
 		m.PostsByPath[post.Path] = post
+		if rec, ok := records[post.PostID]; ok {
+			m.SearchRecords[post.PostID] = rec
+		}
+	}
+	if len(posts) > 0 {
+		m.SearchGen++
 	}
 	return nil
 }
@@ -249,6 +334,8 @@ func (m *MockCacheService) DeletePost(postID string) error {
 		return m.Err
 	}
 	delete(m.Posts, postID)
+	delete(m.SearchRecords, postID)
+	m.SearchGen++
 	return nil
 }
 
@@ -12,6 +12,7 @@ type MockRenderService struct {
 	RenderedGraph   map[string]models.PageData
 	RegisteredFiles map[string]bool
 	Assets          map[string]string
+	Data            map[string]interface{}
 	CallCount       map[string]int
 }
 
@@ -77,6 +78,17 @@ func (m *MockRenderService) GetAssets() map[string]string {
 	return m.Assets
 }
 
+// SetData sets the site data
+func (m *MockRenderService) SetData(data map[string]interface{}) {
+	m.recordCall("SetData")
+	m.Data = data
+}
+
+// ReloadTemplates records the call
+func (m *MockRenderService) ReloadTemplates() {
+	m.recordCall("ReloadTemplates")
+}
+
 // GetRenderedFiles returns all registered files
 func (m *MockRenderService) GetRenderedFiles() map[string]bool {
 	m.recordCall("GetRenderedFiles")
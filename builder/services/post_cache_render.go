@@ -12,6 +12,7 @@ import (
 
 	"github.com/Kush-Singh-26/kosh/builder/cache"
 	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/notebook"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 )
 
@@ -55,7 +56,7 @@ func (s *postServiceImpl) RenderCachedPosts() {
 		cachedData[id] = &CachedPostData{Meta: meta, HTML: htmlBytes}
 
 		// Regenerate Link from current baseURL
-		htmlRelPath := strings.ToLower(strings.Replace(meta.Path, ".md", ".html", 1))
+		htmlRelPath := utils.PostHTMLPath(meta.Path)
 		cleanHtmlRelPath := htmlRelPath
 		if meta.Version != "" {
 			cleanHtmlRelPath = strings.TrimPrefix(htmlRelPath, strings.ToLower(meta.Version)+"/")
@@ -64,7 +65,7 @@ func (s *postServiceImpl) RenderCachedPosts() {
 
 		post := models.PostMetadata{
 			Title: meta.Title, Link: regeneratedLink, Weight: meta.Weight, Version: meta.Version,
-			DateObj: meta.Date,
+			DateObj: meta.Date, LastMod: meta.LastMod, Summary: template.HTML(meta.Summary),
 		}
 		postsByVersion[meta.Version] = append(postsByVersion[meta.Version], post)
 	}
@@ -87,7 +88,7 @@ func (s *postServiceImpl) RenderCachedPosts() {
 			defer func() { <-sem }()
 
 			relPath := cp.Meta.Path
-			htmlRelPath := strings.ToLower(strings.Replace(relPath, ".md", ".html", 1))
+			htmlRelPath := utils.PostHTMLPath(relPath)
 
 			cleanHtmlRelPath := htmlRelPath
 			if cp.Meta.Version != "" {
@@ -104,7 +105,7 @@ func (s *postServiceImpl) RenderCachedPosts() {
 				destPath = filepath.Join(s.cfg.OutputDir, htmlRelPath)
 			}
 
-			if s.cfg.Features.RawMarkdown {
+			if s.cfg.Features.RawMarkdown && !notebook.IsNotebook(relPath) { // Notebooks write their converted Markdown when rendered
 				mdDestPath := destPath[:len(destPath)-len(filepath.Ext(destPath))] + ".md"
 				if _, err := os.Stat(mdDestPath); os.IsNotExist(err) {
 					sourcePath := filepath.Join(s.cfg.ContentDir, relPath)
@@ -135,7 +136,7 @@ func (s *postServiceImpl) RenderCachedPosts() {
 			versionPosts := postsByVersion[cp.Meta.Version]
 			currentPost := models.PostMetadata{
 				Title: cp.Meta.Title, Link: regeneratedLink, Weight: cp.Meta.Weight, Version: cp.Meta.Version,
-				DateObj: cp.Meta.Date,
+				DateObj: cp.Meta.Date, LastMod: cp.Meta.LastMod,
 			}
 			prev, next := utils.FindPrevNext(currentPost, versionPosts)
 
@@ -143,7 +144,8 @@ func (s *postServiceImpl) RenderCachedPosts() {
 				Title: cp.Meta.Title, Description: cp.Meta.Description, Content: template.HTML(string(cp.HTML)),
 				Meta: cp.Meta.Meta, BaseURL: s.cfg.BaseURL, BuildVersion: s.cfg.BuildVersion,
 				TabTitle: cp.Meta.Title + " | " + s.cfg.Title, Permalink: regeneratedLink, Image: imagePath,
-				TOC: toc, Config: s.cfg,
+				ImagePreview: s.coverPlaceholder(cp.Meta.Meta), TOC: toc, Config: s.cfg,
+				Date: cp.Meta.Date, LastMod: cp.Meta.LastMod,
 				SiteTree:       siteTrees[cp.Meta.Version],
 				CurrentVersion: cp.Meta.Version,
 				IsOutdated:     s.isOutdatedVersion(cp.Meta.Version),
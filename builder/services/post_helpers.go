@@ -1,6 +1,26 @@
 package services
 
-const wordsPerMinute = 120.0
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/parser"
+
+	"github.com/Kush-Singh-26/kosh/builder/cache"
+	"github.com/Kush-Singh-26/kosh/builder/frontmatter"
+	"github.com/Kush-Singh-26/kosh/builder/gitinfo"
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/notebook"
+	mdParser "github.com/Kush-Singh-26/kosh/builder/parser"
+	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
+	"github.com/Kush-Singh-26/kosh/builder/search"
+	"github.com/Kush-Singh-26/kosh/builder/utils"
+)
 
 type socialCardTask struct {
 	path, relPath, cardDestPath string
@@ -19,3 +39,432 @@ func (s *postServiceImpl) isOutdatedVersion(version string) bool {
 	}
 	return true
 }
+
+// warnParseProblems reports cross-references to labels the post does not define,
+// unknown citations and diagrams left as code blocks because they failed to render
+func (s *postServiceImpl) warnParseProblems(path string, pc parser.Context) {
+	for _, ref := range mdParser.GetBrokenRefs(pc) {
+		s.logger.Warn("Broken cross-reference", "path", path, "ref", ref)
+	}
+	for _, key := range mdParser.GetMissingCitations(pc) {
+		s.logger.Warn("Unknown citation", "path", path, "key", key)
+	}
+	for _, e := range mdParser.GetDiagramErrors(pc) {
+		s.logger.Warn("Failed to render diagram", "path", path, "kind", e.Kind, "error", e.Err)
+	}
+}
+
+// mathMacros merges the site's math.macros with the post's mathMacros front matter,
+// which wins on conflicts. Names get a leading backslash if they lack one.
+func (s *postServiceImpl) mathMacros(metaData map[string]interface{}) map[string]string {
+	post := make(map[string]string)
+	switch m := metaData["mathMacros"].(type) {
+	case map[string]interface{}:
+		for name, v := range m {
+			if expansion, ok := v.(string); ok {
+				post[name] = expansion
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			name, ok1 := k.(string)
+			expansion, ok2 := v.(string)
+			if ok1 && ok2 {
+				post[name] = expansion
+			}
+		}
+	}
+	return native.MergeMacros(s.cfg.Math.Macros, post)
+}
+
+// embedCode fills code fences that embed source files and reports failed embeds
+func (s *postServiceImpl) embedCode(path string, source []byte) ([]byte, []string) {
+	expanded, includes, errs := mdParser.EmbedCodeFiles(s.sourceFs, path, s.cfg.SnippetsDir, source)
+	for _, err := range errs {
+		s.logger.Warn("Failed to embed code", "path", path, "error", err)
+	}
+	return expanded, includes
+}
+
+// loadBibliography loads the post's bibliography file and reports failures.
+// It returns the file as an include of the post and its contents for the body
+// hash, both empty when the post has none.
+func (s *postServiceImpl) loadBibliography(path string, source []byte) (*mdParser.PostBibliography, []string, []byte) {
+	bib, err := mdParser.LoadBibliography(s.sourceFs, path, source)
+	if err != nil {
+		s.logger.Warn("Failed to load bibliography", "path", path, "error", err)
+	}
+	if bib == nil {
+		return nil, nil, nil
+	}
+	return bib, []string{bib.Path}, bib.Data
+}
+
+// checkFrontMatter reports front matter that fails to parse or does not
+// match the schema. It returns false when the post must not be built.
+func (s *postServiceImpl) checkFrontMatter(path string, pc parser.Context) bool {
+	schema := s.cfg.FrontMatter.Schema
+	report := s.logger.Warn
+	if schema.IsError() {
+		report = s.logger.Error
+	}
+	fm, err := meta.TryGet(pc)
+	if err != nil {
+		report("Invalid front matter", "path", path, "error", err)
+		return !schema.IsError()
+	}
+	violations := schema.Validate(fm)
+	for _, v := range violations {
+		report("Front matter does not match the schema", "path", path, "key", v.Key, "problem", v.Message)
+	}
+	return len(violations) == 0 || !schema.IsError()
+}
+
+// loadGitDates reads the dates of the content files from git when dates.git is on.
+// The history only changes when HEAD moves, so it is kept until then.
+func (s *postServiceImpl) loadGitDates() {
+	if !s.cfg.Dates.Git {
+		s.gitDates = nil
+		return
+	}
+	if s.gitDates != nil {
+		if head, err := gitinfo.Head(s.cfg.ContentDir); err == nil && head == s.gitDates.Head() {
+			return
+		}
+	}
+	s.gitDates = nil
+	history, err := gitinfo.Load(s.cfg.ContentDir)
+	if err != nil {
+		s.logger.Warn("Failed to read dates from git history", "dir", s.cfg.ContentDir, "error", err)
+		return
+	}
+	s.gitDates = history
+}
+
+// postDates returns the date and last modification of a post. The front matter
+// wins over git history, then the file's modification time fills in what git
+// does not know, like the dates of files older than a shallow clone. lastmod
+// falls back to the date.
+func (s *postServiceImpl) postDates(path string, metaData map[string]interface{}) (date, lastMod time.Time) {
+	layouts, loc := s.cfg.FrontMatter.Schema.DateFormats, s.cfg.Location()
+	date, _ = frontmatter.ParseDate(utils.GetString(metaData, "date"), layouts, loc)
+	lastMod, _ = frontmatter.ParseDate(utils.GetString(metaData, "lastmod"), layouts, loc)
+	if s.gitDates != nil {
+		git, _ := s.gitDates.Get(path)
+		if date.IsZero() {
+			date = git.Created.In(loc)
+		}
+		if lastMod.IsZero() {
+			lastMod = git.Modified.In(loc)
+		}
+		if date.IsZero() || lastMod.IsZero() {
+			if info, err := s.sourceFs.Stat(path); err == nil {
+				if date.IsZero() {
+					date = info.ModTime().In(loc)
+				}
+				if lastMod.IsZero() {
+					lastMod = info.ModTime().In(loc)
+				}
+			}
+		}
+	}
+	if lastMod.IsZero() || lastMod.Before(date) {
+		lastMod = date
+	}
+	return date, lastMod
+}
+
+// ImageProfileOverrides assigns the imageProfile front matter of every post to
+// the local images the post shows, its cover included. Posts unchanged since
+// they were cached reuse the assignments stored with them; only the others are
+// parsed, so this can run before the images are encoded. When posts give one
+// image different profiles, the post whose path sorts first wins.
+func (s *postServiceImpl) ImageProfileOverrides() map[string]string {
+	cached := make(map[string]*cache.PostMeta)
+	if s.cache != nil {
+		if ids, err := s.cache.ListAllPosts(); err == nil {
+			posts, _ := s.cache.GetPostsByIDs(ids)
+			for _, p := range posts {
+				if p != nil {
+					cached[p.Path] = p
+				}
+			}
+		}
+	}
+
+	perPost := make(map[string]map[string]string)
+	var paths []string
+	_ = afero.Walk(s.sourceFs, s.cfg.ContentDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || info.IsDir() || (!strings.HasSuffix(path, ".md") && !notebook.IsNotebook(path)) {
+			return nil
+		}
+		relPath, _ := utils.SafeRel(s.cfg.ContentDir, path)
+		front, overrides := s.cachedImageProfiles(cached[relPath], info)
+		if front == nil {
+			if front, overrides = s.parseImageProfiles(path); front == nil {
+				return nil
+			}
+		}
+		if profile := utils.GetString(front, "imageProfile"); profile != "" {
+			if _, ok := s.cfg.Images.Profiles[profile]; !ok {
+				s.logger.Warn("Unknown image profile", "path", path, "profile", profile)
+				return nil
+			}
+		}
+		if len(overrides) > 0 {
+			perPost[path] = overrides
+			paths = append(paths, path)
+		}
+		return nil
+	})
+
+	sort.Strings(paths)
+	result := make(map[string]string)
+	owners := make(map[string]string)
+	for _, path := range paths {
+		images := make([]string, 0, len(perPost[path]))
+		for img := range perPost[path] {
+			images = append(images, img)
+		}
+		sort.Strings(images)
+		for _, img := range images {
+			profile := perPost[path][img]
+			if kept, ok := result[img]; ok {
+				if kept != profile {
+					s.logger.Warn("Conflicting image profiles, keeping the first",
+						"image", img, "profile", kept, "path", owners[img], "ignored", profile, "ignoredPath", path)
+				}
+				continue
+			}
+			result[img] = profile
+			owners[img] = path
+		}
+	}
+	return result
+}
+
+// cachedImageProfiles returns the front matter and image profiles stored with
+// a post, or nil front matter when the post changed since it was cached
+func (s *postServiceImpl) cachedImageProfiles(meta *cache.PostMeta, info fs.FileInfo) (map[string]interface{}, map[string]string) {
+	if meta == nil || meta.Meta == nil || info.ModTime().Unix() > meta.ModTime {
+		return nil, nil
+	}
+	if utils.GetString(meta.Meta, "imageProfile") != "" && meta.ImageProfiles == nil {
+		return nil, nil // Cached before profiles were stored with posts
+	}
+	return meta.Meta, meta.ImageProfiles
+}
+
+// parseImageProfiles reads a post's front matter and image profiles from source
+func (s *postServiceImpl) parseImageProfiles(path string) (map[string]interface{}, map[string]string) {
+	source, err := afero.ReadFile(s.sourceFs, path)
+	if err != nil {
+		return nil, nil
+	}
+	if notebook.IsNotebook(path) {
+		nb, err := s.convertNotebook(path, "", source)
+		if err != nil {
+			return nil, nil // Reported when the post is built
+		}
+		source = nb.Markdown
+	}
+	if source, err = frontmatter.Normalize(source); err != nil {
+		return nil, nil
+	}
+	front, refs, err := mdParser.ImageRefs(source)
+	if err != nil {
+		return nil, nil
+	}
+	return front, s.imageProfiles(front, refs)
+}
+
+// postImageProfiles returns the image profiles of a post being built, which are
+// stored with it for ImageProfileOverrides
+func (s *postServiceImpl) postImageProfiles(front map[string]interface{}, source []byte) map[string]string {
+	if utils.GetString(front, "imageProfile") == "" {
+		return nil
+	}
+	_, refs, err := mdParser.ImageRefs(source)
+	if err != nil {
+		return nil
+	}
+	return s.imageProfiles(front, refs)
+}
+
+// imageProfiles assigns the imageProfile front matter to the local images in
+// refs and the cover. Empty, not nil, when the profile is set but assigns
+// nothing. Profiles are not checked against the config, so the stored result
+// survives profile changes.
+func (s *postServiceImpl) imageProfiles(front map[string]interface{}, refs []string) map[string]string {
+	profile := utils.GetString(front, "imageProfile")
+	if profile == "" {
+		return nil
+	}
+	overrides := make(map[string]string)
+	for _, ref := range append(refs, utils.GetString(front, "image")) {
+		if rel, ok := utils.StaticRelPath(ref); ok {
+			overrides[rel] = profile
+		}
+	}
+	return overrides
+}
+
+// convertNotebook converts a notebook post to Markdown. link is the URL of the
+// post's page; its images are served from the directory of the same name.
+func (s *postServiceImpl) convertNotebook(path, link string, source []byte) (*notebook.Notebook, error) {
+	return notebook.Convert(source, notebook.Options{
+		Language: s.cfg.Notebooks.Language,
+		ImageURL: strings.TrimSuffix(link, ".html"),
+	})
+}
+
+// writeNotebookImages writes a notebook's output images to the directory
+// named after its page, like "posts/intro/" for "posts/intro.html"
+func (s *postServiceImpl) writeNotebookImages(path, destPath string, images []notebook.Image) {
+	dir := strings.TrimSuffix(destPath, filepath.Ext(destPath))
+	for _, img := range images {
+		written, err := utils.WriteImageWithWebP(s.destFs, filepath.Join(dir, img.Name), img.Data)
+		if err != nil {
+			s.logger.Warn("Failed to write notebook image", "path", path, "image", img.Name, "error", err)
+		}
+		for _, p := range written {
+			s.renderer.RegisterFile(p)
+		}
+	}
+}
+
+// postBodyHash hashes what a post's page depends on besides its front matter.
+// Notebooks hash their JSON without execution counts, so re-running one with
+// the same results keeps its cache entry.
+func postBodyHash(source []byte, nb *notebook.Notebook, bibData []byte) string {
+	if nb != nil {
+		return utils.GetContentHash(nb.Stable, bibData)
+	}
+	return utils.GetBodyHash(source, bibData)
+}
+
+// renderMath server-renders the post's LaTeX and reports expressions KaTeX rejected
+func (s *postServiceImpl) renderMath(path, htmlContent string, diagramCache map[string]string, metaData map[string]interface{}) (string, []string) {
+	opts := mdParser.MathOptions{Macros: s.mathMacros(metaData), Output: s.cfg.Math.Output}
+	htmlContent, hashes, mathErrors := mdParser.RenderMathForHTML(htmlContent, s.nativeRenderer, diagramCache, &s.mu, opts)
+	for _, e := range mathErrors {
+		s.logger.Warn("Failed to render math", "path", path, "expression", e.LaTeX, "error", e.Message)
+	}
+	return htmlContent, hashes
+}
+
+// feedSummary returns the summary of a post rendered before its math, with the
+// math rendered using math.feedOutput, since feed readers do not load KaTeX's CSS.
+// Expressions KaTeX rejects are already reported by renderMath.
+func (s *postServiceImpl) feedSummary(htmlContent string, diagramCache map[string]string, metaData map[string]interface{}) (string, []string) {
+	summary, _ := mdParser.SplitSummary(htmlContent)
+	if !strings.Contains(summary, "$") && !strings.Contains(summary, "\\(") {
+		return summary, nil
+	}
+	opts := mdParser.MathOptions{Macros: s.mathMacros(metaData), Output: s.cfg.Math.FeedOutput}
+	summary, hashes, _ := mdParser.RenderMathForHTML(summary, s.nativeRenderer, diagramCache, &s.mu, opts)
+	return summary, hashes
+}
+
+// coverPlaceholder returns the blur-up placeholder of the post's "image" front matter
+func (s *postServiceImpl) coverPlaceholder(metaData map[string]interface{}) models.ImagePlaceholder {
+	img := utils.GetString(metaData, "image")
+	if s.images == nil || img == "" {
+		return models.ImagePlaceholder{}
+	}
+	ph, _ := s.images.Placeholder(img)
+	return ph
+}
+
+// searchAnalyzer picks the analyzer from the post's "lang" front matter,
+// falling back to the site language
+func (s *postServiceImpl) searchAnalyzer(metaData map[string]interface{}) search.Analyzer {
+	if lang := utils.GetString(metaData, "lang"); lang != "" {
+		return search.AnalyzerFor(lang)
+	}
+	return search.AnalyzerFor(s.cfg.Language)
+}
+
+// SetSearchSynonyms sets the synonyms expanded into search records when
+// search.indexSynonyms is on
+func (s *postServiceImpl) SetSearchSynonyms(synonyms map[string][]string) {
+	s.synonyms = nil
+	if s.cfg.Search.IndexSynonyms {
+		s.synonyms = search.NormalizeSynonyms(synonyms)
+	}
+}
+
+// newSearchRecord analyzes a post for the search index. Stems and synonym
+// terms are kept with the record, so index builds do not re-analyze the post.
+func (s *postServiceImpl) newSearchRecord(rec models.PostRecord, analyzer search.Analyzer) *cache.SearchRecord {
+	wordFreqs, docLen := analyzeSearchRecord(rec, analyzer)
+	record := &cache.SearchRecord{
+		Title: rec.Title, NormalizedTitle: rec.NormalizedTitle,
+		BM25Data: wordFreqs, DocLen: docLen, Content: rec.Content,
+		NormalizedTags: rec.NormalizedTags, Analyzer: analyzer.Name(),
+		Stems: searchStems(rec.Content, analyzer),
+	}
+	s.expandSynonyms(record, analyzer)
+	return record
+}
+
+// expandSynonyms stores the synonym terms a record gains from the current table
+func (s *postServiceImpl) expandSynonyms(record *cache.SearchRecord, analyzer search.Analyzer) {
+	record.Synonyms, record.SynonymsKey = nil, search.SynonymsKey(s.synonyms)
+	text := record.Title + " " + record.Content
+	for _, term := range search.ExpandSynonyms(text, s.synonyms, []search.Analyzer{analyzer}, nil) {
+		if _, ok := record.BM25Data[term]; !ok {
+			record.Synonyms = append(record.Synonyms, term)
+		}
+	}
+}
+
+// searchStems maps each stem in text to the original words it came from
+func searchStems(text string, analyzer search.Analyzer) map[string][]string {
+	var stems map[string][]string
+	stemmed, originals := analyzer.AnalyzeWithOriginals(text)
+	for j, stem := range stemmed {
+		if j >= len(originals) || stem == originals[j] {
+			continue
+		}
+		if stems == nil {
+			stems = make(map[string][]string)
+		}
+		found := false
+		for _, e := range stems[stem] {
+			if e == originals[j] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			stems[stem] = append(stems[stem], originals[j])
+		}
+	}
+	return stems
+}
+
+// analyzeSearchRecord tokenizes title, description, tags and content for BM25,
+// returning term frequencies and the document length
+func analyzeSearchRecord(rec models.PostRecord, analyzer search.Analyzer) (map[string]int, int) {
+	var sb strings.Builder
+	sb.Grow(len(rec.Title) + len(rec.Description) + len(rec.Content) + 200)
+	sb.WriteString(rec.Title)
+	sb.WriteByte(' ')
+	sb.WriteString(rec.Description)
+	sb.WriteByte(' ')
+	for _, t := range rec.Tags {
+		sb.WriteString(t)
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(rec.Content)
+
+	words := analyzer.Analyze(sb.String())
+	wordFreqs := make(map[string]int)
+	for _, w := range words {
+		if len(w) >= 2 {
+			wordFreqs[w]++
+		}
+	}
+	return wordFreqs, len(words)
+}
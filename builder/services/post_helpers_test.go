@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/cache"
+	"github.com/Kush-Singh-26/kosh/builder/config"
+	"github.com/Kush-Singh-26/kosh/builder/gitinfo"
+	"github.com/Kush-Singh-26/kosh/builder/services/mocks"
+)
+
+// TestPostDatesWithoutGitDates covers files git has no dates for, as in a
+// shallow clone: the front matter wins, then the modification time.
+func TestPostDatesWithoutGitDates(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	path := filepath.Join(dir, "content", "a.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	history, err := gitinfo.Load(filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("gitinfo.Load() error = %v", err)
+	}
+
+	cfg := &config.Config{Timezone: "UTC", Dates: config.DatesConfig{Git: true}}
+	s := &postServiceImpl{cfg: cfg, sourceFs: afero.NewOsFs(), gitDates: history}
+
+	date, lastMod := s.postDates(path, map[string]interface{}{})
+	if !date.Equal(mtime) || !lastMod.Equal(mtime) {
+		t.Errorf("postDates() = %v, %v, want the modification time %v", date, lastMod, mtime)
+	}
+	date, lastMod = s.postDates(path, map[string]interface{}{"date": "2023-01-02"})
+	if want := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC); !date.Equal(want) || !lastMod.Equal(mtime) {
+		t.Errorf("postDates() with a front matter date = %v, %v, want %v, %v", date, lastMod, want, mtime)
+	}
+}
+
+func TestImageProfileOverrides(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"content/launch.md": "---\ntitle: Launch\nimageProfile: hero\nimage: /static/images/cover.jpg\n---\n\n" +
+			"![UI](/static/images/ui.png)\n\n```md\n![Code](/static/images/code.png)\n```\n",
+		"content/toml.md":      "+++\ntitle = \"TOML\"\nimageProfile = \"hero\"\n+++\n\n- ![Chart](/static/images/chart.png)\n",
+		"content/nb.ipynb":     `{"nbformat": 4, "metadata": {"kosh": {"imageProfile": "screenshot"}}, "cells": [{"cell_type": "markdown", "source": "![Plot](/static/images/plot.png)", "metadata": {}}]}`,
+		"content/typo.md":      "---\ntitle: Typo\nimageProfile: heor\n---\n\n![A](/static/images/a.png)\n",
+		"content/plain.md":     "---\ntitle: Plain\n---\n\n![B](/static/images/b.png)\n",
+		"content/external.md":  "---\nimageProfile: hero\n---\n\n![C](https://example.com/c.png)\n",
+		"content/notes/x.json": `{"imageProfile": "hero"}`,
+		"content/zz.md":        "---\nimageProfile: screenshot\n---\n\n![UI](/static/images/ui.png)\n",
+	}
+	for name, data := range files {
+		if err := afero.WriteFile(fs, name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var logs bytes.Buffer
+	cfg := &config.Config{ContentDir: "content", Images: config.ImagesConfig{
+		Profiles: map[string]config.ImageProfile{"hero": {}, "screenshot": {}},
+	}}
+	s := &postServiceImpl{cfg: cfg, sourceFs: fs, logger: slog.New(slog.NewTextHandler(&logs, nil))}
+
+	want := map[string]string{
+		"images/cover.jpg": "hero",
+		"images/ui.png":    "hero",
+		"images/chart.png": "hero",
+		"images/plot.png":  "screenshot",
+	}
+	if got := s.ImageProfileOverrides(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ImageProfileOverrides() = %v, want %v", got, want)
+	}
+	if !strings.Contains(logs.String(), "Unknown image profile") || !strings.Contains(logs.String(), "profile=heor") {
+		t.Errorf("missing warning about the unknown profile, logs:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "Conflicting image profiles") || !strings.Contains(logs.String(), "ignored=screenshot") {
+		t.Errorf("missing warning about the conflicting profiles, logs:\n%s", logs.String())
+	}
+}
+
+// TestImageProfileOverridesFromCache covers posts cached since they last
+// changed, whose stored profiles are used without parsing the file.
+func TestImageProfileOverridesFromCache(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	mtime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"fresh.md", "edited.md", "legacy.md"} {
+		path := "content/" + name
+		data := "---\nimageProfile: hero\n---\n\n![X](/static/images/" + name + ".png)\n"
+		if err := afero.WriteFile(fs, path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mockCache := mocks.NewMockCacheService()
+	hero := map[string]interface{}{"imageProfile": "hero"}
+	for _, meta := range []*cache.PostMeta{
+		{PostID: "fresh", Path: "fresh.md", ModTime: mtime.Unix(), Meta: hero, ImageProfiles: map[string]string{"images/stored.png": "hero"}},
+		{PostID: "edited", Path: "edited.md", ModTime: mtime.Unix() - 60, Meta: hero, ImageProfiles: map[string]string{"images/old.png": "hero"}},
+		{PostID: "legacy", Path: "legacy.md", ModTime: mtime.Unix(), Meta: hero},
+	} {
+		mockCache.Posts[meta.PostID] = meta
+	}
+
+	cfg := &config.Config{ContentDir: "content", Images: config.ImagesConfig{
+		Profiles: map[string]config.ImageProfile{"hero": {}},
+	}}
+	s := &postServiceImpl{cfg: cfg, sourceFs: fs, cache: mockCache, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	want := map[string]string{
+		"images/stored.png":    "hero",
+		"images/edited.md.png": "hero",
+		"images/legacy.md.png": "hero",
+	}
+	if got := s.ImageProfileOverrides(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ImageProfileOverrides() = %v, want %v", got, want)
+	}
+}
@@ -6,13 +6,11 @@ import (
 	"html/template"
 	"io/fs"
 	"log/slog"
-	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/spf13/afero"
 	"github.com/yuin/goldmark"
@@ -22,9 +20,13 @@ import (
 
 	"github.com/Kush-Singh-26/kosh/builder/cache"
 	"github.com/Kush-Singh-26/kosh/builder/config"
+	"github.com/Kush-Singh-26/kosh/builder/frontmatter"
+	"github.com/Kush-Singh-26/kosh/builder/gitinfo"
 	"github.com/Kush-Singh-26/kosh/builder/metrics"
 	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/notebook"
 	mdParser "github.com/Kush-Singh-26/kosh/builder/parser"
+	"github.com/Kush-Singh-26/kosh/builder/renderer"
 	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
 	"github.com/Kush-Singh-26/kosh/builder/search"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
@@ -41,6 +43,9 @@ type postServiceImpl struct {
 	sourceFs       afero.Fs
 	destFs         afero.Fs
 	diagramAdapter *cache.DiagramCacheAdapter // Kept as specific type or interface?
+	images         *utils.ImageResolver
+	gitDates       *gitinfo.History    // Reloaded by Process when dates.git is on and HEAD moved
+	synonyms       map[string][]string // Normalized synonyms indexed into posts, nil unless search.indexSynonyms
 
 	// Mutex for D2/Math rendering safety if needed
 	mu sync.Mutex
@@ -56,6 +61,7 @@ func NewPostService(
 	nativeRenderer *native.Renderer,
 	sourceFs, destFs afero.Fs,
 	diagramAdapter *cache.DiagramCacheAdapter,
+	images *utils.ImageResolver,
 ) PostService {
 	return &postServiceImpl{
 		cfg:            cfg,
@@ -68,6 +74,7 @@ func NewPostService(
 		sourceFs:       sourceFs,
 		destFs:         destFs,
 		diagramAdapter: diagramAdapter,
+		images:         images,
 	}
 }
 
@@ -101,6 +108,8 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 		Version  string
 	}
 
+	s.loadGitDates()
+
 	var files []string
 	var fileVersions []string
 	if err := afero.Walk(s.sourceFs, s.cfg.ContentDir, func(path string, info fs.FileInfo, err error) error {
@@ -108,7 +117,8 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			s.logger.Error("Error walking content directory", "path", path, "error", err)
 			return nil // Continue walking other files
 		}
-		if strings.HasSuffix(path, ".md") && !strings.Contains(path, "_index.md") {
+		isMarkdown := strings.HasSuffix(path, ".md") && !strings.Contains(path, "_index.md")
+		if isMarkdown || notebook.IsNotebook(path) {
 			if strings.Contains(path, "404.md") {
 				has404 = true
 			} else {
@@ -143,8 +153,9 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			for _, cp := range cachedPosts {
 				allMetadataMap.Store(cp.Link, models.PostMetadata{
 					Title: cp.Title, Link: cp.Link, Weight: cp.Weight, Version: cp.Version,
-					DateObj: cp.Date, ReadingTime: cp.ReadingTime, Description: cp.Description,
-					Tags: cp.Tags, Pinned: cp.Pinned, Draft: cp.Draft,
+					DateObj: cp.Date, LastMod: cp.LastMod, ReadingTime: cp.ReadingTime, Description: cp.Description,
+					Tags: cp.Tags, Pinned: cp.Pinned, Draft: cp.Draft, Summary: template.HTML(cp.Summary),
+					FeedSummary: template.HTML(cp.FeedSummary),
 				})
 			}
 		}
@@ -158,7 +169,7 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 		idx, path, version := pt.idx, pt.path, pt.version
 
 		relPath, _ := utils.SafeRel(s.cfg.ContentDir, path)
-		htmlRelPath := strings.ToLower(strings.Replace(relPath, ".md", ".html", 1))
+		htmlRelPath := utils.PostHTMLPath(relPath)
 
 		cleanHtmlRelPath := htmlRelPath
 		if version != "" {
@@ -179,6 +190,7 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 		var err error
 		var info os.FileInfo
 		var source []byte
+		var includes []string
 		var bodyHash string
 		exists := false
 
@@ -203,7 +215,23 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			return
 		}
 		source, _ = afero.ReadFile(s.sourceFs, path)
-		bodyHash = utils.GetBodyHash(source)
+		var nb *notebook.Notebook
+		if notebook.IsNotebook(path) {
+			if nb, err = s.convertNotebook(path, utils.BuildURL(s.cfg.BaseURL, version, cleanHtmlRelPath), source); err != nil {
+				s.logger.Error("Failed to convert notebook", "path", path, "error", err)
+				return
+			}
+			source = nb.Markdown
+		}
+		if source, err = frontmatter.Normalize(source); err != nil {
+			s.logger.Error("Invalid front matter", "path", path, "error", err)
+			return
+		}
+		// Embedded files are part of the body, so editing one invalidates the post
+		source, includes = s.embedCode(path, source)
+		bib, bibIncludes, bibData := s.loadBibliography(path, source)
+		includes = append(includes, bibIncludes...)
+		bodyHash = postBodyHash(source, nb, bibData)
 
 		// Invalidate cache if body content changed (regardless of ModTime)
 		if exists && cachedMeta != nil && cachedMeta.BodyHash != "" && cachedMeta.BodyHash != bodyHash {
@@ -221,11 +249,12 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 
 		var htmlContent string
 		var metaData map[string]interface{}
+		var imageProfiles map[string]string
 		var post models.PostMetadata
 		var searchRecord models.PostRecord
+		var indexRecord *cache.SearchRecord // Stored record the indexed post is built from
 		var wordFreqs map[string]int
 		var docLen int
-		var words []string
 		var toc []models.TOCEntry
 		var frontmatterHash string
 		var plainText string
@@ -255,6 +284,16 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 					post = cachedPost
 				}
 			}
+			// Cached records can go stale without the file changing; refreshed
+			// ones are saved so renders straight from the cache see them too
+			updated, stale := *cachedMeta, false
+			if s.gitDates != nil { // New commits move git dates
+				post.DateObj, post.LastMod = s.postDates(path, metaData)
+				if !post.DateObj.Equal(cachedMeta.Date) || !post.LastMod.Equal(cachedMeta.LastMod) {
+					updated.Date, updated.LastMod = post.DateObj, post.LastMod
+					stale = true
+				}
+			}
 
 			for _, t := range cachedMeta.TOC {
 				toc = append(toc, models.TOCEntry{ID: t.ID, Text: t.Text, Level: t.Level})
@@ -269,9 +308,35 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 				NormalizedTags:  cachedSearch.NormalizedTags,
 				Content:         cachedSearch.Content,
 				Version:         cachedMeta.Version,
+				Analyzer:        cachedSearch.Analyzer,
+			}
+			indexRecord = cachedSearch
+
+			var reindexed *cache.SearchRecord
+			if analyzer := s.searchAnalyzer(metaData); cachedSearch.Analyzer != analyzer.Name() {
+				// The site language changed since the post was indexed
+				searchRecord.Analyzer = analyzer.Name()
+				reindexed = s.newSearchRecord(searchRecord, analyzer)
+			} else if cachedSearch.SynonymsKey != search.SynonymsKey(s.synonyms) {
+				// The synonym table changed since the post was indexed
+				refreshed := *cachedSearch
+				s.expandSynonyms(&refreshed, analyzer)
+				reindexed = &refreshed
+			}
+			if reindexed != nil {
+				indexRecord = reindexed
+				stale = true
+			}
+			docLen = indexRecord.DocLen
+			wordFreqs = indexRecord.BM25Data
+			if stale {
+				batchMu.Lock()
+				newPostsMeta = append(newPostsMeta, &updated)
+				if reindexed != nil {
+					newSearchRecords[cachedMeta.PostID] = reindexed
+				}
+				batchMu.Unlock()
 			}
-			docLen = cachedSearch.DocLen
-			wordFreqs = cachedSearch.BM25Data
 		} else {
 			s.metrics.IncrementCacheMiss()
 
@@ -289,7 +354,13 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 
 			ctx := parser.NewContext()
 			ctx.Set(mdParser.ContextKeyFilePath, path)
+			if bib != nil {
+				ctx.Set(mdParser.ContextKeyBibliography, bib.Library)
+			}
 			docNode := s.md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+			if !s.checkFrontMatter(path, ctx) {
+				return
+			}
 
 			// Use BufferPool
 			buf := utils.SharedBufferPool.Get()
@@ -311,25 +382,29 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			}
 
 			ssrHashes = mdParser.GetSSRHashes(ctx)
+			s.warnParseProblems(path, ctx)
 
+			var summary, feedSummary string
 			if bytes.Contains(source, []byte("$")) || bytes.Contains(source, []byte("\\(")) {
-				var mathHashes []string
-				htmlContent, mathHashes = mdParser.RenderMathForHTML(htmlContent, s.nativeRenderer, diagramCache, &s.mu)
-				ssrHashes = append(ssrHashes, mathHashes...)
+				var mathHashes, feedHashes []string
+				feedSummary, feedHashes = s.feedSummary(htmlContent, diagramCache, meta.Get(ctx))
+				htmlContent, mathHashes = s.renderMath(path, htmlContent, diagramCache, meta.Get(ctx))
+				ssrHashes = append(append(ssrHashes, mathHashes...), feedHashes...)
 			}
-			if s.cfg.CompressImages {
-				htmlContent = utils.ReplaceToWebP(htmlContent)
+			summary, htmlContent = mdParser.SplitSummary(htmlContent)
+			if feedSummary == "" {
+				feedSummary = summary
 			}
 
 			metaData = meta.Get(ctx)
-			dateStr := utils.GetString(metaData, "date")
-			dateObj, _ := time.Parse("2006-01-02", dateStr)
+			imageProfiles = s.postImageProfiles(metaData, source)
+			dateObj, lastMod := s.postDates(path, metaData)
 			isPinned, _ := metaData["pinned"].(bool)
 			weight, _ := metaData["weight"].(int)
 			if w, ok := metaData["weight"].(float64); ok && weight == 0 {
 				weight = int(w)
 			}
-			wordCount := len(strings.Fields(string(source)))
+			wordCount := mdParser.CountWords(docNode, source)
 			toc = mdParser.GetTOC(ctx)
 
 			postLink := utils.BuildURL(s.cfg.BaseURL, version, cleanHtmlRelPath)
@@ -337,8 +412,9 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			post = models.PostMetadata{
 				Title: utils.GetString(metaData, "title"), Link: postLink,
 				Description: utils.GetString(metaData, "description"), Tags: utils.GetSlice(metaData, "tags"),
-				ReadingTime: int(math.Ceil(float64(wordCount) / wordsPerMinute)), Pinned: isPinned, Weight: weight,
-				DateObj: dateObj, Draft: utils.GetBool(metaData, "draft"), Version: version,
+				ReadingTime: utils.ReadingTime(wordCount), Pinned: isPinned, Weight: weight,
+				DateObj: dateObj, LastMod: lastMod, Draft: utils.GetBool(metaData, "draft"), Version: version,
+				Summary: template.HTML(summary), FeedSummary: template.HTML(feedSummary),
 			}
 
 			plainText = mdParser.ExtractPlainText(docNode, source)
@@ -358,30 +434,11 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 				NormalizedTags:  normalizedTags,
 				Content:         plainText,
 				Version:         version,
+				Analyzer:        s.searchAnalyzer(metaData).Name(),
 			}
 
-			// Use analyzer for tokenization with stemming and stop word removal
-			var sb strings.Builder
-			sb.Grow(len(searchRecord.Title) + len(searchRecord.Description) + len(searchRecord.Content) + 200)
-			sb.WriteString(searchRecord.Title)
-			sb.WriteByte(' ')
-			sb.WriteString(searchRecord.Description)
-			sb.WriteByte(' ')
-			for _, t := range searchRecord.Tags {
-				sb.WriteString(t)
-				sb.WriteByte(' ')
-			}
-			sb.WriteString(searchRecord.Content)
-
-			// Analyze with stemming and stop words
-			words = search.DefaultAnalyzer.Analyze(sb.String())
-			docLen = len(words)
-			wordFreqs = make(map[string]int)
-			for _, w := range words {
-				if len(w) >= 2 {
-					wordFreqs[w]++
-				}
-			}
+			indexRecord = s.newSearchRecord(searchRecord, s.searchAnalyzer(metaData))
+			wordFreqs, docLen = indexRecord.BM25Data, indexRecord.DocLen
 			frontmatterHash, _ = utils.GetFrontmatterHash(metaData)
 		}
 
@@ -432,8 +489,8 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 		}
 
 		willRender := false
-		if outputMissing {
-			willRender = true
+		if outputMissing || shouldForce || cachedMeta == nil {
+			willRender = true // New posts, full rebuilds and posts whose template changed
 		} else if useCache {
 			if _, err := os.Stat(destPath); os.IsNotExist(err) {
 				willRender = true
@@ -452,6 +509,9 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			mdDestPath := destPath[:len(destPath)-len(filepath.Ext(destPath))] + ".md"
 			if _, err := os.Stat(mdDestPath); os.IsNotExist(err) {
 				sourceBytes, err := afero.ReadFile(s.sourceFs, path)
+				if nb != nil {
+					sourceBytes = nb.Markdown // Notebooks are viewed as their converted Markdown
+				}
 				if err != nil {
 					s.logger.Error("Failed to read source file for raw markdown", "path", path, "error", err)
 				} else if len(sourceBytes) > 0 {
@@ -465,6 +525,10 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			}
 		}
 
+		if willRender && nb != nil {
+			s.writeNotebookImages(path, destPath, nb.Images)
+		}
+
 		if willRender {
 			renderQueue[idx] = RenderContext{
 				DestPath: destPath,
@@ -473,7 +537,8 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 					Title: post.Title, Description: post.Description, Content: template.HTML(htmlContent),
 					Meta: metaData, BaseURL: s.cfg.BaseURL, BuildVersion: s.cfg.BuildVersion,
 					TabTitle: post.Title + " | " + s.cfg.Title, Permalink: post.Link, Image: imagePath,
-					TOC: toc, Config: s.cfg,
+					ImagePreview: s.coverPlaceholder(metaData), TOC: toc, Config: s.cfg,
+					Date: post.DateObj, LastMod: post.LastMod,
 					CurrentVersion: version,
 					IsOutdated:     s.isOutdatedVersion(version),
 					Versions:       s.cfg.GetVersionsMetadata(version, cleanHtmlRelPath),
@@ -490,7 +555,13 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 		// Lock-free indexed post assignment using atomic index
 		id := int(atomic.AddInt32(&indexedPostIdx, 1))
 		searchRecord.ID = id
-		indexedPosts[id] = models.IndexedPost{Record: searchRecord, WordFreqs: wordFreqs, DocLen: docLen}
+		indexedPosts[id] = models.IndexedPost{
+			Record: searchRecord, WordFreqs: wordFreqs, DocLen: docLen,
+			Stems: indexRecord.Stems, Synonyms: indexRecord.Synonyms, SynonymsKey: indexRecord.SynonymsKey,
+		}
+		if s.cache != nil {
+			indexedPosts[id].PostID = cache.GeneratePostID("", relPath)
+		}
 
 		// Check for cancellation
 		select {
@@ -503,25 +574,20 @@ func (s *postServiceImpl) Process(ctx context.Context, shouldForce, forceSocialR
 			postID := cache.GeneratePostID("", relPath)
 			newMeta := &cache.PostMeta{
 				PostID: postID, Path: relPath, ModTime: info.ModTime().Unix(),
-				ContentHash: frontmatterHash, BodyHash: bodyHash, Title: post.Title, Date: post.DateObj,
+				ContentHash: frontmatterHash, BodyHash: bodyHash, Title: post.Title, Date: post.DateObj, LastMod: post.LastMod,
 				Tags: post.Tags, ReadingTime: post.ReadingTime, Description: post.Description,
 				Link: post.Link, Pinned: post.Pinned, Weight: post.Weight, Draft: post.Draft,
-				Meta: metaData, TOC: toc, Version: version,
-				SSRInputHashes: ssrHashes,
+				Meta: metaData, TOC: toc, Version: version, Summary: string(post.Summary),
+				FeedSummary: string(post.FeedSummary), SSRInputHashes: ssrHashes, ImageProfiles: imageProfiles,
 			}
 			if err := s.cache.StoreHTMLForPost(newMeta, []byte(htmlContent)); err != nil {
 				s.logger.Error("Failed to store HTML in cache", "path", relPath, "error", err)
 			}
-			newSearch := &cache.SearchRecord{
-				Title: post.Title, NormalizedTitle: searchRecord.NormalizedTitle,
-				BM25Data: wordFreqs, DocLen: docLen, Content: plainText,
-				NormalizedTags: searchRecord.NormalizedTags,
-			}
-			newDep := &cache.Dependencies{Tags: post.Tags}
+			newDep := &cache.Dependencies{Tags: post.Tags, Includes: includes, Templates: []string{renderer.LayoutFile(metaData)}}
 
 			batchMu.Lock()
 			newPostsMeta = append(newPostsMeta, newMeta)
-			newSearchRecords[postID] = newSearch
+			newSearchRecords[postID] = indexRecord
 			newDeps[postID] = newDep
 			batchMu.Unlock()
 		}
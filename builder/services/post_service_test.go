@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/config"
+	"github.com/Kush-Singh-26/kosh/builder/metrics"
+	mdParser "github.com/Kush-Singh-26/kosh/builder/parser"
+	"github.com/Kush-Singh-26/kosh/builder/search"
+	"github.com/Kush-Singh-26/kosh/builder/services/mocks"
+)
+
+// TestProcessReindexesOnLanguageChange checks that cached posts are analyzed
+// again when the site language changes, without re-rendering them.
+func TestProcessReindexesOnLanguageChange(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	source := "---\ntitle: Laufende Netzwerke\n---\n\nDie Netzwerke laufen schneller.\n"
+	if err := afero.WriteFile(fs, "content/netze.md", []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{ContentDir: "content", OutputDir: "public", Language: "en"}
+	cacheSvc := mocks.NewMockCacheService()
+	s := NewPostService(cfg, cacheSvc, mocks.NewMockRenderService(), slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics.NewBuildMetrics(), mdParser.New("", nil, &sync.Map{}), nil, fs, afero.NewMemMapFs(), nil, nil)
+
+	analyzers := func(result *PostResult) []string {
+		var names []string
+		for _, p := range result.IndexedPosts {
+			names = append(names, p.Record.Analyzer)
+		}
+		return names
+	}
+
+	result, err := s.Process(context.Background(), false, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if got := analyzers(result); len(got) != 1 || got[0] != search.EnglishAnalyzerName {
+		t.Fatalf("first build analyzers = %v, want [en]", got)
+	}
+
+	cfg.Language = "de"
+	stored := cacheSvc.CallCount["StoreHTMLForPost"]
+	result, err = s.Process(context.Background(), false, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if got := analyzers(result); len(got) != 1 || got[0] != search.GermanAnalyzerName {
+		t.Errorf("analyzers after the language change = %v, want [de]", got)
+	}
+	if cacheSvc.CallCount["StoreHTMLForPost"] != stored {
+		t.Error("post was re-rendered, want it served from the cache")
+	}
+	rec := cacheSvc.BatchCommitRecords
+	if len(rec) != 1 {
+		t.Fatalf("saved search records = %v, want the re-analyzed one", rec)
+	}
+	for _, r := range rec {
+		want, _ := analyzeSearchRecord(result.IndexedPosts[0].Record, search.AnalyzerFor("de"))
+		if r.Analyzer != search.GermanAnalyzerName || len(r.BM25Data) != len(want) {
+			t.Errorf("saved record analyzer = %q, terms %v, want de terms %v", r.Analyzer, r.BM25Data, want)
+		}
+	}
+}
+
+// TestProcessRefreshesSynonymTerms checks that search records keep their stems
+// and synonym terms, and that cached posts pick up a changed synonym table.
+func TestProcessRefreshesSynonymTerms(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	source := "---\ntitle: Kernels\n---\n\nConvolution layers running kernels.\n"
+	if err := afero.WriteFile(fs, "content/cnn.md", []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{ContentDir: "content", OutputDir: "public", Language: "en"}
+	cfg.Search.IndexSynonyms = true
+	cacheSvc := mocks.NewMockCacheService()
+	s := NewPostService(cfg, cacheSvc, mocks.NewMockRenderService(), slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics.NewBuildMetrics(), mdParser.New("", nil, &sync.Map{}), nil, fs, afero.NewMemMapFs(), nil, nil)
+
+	s.SetSearchSynonyms(map[string][]string{"cnn": {"convolution"}})
+	result, err := s.Process(context.Background(), false, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	post := result.IndexedPosts[0]
+	if len(post.Synonyms) != 1 || post.Synonyms[0] != "cnn" {
+		t.Errorf("synonym terms = %v, want [cnn]", post.Synonyms)
+	}
+	if len(post.Stems["kernel"]) != 1 || post.Stems["kernel"][0] != "kernels" {
+		t.Errorf("stems = %v, want kernel -> [kernels]", post.Stems)
+	}
+
+	s.SetSearchSynonyms(map[string][]string{"kernels": {"filter"}})
+	stored := cacheSvc.CallCount["StoreHTMLForPost"]
+	result, err = s.Process(context.Background(), false, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if got := result.IndexedPosts[0].Synonyms; len(got) != 1 || got[0] != "filter" {
+		t.Errorf("synonym terms after the table changed = %v, want [filter]", got)
+	}
+	if cacheSvc.CallCount["StoreHTMLForPost"] != stored {
+		t.Error("post was re-rendered, want it served from the cache")
+	}
+	for _, r := range cacheSvc.BatchCommitRecords {
+		if len(r.Synonyms) != 1 || r.Synonyms[0] != "filter" || len(r.Stems) == 0 {
+			t.Errorf("saved record synonyms = %v, stems %v", r.Synonyms, r.Stems)
+		}
+	}
+}
@@ -5,10 +5,8 @@ import (
 	"context"
 	"fmt"
 	"html/template"
-	"math"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/spf13/afero"
 	meta "github.com/yuin/goldmark-meta"
@@ -16,8 +14,11 @@ import (
 	"github.com/yuin/goldmark/text"
 
 	"github.com/Kush-Singh-26/kosh/builder/cache"
+	"github.com/Kush-Singh-26/kosh/builder/frontmatter"
 	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/notebook"
 	mdParser "github.com/Kush-Singh-26/kosh/builder/parser"
+	"github.com/Kush-Singh-26/kosh/builder/renderer"
 	"github.com/Kush-Singh-26/kosh/builder/utils"
 )
 
@@ -39,9 +40,11 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 		s.logger.Error("Error reading file", "path", path, "error", err)
 		return err
 	}
-
 	version, relPath := utils.GetVersionFromPath(path)
-	htmlRelPath := strings.ToLower(strings.Replace(relPath, ".md", ".html", 1))
+	htmlRelPath := utils.PostHTMLPath(relPath)
+	if s.gitDates == nil {
+		s.loadGitDates()
+	}
 
 	cleanHtmlRelPath := htmlRelPath
 	if version != "" {
@@ -56,10 +59,33 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 	}
 	fullLink := utils.BuildURL(s.cfg.BaseURL, version, cleanHtmlRelPath)
 
+	var nb *notebook.Notebook
+	if notebook.IsNotebook(path) {
+		if nb, err = s.convertNotebook(path, fullLink, source); err != nil {
+			s.logger.Error("Failed to convert notebook", "path", path, "error", err)
+			return err
+		}
+		source = nb.Markdown
+		s.writeNotebookImages(path, destPath, nb.Images)
+	}
+	if source, err = frontmatter.Normalize(source); err != nil {
+		s.logger.Error("Invalid front matter", "path", path, "error", err)
+		return err
+	}
+	source, includes := s.embedCode(path, source)
+	bib, bibIncludes, bibData := s.loadBibliography(path, source)
+	includes = append(includes, bibIncludes...)
+
 	context := gParser.NewContext()
 	context.Set(mdParser.ContextKeyFilePath, path)
+	if bib != nil {
+		context.Set(mdParser.ContextKeyBibliography, bib.Library)
+	}
 	reader := text.NewReader(source)
 	docNode := s.md.Parser().Parse(reader, gParser.WithContext(context))
+	if !s.checkFrontMatter(path, context) {
+		return fmt.Errorf("front matter of %s does not match the schema", path)
+	}
 
 	buf := utils.SharedBufferPool.Get()
 	defer utils.SharedBufferPool.Put(buf)
@@ -80,14 +106,18 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 	}
 
 	ssrHashes := mdParser.GetSSRHashes(context)
+	s.warnParseProblems(path, context)
 
+	var feedSummary string
 	if bytes.Contains(source, []byte("$")) || bytes.Contains(source, []byte("\\(")) {
-		var mathHashes []string
-		htmlContent, mathHashes = mdParser.RenderMathForHTML(htmlContent, s.nativeRenderer, diagramCache, &s.mu)
-		ssrHashes = append(ssrHashes, mathHashes...)
+		var mathHashes, feedHashes []string
+		feedSummary, feedHashes = s.feedSummary(htmlContent, diagramCache, meta.Get(context))
+		htmlContent, mathHashes = s.renderMath(path, htmlContent, diagramCache, meta.Get(context))
+		ssrHashes = append(append(ssrHashes, mathHashes...), feedHashes...)
 	}
-	if s.cfg.CompressImages {
-		htmlContent = utils.ReplaceToWebP(htmlContent)
+	summary, htmlContent := mdParser.SplitSummary(htmlContent)
+	if feedSummary == "" {
+		feedSummary = summary
 	}
 
 	if s.cfg.Features.RawMarkdown {
@@ -98,11 +128,10 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 
 	metaData := meta.Get(context)
 	plainText := mdParser.ExtractPlainText(docNode, source)
-	wordCount := len(strings.Fields(string(source)))
-	readTime := int(math.Ceil(float64(wordCount) / 120.0))
+	wordCount := mdParser.CountWords(docNode, source)
+	readTime := utils.ReadingTime(wordCount)
 	isPinned, _ := metaData["pinned"].(bool)
-	dateStr := utils.GetString(metaData, "date")
-	dateObj, _ := time.Parse("2006-01-02", dateStr)
+	dateObj, lastMod := s.postDates(path, metaData)
 	isDraft := utils.GetBool(metaData, "draft")
 
 	toc := mdParser.GetTOC(context)
@@ -116,7 +145,10 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 		Pinned:      isPinned,
 		Draft:       isDraft,
 		DateObj:     dateObj,
+		LastMod:     lastMod,
 		Version:     version,
+		Summary:     template.HTML(summary),
+		FeedSummary: template.HTML(feedSummary),
 	}
 
 	var versionPosts []models.PostMetadata
@@ -163,16 +195,17 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 		}
 
 		frontmatterHash, _ := utils.GetFrontmatterHash(metaData)
-		bodyHash := utils.GetBodyHash(source)
+		bodyHash := postBodyHash(source, nb, bibData)
 
 		newMeta := &cache.PostMeta{
 			PostID: postID, Path: relPath, ModTime: info.ModTime().Unix(),
 			ContentHash: frontmatterHash, BodyHash: bodyHash, HTMLHash: htmlHash,
-			Title: post.Title, Date: post.DateObj, Tags: post.Tags,
+			Title: post.Title, Date: post.DateObj, LastMod: post.LastMod, Tags: post.Tags,
 			ReadingTime: post.ReadingTime, Description: post.Description,
 			Link: post.Link, Pinned: post.Pinned, Weight: post.Weight,
-			Draft: post.Draft, Meta: metaData, TOC: cacheTOC, Version: version,
-			SSRInputHashes: ssrHashes,
+			Draft: post.Draft, Meta: metaData, TOC: cacheTOC, Version: version, Summary: summary,
+			FeedSummary: feedSummary, SSRInputHashes: ssrHashes,
+			ImageProfiles: s.postImageProfiles(metaData, source),
 		}
 
 		normalizedTags := make([]string, len(post.Tags))
@@ -180,12 +213,11 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 			normalizedTags[i] = strings.ToLower(t)
 		}
 
-		newSearch := &cache.SearchRecord{
-			Title: post.Title, NormalizedTitle: strings.ToLower(post.Title),
-			BM25Data: make(map[string]int), DocLen: wordCount, Content: plainText,
-			NormalizedTags: normalizedTags,
-		}
-		newDep := &cache.Dependencies{Tags: post.Tags}
+		newSearch := s.newSearchRecord(models.PostRecord{
+			Title: post.Title, NormalizedTitle: strings.ToLower(post.Title), Description: post.Description,
+			Tags: post.Tags, NormalizedTags: normalizedTags, Content: plainText,
+		}, s.searchAnalyzer(metaData))
+		newDep := &cache.Dependencies{Tags: post.Tags, Includes: includes, Templates: []string{renderer.LayoutFile(metaData)}}
 		_ = s.cache.BatchCommit([]*cache.PostMeta{newMeta}, map[string]*cache.SearchRecord{postID: newSearch}, map[string]*cache.Dependencies{postID: newDep})
 	}
 
@@ -205,7 +237,8 @@ func (s *postServiceImpl) ProcessSingle(ctx context.Context, path string) error
 		Title: post.Title, Description: post.Description, Content: template.HTML(htmlContent),
 		Meta: metaData, BaseURL: s.cfg.BaseURL, BuildVersion: s.cfg.BuildVersion,
 		TabTitle: post.Title + " | " + s.cfg.Title, Permalink: post.Link, Image: imagePath,
-		TOC: toc, Config: s.cfg, SiteTree: siteTree,
+		ImagePreview: s.coverPlaceholder(metaData), TOC: toc, Config: s.cfg, SiteTree: siteTree,
+		Date: post.DateObj, LastMod: post.LastMod,
 		CurrentVersion: version, IsOutdated: s.isOutdatedVersion(version),
 		Versions: s.cfg.GetVersionsMetadata(version, cleanHtmlRelPath),
 		PrevPage: prev, NextPage: next,
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/config"
+	mdParser "github.com/Kush-Singh-26/kosh/builder/parser"
+	"github.com/Kush-Singh-26/kosh/builder/renderer/native"
+	"github.com/Kush-Singh-26/kosh/builder/services/mocks"
+	"github.com/Kush-Singh-26/kosh/builder/utils"
+)
+
+type webpResolver struct{}
+
+func (webpResolver) ResolveImage(src string) (utils.ImageInfo, bool) {
+	if src != "/static/images/chart.png" {
+		return utils.ImageInfo{}, false
+	}
+	return utils.ImageInfo{Width: 800, Height: 400, Ext: ".png", WebP: true}, true
+}
+
+// TestProcessSingleKeepsRenderedImages checks that the page content is the
+// parser's HTML as is: image URLs come from the image renderer alone and no
+// rewrite runs over the HTML afterwards.
+func TestProcessSingleKeepsRenderedImages(t *testing.T) {
+	source := "---\ntitle: Images\n---\n\n![Chart](/static/images/chart.png)\n\n" +
+		"![Missing](/static/images/missing.png)\n\n<img src=\"/static/images/raw.png\" alt=\"Raw\">\n"
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "content/images.md", []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	md := mdParser.New("", nil, &sync.Map{}, mdParser.WithImages(webpResolver{}, "100vw"))
+	var want bytes.Buffer
+	if err := md.Convert([]byte(source), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{ContentDir: "content", OutputDir: "public", CompressImages: true}
+	rnd := mocks.NewMockRenderService()
+	s := &postServiceImpl{
+		cfg: cfg, renderer: rnd, md: md, sourceFs: fs, destFs: afero.NewMemMapFs(),
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	if err := s.ProcessSingle(context.Background(), "content/images.md"); err != nil {
+		t.Fatalf("ProcessSingle() error = %v", err)
+	}
+
+	page, ok := rnd.RenderedPages["public/images.html"]
+	if !ok {
+		t.Fatalf("page not rendered, got %v", rnd.RenderedPages)
+	}
+	got := string(page.Content)
+	if got != want.String() {
+		t.Errorf("content differs from the parser output\ngot:  %s\nwant: %s", got, want.String())
+	}
+	for _, src := range []string{`src="/static/images/chart.webp"`, `src="/static/images/missing.png"`, `src="/static/images/raw.png"`} {
+		if !strings.Contains(got, src) {
+			t.Errorf("content missing %s\ngot: %s", src, got)
+		}
+	}
+}
+
+func TestFeedSummaryUsesFeedOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts KaTeX workers")
+	}
+	cfg := &config.Config{Math: config.MathConfig{Output: native.MathOutputHTML, FeedOutput: native.MathOutputMathML}}
+	s := &postServiceImpl{cfg: cfg, nativeRenderer: native.New(), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	html := "<p>Energy $E=mc^2$.</p>\n" + mdParser.SummaryDivider + "\n<p>Later $x$.</p>\n"
+	got, hashes := s.feedSummary(html, map[string]string{}, nil)
+	if !strings.Contains(got, "<math") || strings.Contains(got, "katex-html") {
+		t.Errorf("feed summary math is not MathML only: %s", got)
+	}
+	if strings.Contains(got, "Later") || len(hashes) != 1 {
+		t.Errorf("feed summary = %q with %d hashes, want the summary only", got, len(hashes))
+	}
+
+	if got, hashes := s.feedSummary("<p>No math.</p>\n"+mdParser.SummaryDivider+"\n", nil, nil); got != "<p>No math.</p>" || hashes != nil {
+		t.Errorf("feed summary without math = %q, %v", got, hashes)
+	}
+}
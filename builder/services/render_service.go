@@ -47,6 +47,14 @@ func (s *renderServiceImpl) GetAssets() map[string]string {
 	return s.rnd.GetAssets()
 }
 
+func (s *renderServiceImpl) SetData(data map[string]interface{}) {
+	s.rnd.SetData(data)
+}
+
+func (s *renderServiceImpl) ReloadTemplates() {
+	s.rnd.Reload()
+}
+
 func (s *renderServiceImpl) GetRenderedFiles() map[string]bool {
 	return s.rnd.GetRenderedFiles()
 }
@@ -0,0 +1,174 @@
+// Package sitedata loads the site's data files (YAML, JSON, TOML and CSV)
+// into the nested map templates read as .Data
+package sitedata
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// Extensions are the data file formats, by file extension
+var Extensions = map[string]bool{".yaml": true, ".yml": true, ".json": true, ".toml": true, ".csv": true}
+
+// IsDataFile reports whether path has the extension of a data file
+func IsDataFile(path string) bool {
+	return Extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Load reads the data files under dir. Each file is keyed by its name without
+// the extension and each directory is a nested map, so data/books/2024.yaml is
+// .Data.books.2024 (use index for keys that are not identifiers). A CSV file
+// is a list of rows keyed by its header. A missing dir is no data.
+//
+// Files that fail to parse are left out and reported in the error; the rest
+// are still returned.
+func Load(fs afero.Fs, dir string) (map[string]interface{}, error) {
+	root := map[string]interface{}{}
+	if _, err := fs.Stat(dir); os.IsNotExist(err) {
+		return root, nil
+	}
+	var errs []error
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !IsDataFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		value, err := readFile(fs, path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			return nil
+		}
+		if err := insert(root, strings.Split(filepath.ToSlash(rel), "/"), value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return root, errors.Join(errs...)
+}
+
+// Paths returns dir, its subdirectories and its data files, sorted. A
+// directory changes when files are added to or removed from it.
+func Paths(fs afero.Fs, dir string) []string {
+	var paths []string
+	_ = afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() || IsDataFile(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths
+}
+
+// insert places value at the path of its file: directories become maps and
+// the file name without its extension is the key
+func insert(root map[string]interface{}, parts []string, value interface{}) error {
+	m := root
+	for _, dir := range parts[:len(parts)-1] {
+		next, ok := m[dir].(map[string]interface{})
+		if !ok {
+			if _, taken := m[dir]; taken {
+				return fmt.Errorf("directory %q has the name of a data file that is not a map", dir)
+			}
+			next = map[string]interface{}{}
+			m[dir] = next
+		}
+		m = next
+	}
+
+	file := parts[len(parts)-1]
+	key := strings.TrimSuffix(file, filepath.Ext(file))
+	existing, taken := m[key]
+	if !taken {
+		m[key] = value
+		return nil
+	}
+	// A file next to the directory of the same name adds its keys to it
+	dirMap, isDir := existing.(map[string]interface{})
+	fileMap, isMap := value.(map[string]interface{})
+	if !isDir || !isMap {
+		return fmt.Errorf("key %q is already defined by another data file", key)
+	}
+	for k, v := range fileMap {
+		if _, ok := dirMap[k]; ok {
+			return fmt.Errorf("key %q.%q is already defined", key, k)
+		}
+		dirMap[k] = v
+	}
+	return nil
+}
+
+func readFile(fs afero.Fs, path string) (interface{}, error) {
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &value)
+	case ".json":
+		err = json.Unmarshal(content, &value)
+	case ".toml":
+		var m map[string]interface{}
+		err = toml.Unmarshal(content, &m)
+		value = m
+	case ".csv":
+		value, err = readCSV(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// readCSV reads rows keyed by the header row
+func readCSV(content []byte) ([]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	header, err := r.Read()
+	if err == io.EOF {
+		return []map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[strings.TrimSpace(name)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,115 @@
+package sitedata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestLoad(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"data/projects.yaml":     "- name: kosh\n  stars: 10\n- name: notes\n  stars: 2\n",
+		"data/site.json":         `{"links": {"github": "https://github.com/example"}}`,
+		"data/books/2024.toml":   "title = \"Reading list\"\ncount = 2\n",
+		"data/books/queue.csv":   "title,author\nSICP,Abelson\n\"Go, in Practice\",Butcher\n",
+		"data/books.yml":         "shelf: top\n",
+		"data/notes.txt":         "not data",
+		"data/empty/.gitkeep":    "",
+		"content/ignored.yaml":   "x: 1\n",
+		"data/nested/deep/a.yml": "ok: true\n",
+	}
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Load(fs, "data")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"projects": []interface{}{
+			map[string]interface{}{"name": "kosh", "stars": 10},
+			map[string]interface{}{"name": "notes", "stars": 2},
+		},
+		"site": map[string]interface{}{"links": map[string]interface{}{"github": "https://github.com/example"}},
+		"books": map[string]interface{}{
+			"2024": map[string]interface{}{"title": "Reading list", "count": int64(2)},
+			"queue": []map[string]string{
+				{"title": "SICP", "author": "Abelson"},
+				{"title": "Go, in Practice", "author": "Butcher"},
+			},
+			"shelf": "top",
+		},
+		"nested": map[string]interface{}{"deep": map[string]interface{}{"a": map[string]interface{}{"ok": true}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+		keep    string // Key that still loads
+	}{
+		{
+			name:    "invalid file",
+			files:   map[string]string{"data/bad.json": "{", "data/good.yaml": "a: 1\n"},
+			wantErr: "bad.json",
+			keep:    "good",
+		},
+		{
+			name:    "same key twice",
+			files:   map[string]string{"data/menu.json": "[]", "data/menu.yaml": "[]"},
+			wantErr: `key "menu" is already defined`,
+			keep:    "menu",
+		},
+		{
+			name:    "ragged csv",
+			files:   map[string]string{"data/rows.csv": "a,b\n1\n", "data/ok.csv": "a\n1\n"},
+			wantErr: "rows.csv",
+			keep:    "ok",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, content := range tt.files {
+				_ = afero.WriteFile(fs, path, []byte(content), 0644)
+			}
+			got, err := Load(fs, "data")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, ok := got[tt.keep]; !ok {
+				t.Errorf("Load() = %v, want key %q", got, tt.keep)
+			}
+		})
+	}
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	got, err := Load(afero.NewMemMapFs(), "data")
+	if err != nil || len(got) != 0 {
+		t.Errorf("Load() = %v, %v; want empty, nil", got, err)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, path := range []string{"data/a.yaml", "data/sub/b.csv", "data/readme.md"} {
+		_ = afero.WriteFile(fs, path, nil, 0644)
+	}
+	got := Paths(fs, "data")
+	want := []string{"data", "data/a.yaml", "data/sub", "data/sub/b.csv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+}
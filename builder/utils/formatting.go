@@ -2,11 +2,20 @@ package utils
 
 import (
 	"fmt"
+	"math"
 	"sort"
 
 	"github.com/Kush-Singh-26/kosh/builder/models"
 )
 
+// WordsPerMinute is the reading speed of reading time estimates
+const WordsPerMinute = 120.0
+
+// ReadingTime estimates the minutes it takes to read words words
+func ReadingTime(words int) int {
+	return int(math.Ceil(float64(words) / WordsPerMinute))
+}
+
 func SortPosts(posts []models.PostMetadata) {
 	sort.Slice(posts, func(i, j int) bool {
 		wi, wj := posts[i].Weight, posts[j].Weight
@@ -1,25 +1,18 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"io/fs"
-	"log/slog"
-	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 
-	"github.com/chai2010/webp"
-	"github.com/disintegration/imaging"
 	"github.com/spf13/afero"
-	"github.com/zeebo/blake3"
 )
 
-func CopyDirVFS(srcFs afero.Fs, destFs afero.Fs, srcDir, dstDir string, compress bool, excludeExts []string, onWrite func(string), cacheDir string, imageWorkers int) error {
+func CopyDirVFS(srcFs afero.Fs, destFs afero.Fs, srcDir, dstDir string, compress bool, excludeExts []string, onWrite func(string), cacheDir string, imageWorkers int, images *ImageProfiles) error {
 	srcDir = NormalizePath(srcDir)
 	dstDir = NormalizePath(dstDir)
 	if err := destFs.MkdirAll(dstDir, 0755); err != nil {
@@ -28,6 +21,7 @@ func CopyDirVFS(srcFs afero.Fs, destFs afero.Fs, srcDir, dstDir string, compress
 
 	type fileTask struct {
 		path    string
+		srcRel  string
 		relPath string
 		info    fs.FileInfo
 	}
@@ -50,7 +44,7 @@ func CopyDirVFS(srcFs afero.Fs, destFs afero.Fs, srcDir, dstDir string, compress
 
 				if compress && isImage {
 					target := filepath.Join(dstDir, task.relPath)
-					if err := processImageVFS(srcFs, destFs, task.path, target, cacheDir); err != nil {
+					if err := processImageVFS(srcFs, destFs, task.path, target, cacheDir, images.For(task.srcRel)); err != nil {
 						errChan <- fmt.Errorf("failed to process image %s: %w", task.path, err)
 					} else if onWrite != nil {
 						onWrite(target)
@@ -114,7 +108,7 @@ func CopyDirVFS(srcFs afero.Fs, destFs afero.Fs, srcDir, dstDir string, compress
 			finalRelPath = relPath[:len(relPath)-len(filepath.Ext(relPath))] + ".webp"
 		}
 
-		taskQueue <- fileTask{path, finalRelPath, info}
+		taskQueue <- fileTask{path, relPath, finalRelPath, info}
 		return nil
 	})
 
@@ -134,65 +128,3 @@ func CopyDirVFS(srcFs afero.Fs, destFs afero.Fs, srcDir, dstDir string, compress
 
 	return nil
 }
-
-func processImageVFS(srcFs afero.Fs, destFs afero.Fs, srcPath, dstPath string, cacheDir string) error {
-	srcInfo, err := srcFs.Stat(srcPath)
-	if err == nil {
-		if dstInfo, err := os.Stat(dstPath); err == nil {
-			if !srcInfo.ModTime().After(dstInfo.ModTime()) {
-				data, err := os.ReadFile(dstPath)
-				if err == nil {
-					return afero.WriteFile(destFs, dstPath, data, 0644)
-				}
-			}
-		}
-	}
-
-	var cacheFile string
-	if cacheDir != "" && err == nil {
-		key := fmt.Sprintf("%s-%d-%d", srcPath, srcInfo.Size(), srcInfo.ModTime().UnixNano())
-		hash := blake3.Sum256([]byte(key))
-		hashStr := hex.EncodeToString(hash[:])
-		cacheFile = filepath.Join(cacheDir, hashStr+".webp")
-
-		if data, err := os.ReadFile(cacheFile); err == nil {
-			return WriteFileVFS(destFs, dstPath, data)
-		}
-	}
-
-	file, err := srcFs.Open(srcPath)
-	if err != nil {
-		return fmt.Errorf("failed to open source image %s: %w", srcPath, err)
-	}
-	defer func() { _ = file.Close() }()
-
-	img, err := imaging.Decode(file)
-	if err != nil {
-		return fmt.Errorf("failed to decode image %s: %w", srcPath, err)
-	}
-
-	if img.Bounds().Dx() > 1200 {
-		img = imaging.Resize(img, 1200, 0, imaging.Lanczos)
-	}
-
-	if err := destFs.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
-		return fmt.Errorf("failed to create image directory: %w", err)
-	}
-
-	// Encode to buffer once, then write to both cache and destination
-	var buf bytes.Buffer
-	if err := webp.Encode(&buf, img, &webp.Options{Lossless: false, Quality: 80}); err != nil {
-		return fmt.Errorf("failed to encode webp %s: %w", dstPath, err)
-	}
-	encodedData := buf.Bytes()
-
-	// Write to cache file if configured
-	if cacheFile != "" {
-		if err := os.WriteFile(cacheFile, encodedData, 0644); err != nil {
-			slog.Warn("Failed to write image cache file", "path", cacheFile, "error", err)
-		}
-	}
-
-	// Write to destination
-	return afero.WriteFile(destFs, dstPath, encodedData, 0644)
-}
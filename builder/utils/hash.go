@@ -51,17 +51,29 @@ var yamlDelim = []byte("---")
 
 // GetBodyHash extracts the body content (after frontmatter) and returns its BLAKE3 hash
 // This is CRITICAL for cache validity - body changes without frontmatter changes
-// would otherwise be silently ignored
-func GetBodyHash(source []byte) string {
-	parts := bytes.SplitN(source, yamlDelim, 3)
-	if len(parts) >= 3 {
-		body := parts[2]
-		body = bytes.TrimSpace(body)
-		hash := blake3.Sum256(body)
-		return hex.EncodeToString(hash[:])
+// would otherwise be silently ignored. deps are the contents of files the body
+// depends on, like its bibliography; empty deps leave the hash unchanged.
+func GetBodyHash(source []byte, deps ...[]byte) string {
+	body := source
+	if parts := bytes.SplitN(source, yamlDelim, 3); len(parts) >= 3 {
+		body = bytes.TrimSpace(parts[2])
 	}
-	hash := blake3.Sum256(source)
-	return hex.EncodeToString(hash[:])
+	return GetContentHash(body, deps...)
+}
+
+// GetContentHash hashes a post body that has no YAML frontmatter to split
+// off, like a notebook, together with its deps as in GetBodyHash
+func GetContentHash(content []byte, deps ...[]byte) string {
+	h := blake3.New()
+	_, _ = h.Write(content)
+	for _, dep := range deps {
+		if len(dep) == 0 {
+			continue
+		}
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write(dep)
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 type postGraphInfo struct {
@@ -80,6 +80,32 @@ func TestGetBodyHashIgnoresFrontmatter(t *testing.T) {
 	}
 }
 
+func TestGetBodyHashDeps(t *testing.T) {
+	source := []byte("---\ntitle: Test\n---\nBody [@key]")
+
+	plain := GetBodyHash(source)
+	if got := GetBodyHash(source, nil); got != plain {
+		t.Error("Empty deps should not change the body hash")
+	}
+	withBib := GetBodyHash(source, []byte("@book{key, title={A}}"))
+	if withBib == plain {
+		t.Error("Deps should change the body hash")
+	}
+	if GetBodyHash(source, []byte("@book{key, title={B}}")) == withBib {
+		t.Error("Different deps should produce different hashes")
+	}
+}
+
+func TestGetContentHash(t *testing.T) {
+	content := []byte("{\"cells\": []}")
+	if GetContentHash(content) != GetBodyHash(content) {
+		t.Error("Content without frontmatter should hash like a body")
+	}
+	if GetContentHash(content, []byte("dep")) == GetContentHash(content) {
+		t.Error("Deps should change the content hash")
+	}
+}
+
 func TestGetFrontmatterHash(t *testing.T) {
 	tests := []struct {
 		name     string
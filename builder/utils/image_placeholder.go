@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"github.com/chai2010/webp"
+	"github.com/disintegration/imaging"
+	"github.com/spf13/afero"
+	"github.com/zeebo/blake3"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+// Placeholder modes for images.placeholder
+const (
+	PlaceholderNone  = "none"
+	PlaceholderColor = "color"
+	PlaceholderLQIP  = "lqip"
+)
+
+const (
+	lqipWidth      = 16 // Width of the inline preview
+	lqipQuality    = 40
+	histogramWidth = 32 // Sample size for dominant colour extraction
+)
+
+// ComputePlaceholder derives the dominant colour and a tiny preview of img
+func ComputePlaceholder(img image.Image) (models.ImagePlaceholder, error) {
+	opaque := true
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		opaque = o.Opaque()
+	}
+
+	preview := imaging.Resize(img, lqipWidth, 0, imaging.Lanczos)
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, preview, &webp.Options{Quality: lqipQuality}); err != nil {
+		return models.ImagePlaceholder{}, err
+	}
+
+	return models.ImagePlaceholder{
+		Color:  DominantColor(img),
+		LQIP:   "data:image/webp;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Opaque: opaque,
+	}, nil
+}
+
+// DominantColor returns the most common colour of img as #rrggbb. Pixels are
+// bucketed at 4 bits per channel and the winning bucket's mean is returned, so
+// large flat areas win over noisy detail. Mostly transparent pixels are ignored.
+func DominantColor(img image.Image) string {
+	sample := imaging.Resize(img, histogramWidth, histogramWidth, imaging.Box)
+
+	type bucket struct{ r, g, b, n int }
+	buckets := make(map[int]*bucket)
+	for i := 0; i+3 < len(sample.Pix); i += 4 {
+		r, g, b, a := int(sample.Pix[i]), int(sample.Pix[i+1]), int(sample.Pix[i+2]), sample.Pix[i+3]
+		if a < 128 {
+			continue
+		}
+		key := (r>>4)<<8 | (g>>4)<<4 | b>>4
+		bk, ok := buckets[key]
+		if !ok {
+			bk = &bucket{}
+			buckets[key] = bk
+		}
+		bk.r += r
+		bk.g += g
+		bk.b += b
+		bk.n++
+	}
+
+	// Lowest key breaks ties so the result does not depend on map order
+	bestKey := -1
+	for key, bk := range buckets {
+		if bestKey < 0 || bk.n > buckets[bestKey].n || (bk.n == buckets[bestKey].n && key < bestKey) {
+			bestKey = key
+		}
+	}
+	if bestKey < 0 {
+		return "#000000"
+	}
+	best := buckets[bestKey]
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.n, best.g/best.n, best.b/best.n)
+}
+
+// placeholderCachePath returns where the placeholder of a source image is cached
+func placeholderCachePath(cacheDir, srcPath string, info os.FileInfo) string {
+	key := fmt.Sprintf("%s-%d-%d-lqip", srcPath, info.Size(), info.ModTime().UnixNano())
+	hash := blake3.Sum256([]byte(key))
+	return filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".lqip.json")
+}
+
+// storePlaceholder computes and caches the placeholder of an already decoded image
+func storePlaceholder(cacheDir, srcPath string, info os.FileInfo, img image.Image) {
+	if cacheDir == "" {
+		return
+	}
+	cachePath := placeholderCachePath(cacheDir, srcPath, info)
+	if _, err := os.Stat(cachePath); err == nil {
+		return
+	}
+	if ph, err := ComputePlaceholder(img); err == nil {
+		if data, err := json.Marshal(ph); err == nil {
+			_ = os.WriteFile(cachePath, data, 0644)
+		}
+	}
+}
+
+// LoadImagePlaceholder returns the placeholder of a source image, from the image
+// cache when available, decoding the image otherwise
+func LoadImagePlaceholder(srcFs afero.Fs, srcPath, cacheDir string) (models.ImagePlaceholder, error) {
+	info, err := srcFs.Stat(srcPath)
+	if err != nil {
+		return models.ImagePlaceholder{}, err
+	}
+
+	var cachePath string
+	if cacheDir != "" {
+		cachePath = placeholderCachePath(cacheDir, srcPath, info)
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var ph models.ImagePlaceholder
+			if err := json.Unmarshal(data, &ph); err == nil {
+				return ph, nil
+			}
+		}
+	}
+
+	file, err := srcFs.Open(srcPath)
+	if err != nil {
+		return models.ImagePlaceholder{}, err
+	}
+	img, err := imaging.Decode(file, imaging.AutoOrientation(true))
+	_ = file.Close()
+	if err != nil {
+		return models.ImagePlaceholder{}, err
+	}
+
+	ph, err := ComputePlaceholder(img)
+	if err != nil {
+		return models.ImagePlaceholder{}, err
+	}
+	if cachePath != "" {
+		if data, err := json.Marshal(ph); err == nil {
+			_ = os.MkdirAll(cacheDir, 0755)
+			_ = os.WriteFile(cachePath, data, 0644)
+		}
+	}
+	return ph, nil
+}
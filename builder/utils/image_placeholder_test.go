@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestDominantColor(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			c := color.NRGBA{R: 200, G: 30, B: 40, A: 255}
+			if x < 20 {
+				c = color.NRGBA{R: 10, G: 20, B: 220, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	if got := DominantColor(img); got != "#c81e28" {
+		t.Errorf("DominantColor() = %s, want #c81e28", got)
+	}
+}
+
+func TestComputePlaceholder(t *testing.T) {
+	opaque := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 255
+	}
+	ph, err := ComputePlaceholder(opaque)
+	if err != nil {
+		t.Fatalf("ComputePlaceholder() error = %v", err)
+	}
+	if !ph.Opaque || ph.Color != "#000000" || !strings.HasPrefix(ph.LQIP, "data:image/webp;base64,") {
+		t.Errorf("ComputePlaceholder() = %+v", ph)
+	}
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	if ph, _ := ComputePlaceholder(transparent); ph.Opaque {
+		t.Error("transparent image reported as opaque")
+	}
+}
+
+func TestLoadImagePlaceholderUsesCache(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeTestPNG(t, fs, "static/a.png", 40, 20)
+	cacheDir := t.TempDir()
+
+	first, err := LoadImagePlaceholder(fs, "static/a.png", cacheDir)
+	if err != nil {
+		t.Fatalf("LoadImagePlaceholder() error = %v", err)
+	}
+	entries, _ := os.ReadDir(cacheDir)
+	if len(entries) != 1 {
+		t.Fatalf("expected one cached placeholder, found %d", len(entries))
+	}
+
+	second, err := LoadImagePlaceholder(fs, "static/a.png", cacheDir)
+	if err != nil || second != first {
+		t.Errorf("cached placeholder = %+v (err %v), want %+v", second, err, first)
+	}
+}
+
+func TestImageResolverPlaceholders(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeTestPNG(t, fs, "static/images/a.png", 40, 20)
+
+	resolver := NewImageResolver(fs, []string{"static"}, true, NewImageProfiles(ImageOptions{Formats: []string{"webp"}}))
+	if _, ok := resolver.Placeholder("/static/images/a.png"); ok {
+		t.Error("placeholder returned while disabled")
+	}
+
+	resolver = NewImageResolver(fs, []string{"static"}, true, NewImageProfiles(ImageOptions{Formats: []string{"webp"}}))
+	resolver.EnablePlaceholders(PlaceholderColor, t.TempDir())
+	ph, ok := resolver.Placeholder("/static/images/a.png")
+	if !ok || ph.Color == "" || ph.LQIP != "" {
+		t.Errorf("color mode placeholder = %+v, %v", ph, ok)
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ImageRule maps static image paths matching a glob to a named profile
+type ImageRule struct {
+	Match   string // Glob relative to the static dir; "**" matches any number of directories
+	Profile string
+}
+
+// ImageProfiles selects the encoding options of each image. Precedence: a post's
+// imageProfile front matter, then the first matching rule, then the default.
+type ImageProfiles struct {
+	Default  ImageOptions
+	Profiles map[string]ImageOptions
+	Rules    []ImageRule
+
+	mu        sync.RWMutex
+	overrides map[string]string // static-relative path -> profile from front matter
+}
+
+// NewImageProfiles returns profiles with only a default
+func NewImageProfiles(def ImageOptions) *ImageProfiles {
+	return &ImageProfiles{Default: def}
+}
+
+// For returns the options for an image path relative to the static dir
+func (p *ImageProfiles) For(rel string) ImageOptions {
+	rel = filepath.ToSlash(rel)
+
+	p.mu.RLock()
+	name, ok := p.overrides[rel]
+	p.mu.RUnlock()
+	if ok {
+		if opts, ok := p.Profiles[name]; ok {
+			return opts
+		}
+	}
+
+	for _, rule := range p.Rules {
+		if MatchGlob(rule.Match, rel) {
+			if opts, ok := p.Profiles[rule.Profile]; ok {
+				return opts
+			}
+		}
+	}
+	return p.Default
+}
+
+// SetOverrides replaces the profiles that posts assign to images, keyed by
+// path relative to the static dir
+func (p *ImageProfiles) SetOverrides(overrides map[string]string) {
+	p.mu.Lock()
+	p.overrides = overrides
+	p.mu.Unlock()
+}
+
+// StaticRelPath converts a "/static/..." URL into a path relative to the static dir
+func StaticRelPath(src string) (string, bool) {
+	if src == "" || strings.HasPrefix(src, "http") || strings.HasPrefix(src, "//") {
+		return "", false
+	}
+	rel := strings.TrimLeft(strings.TrimPrefix(src, "."), "/")
+	return strings.CutPrefix(rel, "static/")
+}
+
+// MatchGlob reports whether name matches pattern, where "**" matches zero or more
+// path segments and other segments use path.Match syntax
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,57 @@
+package utils
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"images/screenshots/**", "images/screenshots/a.png", true},
+		{"images/screenshots/**", "images/screenshots/ui/b.png", true},
+		{"images/screenshots/**", "images/photos/a.png", false},
+		{"**/*-screenshot.png", "a-screenshot.png", true},
+		{"**/*-screenshot.png", "images/deep/b-screenshot.png", true},
+		{"images/*.jpg", "images/a.jpg", true},
+		{"images/*.jpg", "images/sub/a.jpg", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImageProfilesPrecedence(t *testing.T) {
+	profiles := NewImageProfiles(ImageOptions{Profile: "default"})
+	profiles.Profiles = map[string]ImageOptions{
+		"screenshot": {Profile: "screenshot", Lossless: true},
+		"hero":       {Profile: "hero", MaxWidth: 2400},
+	}
+	profiles.Rules = []ImageRule{
+		{Match: "images/screenshots/**", Profile: "screenshot"},
+		{Match: "**/*.png", Profile: "missing"},
+	}
+
+	profiles.SetOverrides(map[string]string{
+		"images/screenshots/ui.png": "hero",
+		"images/cover.jpg":          "hero",
+	})
+
+	tests := []struct {
+		rel  string
+		want string
+	}{
+		{"images/screenshots/ui.png", "hero"},          // post override beats rules
+		{"images/cover.jpg", "hero"},                   // override without a rule
+		{"images/screenshots/other.png", "screenshot"}, // rule
+		{"images/a.png", "default"},                    // rule naming an unknown profile falls through
+	}
+	for _, tt := range tests {
+		if got := profiles.For(tt.rel).Profile; got != tt.want {
+			t.Errorf("For(%q) = %q, want %q", tt.rel, got, tt.want)
+		}
+	}
+}
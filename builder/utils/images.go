@@ -0,0 +1,428 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chai2010/webp"
+	"github.com/disintegration/imaging"
+	"github.com/spf13/afero"
+	"github.com/zeebo/blake3"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+)
+
+// DefaultImageWidth caps the default rendition that plain <img> tags and social cards use
+const DefaultImageWidth = 1200
+
+// ImageOptions controls the renditions generated for local images
+type ImageOptions struct {
+	Profile    string   // Name of the profile these options came from
+	MaxWidth   int      // Width cap of the default rendition
+	Widths     []int    // Responsive variant widths; wider than the source are skipped
+	Formats    []string // Variant formats in preference order ("avif", "webp")
+	Quality    int      // Lossy encoder quality (1-100)
+	Lossless   bool     // Encode WebP/AVIF losslessly, e.g. for PNG screenshots
+	Sharpen    float64  // Sharpening sigma applied after resizing (0 disables)
+	AutoOrient bool     // Rotate according to EXIF orientation before encoding
+}
+
+// DefaultImageOptions returns the options used when the site config sets none
+func DefaultImageOptions() ImageOptions {
+	return ImageOptions{
+		Profile:    "default",
+		MaxWidth:   DefaultImageWidth,
+		Widths:     []int{480, 800, 1200, 2000},
+		Formats:    []string{"avif", "webp"},
+		Quality:    80,
+		AutoOrient: true,
+	}
+}
+
+// fingerprint identifies every setting that affects encoded output, for cache keys
+func (o ImageOptions) fingerprint() string {
+	return fmt.Sprintf("%s|%d|%v|%v|%d|%t|%g|%t", o.Profile, o.MaxWidth, o.Widths, o.Formats, o.Quality, o.Lossless, o.Sharpen, o.AutoOrient)
+}
+
+func (o ImageOptions) maxWidth() int {
+	if o.MaxWidth <= 0 {
+		return DefaultImageWidth
+	}
+	return o.MaxWidth
+}
+
+// EnabledFormats returns the configured formats this build can actually encode.
+// AVIF needs the avifenc binary on PATH and is skipped without it.
+func (o ImageOptions) EnabledFormats() []string {
+	formats := make([]string, 0, len(o.Formats))
+	for _, f := range o.Formats {
+		f = strings.ToLower(f)
+		switch f {
+		case "webp":
+			formats = append(formats, f)
+		case "avif":
+			if AVIFAvailable() {
+				formats = append(formats, f)
+			}
+		}
+	}
+	return formats
+}
+
+// ResponsiveWidths returns the variant widths to generate for a source image:
+// every configured width narrower than the source, plus the source width itself
+// when it falls below the largest configured width
+func ResponsiveWidths(sourceWidth int, widths []int) []int {
+	sorted := append([]int(nil), widths...)
+	sort.Ints(sorted)
+
+	var result []int
+	for _, w := range sorted {
+		if w <= 0 || (len(result) > 0 && result[len(result)-1] == w) {
+			continue
+		}
+		if w >= sourceWidth {
+			result = append(result, sourceWidth)
+			break
+		}
+		result = append(result, w)
+	}
+	return result
+}
+
+// ImageVariantPath returns the path of a width variant, e.g. "a/b.webp" -> "a/b-480w.avif"
+func ImageVariantPath(path string, width int, format string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	return base + "-" + strconv.Itoa(width) + "w." + format
+}
+
+// ScaledSize returns the dimensions of a width x height image scaled down to at most maxWidth
+func ScaledSize(width, height, maxWidth int) (int, int) {
+	if width <= maxWidth || width == 0 {
+		return width, height
+	}
+	return maxWidth, (height*maxWidth + width/2) / width
+}
+
+var (
+	avifencPath string
+	avifOnce    sync.Once
+)
+
+// AVIFAvailable reports whether the avifenc encoder was found on PATH
+func AVIFAvailable() bool {
+	avifOnce.Do(func() {
+		avifencPath, _ = exec.LookPath("avifenc")
+		if avifencPath == "" {
+			slog.Debug("avifenc not found, skipping AVIF image variants")
+		}
+	})
+	return avifencPath != ""
+}
+
+// imageOutput is one rendition written for a source image
+type imageOutput struct {
+	path   string
+	width  int
+	format string
+}
+
+// imageOutputs lists every rendition of a source image: the default WebP at dstPath,
+// an original-format fallback next to it, and the responsive width variants
+func imageOutputs(dstPath, srcExt string, sourceWidth int, opts ImageOptions) []imageOutput {
+	defaultWidth := min(sourceWidth, opts.maxWidth())
+	base := strings.TrimSuffix(dstPath, filepath.Ext(dstPath))
+
+	outputs := []imageOutput{
+		{path: dstPath, width: defaultWidth, format: "webp"},
+		{path: base + srcExt, width: defaultWidth, format: strings.TrimPrefix(srcExt, ".")},
+	}
+	formats := opts.EnabledFormats()
+	for _, w := range ResponsiveWidths(sourceWidth, opts.Widths) {
+		for _, f := range formats {
+			outputs = append(outputs, imageOutput{path: ImageVariantPath(dstPath, w, f), width: w, format: f})
+		}
+	}
+	return outputs
+}
+
+func processImageVFS(srcFs afero.Fs, destFs afero.Fs, srcPath, dstPath string, cacheDir string, opts ImageOptions) error {
+	srcInfo, err := srcFs.Stat(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat source image %s: %w", srcPath, err)
+	}
+
+	cfg, err := decodeImageConfig(srcFs, srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to decode image %s: %w", srcPath, err)
+	}
+
+	if err := destFs.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	// Decode the full image at most once, and only if some rendition must be encoded
+	var img image.Image
+	for _, out := range imageOutputs(dstPath, strings.ToLower(filepath.Ext(srcPath)), cfg.Width, opts) {
+		var cacheFile string
+		if cacheDir != "" {
+			// The profile fingerprint makes setting changes re-encode only the images they apply to
+			key := fmt.Sprintf("%s-%d-%d-%d-%s-%s", srcPath, srcInfo.Size(), srcInfo.ModTime().UnixNano(), out.width, out.format, opts.fingerprint())
+			hash := blake3.Sum256([]byte(key))
+			cacheFile = filepath.Join(cacheDir, hex.EncodeToString(hash[:])+"."+out.format)
+
+			if data, err := os.ReadFile(cacheFile); err == nil {
+				if err := WriteFileVFS(destFs, out.path, data); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if img == nil {
+			file, err := srcFs.Open(srcPath)
+			if err != nil {
+				return fmt.Errorf("failed to open source image %s: %w", srcPath, err)
+			}
+			img, err = imaging.Decode(file, imaging.AutoOrientation(opts.AutoOrient))
+			_ = file.Close()
+			if err != nil {
+				return fmt.Errorf("failed to decode image %s: %w", srcPath, err)
+			}
+			storePlaceholder(cacheDir, srcPath, srcInfo, img)
+		}
+
+		resized := img
+		if out.width < img.Bounds().Dx() {
+			resized = imaging.Resize(img, out.width, 0, imaging.Lanczos)
+		}
+		if opts.Sharpen > 0 {
+			resized = imaging.Sharpen(resized, opts.Sharpen)
+		}
+
+		encodedData, err := encodeImage(resized, out.format, opts)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", out.path, err)
+		}
+
+		// Write to cache file if configured
+		if cacheFile != "" {
+			if err := os.WriteFile(cacheFile, encodedData, 0644); err != nil {
+				slog.Warn("Failed to write image cache file", "path", cacheFile, "error", err)
+			}
+		}
+
+		if err := afero.WriteFile(destFs, out.path, encodedData, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteImageWithWebP writes an image generated during the build, like a
+// notebook output, plus the .webp rendition that .png and .jpg links are
+// rewritten to. It returns the written paths.
+func WriteImageWithWebP(destFs afero.Fs, dstPath string, data []byte) ([]string, error) {
+	if err := WriteFileVFS(destFs, dstPath, data); err != nil {
+		return nil, err
+	}
+	written := []string{dstPath}
+	switch strings.ToLower(filepath.Ext(dstPath)) {
+	case ".png", ".jpg", ".jpeg":
+	default:
+		return written, nil
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return written, fmt.Errorf("failed to decode image %s: %w", dstPath, err)
+	}
+	encoded, err := encodeImage(img, "webp", DefaultImageOptions())
+	if err != nil {
+		return written, fmt.Errorf("failed to encode %s: %w", dstPath, err)
+	}
+	webpPath := strings.TrimSuffix(dstPath, filepath.Ext(dstPath)) + ".webp"
+	if err := WriteFileVFS(destFs, webpPath, encoded); err != nil {
+		return written, err
+	}
+	return append(written, webpPath), nil
+}
+
+func decodeImageConfig(fs afero.Fs, path string) (image.Config, error) {
+	file, err := fs.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer func() { _ = file.Close() }()
+	cfg, _, err := image.DecodeConfig(file)
+	return cfg, err
+}
+
+// encodeImage encodes one rendition. The encoders write no metadata, so EXIF is always stripped.
+func encodeImage(img image.Image, format string, opts ImageOptions) ([]byte, error) {
+	quality := opts.Quality
+	if quality <= 0 || quality > 100 {
+		quality = 80
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "webp":
+		if err := webp.Encode(&buf, img, &webp.Options{Lossless: opts.Lossless, Quality: float32(quality)}); err != nil {
+			return nil, err
+		}
+	case "avif":
+		return encodeAVIF(img, quality, opts.Lossless)
+	case "png":
+		if err := imaging.Encode(&buf, img, imaging.PNG); err != nil {
+			return nil, err
+		}
+	default:
+		if err := imaging.Encode(&buf, img, imaging.JPEG, imaging.JPEGQuality(quality)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeAVIF shells out to avifenc, since there is no pure Go AVIF encoder
+func encodeAVIF(img image.Image, quality int, lossless bool) ([]byte, error) {
+	if !AVIFAvailable() {
+		return nil, fmt.Errorf("avifenc not available")
+	}
+
+	dir, err := os.MkdirTemp("", "kosh-avif-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.avif")
+	if err := imaging.Save(img, in); err != nil {
+		return nil, err
+	}
+
+	args := []string{"-q", strconv.Itoa(quality), "-s", "6"}
+	if lossless {
+		args = []string{"--lossless", "-s", "6"}
+	}
+	cmd := exec.Command(avifencPath, append(args, in, out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("avifenc: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(out)
+}
+
+// ImageInfo describes the renditions generated for a local image
+type ImageInfo struct {
+	Width   int      // Width of the default rendition
+	Height  int      // Height of the default rendition
+	Widths  []int    // Responsive variant widths, ascending; empty when images are not compressed
+	Formats []string // Variant formats in preference order
+	Ext     string   // Original extension, used for the <img> fallback
+	WebP    bool     // A default .webp rendition sits beside the image
+
+	Placeholder models.ImagePlaceholder // Empty unless placeholders are enabled
+}
+
+// ImageResolver maps image URLs in markdown to their source files and renditions
+type ImageResolver struct {
+	fs         afero.Fs
+	staticDirs []string
+	compress   bool
+	profiles   *ImageProfiles
+	cache      sync.Map // src|profile -> ImageInfo (or nil when unresolved)
+
+	placeholder string // One of the Placeholder* modes
+	cacheDir    string // Image cache holding computed placeholders
+}
+
+// NewImageResolver returns a resolver for "/static/..." image URLs. staticDirs are
+// searched in order, so the site's static directory should precede the theme's.
+func NewImageResolver(fs afero.Fs, staticDirs []string, compress bool, profiles *ImageProfiles) *ImageResolver {
+	return &ImageResolver{fs: fs, staticDirs: staticDirs, compress: compress, profiles: profiles}
+}
+
+// EnablePlaceholders makes resolved images carry a placeholder; mode is
+// PlaceholderColor or PlaceholderLQIP
+func (r *ImageResolver) EnablePlaceholders(mode, cacheDir string) {
+	r.placeholder = mode
+	r.cacheDir = cacheDir
+}
+
+// ResolveImage returns the renditions generated for src, if it is a local raster image
+func (r *ImageResolver) ResolveImage(src string) (ImageInfo, bool) {
+	ext := strings.ToLower(filepath.Ext(src))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return ImageInfo{}, false
+	}
+	rel, ok := StaticRelPath(src)
+	if !ok {
+		return ImageInfo{}, false
+	}
+
+	// Profiles can change between dev rebuilds, so they are part of the cache key
+	opts := r.profiles.For(rel)
+	key := src + "|" + opts.fingerprint()
+	if cached, ok := r.cache.Load(key); ok {
+		info, ok := cached.(ImageInfo)
+		return info, ok
+	}
+
+	info, ok := r.resolve(rel, ext, opts)
+	if ok {
+		r.cache.Store(key, info)
+	} else {
+		r.cache.Store(key, nil)
+	}
+	return info, ok
+}
+
+func (r *ImageResolver) resolve(rel, ext string, opts ImageOptions) (ImageInfo, bool) {
+	for _, dir := range r.staticDirs {
+		srcPath := filepath.Join(dir, filepath.FromSlash(rel))
+		cfg, err := decodeImageConfig(r.fs, srcPath)
+		if err != nil {
+			continue
+		}
+
+		info := ImageInfo{Width: cfg.Width, Height: cfg.Height, Ext: filepath.Ext(rel)}
+		if r.compress {
+			info.Width, info.Height = ScaledSize(cfg.Width, cfg.Height, opts.maxWidth())
+			info.Widths = ResponsiveWidths(cfg.Width, opts.Widths)
+			info.Formats = opts.EnabledFormats()
+			info.Ext = ext
+			info.WebP = true
+		}
+		if r.placeholder == PlaceholderColor || r.placeholder == PlaceholderLQIP {
+			if ph, err := LoadImagePlaceholder(r.fs, srcPath, r.cacheDir); err == nil {
+				if r.placeholder == PlaceholderColor {
+					ph.LQIP = ""
+				}
+				info.Placeholder = ph
+			}
+		}
+		return info, true
+	}
+	return ImageInfo{}, false
+}
+
+// Placeholder returns the placeholder of a "/static/..." image, if placeholders are enabled
+func (r *ImageResolver) Placeholder(src string) (models.ImagePlaceholder, bool) {
+	info, ok := r.ResolveImage(src)
+	if !ok || info.Placeholder.Color == "" {
+		return models.ImagePlaceholder{}, false
+	}
+	return info.Placeholder, true
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestResponsiveWidths(t *testing.T) {
+	widths := []int{2000, 480, 800, 1200}
+
+	tests := []struct {
+		source int
+		want   []int
+	}{
+		{3000, []int{480, 800, 1200, 2000}},
+		{1000, []int{480, 800, 1000}},
+		{800, []int{480, 800}},
+		{300, []int{300}},
+	}
+
+	for _, tt := range tests {
+		if got := ResponsiveWidths(tt.source, widths); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResponsiveWidths(%d) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestImageVariantPath(t *testing.T) {
+	if got := ImageVariantPath("public/static/images/a.webp", 480, "avif"); got != "public/static/images/a-480w.avif" {
+		t.Errorf("ImageVariantPath() = %q", got)
+	}
+}
+
+func writeTestPNG(t *testing.T, fs afero.Fs, path string, width, height int) {
+	t.Helper()
+	f, err := fs.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessImageVFSWritesVariants(t *testing.T) {
+	srcFs := afero.NewMemMapFs()
+	destFs := afero.NewMemMapFs()
+	writeTestPNG(t, srcFs, "static/images/chart.png", 1000, 500)
+
+	opts := ImageOptions{Widths: []int{480, 800, 1200}, Formats: []string{"webp"}, Quality: 80}
+	if err := processImageVFS(srcFs, destFs, "static/images/chart.png", "public/static/images/chart.webp", "", opts); err != nil {
+		t.Fatalf("processImageVFS() error = %v", err)
+	}
+
+	for _, path := range []string{
+		"public/static/images/chart.webp",
+		"public/static/images/chart.png",
+		"public/static/images/chart-480w.webp",
+		"public/static/images/chart-800w.webp",
+		"public/static/images/chart-1000w.webp",
+	} {
+		if ok, _ := afero.Exists(destFs, path); !ok {
+			t.Errorf("missing rendition %s", path)
+		}
+	}
+
+	f, err := destFs.Open("public/static/images/chart.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 1000 || cfg.Height != 500 {
+		t.Errorf("fallback is %dx%d, want 1000x500", cfg.Width, cfg.Height)
+	}
+}
+
+func TestImageResolver(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeTestPNG(t, fs, "themes/blog/static/images/wide.png", 2400, 1200)
+	writeTestPNG(t, fs, "static/images/wide.png", 1600, 400)
+
+	opts := ImageOptions{Widths: []int{480, 2000}, Formats: []string{"webp"}}
+	resolver := NewImageResolver(fs, []string{"static", "themes/blog/static"}, true, NewImageProfiles(opts))
+
+	info, ok := resolver.ResolveImage("/static/images/wide.png")
+	if !ok {
+		t.Fatal("ResolveImage() did not resolve a local image")
+	}
+	want := ImageInfo{Width: 1200, Height: 300, Widths: []int{480, 1600}, Formats: []string{"webp"}, Ext: ".png", WebP: true}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("ResolveImage() = %+v, want %+v", info, want)
+	}
+
+	for _, src := range []string{"https://example.com/a.png", "/static/images/missing.png", "/static/images/anim.gif"} {
+		if _, ok := resolver.ResolveImage(src); ok {
+			t.Errorf("ResolveImage(%q) resolved, want miss", src)
+		}
+	}
+}
+
+func TestProcessImageVFSCacheKeyIncludesProfile(t *testing.T) {
+	srcFs := afero.NewMemMapFs()
+	writeTestPNG(t, srcFs, "static/a.png", 64, 64)
+	cacheDir := t.TempDir()
+
+	countCached := func() int {
+		entries, err := os.ReadDir(cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	opts := ImageOptions{Profile: "default", Formats: []string{"webp"}, Quality: 80}
+	for i := 0; i < 2; i++ {
+		if err := processImageVFS(srcFs, afero.NewMemMapFs(), "static/a.png", "public/static/a.webp", cacheDir, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cached := countCached()
+
+	opts.Lossless = true
+	if err := processImageVFS(srcFs, afero.NewMemMapFs(), "static/a.png", "public/static/a.webp", cacheDir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if countCached() <= cached {
+		t.Errorf("changing the profile did not produce new cache entries (%d before, %d after)", cached, countCached())
+	}
+}
+
+func TestWriteImageWithWebP(t *testing.T) {
+	srcFs := afero.NewMemMapFs()
+	destFs := afero.NewMemMapFs()
+	writeTestPNG(t, srcFs, "plot.png", 4, 4)
+	data, _ := afero.ReadFile(srcFs, "plot.png")
+
+	written, err := WriteImageWithWebP(destFs, "public/posts/nb/plot.png", data)
+	if err != nil {
+		t.Fatalf("WriteImageWithWebP() error = %v", err)
+	}
+	want := []string{"public/posts/nb/plot.png", "public/posts/nb/plot.webp"}
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+	for _, path := range want {
+		if ok, _ := afero.Exists(destFs, path); !ok {
+			t.Errorf("missing %s", path)
+		}
+	}
+
+	written, err = WriteImageWithWebP(destFs, "public/posts/nb/plot.svg", []byte("<svg/>"))
+	if err != nil || len(written) != 1 {
+		t.Errorf("WriteImageWithWebP(svg) = %v, %v, want only the original", written, err)
+	}
+}
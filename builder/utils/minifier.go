@@ -1,9 +1,6 @@
 package utils
 
 import (
-	"regexp"
-	"strings"
-
 	"github.com/tdewolff/minify/v2"
 	"github.com/tdewolff/minify/v2/html"
 )
@@ -20,15 +17,3 @@ func InitMinifier() {
 	}
 	Minifier.Add("text/html", htmlMinifier)
 }
-
-var imgRe = regexp.MustCompile(`(?i)(<img[^>]+src=["'])([^"']+)((?:\.jpg|\.jpeg|\.png))(["'])`)
-
-func ReplaceToWebP(html string) string {
-	return imgRe.ReplaceAllStringFunc(html, func(m string) string {
-		parts := imgRe.FindStringSubmatch(m)
-		if strings.HasPrefix(parts[2], "http") || strings.HasPrefix(parts[2], "//") {
-			return m
-		}
-		return parts[1] + parts[2] + ".webp" + parts[4]
-	})
-}
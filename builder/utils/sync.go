@@ -66,10 +66,11 @@ func SyncVFS(srcFs afero.Fs, targetDir string, dirtyFiles map[string]bool) error
 
 			isAlwaysSync := alwaysSyncPaths[relPath]
 			isStatic := strings.HasPrefix(relPath, "static/")
+			isSearchShard := strings.HasPrefix(relPath, "search/") && strings.HasSuffix(relPath, ".bin")
 			isMarkdown := strings.HasSuffix(relPath, ".md")
 			isDirty := dirtyFiles[pathNormalized]
 
-			if !isDirty && !isAlwaysSync && !isStatic && !isMarkdown {
+			if !isDirty && !isAlwaysSync && !isStatic && !isMarkdown && !isSearchShard {
 				return nil
 			}
 		}
@@ -5,6 +5,18 @@ import (
 	"strings"
 )
 
+// PostHTMLPath maps a post's source path (.md or .ipynb) to its lowercase .html output path
+// Input: "posts/Intro.ipynb"
+// Output: "posts/intro.html"
+func PostHTMLPath(relPath string) string {
+	ext := filepath.Ext(relPath)
+	switch strings.ToLower(ext) {
+	case ".md", ".ipynb":
+		relPath = strings.TrimSuffix(relPath, ext) + ".html"
+	}
+	return strings.ToLower(relPath)
+}
+
 // GetVersionFromPath extracts version from file path
 // Input: "content/v2.0/getting-started.md"
 // Output: "v2.0", "getting-started.md"
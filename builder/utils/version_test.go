@@ -0,0 +1,18 @@
+package utils
+
+import "testing"
+
+func TestPostHTMLPath(t *testing.T) {
+	tests := map[string]string{
+		"posts/Intro.md":         "posts/intro.html",
+		"posts/Intro.ipynb":      "posts/intro.html",
+		"v2.0/nb/Analysis.IPYNB": "v2.0/nb/analysis.html",
+		"docs.md/readme.md":      "docs.md/readme.html",
+		"about.html":             "about.html",
+	}
+	for in, want := range tests {
+		if got := PostHTMLPath(in); got != want {
+			t.Errorf("PostHTMLPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -54,7 +54,7 @@ func printCacheUsage() {
 	fmt.Println("\nSubcommands:")
 	fmt.Println("  stats          Show cache statistics")
 	fmt.Println("  gc             Run garbage collection")
-	fmt.Println("  verify         Check cache integrity")
+	fmt.Println("  verify         Check cache integrity (incl. search index)")
 	fmt.Println("  rebuild        Force full cache rebuild")
 	fmt.Println("  clear          Delete all cache data")
 	fmt.Println("  inspect <path> Show cache entry for a specific file")
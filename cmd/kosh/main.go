@@ -13,6 +13,7 @@ import (
 	"github.com/Kush-Singh-26/kosh/builder/config"
 	"github.com/Kush-Singh-26/kosh/builder/run"
 	"github.com/Kush-Singh-26/kosh/internal/clean"
+	"github.com/Kush-Singh-26/kosh/internal/highlight"
 	"github.com/Kush-Singh-26/kosh/internal/new"
 	"github.com/Kush-Singh-26/kosh/internal/scaffold"
 	"github.com/Kush-Singh-26/kosh/internal/server"
@@ -93,7 +94,7 @@ func main() {
 			}
 
 			go func() {
-				w, err := watch.New([]string{"content", b.Config().TemplateDir, b.Config().StaticDir, "kosh.yaml"}, func(event watch.Event) {
+				w, err := watch.New([]string{"content", b.Config().TemplateDir, b.Config().StaticDir, b.Config().SnippetsDir, b.Config().DataDir, "kosh.yaml"}, func(event watch.Event) {
 					fmt.Printf("\n⚡ Change detected: %s | Rebuilding...\n", event.Name)
 					b.BuildChanged(ctx, event.Name)
 				})
@@ -152,7 +153,7 @@ func main() {
 				os.Exit(1)
 			}
 
-			w, err := watch.New([]string{"content", b.Config().TemplateDir, b.Config().StaticDir, "kosh.yaml"}, func(event watch.Event) {
+			w, err := watch.New([]string{"content", b.Config().TemplateDir, b.Config().StaticDir, b.Config().SnippetsDir, b.Config().DataDir, "kosh.yaml"}, func(event watch.Event) {
 				fmt.Printf("\n⚡ Change detected: %s | Rebuilding...\n", event.Name)
 				b.BuildChanged(ctx, event.Name)
 			})
@@ -182,6 +183,9 @@ func main() {
 	case "cache":
 		handleCacheCommand(args)
 
+	case "highlight-css":
+		highlight.Run(args)
+
 	case "version":
 		if len(args) > 0 && (args[0] == "-info" || args[0] == "--info") {
 			printVersion()
@@ -213,6 +217,7 @@ func printUsage() {
 	fmt.Println("  serve          Start the preview server")
 	fmt.Println("  clean          Clean output directory")
 	fmt.Println("  cache          Cache management commands")
+	fmt.Println("  highlight-css  Export code highlighting CSS")
 	fmt.Println("  version        Version management commands")
 	fmt.Println("  help           Show this help message")
 	fmt.Println("\nBuild Flags:")
@@ -234,12 +239,17 @@ func printUsage() {
 	fmt.Println("\nCache Commands:")
 	fmt.Println("  cache stats          Show cache statistics")
 	fmt.Println("  cache gc             Run garbage collection on cache")
-	fmt.Println("  cache verify         Check cache integrity")
+	fmt.Println("  cache verify         Check cache integrity (incl. search index)")
 	fmt.Println("  cache rebuild        Clear cache for full rebuild")
 	fmt.Println("  cache clear          Delete all cache data")
 	fmt.Println("  cache inspect <path> Show cache entry for a file")
 	fmt.Println("\nCache GC Flags:")
 	fmt.Println("  --dry-run, -n        Show what would be deleted without deleting")
+	fmt.Println("\nHighlight CSS Flags:")
+	fmt.Println("  --style <name>       Default (dark) chroma style (default: highlight.style)")
+	fmt.Println("  --light-style <name> Light theme style (default: highlight.lightStyle)")
+	fmt.Println("  --out <file>         Output file (default: <staticDir>/css/chroma.css)")
+	fmt.Println("  --list               List available styles")
 	fmt.Println("\nVersion Commands:")
 	fmt.Println("  version              Show current documentation version info")
 	fmt.Println("  version <vX.X>       Freeze current latest and start new version")
@@ -6,6 +6,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"syscall/js"
 
 	"github.com/vmihailenco/msgpack/v5"
@@ -14,7 +15,11 @@ import (
 	"github.com/Kush-Singh-26/kosh/builder/search"
 )
 
-var index models.SearchIndex
+var (
+	index models.SearchIndex
+	// sharded is set when initSearch was given a shard manifest instead of search.bin
+	sharded *search.ShardedIndex
+)
 
 func main() {
 	c := make(chan struct{}, 0)
@@ -38,12 +43,23 @@ func initSearch(this js.Value, args []js.Value) interface{} {
 		reject := args[1]
 
 		go func() {
-			data, err := fetchAndDecompress(url)
+			data, err := fetchAndDecompress(url, true)
 			if err != nil {
 				reject.Invoke(fmt.Sprintf("Fetch/Decompress error: %v", err))
 				return
 			}
 
+			// A manifest only decodes with a non-zero format; anything else is a full index
+			var manifest models.SearchManifest
+			if err := msgpack.Unmarshal(data, &manifest); err == nil && manifest.Format > 0 {
+				baseDir := url[:strings.LastIndex(url, "/")+1]
+				sharded = search.NewShardedIndex(manifest, func(ref models.SearchShardRef) ([]byte, error) {
+					return fetchAndDecompress(baseDir+ref.File+"?v="+ref.Hash, false)
+				})
+				resolve.Invoke(manifest.TotalDocs)
+				return
+			}
+
 			dec := msgpack.NewDecoder(bytes.NewReader(data))
 			if err := dec.Decode(&index); err != nil {
 				reject.Invoke(fmt.Sprintf("Decode error: %v", err))
@@ -60,11 +76,18 @@ func initSearch(this js.Value, args []js.Value) interface{} {
 	return promiseConstructor.New(handler)
 }
 
-func fetchAndDecompress(url string) ([]byte, error) {
+// fetchAndDecompress fetches a gzip file. The entry file keeps its URL across
+// builds, so revalidate makes the browser check it instead of trusting its cache;
+// shards are versioned by hash and may come from cache.
+func fetchAndDecompress(url string, revalidate bool) ([]byte, error) {
 	ch := make(chan interface{}, 1)
 
 	window := js.Global()
-	promise := window.Call("fetch", url)
+	init := map[string]interface{}{}
+	if revalidate {
+		init["cache"] = "no-cache"
+	}
+	promise := window.Call("fetch", url, init)
 
 	success := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		resp := args[0]
@@ -127,8 +150,30 @@ func searchPosts(this js.Value, args []js.Value) interface{} {
 		versionFilter = args[1].String()
 	}
 
-	results := search.PerformSearch(&index, query, versionFilter)
+	// Sharded indexes may need to fetch shards first, so results come back as a Promise
+	if sharded != nil {
+		handler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			resolve := args[0]
+			reject := args[1]
+
+			go func() {
+				results, err := sharded.Search(query, versionFilter, "")
+				if err != nil {
+					reject.Invoke(fmt.Sprintf("Search error: %v", err))
+					return
+				}
+				resolve.Invoke(resultsToJS(results))
+			}()
+
+			return nil
+		})
+		return js.Global().Get("Promise").New(handler)
+	}
+
+	return resultsToJS(search.PerformSearch(&index, query, versionFilter, ""))
+}
 
+func resultsToJS(results []search.Result) js.Value {
 	finalResults := make([]interface{}, 0, len(results))
 	for _, res := range results {
 		jsRes := make(map[string]interface{})
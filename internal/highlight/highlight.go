@@ -0,0 +1,117 @@
+package highlight
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+
+	"github.com/Kush-Singh-26/kosh/builder/config"
+)
+
+// LightScope prefixes the selectors of the light style
+const LightScope = `[data-theme="light"] `
+
+// ruleRe matches the start of each rule chroma writes: "/* Name */ .chroma .k {"
+var ruleRe = regexp.MustCompile(`(?m)^(/\* [^*]+ \*/ )`)
+
+// Run exports the configured chroma styles as <staticDir>/css/chroma.css.
+// Flags: --style <name>, --light-style <name>, --out <file>, --list.
+func Run(args []string) {
+	cfg := config.Load([]string{})
+	style, lightStyle := cfg.Highlight.Style, cfg.Highlight.LightStyle
+	out := filepath.Join(cfg.StaticDir, "css", "chroma.css")
+
+	for i := 0; i < len(args); i++ {
+		next := func() string {
+			if i+1 >= len(args) {
+				fmt.Printf("❌ Missing value for %s\n", args[i])
+				os.Exit(1)
+			}
+			i++
+			return args[i]
+		}
+		switch args[i] {
+		case "--list", "-list":
+			names := styles.Names()
+			sort.Strings(names)
+			fmt.Println(strings.Join(names, "\n"))
+			return
+		case "--style", "-style":
+			style = next()
+		case "--light-style", "-light-style":
+			lightStyle = next()
+		case "--out", "-out":
+			out = next()
+		default:
+			fmt.Printf("❌ Unknown flag: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSS(&buf, style, lightStyle); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		fmt.Printf("❌ Failed to create %s: %v\n", filepath.Dir(out), err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		fmt.Printf("❌ Failed to write %s: %v\n", out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("🎨 Wrote %s (style: %s, light: %s)\n", out, style, lightStyle)
+}
+
+// WriteCSS writes the CSS of style, followed by lightStyle scoped to
+// LightScope. An empty lightStyle writes style only.
+func WriteCSS(w io.Writer, style, lightStyle string) error {
+	formatter := chromahtml.New(chromahtml.WithClasses(true))
+
+	dark, err := lookup(style)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "/* Generated by kosh highlight-css: %s */\n", style); err != nil {
+		return err
+	}
+	if err := formatter.WriteCSS(w, dark); err != nil {
+		return err
+	}
+	if lightStyle == "" {
+		return nil
+	}
+
+	light, err := lookup(lightStyle)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := formatter.WriteCSS(&buf, light); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "\n/* Light theme: %s */\n", lightStyle); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, ruleRe.ReplaceAllString(buf.String(), "${1}"+LightScope))
+	return err
+}
+
+// lookup returns a registered style; styles.Get silently falls back instead
+func lookup(name string) (*chroma.Style, error) {
+	style, ok := styles.Registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown highlight style %q (see kosh highlight-css --list)", name)
+	}
+	return style, nil
+}
@@ -0,0 +1,41 @@
+package highlight
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSS(&buf, "nord", "github"); err != nil {
+		t.Fatalf("WriteCSS() error = %v", err)
+	}
+	css := buf.String()
+	dark, light, ok := strings.Cut(css, "/* Light theme: github */")
+	if !ok {
+		t.Fatalf("no light section:\n%s", css)
+	}
+	if !strings.Contains(dark, "/* Keyword */ .chroma .k {") || strings.Contains(dark, LightScope) {
+		t.Errorf("dark style should be unscoped:\n%s", dark)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(light), "\n") {
+		if !strings.Contains(line, "*/ "+LightScope) {
+			t.Errorf("light rule not scoped: %q", line)
+		}
+	}
+}
+
+func TestWriteCSSErrors(t *testing.T) {
+	tests := []struct {
+		style, light string
+	}{
+		{"no-such-style", ""},
+		{"nord", "no-such-style"},
+	}
+	for _, tt := range tests {
+		if err := WriteCSS(&bytes.Buffer{}, tt.style, tt.light); err == nil {
+			t.Errorf("WriteCSS(%q, %q) expected error", tt.style, tt.light)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/search"
+)
+
+// searchAPI serves /api/search over the search.bin of the output directory.
+// The index is loaded lazily and dropped on every rebuild, so the next request
+// sees the freshly written index.
+type searchAPI struct {
+	dir   string
+	mu    sync.Mutex
+	index *models.SearchIndex
+}
+
+type searchResponse struct {
+	Query   string         `json:"query"`
+	Version string         `json:"version"`
+	Tag     string         `json:"tag,omitempty"`
+	Results []searchResult `json:"results"`
+}
+
+type searchResult struct {
+	Title       string             `json:"title"`
+	Link        string             `json:"link"`
+	Description string             `json:"description,omitempty"`
+	Snippet     string             `json:"snippet"`
+	Version     string             `json:"version,omitempty"`
+	Score       float64            `json:"score"`
+	Explain     []search.TermScore `json:"explain,omitempty"`
+}
+
+func newSearchAPI(dir string) *searchAPI {
+	return &searchAPI{dir: dir}
+}
+
+// invalidate drops the loaded index; called after each rebuild
+func (a *searchAPI) invalidate() {
+	a.mu.Lock()
+	a.index = nil
+	a.mu.Unlock()
+}
+
+func (a *searchAPI) load() (*models.SearchIndex, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.index != nil {
+		return a.index, nil
+	}
+
+	file, err := os.Open(filepath.Join(a.dir, "search.bin"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search index: %w", err)
+	}
+	defer func() { _ = gr.Close() }()
+
+	var index models.SearchIndex
+	if err := msgpack.NewDecoder(gr).Decode(&index); err != nil {
+		return nil, fmt.Errorf("invalid search index: %w", err)
+	}
+	a.index = &index
+	return a.index, nil
+}
+
+func (a *searchAPI) handleSearch(w http.ResponseWriter, r *http.Request) {
+	a.serve(w, r, search.PerformSearch)
+}
+
+func (a *searchAPI) handleExplain(w http.ResponseWriter, r *http.Request) {
+	a.serve(w, r, search.ExplainSearch)
+}
+
+func (a *searchAPI) serve(w http.ResponseWriter, r *http.Request, run func(*models.SearchIndex, string, string, string) []search.Result) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	params := r.URL.Query()
+	query := strings.TrimSpace(params.Get("q"))
+	version := params.Get("version")
+	tag := strings.TrimSpace(params.Get("tag"))
+	if query == "" && tag == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing q parameter")
+		return
+	}
+
+	index, err := a.load()
+	if err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "search index not available: "+err.Error())
+		return
+	}
+
+	resp := searchResponse{Query: query, Version: version, Tag: tag, Results: []searchResult{}}
+	for _, res := range run(index, query, version, tag) {
+		resp.Results = append(resp.Results, searchResult{
+			Title:       res.Title,
+			Link:        res.Link,
+			Description: res.Description,
+			Snippet:     res.Snippet,
+			Version:     res.Version,
+			Score:       res.Score,
+			Explain:     res.Explain,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/Kush-Singh-26/kosh/builder/generators"
+	"github.com/Kush-Singh-26/kosh/builder/models"
+	"github.com/Kush-Singh-26/kosh/builder/search"
+)
+
+func writeTestIndex(t *testing.T, dir string, titles ...string) {
+	t.Helper()
+	posts := make([]models.IndexedPost, len(titles))
+	for i, title := range titles {
+		tags, normalized := []string{"ml"}, []string{"ml"}
+		if strings.Contains(title, "convolution") {
+			tags, normalized = append(tags, "Deep Learning"), append(normalized, "deep learning")
+		}
+		words := search.DefaultAnalyzer.Analyze(title)
+		freqs := make(map[string]int)
+		for _, w := range words {
+			freqs[w]++
+		}
+		posts[i] = models.IndexedPost{
+			Record: models.PostRecord{
+				ID: i, Title: title, Link: title + ".html", Content: title,
+				Tags: tags, NormalizedTags: normalized,
+			},
+			WordFreqs: freqs,
+			DocLen:    len(words),
+		}
+	}
+	if err := generators.GenerateSearchIndex(afero.NewOsFs(), dir, posts, generators.SearchIndexOptions{}); err != nil {
+		t.Fatalf("GenerateSearchIndex: %v", err)
+	}
+}
+
+func getSearch(t *testing.T, handler http.HandlerFunc, url string) (int, searchResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	var resp searchResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestSearchAPI(t *testing.T) {
+	dir := t.TempDir()
+	api := newSearchAPI(dir)
+
+	if code, _ := getSearch(t, api.handleSearch, "/api/search?q=attention"); code != http.StatusServiceUnavailable {
+		t.Errorf("status without index = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if code, _ := getSearch(t, api.handleSearch, "/api/search"); code != http.StatusBadRequest {
+		t.Errorf("status without query = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	writeTestIndex(t, dir, "attention mechanisms", "convolution kernels")
+
+	code, resp := getSearch(t, api.handleSearch, "/api/search?q=attention&tag=ml")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Link != "attention mechanisms.html" {
+		t.Fatalf("results = %+v", resp.Results)
+	}
+	if resp.Results[0].Explain != nil {
+		t.Errorf("plain search should not include explanations")
+	}
+
+	_, resp = getSearch(t, api.handleExplain, "/api/search/explain?q=attention")
+	if len(resp.Results) != 1 || len(resp.Results[0].Explain) == 0 {
+		t.Fatalf("explain results = %+v", resp.Results)
+	}
+	if e := resp.Results[0].Explain[0]; e.Kind != "term" || e.Term != "attent" || e.DF != 1 {
+		t.Errorf("explain[0] = %+v", e)
+	}
+
+	// Tags are matched whole, so one with a space must not be rewritten
+	_, resp = getSearch(t, api.handleSearch, "/api/search?q=kernels&tag=Deep+Learning")
+	if len(resp.Results) != 1 || resp.Results[0].Link != "convolution kernels.html" || resp.Tag != "Deep Learning" {
+		t.Errorf("tag with space results = %+v", resp)
+	}
+	_, resp = getSearch(t, api.handleSearch, "/api/search?tag=deep+learning")
+	if len(resp.Results) != 1 {
+		t.Errorf("tag-only results = %+v", resp.Results)
+	}
+
+	// A rebuild replaces the index; the next request must see it after invalidation
+	writeTestIndex(t, dir, "pooling layers")
+	api.invalidate()
+	_, resp = getSearch(t, api.handleSearch, "/api/search?q=pooling")
+	if len(resp.Results) != 1 {
+		t.Errorf("results after reload = %+v", resp.Results)
+	}
+}
@@ -57,7 +57,11 @@ func Run(ctx context.Context, args []string, outputDir string, buildCfg *config.
 
 	fileServer := http.FileServer(http.Dir(staticDir))
 
+	searchAPI := newSearchAPI(staticDir)
+
 	http.HandleFunc("/events", handleSSE)
+	http.HandleFunc("/api/search", gzipHandler(searchAPI.handleSearch))
+	http.HandleFunc("/api/search/explain", gzipHandler(searchAPI.handleExplain))
 
 	http.HandleFunc("/", gzipHandler(func(w http.ResponseWriter, r *http.Request) {
 		rawPath := r.URL.Path
@@ -101,7 +105,7 @@ func Run(ctx context.Context, args []string, outputDir string, buildCfg *config.
 		fileServer.ServeHTTP(w, r)
 	}))
 
-	go broadcastReload()
+	go broadcastReload(searchAPI.invalidate)
 
 	httpServer := &http.Server{
 		Addr:    addr,
@@ -123,6 +127,7 @@ func Run(ctx context.Context, args []string, outputDir string, buildCfg *config.
 		fmt.Println("   (Accessible on your local network)")
 	}
 	fmt.Println("   (Auto-reload enabled via /events)")
+	fmt.Println("   (Search API at /api/search?q=...)")
 
 	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
 		log.Fatal(err)
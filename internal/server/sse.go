@@ -35,8 +35,13 @@ func handleSSE(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func broadcastReload() {
+// broadcastReload notifies SSE clients of every rebuild, running the
+// onReload hooks first so reloaded pages never see stale server state
+func broadcastReload(onReload ...func()) {
 	for range reloadChan {
+		for _, hook := range onReload {
+			hook()
+		}
 		clientMu.Lock()
 		for clientChan := range clients {
 			select {
@@ -30,6 +30,9 @@
 
         // 7. Theme Toggle Enhancement
         initThemeToggle();
+
+        // 8. Tabs & Code Groups
+        initTabs();
     }
 
     // 1. Reading Progress Bar
@@ -66,7 +69,12 @@
                 const code = pre.querySelector('code');
                 if (!code) return;
 
-                const text = code.textContent.trimEnd();
+                // Copy code only: no line numbers, and no removed lines of diffs
+                const lines = Array.from(code.querySelectorAll('.line'));
+                const text = (lines.length
+                    ? lines.filter(line => !line.classList.contains('diff-del'))
+                        .map(line => (line.querySelector('.cl') || line).textContent).join('')
+                    : code.textContent).trimEnd();
 
                 try {
                     await navigator.clipboard.writeText(text);
@@ -262,4 +270,60 @@
             localStorage.setItem('theme', nextTheme);
         });
     }
+
+    // 8. Tabs & Code Groups
+    function initTabs() {
+        const tabs = document.querySelectorAll('.tabs [role="tab"]');
+        if (!tabs.length) return;
+
+        function select(tab) {
+            const list = tab.closest('[role="tablist"]');
+            list.querySelectorAll('[role="tab"]').forEach(t => {
+                const selected = t === tab;
+                t.setAttribute('aria-selected', selected);
+                t.tabIndex = selected ? 0 : -1;
+                const panel = document.getElementById(t.getAttribute('aria-controls'));
+                if (panel) panel.hidden = !selected;
+            });
+        }
+
+        // Selecting a tab selects the same label in every other set
+        function selectEverywhere(key) {
+            tabs.forEach(t => {
+                if (t.dataset.tab === key) select(t);
+            });
+            localStorage.setItem('tab', key);
+        }
+
+        const saved = localStorage.getItem('tab');
+        if (saved) {
+            tabs.forEach(t => {
+                if (t.dataset.tab === saved) select(t);
+            });
+        }
+
+        tabs.forEach(tab => {
+            tab.addEventListener('click', () => {
+                const top = tab.getBoundingClientRect().top;
+                selectEverywhere(tab.dataset.tab);
+                // Keep the clicked tab in place when sets above change height
+                window.scrollBy(0, tab.getBoundingClientRect().top - top);
+            });
+
+            tab.addEventListener('keydown', (e) => {
+                const siblings = Array.from(tab.closest('[role="tablist"]').querySelectorAll('[role="tab"]'));
+                const i = siblings.indexOf(tab);
+                let next;
+                if (e.key === 'ArrowRight') next = siblings[(i + 1) % siblings.length];
+                else if (e.key === 'ArrowLeft') next = siblings[(i - 1 + siblings.length) % siblings.length];
+                else if (e.key === 'Home') next = siblings[0];
+                else if (e.key === 'End') next = siblings[siblings.length - 1];
+                if (!next) return;
+
+                e.preventDefault();
+                select(next);
+                next.focus();
+            });
+        });
+    }
 })();
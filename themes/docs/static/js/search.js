@@ -52,8 +52,9 @@
                     const result = await WebAssembly.instantiateStreaming(response, go.importObject);
                     go.run(result.instance);
 
-                    const binPath = joinPath(baseURL, '/search.bin');
-                    await window.initSearch(binPath);
+                    // Sharded index: only the manifest is fetched up front
+                    const manifestPath = joinPath(baseURL, '/search/manifest.bin');
+                    await window.initSearch(manifestPath);
 
                     wasmLoaded = true;
                 } catch (err) {
@@ -86,7 +87,9 @@
             });
         }
 
-        function performSearch() {
+        let searchSeq = 0;
+
+        async function performSearch() {
             if (!wasmLoaded || !searchInput) return;
             const query = searchInput.value.trim();
             if (!query) {
@@ -95,9 +98,12 @@
             }
 
             const versionFilter = searchAllVersions && searchAllVersions.checked ? "all" : getCurrentVersion();
+            const seq = ++searchSeq;
             
             try {
-                const results = window.searchPosts(query, versionFilter);
+                // Resolves after any shards needed by the query are fetched
+                const results = await window.searchPosts(query, versionFilter);
+                if (seq !== searchSeq) return; // A newer query is in flight
                 renderResults(results);
             } catch (err) {
                 console.error("Search execution failed:", err);